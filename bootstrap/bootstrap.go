@@ -0,0 +1,166 @@
+// Package bootstrap builds the infrastructure shared by every service, such as Kafka clients,
+// Postgres pools and HTTP servers, from their configuration.
+package bootstrap
+
+import (
+	"context"
+	"crypto/tls"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"golang.org/x/exp/slog"
+
+	"github.com/giornetta/microshop/config"
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/kafka"
+	"github.com/giornetta/microshop/log"
+	"github.com/giornetta/microshop/notify"
+	"github.com/giornetta/microshop/postgres"
+	"github.com/giornetta/microshop/server"
+	"github.com/giornetta/microshop/tracing"
+)
+
+// KafkaClient returns a client connecting to the brokers of cfg with its security and tuning options,
+// followed by opts.
+func KafkaClient(cfg *config.KafkaConfig, opts ...kgo.Opt) (*kgo.Client, error) {
+	security, err := kafka.SecurityOpts(&kafka.SecurityOptions{
+		TLS:                cfg.TLS.Enabled,
+		CAFile:             cfg.TLS.CAFile,
+		CertFile:           cfg.TLS.CertFile,
+		KeyFile:            cfg.TLS.KeyFile,
+		InsecureSkipVerify: cfg.TLS.InsecureSkipVerify,
+		SASLMechanism:      cfg.SASL.Mechanism,
+		Username:           cfg.SASL.Username,
+		Password:           string(cfg.SASL.Password),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tuning, err := kafka.TuningOpts(&kafka.TuningOptions{
+		ClientID:              cfg.ClientID,
+		AutoCreateTopics:      cfg.AutoCreateTopics,
+		SessionTimeout:        cfg.SessionTimeout,
+		RebalanceTimeout:      cfg.RebalanceTimeout,
+		FetchMaxWait:          cfg.FetchMaxWait,
+		FetchMaxBytes:         cfg.FetchMaxBytes,
+		ProducerAcks:          cfg.ProducerAcks,
+		ProducerLinger:        cfg.ProducerLinger,
+		ProducerBatchMaxBytes: cfg.ProducerBatchMaxBytes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	all := []kgo.Opt{kgo.SeedBrokers(cfg.BrokerAddrs...)}
+	all = append(all, security...)
+	all = append(all, tuning...)
+
+	return kgo.NewClient(append(all, opts...)...)
+}
+
+// Publisher returns a Publisher producing events with client, as CloudEvents with the given source
+// when cfg sets a binding.
+func Publisher(client *kgo.Client, codecs *events.Codecs, cfg *config.KafkaConfig, source string) (events.Publisher, error) {
+	if cfg.Binding == "" {
+		return kafka.NewEventPublisher(client, codecs), nil
+	}
+
+	mode, err := kafka.ParseBindingMode(cfg.Binding)
+	if err != nil {
+		return nil, err
+	}
+
+	return kafka.NewCloudEventPublisher(client, codecs, source, mode), nil
+}
+
+// Postgres returns a pool connected to the database of cfg, applying the pending migrations first
+// unless auto-migration is disabled.
+func Postgres(cfg *config.PostgresConfig, migrations fs.FS, ctx context.Context) (*pgxpool.Pool, error) {
+	if cfg.AutoMigrate {
+		if err := postgres.Migrate(migrations, cfg.MigrationsConnectionString()); err != nil {
+			return nil, err
+		}
+	}
+
+	return postgres.Connect(ctx, cfg.ConnectionString())
+}
+
+// Server returns an HTTP server for h, serving HTTPS when cfg enables TLS.
+// Certificates that cannot be reloaded are logged with logger.
+func Server(h http.Handler, cfg *config.ServerConfig, logger *slog.Logger) (*http.Server, error) {
+	var tlsConfig *tls.Config
+	if cfg.TLS.Enabled() {
+		var err error
+		tlsConfig, err = server.NewTLSConfig(&server.TLSOptions{
+			CertFile:     cfg.TLS.CertFile,
+			KeyFile:      cfg.TLS.KeyFile,
+			ClientCAFile: cfg.TLS.ClientCAFile,
+			Logger:       logger,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return server.New(h, &server.Options{
+		Port:              cfg.Port,
+		TLSConfig:         tlsConfig,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}), nil
+}
+
+// Tracing sets up the global tracer provider for the named service, returning its shutdown function.
+func Tracing(name string, cfg *config.TracingConfig, ctx context.Context) (func(context.Context) error, error) {
+	return tracing.Setup(&tracing.Options{
+		ServiceName: name,
+		Exporter:    cfg.Exporter,
+		Endpoint:    cfg.Endpoint,
+		Insecure:    cfg.Insecure,
+	}, ctx)
+}
+
+// Logger returns the logger configured by cfg, writing to stderr.
+func Logger(cfg *config.LogConfig) (*slog.Logger, error) {
+	return log.New(os.Stderr, &log.Options{
+		Format: cfg.Format,
+		Level:  cfg.Level,
+		Levels: cfg.Levels,
+	})
+}
+
+// Notifications returns the sink delivering notifications to every sink enabled by cfg,
+// or nil when none is.
+func Notifications(cfg *config.NotificationsConfig, logger *slog.Logger) notify.Sink {
+	var sinks []notify.Sink
+	for _, name := range cfg.Sinks {
+		switch name {
+		case "log":
+			sinks = append(sinks, notify.NewLogSink(logger))
+		case "webhook":
+			sinks = append(sinks, notify.WithTimeout(notify.NewWebhookSink(cfg.Webhook.URL, nil), cfg.Webhook.Timeout))
+		case "email":
+			var auth smtp.Auth
+			if cfg.Email.Username != "" {
+				host, _, _ := net.SplitHostPort(cfg.Email.SMTPAddr)
+				auth = smtp.PlainAuth("", cfg.Email.Username, string(cfg.Email.Password), host)
+			}
+
+			sinks = append(sinks, notify.WithTimeout(notify.NewEmailSink(cfg.Email.SMTPAddr, cfg.Email.From, cfg.Email.To, auth), cfg.Email.Timeout))
+		}
+	}
+
+	if len(sinks) == 0 {
+		return nil
+	}
+
+	return notify.Multi(sinks...)
+}
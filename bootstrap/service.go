@@ -0,0 +1,188 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"golang.org/x/exp/slog"
+
+	"github.com/giornetta/microshop/cloudevents"
+	"github.com/giornetta/microshop/config"
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/events/encoding"
+	"github.com/giornetta/microshop/health"
+	"github.com/giornetta/microshop/kafka"
+	"github.com/giornetta/microshop/lifecycle"
+	"github.com/giornetta/microshop/log"
+	"github.com/giornetta/microshop/metrics"
+	"github.com/giornetta/microshop/postgres"
+	"github.com/giornetta/microshop/tracing"
+)
+
+// Service describes the parts of a service that differ from the others. Start wires everything else.
+type Service struct {
+	// Name identifies the service in logs, components and the source of its CloudEvents.
+	Name string
+	// Topic is where the service publishes its events. Those POSTed to /api/v1/events are accepted only if
+	// they belong to it, and the service passed an Ingester to Deps.AcceptEvents.
+	Topic events.Topic
+	// Migrations are applied to the database of the service before it is built. A service without them
+	// keeps nothing in Postgres: Start does not connect to it, and leaves Deps.Pool nil.
+	Migrations fs.FS
+
+	// Build returns the router of the service API, registering its event handlers on the listener
+	// and its background components on the Deps.
+	Build func(d *Deps) http.Handler
+}
+
+// Deps is the infrastructure a Service is built with.
+type Deps struct {
+	Config    *config.Config
+	Logger    *slog.Logger
+	Pool      *pgxpool.Pool
+	Listener  *kafka.Listener
+	Publisher events.Publisher
+
+	background []background
+	ingester   cloudevents.Ingester
+}
+
+type background struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// Go adds a background component to the service, such as a scheduler, run along with its server and listener
+// with the same contract as lifecycle.Runner.Go.
+func (d *Deps) Go(name string, run func(ctx context.Context) error) {
+	d.background = append(d.background, background{name: name, run: run})
+}
+
+// AcceptEvents lets the events of the service be POSTed to /api/v1/events, passing them to ingester.
+// The endpoint is only served when the server is configured with an events token.
+func (d *Deps) AcceptEvents(ingester cloudevents.Ingester) {
+	d.ingester = ingester
+}
+
+// Start connects svc to Kafka and Postgres, then adds its HTTP server, event listener and background components
+// to runner, along with the cleanup functions flushing and closing its clients.
+func Start(svc *Service, cfg *config.Config, logger *slog.Logger, runner *lifecycle.Runner, ctx context.Context) (err error) {
+	if cfg.Kafka.ConsumerGroup == "" {
+		return errors.New("kafka consumer-group is required to run a service")
+	}
+
+	client, err := KafkaClient(&cfg.Kafka, kgo.ConsumerGroup(cfg.Kafka.ConsumerGroup))
+	if err != nil {
+		return fmt.Errorf("could not create kafka client: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			client.Close()
+		}
+	}()
+
+	codecs, err := encoding.NewCodecs(cfg.Kafka.SchemaRegistry, cfg.Kafka.Encodings)
+	if err != nil {
+		return fmt.Errorf("could not set up event codecs: %w", err)
+	}
+
+	publisher, err := Publisher(client, codecs, &cfg.Kafka, "/microshop/"+svc.Name)
+	if err != nil {
+		return fmt.Errorf("could not set up publisher: %w", err)
+	}
+	publisher = metrics.NewPublisher(publisher)
+
+	listener := kafka.NewListener(client, codecs)
+
+	var pool *pgxpool.Pool
+	if svc.Migrations != nil {
+		pool, err = Postgres(&cfg.Postgres, svc.Migrations, ctx)
+		if err != nil {
+			return fmt.Errorf("could not connect to postgres: %w", err)
+		}
+		defer func() {
+			if err != nil {
+				pool.Close()
+			}
+		}()
+	}
+
+	deps := &Deps{
+		Config:    cfg,
+		Logger:    log.Named(logger, svc.Name),
+		Pool:      pool,
+		Listener:  listener,
+		Publisher: publisher,
+	}
+	api := svc.Build(deps)
+
+	checks := health.NewRegistry()
+	checks.RegisterLiveness(kafka.NewListenerChecker(listener))
+
+	collectors := []prometheus.Collector{metrics.NewLagCollector(client, cfg.Kafka.ConsumerGroup)}
+	if pool != nil {
+		collectors = append(collectors, metrics.NewPoolCollector(pool, cfg.Postgres.Database))
+		checks.RegisterReadiness(postgres.NewHealthChecker(pool))
+	}
+	checks.RegisterReadiness(kafka.NewBrokerChecker(client))
+	if cfg.Kafka.MaxConsumerLag > 0 {
+		checks.RegisterReadiness(kafka.NewLagChecker(client, cfg.Kafka.ConsumerGroup, cfg.Kafka.MaxConsumerLag))
+	}
+
+	if err := metrics.Register(collectors...); err != nil {
+		return fmt.Errorf("could not register metrics: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			metrics.Unregister(collectors...)
+		}
+	}()
+
+	router := chi.NewRouter()
+	router.Handle("/healthz", checks.LivenessHandler())
+	router.Handle("/readyz", checks.ReadinessHandler())
+	router.Handle("/metrics", metrics.Handler())
+	router.Group(func(r chi.Router) {
+		r.Use(tracing.Middleware, metrics.Middleware, log.Middleware(log.Named(logger, "http")))
+		r.Mount("/", api)
+		if deps.ingester != nil && cfg.Server.EventsToken != "" {
+			r.Handle("/api/v1/events", cloudevents.NewHandler(deps.ingester, codecs, string(cfg.Server.EventsToken), svc.Topic))
+		}
+	})
+
+	s, err := Server(router, &cfg.Server, log.Named(logger, "server"))
+	if err != nil {
+		return fmt.Errorf("could not configure server: %w", err)
+	}
+
+	runner.Go(svc.Name+" server", lifecycle.HTTPServer(s, cfg.Server.ShutdownTimeout))
+	runner.Go(svc.Name+" listener", listener.Listen)
+	for _, b := range deps.background {
+		runner.Go(svc.Name+" "+b.name, b.run)
+	}
+	runner.Cleanup(svc.Name+" kafka", func(ctx context.Context) error {
+		defer client.Close()
+		return client.Flush(ctx)
+	})
+	if pool != nil {
+		runner.Cleanup(svc.Name+" postgres", func(context.Context) error {
+			pool.Close()
+			return nil
+		})
+	}
+	// The collectors of closed clients are removed, so that the service can be started again in the same process.
+	runner.Cleanup(svc.Name+" metrics", func(context.Context) error {
+		metrics.Unregister(collectors...)
+		return nil
+	})
+
+	logger.Info("Server started", slog.Int("port", cfg.Server.Port), slog.Bool("tls", s.TLSConfig != nil))
+	return nil
+}
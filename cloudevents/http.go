@@ -0,0 +1,226 @@
+// Package cloudevents accepts CloudEvents over HTTP, so that external systems can inject events into microshop.
+package cloudevents
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/giornetta/microshop/errors"
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/respond"
+)
+
+const maxBodySize = 1 << 20
+
+// Received is an event accepted by the handler, along with the CloudEvent it was received as.
+type Received struct {
+	Event      events.Event
+	CloudEvent *events.CloudEvent
+}
+
+// Ingester validates the events accepted by the handler, then stores or publishes them.
+type Ingester interface {
+	// Ingest ingests all the events, in order, or none of them if any is invalid, failing with an error
+	// whose StatusCode is below 500. It fails with *ErrPartiallyIngested if it could ingest only some of them.
+	Ingest(received []Received, ctx context.Context) error
+}
+
+// ErrPartiallyIngested is returned by an Ingester that could only ingest the first events it was given,
+// which must not be sent again.
+type ErrPartiallyIngested struct {
+	Ingested int
+	Err      error
+}
+
+func (err *ErrPartiallyIngested) Error() string {
+	return fmt.Sprintf("only the first %d events were ingested", err.Ingested)
+}
+
+func (err *ErrPartiallyIngested) Cause() error {
+	return err.Err
+}
+
+func (err *ErrPartiallyIngested) StatusCode() int {
+	return http.StatusInternalServerError
+}
+
+type handler struct {
+	ingester Ingester
+	codecs   *events.Codecs
+	token    string
+	topics   map[events.Topic]bool
+}
+
+// NewHandler returns a handler accepting CloudEvents POSTed in the binary, structured or batched
+// HTTP content modes, and passing them to the ingester. Only events belonging to the given topics are accepted,
+// from requests carrying token as their bearer token. Every request is refused when token is empty.
+func NewHandler(ingester Ingester, codecs *events.Codecs, token string, topics ...events.Topic) http.Handler {
+	h := &handler{
+		ingester: ingester,
+		codecs:   codecs,
+		token:    token,
+		topics:   make(map[events.Topic]bool),
+	}
+
+	for _, t := range topics {
+		h.topics[t] = true
+	}
+
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		respond.JSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method Not Allowed"})
+		return
+	}
+
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		respond.JSON(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		if _, ok := err.(*http.MaxBytesError); ok {
+			respond.JSON(w, http.StatusRequestEntityTooLarge, map[string]string{
+				"error": fmt.Sprintf("request body is larger than %d bytes", maxBodySize),
+			})
+			return
+		}
+
+		respond.Err(w, &errors.ErrBadRequest{})
+		return
+	}
+
+	ces, err := parseRequest(r.Header, body)
+	if err != nil {
+		respond.Err(w, &errors.ErrBadRequest{Err: err})
+		return
+	}
+
+	if len(ces) == 0 {
+		respond.Err(w, &errors.ErrBadRequest{Err: fmt.Errorf("no events were given")})
+		return
+	}
+
+	received := make([]Received, len(ces))
+	for i, ce := range ces {
+		evt, err := h.event(ce)
+		if err != nil {
+			respond.Err(w, &errors.ErrBadRequest{Err: err})
+			return
+		}
+
+		received[i] = Received{Event: evt, CloudEvent: ce}
+	}
+
+	if err := h.ingester.Ingest(received, r.Context()); err != nil {
+		respond.Err(w, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusAccepted, nil)
+}
+
+// authorized reports whether the request carries the bearer token of the handler.
+func (h *handler) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && h.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+func (h *handler) event(ce *events.CloudEvent) (events.Event, error) {
+	if err := ce.Validate(); err != nil {
+		return nil, err
+	}
+
+	reg, ok := events.Lookup(ce.Type)
+	if !ok || !h.topics[reg.Topic] {
+		return nil, fmt.Errorf("event type %s is not accepted", ce.Type)
+	}
+
+	evt, err := ce.Event(h.codecs)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode event %s: %w", ce.ID, err)
+	}
+
+	if ce.Subject != "" && ce.Subject != evt.Key().String() {
+		return nil, fmt.Errorf("subject of event %s does not match its key", ce.ID)
+	}
+
+	return evt, nil
+}
+
+func parseRequest(header http.Header, body []byte) ([]*events.CloudEvent, error) {
+	contentType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		contentType = ""
+	}
+
+	switch contentType {
+	case events.CloudEventsJSONContentType:
+		var ce events.CloudEvent
+		if err := json.Unmarshal(body, &ce); err != nil {
+			return nil, err
+		}
+
+		return []*events.CloudEvent{&ce}, nil
+	case events.CloudEventsBatchContentType:
+		var ces []*events.CloudEvent
+		if err := json.Unmarshal(body, &ces); err != nil {
+			return nil, err
+		}
+
+		return ces, nil
+	default:
+		ce, err := parseBinary(header, contentType, body)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*events.CloudEvent{ce}, nil
+	}
+}
+
+func parseBinary(header http.Header, contentType string, body []byte) (*events.CloudEvent, error) {
+	if v := header.Get("ce-specversion"); v != events.CloudEventsSpecVersion {
+		return nil, fmt.Errorf("cloudevents spec version %q is not supported", v)
+	}
+
+	ce := &events.CloudEvent{
+		ID:              header.Get("ce-id"),
+		Source:          header.Get("ce-source"),
+		Type:            events.Type(header.Get("ce-type")),
+		Subject:         header.Get("ce-subject"),
+		DataContentType: contentType,
+		Data:            body,
+	}
+
+	if v := header.Get("ce-time"); v != "" {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ce-time header: %w", err)
+		}
+		ce.Time = t
+	}
+
+	if v := header.Get("ce-" + events.VersionExtension); v != "" {
+		version, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ce-%s header: %w", events.VersionExtension, err)
+		}
+		ce.Version = version
+	}
+
+	return ce, nil
+}
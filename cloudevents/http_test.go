@@ -0,0 +1,75 @@
+package cloudevents
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/giornetta/microshop/events"
+)
+
+type ingesterFunc func(received []Received, ctx context.Context) error
+
+func (f ingesterFunc) Ingest(received []Received, ctx context.Context) error { return f(received, ctx) }
+
+func TestBodySize(t *testing.T) {
+	batch := func(description string) string {
+		b, err := json.Marshal([]map[string]any{{
+			"specversion":     events.CloudEventsSpecVersion,
+			"id":              "event-1",
+			"source":          "/test",
+			"type":            events.ProductCreatedType,
+			"subject":         "6b0f7a64-3a36-4b8f-9d0c-5f2d7a9b1e01",
+			"datacontenttype": "application/json",
+			"data": map[string]any{
+				"product_id":  "6b0f7a64-3a36-4b8f-9d0c-5f2d7a9b1e01",
+				"name":        "Keyboard",
+				"description": description,
+				"price":       49.5,
+			},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return string(b)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "within the limit", body: batch("A mechanical keyboard"), want: http.StatusAccepted},
+		{name: "over the limit", body: batch(strings.Repeat("a", maxBodySize)), want: http.StatusRequestEntityTooLarge},
+		{name: "truncated at the limit", body: strings.Repeat(" ", maxBodySize) + batch("A mechanical keyboard"), want: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ingested := 0
+			h := NewHandler(ingesterFunc(func(received []Received, _ context.Context) error {
+				ingested += len(received)
+				return nil
+			}), events.NewCodecs(), "token", events.ProductTopic)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/events", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", events.CloudEventsBatchContentType)
+			req.Header.Set("Authorization", "Bearer token")
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("got status %d (%s), want %d", rec.Code, rec.Body, tt.want)
+			}
+
+			if wantIngested := tt.want == http.StatusAccepted; (ingested > 0) != wantIngested {
+				t.Errorf("ingested %d events", ingested)
+			}
+		})
+	}
+}
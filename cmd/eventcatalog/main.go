@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/events/asyncapi"
+)
+
+func main() {
+	out := flag.String("out", "./catalog", "directory where the AsyncAPI document and the JSON Schemas are written")
+	check := flag.String("check", "", "directory of a previously generated catalog to check for breaking changes")
+	version := flag.String("version", "1.0.0", "version of the generated AsyncAPI document")
+	flag.Parse()
+
+	regs := events.Registrations()
+
+	if *check != "" {
+		if err := checkCatalog(*check, regs); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	if err := writeCatalog(*out, *version, regs); err != nil {
+		log.Fatalf("could not write catalog: %v", err)
+	}
+}
+
+func writeCatalog(dir, version string, regs []*events.Registration) error {
+	if err := os.MkdirAll(filepath.Join(dir, "schemas"), 0o755); err != nil {
+		return err
+	}
+
+	doc := asyncapi.Generate(asyncapi.Info{
+		Title:       "Microshop Events",
+		Version:     version,
+		Description: "Events published by the microshop services.",
+	}, regs)
+
+	f, err := os.Create(filepath.Join(dir, "asyncapi.yaml"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := yaml.NewEncoder(f)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	for _, reg := range regs {
+		payload, err := json.MarshalIndent(reg.Schema, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(schemaPath(dir, reg.Type), payload, 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func checkCatalog(dir string, regs []*events.Registration) error {
+	broken := false
+
+	for _, reg := range regs {
+		payload, err := os.ReadFile(schemaPath(dir, reg.Type))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		var old events.Schema
+		if err := json.Unmarshal(payload, &old); err != nil {
+			return fmt.Errorf("could not parse schema of %s: %w", reg.Type, err)
+		}
+
+		for _, issue := range events.CheckCompatibility(&old, reg.Schema) {
+			fmt.Printf("%s %s\n", reg.Type, issue)
+			broken = true
+		}
+	}
+
+	if broken {
+		return fmt.Errorf("event schemas are not compatible with %s", dir)
+	}
+
+	return nil
+}
+
+func schemaPath(dir string, t events.Type) string {
+	return filepath.Join(dir, "schemas", t.String()+".json")
+}
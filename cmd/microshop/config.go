@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"golang.org/x/exp/slog"
+	"gopkg.in/yaml.v3"
+
+	"github.com/giornetta/microshop/config"
+)
+
+// printConfig prints the effective configuration of a service, after applying the defaults,
+// the configuration file, environment variables and -set overrides. Secrets are redacted.
+func printConfig(args []string, _ *slog.Logger) error {
+	fs := flag.NewFlagSet("config", flag.ExitOnError)
+	var loader config.Loader
+	loader.RegisterFlags(fs)
+	fs.Parse(args)
+
+	cfg, err := loader.Load()
+	if err != nil {
+		return fmt.Errorf("could not load config: %w", err)
+	}
+
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+
+	return enc.Encode(cfg)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/giornetta/microshop/events"
+)
+
+const eventsUsage = `usage: microshop events <command> [flags]
+
+commands:
+  tail     print the events of one or more topics, optionally filtered, from any offset or time
+  publish  publish events read from JSON files
+  lag      print the lag of consumer groups
+
+Run microshop events <command> -h for the flags of a command.
+`
+
+var eventsCommands = map[string]command{
+	"tail":    tailEvents,
+	"publish": publishEvents,
+	"lag":     printLag,
+}
+
+// eventsCmd groups the operator tools inspecting and producing the events of the shop.
+func eventsCmd(args []string, logger *slog.Logger) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, eventsUsage)
+		return errors.New("missing command")
+	}
+
+	cmd, ok := eventsCommands[args[0]]
+	if !ok {
+		fmt.Fprint(os.Stderr, eventsUsage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+
+	return cmd(args[1:], logger)
+}
+
+// splitList splits a comma separated flag, returning nil when it is empty.
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
+func parseTopics(s string) []events.Topic {
+	list := splitList(s)
+	if list == nil {
+		return events.Topics()
+	}
+
+	topics := make([]events.Topic, len(list))
+	for i, t := range list {
+		topics[i] = events.Topic(t)
+	}
+
+	return topics
+}
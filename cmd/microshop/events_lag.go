@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+	"golang.org/x/exp/slog"
+
+	"github.com/giornetta/microshop/bootstrap"
+	"github.com/giornetta/microshop/config"
+)
+
+// printLag prints the committed offset, end offset and lag of every partition consumed by the groups.
+func printLag(args []string, _ *slog.Logger) error {
+	fs := flag.NewFlagSet("events lag", flag.ExitOnError)
+	var loader config.Loader
+	loader.RegisterFlags(fs)
+	groups := fs.String("groups", "", "comma separated consumer groups, the configured one when empty")
+	fs.Parse(args)
+
+	cfg, err := loader.LoadKafka()
+	if err != nil {
+		return fmt.Errorf("could not load config: %w", err)
+	}
+
+	names := splitList(*groups)
+	if names == nil && cfg.ConsumerGroup != "" {
+		names = []string{cfg.ConsumerGroup}
+	}
+	if names == nil {
+		return errors.New("no consumer group given")
+	}
+
+	client, err := bootstrap.KafkaClient(cfg)
+	if err != nil {
+		return fmt.Errorf("could not create kafka client: %w", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	lags, err := kadm.NewClient(client).Lag(ctx, names...)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tSTATE\tTOPIC\tPARTITION\tCOMMITTED\tEND\tLAG")
+
+	for _, name := range names {
+		l, ok := lags[name]
+		if !ok {
+			continue
+		}
+		if err := l.Error(); err != nil {
+			fmt.Fprintf(w, "%s\t%s\t\t\t\t\t%v\n", name, l.State, err)
+			continue
+		}
+
+		for _, lag := range l.Lag.Sorted() {
+			if lag.Err != nil {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t\t\t%v\n", name, l.State, lag.Topic, lag.Partition, lag.Err)
+				continue
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\t%d\n", name, l.State, lag.Topic, lag.Partition, lag.Commit.At, lag.End.Offset, lag.Lag)
+		}
+	}
+
+	return w.Flush()
+}
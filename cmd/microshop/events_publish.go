@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/giornetta/microshop/bootstrap"
+	"github.com/giornetta/microshop/config"
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/events/encoding"
+)
+
+// eventFile is an event to publish, as read from a file holding one of them or an array.
+type eventFile struct {
+	Type    events.Type     `json:"type"`
+	Version int             `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// publishEvents publishes the events of JSON files, with the same encoding and binding as the services,
+// to exercise handlers while testing.
+func publishEvents(args []string, logger *slog.Logger) error {
+	fs := flag.NewFlagSet("events publish", flag.ExitOnError)
+	var loader config.Loader
+	loader.RegisterFlags(fs)
+	dryRun := fs.Bool("dry-run", false, "only validate the events")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), `usage: microshop events publish [flags] file...
+
+Files hold an event, or an array of them, such as:
+  {"type": "Product.Restocked", "version": 1, "data": {"product_id": "...", "amount": 5}}
+
+flags:`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("missing files")
+	}
+
+	var evts []events.Event
+	for _, file := range fs.Args() {
+		fileEvts, err := readEventFile(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+
+		evts = append(evts, fileEvts...)
+	}
+
+	if *dryRun {
+		logger.Info("Events are valid", slog.Int("events", len(evts)))
+		return nil
+	}
+
+	cfg, err := loader.LoadKafka()
+	if err != nil {
+		return fmt.Errorf("could not load config: %w", err)
+	}
+
+	codecs, err := encoding.NewCodecs(cfg.SchemaRegistry, cfg.Encodings)
+	if err != nil {
+		return fmt.Errorf("could not set up event codecs: %w", err)
+	}
+
+	client, err := bootstrap.KafkaClient(cfg)
+	if err != nil {
+		return fmt.Errorf("could not create kafka client: %w", err)
+	}
+	defer client.Close()
+
+	publisher, err := bootstrap.Publisher(client, codecs, cfg, "/microshop/cli")
+	if err != nil {
+		return fmt.Errorf("could not set up publisher: %w", err)
+	}
+
+	ctx := context.Background()
+	for _, evt := range evts {
+		if err := publisher.Publish(evt, ctx); err != nil {
+			return fmt.Errorf("could not publish %s %s: %w", evt.Type(), evt.Key(), err)
+		}
+
+		logger.Info("Event published", slog.String("type", evt.Type().String()), slog.String("key", evt.Key().String()))
+	}
+
+	return nil
+}
+
+func readEventFile(file string) ([]events.Event, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []eventFile
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '[' {
+		err = json.Unmarshal(b, &files)
+	} else {
+		files = make([]eventFile, 1)
+		err = json.Unmarshal(b, &files[0])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	evts := make([]events.Event, len(files))
+	for i, f := range files {
+		if evts[i], err = events.Decode(f.Type, f.Version, f.Data); err != nil {
+			return nil, fmt.Errorf("event %d: %w", i, err)
+		}
+	}
+
+	return evts, nil
+}
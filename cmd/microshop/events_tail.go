@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"golang.org/x/exp/slog"
+
+	"github.com/giornetta/microshop/bootstrap"
+	"github.com/giornetta/microshop/config"
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/events/encoding"
+	"github.com/giornetta/microshop/kafka"
+)
+
+// tailEvents prints the events of the given topics. It consumes without a consumer group,
+// so it never moves the offsets of the services.
+func tailEvents(args []string, _ *slog.Logger) error {
+	fs := flag.NewFlagSet("events tail", flag.ExitOnError)
+	var loader config.Loader
+	loader.RegisterFlags(fs)
+	topics := fs.String("topics", "", "comma separated topics, all of them when empty")
+	types := fs.String("type", "", "comma separated event types to print, all of them when empty")
+	keys := fs.String("key", "", "comma separated event keys to print, all of them when empty")
+	from := fs.String("from", "end", "where to start: start, end, an offset, -n for the last n records of each partition,\n"+
+		"an RFC 3339 time or a duration ago, such as 1h")
+	partition := fs.Int("partition", -1, "only read this partition")
+	limit := fs.Int("n", 0, "stop after printing n events, zero to keep tailing")
+	headers := fs.Bool("headers", false, "print the record headers")
+	output := fs.String("output", "pretty", "pretty, or json to print one JSON object per line")
+	fs.Parse(args)
+
+	cfg, err := loader.LoadKafka()
+	if err != nil {
+		return fmt.Errorf("could not load config: %w", err)
+	}
+
+	offset, err := parseOffset(*from, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var print func(io.Writer, *tailedRecord) error
+	switch *output {
+	case "pretty":
+		print = printPretty
+	case "json":
+		print = printJSON
+	default:
+		return fmt.Errorf("unknown output %q", *output)
+	}
+
+	codecs, err := encoding.NewCodecs(cfg.SchemaRegistry, cfg.Encodings)
+	if err != nil {
+		return fmt.Errorf("could not set up event codecs: %w", err)
+	}
+
+	var consume kgo.Opt
+	if *partition >= 0 {
+		partitions := make(map[string]map[int32]kgo.Offset)
+		for _, t := range parseTopics(*topics) {
+			partitions[t.String()] = map[int32]kgo.Offset{int32(*partition): offset}
+		}
+		consume = kgo.ConsumePartitions(partitions)
+	} else {
+		var names []string
+		for _, t := range parseTopics(*topics) {
+			names = append(names, t.String())
+		}
+		consume = kgo.ConsumeTopics(names...)
+	}
+
+	client, err := bootstrap.KafkaClient(cfg, consume, kgo.ConsumeResetOffset(offset))
+	if err != nil {
+		return fmt.Errorf("could not create kafka client: %w", err)
+	}
+	defer client.Close()
+
+	filter := &eventFilter{types: splitList(*types), keys: splitList(*keys)}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	printed := 0
+	for {
+		fetches := client.PollFetches(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if errs := fetches.Errors(); errs != nil {
+			return errs[0].Err
+		}
+
+		for iter := fetches.RecordIter(); !iter.Done(); {
+			record := iter.Next()
+
+			tr := &tailedRecord{Record: record, headers: *headers}
+			tr.event, tr.id, tr.err = kafka.DecodeRecord(record, codecs)
+
+			if !filter.match(tr) {
+				continue
+			}
+
+			if err := print(os.Stdout, tr); err != nil {
+				return err
+			}
+
+			printed++
+			if *limit > 0 && printed >= *limit {
+				return nil
+			}
+		}
+	}
+}
+
+// parseOffset parses the -from flag of tail, relative to now.
+func parseOffset(from string, now time.Time) (kgo.Offset, error) {
+	switch from {
+	case "start":
+		return kgo.NewOffset().AtStart(), nil
+	case "end":
+		return kgo.NewOffset().AtEnd(), nil
+	}
+
+	if n, err := strconv.ParseInt(from, 10, 64); err == nil {
+		if n < 0 {
+			return kgo.NewOffset().AtEnd().Relative(n), nil
+		}
+
+		return kgo.NewOffset().At(n), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, from); err == nil {
+		return kgo.NewOffset().AfterMilli(t.UnixMilli()), nil
+	}
+
+	if d, err := time.ParseDuration(from); err == nil {
+		return kgo.NewOffset().AfterMilli(now.Add(-d).UnixMilli()), nil
+	}
+
+	return kgo.Offset{}, fmt.Errorf("invalid -from %q", from)
+}
+
+type tailedRecord struct {
+	*kgo.Record
+
+	event   events.Event
+	id      string
+	err     error
+	headers bool
+}
+
+// eventType returns the type of the event, read from the record headers when it could not be decoded.
+func (r *tailedRecord) eventType() string {
+	if r.event != nil {
+		return r.event.Type().String()
+	}
+
+	for _, h := range r.Headers {
+		if h.Key == "EventType" || h.Key == "ce_type" {
+			return string(h.Value)
+		}
+	}
+
+	return ""
+}
+
+type eventFilter struct {
+	types []string
+	keys  []string
+}
+
+func (f *eventFilter) match(r *tailedRecord) bool {
+	return matchAny(f.types, r.eventType()) && matchAny(f.keys, string(r.Key))
+}
+
+func matchAny(list []string, v string) bool {
+	if len(list) == 0 {
+		return true
+	}
+
+	for _, l := range list {
+		if l == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+func printPretty(w io.Writer, r *tailedRecord) error {
+	fmt.Fprintf(w, "%s [%d] @%d  %s  key=%s\n", r.Topic, r.Partition, r.Offset, r.Timestamp.Format(time.RFC3339Nano), r.Key)
+	fmt.Fprintf(w, "  type: %s  id: %s\n", r.eventType(), r.id)
+
+	if r.headers {
+		fmt.Fprintln(w, "  headers:")
+		for _, h := range r.Headers {
+			fmt.Fprintf(w, "    %s: %s\n", h.Key, h.Value)
+		}
+	}
+
+	if r.err != nil {
+		fmt.Fprintf(w, "  could not decode: %v\n  value: %q\n\n", r.err, r.Value)
+		return nil
+	}
+
+	payload, err := json.MarshalIndent(r.event, "  ", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "  %s\n\n", payload)
+	return err
+}
+
+type jsonRecord struct {
+	Topic     string            `json:"topic"`
+	Partition int32             `json:"partition"`
+	Offset    int64             `json:"offset"`
+	Timestamp time.Time         `json:"timestamp"`
+	Key       string            `json:"key"`
+	Id        string            `json:"id,omitempty"`
+	Type      string            `json:"type,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Event     events.Event      `json:"event,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	Value     string            `json:"value,omitempty"`
+}
+
+func printJSON(w io.Writer, r *tailedRecord) error {
+	out := jsonRecord{
+		Topic:     r.Topic,
+		Partition: r.Partition,
+		Offset:    r.Offset,
+		Timestamp: r.Timestamp,
+		Key:       string(r.Key),
+		Id:        r.id,
+		Type:      r.eventType(),
+		Event:     r.event,
+	}
+
+	if r.headers {
+		out.Headers = make(map[string]string, len(r.Headers))
+		for _, h := range r.Headers {
+			out.Headers[h.Key] = string(h.Value)
+		}
+	}
+
+	if r.err != nil {
+		out.Error = r.err.Error()
+		out.Value = strings.ToValidUTF8(string(r.Value), "�")
+	}
+
+	return json.NewEncoder(w).Encode(out)
+}
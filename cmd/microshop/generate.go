@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/giornetta/microshop/customers"
+	"github.com/giornetta/microshop/products"
+)
+
+var (
+	productAdjectives = []string{"Compact", "Wireless", "Ergonomic", "Classic", "Portable", "Deluxe", "Smart", "Rugged"}
+	productNouns      = []string{"Keyboard", "Mouse", "Monitor", "Headset", "Lamp", "Backpack", "Speaker", "Charger"}
+	productMaterials  = []string{"aluminium", "recycled plastic", "oak wood", "stainless steel", "soft fabric"}
+
+	firstNames = []string{"Ada", "Alan", "Grace", "Edsger", "Barbara", "Donald", "Margaret", "Linus", "Radia", "Ken"}
+	lastNames  = []string{"Lovelace", "Turing", "Hopper", "Dijkstra", "Liskov", "Knuth", "Hamilton", "Torvalds", "Perlman", "Thompson"}
+
+	countries = []string{"Italy", "France", "Germany", "Spain", "Portugal", "Austria", "Belgium", "Ireland"}
+	cities    = []string{"Rome", "Milan", "Turin", "Paris", "Lyon", "Berlin", "Madrid", "Lisbon", "Vienna", "Dublin"}
+	streets   = []string{"Main Street", "Station Road", "Church Lane", "Park Avenue", "Market Square", "Mill Road"}
+)
+
+// generator produces random requests that pass the validation of the services. Names and emails
+// end with the run id and a counter, so they do not collide with those of other runs.
+type generator struct {
+	lock sync.Mutex
+	rand *rand.Rand
+	run  string
+	n    int
+}
+
+// newGenerator returns a generator whose requests only depend on seed.
+func newGenerator(seed uint64) *generator {
+	r := rand.New(rand.NewPCG(seed, seed))
+
+	return &generator{
+		rand: r,
+		run:  fmt.Sprintf("%04x", r.IntN(1<<16)),
+	}
+}
+
+func (g *generator) product() *products.CreateProductRequest {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	adjective, noun := pick(g.rand, productAdjectives), pick(g.rand, productNouns)
+	g.n++
+
+	return &products.CreateProductRequest{
+		Name:        fmt.Sprintf("%s %s %s-%d", adjective, noun, g.run, g.n),
+		Description: fmt.Sprintf("%s %s made of %s.", adjective, strings.ToLower(noun), pick(g.rand, productMaterials)),
+		Price:       g.price(),
+		Amount:      g.rand.IntN(100),
+	}
+}
+
+// price returns a price between 1 and 500, with cents.
+func (g *generator) price() float32 {
+	return float32(100+g.rand.IntN(49900)) / 100
+}
+
+// productUpdate returns a new price for a product.
+func (g *generator) productUpdate() float32 {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	return g.price()
+}
+
+// restock returns an amount of items to add to the stock of a product.
+func (g *generator) restock() int {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	return 1 + g.rand.IntN(20)
+}
+
+func (g *generator) customer() *customers.CreateCustomerRequest {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	first, last := pick(g.rand, firstNames), pick(g.rand, lastNames)
+	g.n++
+
+	return &customers.CreateCustomerRequest{
+		FirstName: first,
+		LastName:  last,
+		Email:     fmt.Sprintf("%s.%s.%s-%d@example.com", strings.ToLower(first), strings.ToLower(last), g.run, g.n),
+	}
+}
+
+func (g *generator) address(id customers.CustomerId) *customers.UpdateShippingAddressRequest {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	return &customers.UpdateShippingAddressRequest{
+		Id:      id,
+		Country: pick(g.rand, countries),
+		City:    pick(g.rand, cities),
+		ZipCode: strconv.Itoa(10000 + g.rand.IntN(90000)),
+		Street:  fmt.Sprintf("%d %s", 1+g.rand.IntN(200), pick(g.rand, streets)),
+	}
+}
+
+func pick(r *rand.Rand, values []string) string {
+	return values[r.IntN(len(values))]
+}
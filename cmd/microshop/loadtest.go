@@ -0,0 +1,601 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+	"golang.org/x/exp/slog"
+
+	"github.com/giornetta/microshop/bootstrap"
+	"github.com/giornetta/microshop/config"
+	"github.com/giornetta/microshop/customers"
+	"github.com/giornetta/microshop/products"
+)
+
+const (
+	opCreateProduct  = "create-product"
+	opUpdateProduct  = "update-product"
+	opRestockProduct = "restock-product"
+	opGetProduct     = "get-product"
+	opListProducts   = "list-products"
+	opCreateCustomer = "create-customer"
+	opUpdateAddress  = "update-address"
+	opGetCustomer    = "get-customer"
+)
+
+const defaultMix = "create-product=3,update-product=1,restock-product=2,get-product=10,list-products=1," +
+	"create-customer=2,update-address=1,get-customer=5"
+
+// visibleSuffix names the metric of a write measured until its effect is visible through GET.
+const visibleSuffix = " (visible)"
+
+// loadtest sends a weighted mix of product and customer requests to running services from concurrent workers.
+// Writes are measured twice: until the API responds, and until their effect is visible through GET,
+// which covers the whole publish, Kafka and projection path. Progress, and the lag of the given consumer
+// groups, is printed at every interval, followed by a report of the latency percentiles of every operation.
+func loadtest(args []string, _ *slog.Logger) error {
+	fs := flag.NewFlagSet("loadtest", flag.ExitOnError)
+	var loader config.Loader
+	loader.RegisterFlags(fs)
+	productsUrl := fs.String("products", "http://localhost:8080", "base URL of the products service")
+	customersUrl := fs.String("customers", "http://localhost:8081", "base URL of the customers service")
+	concurrency := fs.Int("concurrency", 10, "number of concurrent workers")
+	duration := fs.Duration("duration", 30*time.Second, "how long load is generated for")
+	requests := fs.Int64("requests", 0, "stop after this many operations, when positive")
+	mix := fs.String("mix", defaultMix, "comma separated operation=weight pairs")
+	visibilityTimeout := fs.Duration("visibility-timeout", 10*time.Second, "how long writes are waited for to become visible")
+	pollInterval := fs.Duration("poll", 10*time.Millisecond, "how often GET is polled while waiting for a write")
+	interval := fs.Duration("interval", time.Second, "how often progress and consumer lag are printed")
+	groups := fs.String("groups", "", "comma separated consumer groups whose lag is sampled, the configured one when empty")
+	fs.Parse(args)
+
+	ops, err := parseMix(*mix)
+	if err != nil {
+		return err
+	}
+	if *concurrency < 1 {
+		return errors.New("concurrency must be positive")
+	}
+
+	lag, err := newLagSampler(&loader, splitList(*groups))
+	if err != nil {
+		return err
+	}
+	defer lag.Close()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	ctx, cancel = context.WithTimeout(ctx, *duration)
+	defer cancel()
+
+	lt := &loadTest{
+		client:            &http.Client{Timeout: 10 * time.Second},
+		productsUrl:       strings.TrimRight(*productsUrl, "/") + "/api/v1/products",
+		customersUrl:      strings.TrimRight(*customersUrl, "/") + "/api/v1/customers",
+		generator:         newGenerator(uint64(time.Now().UnixNano())),
+		ops:               ops,
+		visibilityTimeout: *visibilityTimeout,
+		pollInterval:      *pollInterval,
+		limit:             *requests,
+		recorder:          newRecorder(),
+	}
+
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func(seed uint64) {
+			defer wg.Done()
+			lt.work(ctx, seed)
+		}(uint64(i))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	fmt.Printf("Sending load with %d workers for %v\n", *concurrency, *duration)
+loop:
+	for {
+		select {
+		case <-ticker.C:
+			lt.recorder.printProgress(time.Since(start), *interval, lag.Sample())
+		case <-done:
+			break loop
+		}
+	}
+
+	elapsed := time.Since(start)
+	fmt.Printf("\nCompleted %d operations in %v\n\n", lt.recorder.total(), elapsed.Round(time.Millisecond))
+
+	return lt.recorder.printReport(elapsed)
+}
+
+type weightedOp struct {
+	name   string
+	weight int
+}
+
+func parseMix(s string) ([]weightedOp, error) {
+	valid := map[string]bool{
+		opCreateProduct: true, opUpdateProduct: true, opRestockProduct: true, opGetProduct: true, opListProducts: true,
+		opCreateCustomer: true, opUpdateAddress: true, opGetCustomer: true,
+	}
+
+	var ops []weightedOp
+	for _, pair := range splitList(s) {
+		name, w, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mix %q: expected operation=weight", pair)
+		}
+		if !valid[name] {
+			return nil, fmt.Errorf("invalid mix %q: unknown operation %s", pair, name)
+		}
+
+		weight, err := strconv.Atoi(w)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid mix %q: weight must be a non negative integer", pair)
+		}
+		if weight > 0 {
+			ops = append(ops, weightedOp{name: name, weight: weight})
+		}
+	}
+
+	if len(ops) == 0 {
+		return nil, errors.New("invalid mix: no operation has a positive weight")
+	}
+
+	return ops, nil
+}
+
+type loadTest struct {
+	client       *http.Client
+	productsUrl  string
+	customersUrl string
+	generator    *generator
+	ops          []weightedOp
+
+	visibilityTimeout time.Duration
+	pollInterval      time.Duration
+
+	limit    int64
+	started  atomic.Int64
+	recorder *recorder
+}
+
+// worker keeps the entities it created, so that its updates are never concurrent with those of other
+// workers and the state they must become visible with is known.
+type worker struct {
+	*loadTest
+	rand      *rand.Rand
+	products  []products.Product
+	customers []customers.Customer
+}
+
+func (lt *loadTest) work(ctx context.Context, seed uint64) {
+	w := &worker{
+		loadTest: lt,
+		rand:     rand.New(rand.NewPCG(seed, uint64(time.Now().UnixNano()))),
+	}
+
+	for ctx.Err() == nil {
+		if lt.limit > 0 && lt.started.Add(1) > lt.limit {
+			return
+		}
+
+		// Operations are not interrupted when the test ends, so that their latency is always recorded.
+		w.run(w.pick(), context.WithoutCancel(ctx))
+	}
+}
+
+func (w *worker) pick() string {
+	total := 0
+	for _, op := range w.ops {
+		total += op.weight
+	}
+
+	n := w.rand.IntN(total)
+	for _, op := range w.ops {
+		if n < op.weight {
+			return op.name
+		}
+		n -= op.weight
+	}
+
+	return w.ops[0].name
+}
+
+func (w *worker) run(op string, ctx context.Context) {
+	// Operations on existing entities create one first when the worker has none yet.
+	if len(w.products) == 0 && (op == opUpdateProduct || op == opRestockProduct || op == opGetProduct) {
+		op = opCreateProduct
+	}
+	if len(w.customers) == 0 && (op == opUpdateAddress || op == opGetCustomer) {
+		op = opCreateCustomer
+	}
+
+	switch op {
+	case opCreateProduct:
+		w.createProduct(ctx)
+	case opUpdateProduct:
+		w.updateProduct(ctx)
+	case opRestockProduct:
+		w.restockProduct(ctx)
+	case opGetProduct:
+		p := &w.products[w.rand.IntN(len(w.products))]
+		w.read(opGetProduct, w.productsUrl+"/"+p.Id.String(), ctx)
+	case opListProducts:
+		w.read(opListProducts, w.productsUrl+"/", ctx)
+	case opCreateCustomer:
+		w.createCustomer(ctx)
+	case opUpdateAddress:
+		w.updateAddress(ctx)
+	case opGetCustomer:
+		c := &w.customers[w.rand.IntN(len(w.customers))]
+		w.read(opGetCustomer, w.customersUrl+"/"+c.Id.String(), ctx)
+	}
+}
+
+func (w *worker) createProduct(ctx context.Context) {
+	req := w.generator.product()
+
+	start := time.Now()
+	var p products.Product
+	err := w.send(http.MethodPost, w.productsUrl+"/", map[string]any{
+		"name":        req.Name,
+		"description": req.Description,
+		"price":       req.Price,
+		"amount":      req.Amount,
+	}, http.StatusCreated, &p, ctx)
+	w.recorder.observe(opCreateProduct, time.Since(start), err)
+	if err != nil {
+		return
+	}
+
+	err = await(w.loadTest, w.productsUrl+"/"+p.Id.String(), func(got *products.Product) bool { return true }, ctx)
+	w.recorder.observe(opCreateProduct+visibleSuffix, time.Since(start), err)
+	if err == nil {
+		w.products = append(w.products, p)
+	}
+}
+
+func (w *worker) updateProduct(ctx context.Context) {
+	p := &w.products[w.rand.IntN(len(w.products))]
+	price := w.generator.productUpdate()
+
+	start := time.Now()
+	err := w.send(http.MethodPut, w.productsUrl+"/"+p.Id.String(), map[string]any{"price": price}, http.StatusOK, nil, ctx)
+	w.recorder.observe(opUpdateProduct, time.Since(start), err)
+	if err != nil {
+		return
+	}
+
+	err = await(w.loadTest, w.productsUrl+"/"+p.Id.String(), func(got *products.Product) bool { return got.Price == price }, ctx)
+	w.recorder.observe(opUpdateProduct+visibleSuffix, time.Since(start), err)
+	if err == nil {
+		p.Price = price
+	}
+}
+
+func (w *worker) restockProduct(ctx context.Context) {
+	p := &w.products[w.rand.IntN(len(w.products))]
+	amount := w.generator.restock()
+
+	start := time.Now()
+	err := w.send(http.MethodPut, w.productsUrl+"/restock/"+p.Id.String(), map[string]any{"amount": amount}, http.StatusOK, nil, ctx)
+	w.recorder.observe(opRestockProduct, time.Since(start), err)
+	if err != nil {
+		return
+	}
+
+	want := p.Amount + amount
+	err = await(w.loadTest, w.productsUrl+"/"+p.Id.String(), func(got *products.Product) bool { return got.Amount == want }, ctx)
+	w.recorder.observe(opRestockProduct+visibleSuffix, time.Since(start), err)
+	if err == nil {
+		p.Amount = want
+	}
+}
+
+func (w *worker) createCustomer(ctx context.Context) {
+	req := w.generator.customer()
+
+	start := time.Now()
+	var c customers.Customer
+	err := w.send(http.MethodPost, w.customersUrl+"/", map[string]any{
+		"first_name": req.FirstName,
+		"last_name":  req.LastName,
+		"email":      req.Email,
+	}, http.StatusCreated, &c, ctx)
+	w.recorder.observe(opCreateCustomer, time.Since(start), err)
+	if err != nil {
+		return
+	}
+
+	err = await(w.loadTest, w.customersUrl+"/"+c.Id.String(), func(got *customers.Customer) bool { return true }, ctx)
+	w.recorder.observe(opCreateCustomer+visibleSuffix, time.Since(start), err)
+	if err == nil {
+		w.customers = append(w.customers, c)
+	}
+}
+
+func (w *worker) updateAddress(ctx context.Context) {
+	c := &w.customers[w.rand.IntN(len(w.customers))]
+	req := w.generator.address(c.Id)
+	want := customers.ShippingAddress{Country: req.Country, City: req.City, ZipCode: req.ZipCode, Street: req.Street}
+
+	start := time.Now()
+	err := w.send(http.MethodPut, w.customersUrl+"/"+c.Id.String()+"/shipping", want, http.StatusOK, nil, ctx)
+	w.recorder.observe(opUpdateAddress, time.Since(start), err)
+	if err != nil {
+		return
+	}
+
+	err = await(w.loadTest, w.customersUrl+"/"+c.Id.String(), func(got *customers.Customer) bool {
+		return got.ShippingAddress != nil && *got.ShippingAddress == want
+	}, ctx)
+	w.recorder.observe(opUpdateAddress+visibleSuffix, time.Since(start), err)
+}
+
+func (w *worker) read(op, url string, ctx context.Context) {
+	start := time.Now()
+	err := w.send(http.MethodGet, url, nil, http.StatusOK, nil, ctx)
+	w.recorder.observe(op, time.Since(start), err)
+}
+
+// send sends a request with body encoded as JSON, failing unless the response has the wanted status.
+// The response body is decoded into out, when given.
+func (lt *loadTest) send(method, url string, body any, want int, out any, ctx context.Context) error {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := lt.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != want {
+		return fmt.Errorf("%s %s: unexpected status %d", method, url, res.StatusCode)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+var errNotVisible = errors.New("write not visible before the timeout")
+
+// await polls url until the entity it returns satisfies cond, for at most the visibility timeout.
+func await[T any](lt *loadTest, url string, cond func(got *T) bool, ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, lt.visibilityTimeout)
+	defer cancel()
+
+	for {
+		var got T
+		if err := lt.send(http.MethodGet, url, nil, http.StatusOK, &got, ctx); err == nil && cond(&got) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return errNotVisible
+		case <-time.After(lt.pollInterval):
+		}
+	}
+}
+
+// recorder collects the latencies and errors of every operation.
+type recorder struct {
+	lock      sync.Mutex
+	latencies map[string][]time.Duration
+	errors    map[string]int
+
+	count int
+	// completed and failed count the operations since the last progress line.
+	completed int
+	failed    int
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		latencies: make(map[string][]time.Duration),
+		errors:    make(map[string]int),
+	}
+}
+
+func (r *recorder) observe(metric string, latency time.Duration, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if err != nil {
+		r.errors[metric]++
+	} else {
+		r.latencies[metric] = append(r.latencies[metric], latency)
+	}
+
+	// Visibility is part of the operation already counted.
+	if strings.HasSuffix(metric, visibleSuffix) {
+		return
+	}
+
+	r.count++
+	r.completed++
+	if err != nil {
+		r.failed++
+	}
+}
+
+func (r *recorder) total() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.count
+}
+
+func (r *recorder) printProgress(elapsed, interval time.Duration, lag string) {
+	r.lock.Lock()
+	completed, failed := r.completed, r.failed
+	r.completed, r.failed = 0, 0
+	r.lock.Unlock()
+
+	fmt.Printf("%8v  %8.1f ops/s  %5d errors", elapsed.Round(100*time.Millisecond), float64(completed)/interval.Seconds(), failed)
+	if lag != "" {
+		fmt.Printf("  lag %s", lag)
+	}
+	fmt.Println()
+}
+
+func (r *recorder) printReport(elapsed time.Duration) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	metrics := make(map[string]bool)
+	for metric := range r.latencies {
+		metrics[metric] = true
+	}
+	for metric := range r.errors {
+		metrics[metric] = true
+	}
+
+	names := keys(metrics)
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "OPERATION\tCOUNT\tERRORS\tRATE/s\tP50\tP90\tP99\tMAX")
+
+	for _, name := range names {
+		l := r.latencies[name]
+		sort.Slice(l, func(i, j int) bool { return l[i] < l[j] })
+
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.1f\t%v\t%v\t%v\t%v\n",
+			name, len(l), r.errors[name], float64(len(l))/elapsed.Seconds(),
+			percentile(l, 0.5), percentile(l, 0.9), percentile(l, 0.99), percentile(l, 1),
+		)
+	}
+
+	return w.Flush()
+}
+
+// percentile returns the latency below which the fraction p of the sorted latencies fall.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	i := int(p*float64(len(sorted))+0.5) - 1
+	i = max(0, min(i, len(sorted)-1))
+
+	return sorted[i].Round(time.Microsecond)
+}
+
+func keys[K comparable, V any](m map[K]V) []K {
+	ks := make([]K, 0, len(m))
+	for k := range m {
+		ks = append(ks, k)
+	}
+
+	return ks
+}
+
+// lagSampler reads the lag of consumer groups, doing nothing when there are none.
+type lagSampler struct {
+	admin  *kadm.Client
+	groups []string
+}
+
+func newLagSampler(loader *config.Loader, groups []string) (*lagSampler, error) {
+	cfg, err := loader.LoadKafka()
+	if err != nil {
+		return nil, fmt.Errorf("could not load config: %w", err)
+	}
+
+	if groups == nil && cfg.ConsumerGroup != "" {
+		groups = []string{cfg.ConsumerGroup}
+	}
+	if groups == nil {
+		return &lagSampler{}, nil
+	}
+
+	client, err := bootstrap.KafkaClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not create kafka client: %w", err)
+	}
+
+	return &lagSampler{
+		admin:  kadm.NewClient(client),
+		groups: groups,
+	}, nil
+}
+
+// Sample returns the total lag of every group, formatted as group=lag pairs.
+func (s *lagSampler) Sample() string {
+	if s.admin == nil {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// Lag reorders the groups it is given.
+	lags, err := s.admin.Lag(ctx, slices.Clone(s.groups)...)
+	if err != nil {
+		return "unavailable: " + err.Error()
+	}
+
+	pairs := make([]string, len(s.groups))
+	for i, group := range s.groups {
+		l, ok := lags[group]
+		switch {
+		case !ok:
+			pairs[i] = group + "=?"
+		case l.Error() != nil:
+			pairs[i] = fmt.Sprintf("%s=%v", group, l.Error())
+		default:
+			pairs[i] = fmt.Sprintf("%s=%d", group, l.Lag.Total())
+		}
+	}
+
+	return strings.Join(pairs, " ")
+}
+
+func (s *lagSampler) Close() {
+	if s.admin != nil {
+		s.admin.Close()
+	}
+}
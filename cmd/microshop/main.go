@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/exp/slog"
+)
+
+const usage = `usage: microshop <command> [arguments]
+
+commands:
+  serve products|customers|all  run services, all of them in a single process with all
+  events tail|publish|lag       inspect and produce the events in Kafka
+  migrate <service> <command>   manage the schema of a service database
+  replay <service>              rebuild the projection of a service from its topic
+  config                        print the effective configuration, with secrets redacted
+  seed                          create products and customers from fixture files, or random ones
+  loadtest                      send load to running services and report latencies and consumer lag
+
+Run microshop <command> -h for the flags of a command.
+`
+
+type command func(args []string, logger *slog.Logger) error
+
+var commands = map[string]command{
+	"serve":    serve,
+	"events":   eventsCmd,
+	"migrate":  migrate,
+	"replay":   replay,
+	"config":   printConfig,
+	"seed":     seed,
+	"loadtest": loadtest,
+}
+
+func main() {
+	logger := slog.New(slog.NewTextHandler(os.Stderr))
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err := cmd(os.Args[2:], logger); err != nil {
+		logger.Error("microshop "+os.Args[1]+" failed", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+}
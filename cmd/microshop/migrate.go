@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/giornetta/microshop/config"
+	"github.com/giornetta/microshop/postgres"
+)
+
+const migrateUsage = `usage: microshop migrate products|customers [flags] command
+
+commands:
+  up              apply every pending migration
+  down [n|all]    revert the last n migrations (default 1), or all of them
+  to <version>    migrate up or down to version
+  status          print the applied, latest and pending versions
+  force <version> set the version without migrating, clearing the dirty flag
+
+flags:
+`
+
+// migrate manages the schema of a service database with the migrations embedded in the binary,
+// for deployments running services with auto-migrate disabled.
+func migrate(args []string, logger *slog.Logger) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	var loader config.Loader
+	loader.RegisterFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), migrateUsage)
+		fs.PrintDefaults()
+	}
+
+	if len(args) == 0 {
+		fs.Usage()
+		return errors.New("missing service")
+	}
+
+	svc, err := lookupService(args[0])
+	if err != nil {
+		return err
+	}
+	loader.Defaults = svc.defaults
+
+	fs.Parse(args[1:])
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("missing command")
+	}
+
+	cfg, err := loader.Load()
+	if err != nil {
+		return fmt.Errorf("could not load config: %w", err)
+	}
+
+	m, err := postgres.NewMigrator(svc.Migrations, cfg.Postgres.MigrationsConnectionString())
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	switch cmd, arg := fs.Arg(0), fs.Arg(1); cmd {
+	case "up":
+		err = m.Up()
+	case "down":
+		steps := 1
+		if arg == "all" {
+			steps = 0
+		} else if arg != "" {
+			if steps, err = strconv.Atoi(arg); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations %q", arg)
+			}
+		}
+		err = m.Down(steps)
+	case "to":
+		version, parseErr := strconv.ParseUint(arg, 10, 0)
+		if parseErr != nil {
+			return fmt.Errorf("invalid version %q", arg)
+		}
+		err = m.To(uint(version))
+	case "force":
+		version, parseErr := strconv.Atoi(arg)
+		if parseErr != nil {
+			return fmt.Errorf("invalid version %q", arg)
+		}
+		err = m.Force(version)
+	case "status":
+		status, statusErr := m.Status()
+		if statusErr != nil {
+			return statusErr
+		}
+
+		fmt.Printf("version: %d\ndirty: %t\nlatest: %d\npending: %v\n", status.Version, status.Dirty, status.Latest, status.Pending)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+	if err != nil {
+		return err
+	}
+
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Migrated", slog.String("service", svc.Name), slog.Uint64("version", uint64(status.Version)))
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"golang.org/x/exp/slog"
+
+	"github.com/giornetta/microshop/bootstrap"
+	"github.com/giornetta/microshop/config"
+	"github.com/giornetta/microshop/events/encoding"
+	"github.com/giornetta/microshop/kafka"
+	"github.com/giornetta/microshop/postgres"
+)
+
+// replay rebuilds the projection of a service from its topic: it resets a shadow of each of its tables,
+// consumes the topic from offset zero into them and atomically swaps them with the live tables.
+// The service listener should be stopped while the projection is rebuilt, as events it handles
+// during the replay would be lost with the old table.
+func replay(args []string, logger *slog.Logger) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	var loader config.Loader
+	loader.RegisterFlags(fs)
+	commitGroup := fs.Bool("commit-group", false, "move the service consumer group past the replayed records")
+
+	if len(args) == 0 {
+		return errors.New("usage: microshop replay products|customers [flags]")
+	}
+
+	p, err := lookupService(args[0])
+	if err != nil {
+		return err
+	}
+	loader.Defaults = p.defaults
+
+	fs.Parse(args[1:])
+
+	cfg, err := loader.Load()
+	if err != nil {
+		return fmt.Errorf("could not load config: %w", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	codecs, err := encoding.NewCodecs(cfg.Kafka.SchemaRegistry, cfg.Kafka.Encodings)
+	if err != nil {
+		return fmt.Errorf("could not set up event codecs: %w", err)
+	}
+
+	client, err := bootstrap.KafkaClient(&cfg.Kafka, kgo.KeepControlRecords())
+	if err != nil {
+		return fmt.Errorf("could not create kafka client: %w", err)
+	}
+	defer client.Close()
+
+	pool, err := pgxpool.New(ctx, cfg.Postgres.ConnectionString())
+	if err != nil {
+		return fmt.Errorf("could not connect to postgres: %w", err)
+	}
+	defer pool.Close()
+
+	shadows := make([]string, len(p.tables))
+	for i, table := range p.tables {
+		if err := postgres.ResetShadowTable(ctx, pool, table); err != nil {
+			return fmt.Errorf("could not reset shadow table of %s: %w", table, err)
+		}
+		shadows[i] = postgres.ShadowTable(table)
+	}
+
+	logger.Info("Replaying topic", slog.String("topic", p.Topic.String()), slog.Any("tables", shadows))
+
+	start := time.Now()
+	lastReport := start
+
+	ends, err := kafka.Replay(client, codecs, p.Topic, p.projection(pool, postgres.ShadowTable), func(progress kafka.ReplayProgress) {
+		if progress.Replayed != progress.Total && time.Since(lastReport) < time.Second {
+			return
+		}
+		lastReport = time.Now()
+
+		percent := 100.0
+		if progress.Total > 0 {
+			percent = float64(progress.Replayed) / float64(progress.Total) * 100
+		}
+
+		logger.Info("Replay progress",
+			slog.Int64("replayed", progress.Replayed),
+			slog.Int64("total", progress.Total),
+			slog.String("percent", fmt.Sprintf("%.1f%%", percent)),
+			slog.Duration("elapsed", time.Since(start)),
+		)
+	}, ctx)
+	if err != nil {
+		return fmt.Errorf("could not replay topic: %w", err)
+	}
+
+	if err := postgres.SwapShadowTables(ctx, pool, p.tables...); err != nil {
+		return fmt.Errorf("could not swap projection: %w", err)
+	}
+
+	logger.Info("Projection rebuilt", slog.Any("tables", p.tables), slog.Duration("elapsed", time.Since(start)))
+
+	if *commitGroup {
+		if cfg.Kafka.ConsumerGroup == "" {
+			return errors.New("kafka consumer-group is required to commit its offsets")
+		}
+
+		offsets := make(kadm.Offsets)
+		ends.Each(func(o kadm.ListedOffset) {
+			offsets.Add(kadm.Offset{Topic: o.Topic, Partition: o.Partition, At: o.Offset, LeaderEpoch: -1})
+		})
+
+		if err := kadm.NewClient(client).CommitAllOffsets(ctx, cfg.Kafka.ConsumerGroup, offsets); err != nil {
+			return fmt.Errorf("could not commit consumer group offsets: %w", err)
+		}
+
+		logger.Info("Consumer group moved past replayed records", slog.String("group", cfg.Kafka.ConsumerGroup))
+	}
+
+	return nil
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"golang.org/x/exp/slog"
+	"gopkg.in/yaml.v3"
+
+	"github.com/giornetta/microshop/bootstrap"
+	"github.com/giornetta/microshop/config"
+	"github.com/giornetta/microshop/customers"
+	customersPg "github.com/giornetta/microshop/customers/pg"
+	"github.com/giornetta/microshop/events/encoding"
+	eventstorePg "github.com/giornetta/microshop/eventstore/pg"
+	"github.com/giornetta/microshop/products"
+	productsPg "github.com/giornetta/microshop/products/pg"
+	"github.com/giornetta/microshop/services"
+)
+
+// fixtures are the entities created by seed, read from YAML or JSON files.
+type fixtures struct {
+	Products  []productFixture  `yaml:"products"`
+	Customers []customerFixture `yaml:"customers"`
+}
+
+type productFixture struct {
+	Name        string  `yaml:"name"`
+	Description string  `yaml:"description"`
+	Price       float32 `yaml:"price"`
+	Amount      int     `yaml:"amount"`
+}
+
+type customerFixture struct {
+	FirstName string `yaml:"first_name"`
+	LastName  string `yaml:"last_name"`
+	Email     string `yaml:"email"`
+	// ShippingAddress is set once the customer is visible in the projection, when given.
+	ShippingAddress *addressFixture `yaml:"shipping_address"`
+}
+
+type addressFixture struct {
+	Country string `yaml:"country"`
+	City    string `yaml:"city"`
+	ZipCode string `yaml:"zip_code"`
+	Street  string `yaml:"street"`
+}
+
+const seedUsage = `usage: microshop seed [flags] [file...]
+
+Files hold fixtures, in YAML or JSON, such as:
+  products:
+    - {name: Keyboard, description: A mechanical keyboard, price: 49.5, amount: 10}
+  customers:
+    - first_name: Ada
+      last_name: Lovelace
+      email: ada@example.com
+      shipping_address: {country: England, city: London, zip_code: "12345", street: St James's Square}
+
+Entities are created through the services, publishing their events as the APIs would.
+Entities that already exist are skipped, so seeding again with the same fixtures is harmless.
+
+flags:`
+
+// seed creates the entities of fixture files, and random ones, through the Service of their service.
+// Each service reads its own configuration, as with serve all.
+func seed(args []string, logger *slog.Logger) error {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	svcs, loaders, err := registerLoaders(fs, serviceNames)
+	if err != nil {
+		return err
+	}
+	randomProducts := fs.Int("products", 0, "number of random products to create")
+	randomCustomers := fs.Int("customers", 0, "number of random customers to create")
+	addresses := fs.Bool("addresses", true, "give random customers a shipping address")
+	seedValue := fs.Uint64("seed", 0, "seed of the random entities, a random one when 0")
+	wait := fs.Duration("wait", 30*time.Second, "how long a customer is waited for to be projected before setting its address")
+	dryRun := fs.Bool("dry-run", false, "only validate the entities")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), seedUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var f fixtures
+	for _, file := range fs.Args() {
+		if err := readFixtures(file, &f); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	}
+
+	if *seedValue == 0 {
+		*seedValue = uint64(time.Now().UnixNano())
+	}
+	f.generate(newGenerator(*seedValue), *randomProducts, *randomCustomers, *addresses)
+
+	if err := f.validate(); err != nil {
+		return err
+	}
+
+	if *dryRun {
+		logger.Info("Fixtures are valid", slog.Int("products", len(f.Products)), slog.Int("customers", len(f.Customers)))
+		return nil
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	for i, svc := range svcs {
+		var seedFunc func(d *bootstrap.Deps, ctx context.Context) error
+		switch serviceNames[i] {
+		case "products":
+			if len(f.Products) == 0 {
+				continue
+			}
+			seedFunc = f.seedProducts
+		case "customers":
+			if len(f.Customers) == 0 {
+				continue
+			}
+			seedFunc = func(d *bootstrap.Deps, ctx context.Context) error {
+				return f.seedCustomers(d, *wait, ctx)
+			}
+		}
+
+		cfg, err := loaders[i].Load()
+		if err != nil {
+			return fmt.Errorf("could not load %s config: %w", serviceNames[i], err)
+		}
+
+		if err := withDeps(&svc.Service, cfg, logger, ctx, seedFunc); err != nil {
+			return fmt.Errorf("could not seed %s: %w", serviceNames[i], err)
+		}
+	}
+
+	return nil
+}
+
+// readFixtures appends the fixtures of file to f. JSON is read as YAML, which it is a subset of.
+func readFixtures(file string, f *fixtures) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	var read fixtures
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&read); err != nil && err != io.EOF {
+		return err
+	}
+
+	f.Products = append(f.Products, read.Products...)
+	f.Customers = append(f.Customers, read.Customers...)
+
+	return nil
+}
+
+// generate adds random products and customers to f.
+func (f *fixtures) generate(g *generator, nProducts, nCustomers int, addresses bool) {
+	for i := 0; i < nProducts; i++ {
+		req := g.product()
+		f.Products = append(f.Products, productFixture{
+			Name:        req.Name,
+			Description: req.Description,
+			Price:       req.Price,
+			Amount:      req.Amount,
+		})
+	}
+
+	for i := 0; i < nCustomers; i++ {
+		req := g.customer()
+		c := customerFixture{
+			FirstName: req.FirstName,
+			LastName:  req.LastName,
+			Email:     req.Email,
+		}
+
+		if addresses {
+			addr := g.address("")
+			c.ShippingAddress = &addressFixture{
+				Country: addr.Country,
+				City:    addr.City,
+				ZipCode: addr.ZipCode,
+				Street:  addr.Street,
+			}
+		}
+
+		f.Customers = append(f.Customers, c)
+	}
+}
+
+func (p *productFixture) request() *products.CreateProductRequest {
+	return &products.CreateProductRequest{
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       p.Price,
+		Amount:      p.Amount,
+	}
+}
+
+func (c *customerFixture) request() *customers.CreateCustomerRequest {
+	return &customers.CreateCustomerRequest{
+		FirstName: c.FirstName,
+		LastName:  c.LastName,
+		Email:     c.Email,
+	}
+}
+
+func (a *addressFixture) request(id customers.CustomerId) *customers.UpdateShippingAddressRequest {
+	return &customers.UpdateShippingAddressRequest{
+		Id:      id,
+		Country: a.Country,
+		City:    a.City,
+		ZipCode: a.ZipCode,
+		Street:  a.Street,
+	}
+}
+
+// validate checks every entity with the validation of the services, so that none is created
+// unless all of them are valid. Products must have different names, and customers different emails,
+// across every file, as the services would only create the first of them.
+func (f *fixtures) validate() error {
+	var errs []error
+	names := make(map[string]int)
+	for i := range f.Products {
+		req := f.Products[i].request()
+		if err := req.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("product %d (%s): %w", i, f.Products[i].Name, err))
+			continue
+		}
+
+		if first, ok := names[req.Name]; ok {
+			errs = append(errs, fmt.Errorf("product %d (%s): name was already given to product %d", i, req.Name, first))
+			continue
+		}
+		names[req.Name] = i
+	}
+
+	emails := make(map[string]int)
+	for i, c := range f.Customers {
+		req := c.request()
+		if err := req.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("customer %d (%s): %w", i, c.Email, err))
+		} else if first, ok := emails[req.Email]; ok {
+			errs = append(errs, fmt.Errorf("customer %d (%s): email was already given to customer %d", i, req.Email, first))
+		} else {
+			emails[req.Email] = i
+		}
+
+		if c.ShippingAddress == nil {
+			continue
+		}
+		if err := c.ShippingAddress.request("").Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("shipping address of customer %d (%s): %w", i, c.Email, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// withDeps connects to the Kafka cluster and database of svc, and calls fn with the dependencies
+// its Service is built with. The listener is not set, as nothing is consumed.
+func withDeps(svc *bootstrap.Service, cfg *config.Config, logger *slog.Logger, ctx context.Context, fn func(d *bootstrap.Deps, ctx context.Context) error) error {
+	client, err := bootstrap.KafkaClient(&cfg.Kafka)
+	if err != nil {
+		return fmt.Errorf("could not create kafka client: %w", err)
+	}
+	defer client.Close()
+
+	codecs, err := encoding.NewCodecs(cfg.Kafka.SchemaRegistry, cfg.Kafka.Encodings)
+	if err != nil {
+		return fmt.Errorf("could not set up event codecs: %w", err)
+	}
+
+	// Events are published with the source of the service, as if it had created the entities itself.
+	publisher, err := bootstrap.Publisher(client, codecs, &cfg.Kafka, "/microshop/"+svc.Name)
+	if err != nil {
+		return fmt.Errorf("could not set up publisher: %w", err)
+	}
+
+	pool, err := bootstrap.Postgres(&cfg.Postgres, svc.Migrations, ctx)
+	if err != nil {
+		return fmt.Errorf("could not connect to postgres: %w", err)
+	}
+	defer pool.Close()
+
+	return fn(&bootstrap.Deps{
+		Config:    cfg,
+		Logger:    logger.With("service", svc.Name),
+		Pool:      pool,
+		Publisher: publisher,
+	}, ctx)
+}
+
+func (f *fixtures) seedProducts(d *bootstrap.Deps, ctx context.Context) error {
+	store := eventstorePg.NewEventStore(d.Pool)
+	productRepository := productsPg.NewProductRepository(d.Pool)
+	service := services.NewProductService(d, services.NewAggregateRepository(d, store, productRepository), productRepository, productsPg.NewWarehouseRepository(d.Pool))
+
+	var created, skipped int
+	for _, p := range f.Products {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if _, err := service.Create(p.request(), ctx); err != nil {
+			var exists *products.ErrAlreadyExists
+			if !errors.As(err, &exists) {
+				return fmt.Errorf("could not create product %s: %w", p.Name, err)
+			}
+
+			skipped++
+			continue
+		}
+
+		created++
+	}
+
+	// The products are created once their events are published, which a running service would do as well.
+	if err := services.NewRelay(d, store).Flush(ctx); err != nil {
+		return fmt.Errorf("could not publish the product events: %w", err)
+	}
+
+	d.Logger.Info("Seeded products", slog.Int("created", created), slog.Int("skipped", skipped))
+	return nil
+}
+
+func (f *fixtures) seedCustomers(d *bootstrap.Deps, wait time.Duration, ctx context.Context) error {
+	repository := customersPg.NewCustomerRepository(d.Pool)
+	service := services.NewCustomerService(d, repository)
+
+	var created, skipped int
+	addresses := make(map[customers.CustomerId]*addressFixture)
+	for _, c := range f.Customers {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		customer, err := service.Create(c.request(), ctx)
+		if err != nil {
+			var exists *customers.ErrAlreadyExists
+			if !errors.As(err, &exists) {
+				return fmt.Errorf("could not create customer %s: %w", c.Email, err)
+			}
+
+			skipped++
+			continue
+		}
+
+		created++
+		if c.ShippingAddress != nil {
+			addresses[customer.Id] = c.ShippingAddress
+		}
+	}
+
+	// Addresses are only accepted for projected customers. They are set after creating every customer,
+	// which leaves the projection time to catch up.
+	for id, addr := range addresses {
+		if err := awaitCustomer(repository, id, wait, ctx); err != nil {
+			return err
+		}
+
+		if err := service.UpdateShippingAddress(addr.request(id), ctx); err != nil {
+			return fmt.Errorf("could not set the shipping address of customer %s: %w", id, err)
+		}
+	}
+
+	d.Logger.Info("Seeded customers", slog.Int("created", created), slog.Int("skipped", skipped), slog.Int("addresses", len(addresses)))
+	return nil
+}
+
+// awaitCustomer polls the projection until it holds the customer, for at most wait.
+func awaitCustomer(querier customers.CustomerQuerier, id customers.CustomerId, wait time.Duration, ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	for {
+		_, err := querier.FindById(id, ctx)
+		if err == nil {
+			return nil
+		}
+
+		var notFound *customers.ErrNotFound
+		if !errors.As(err, &notFound) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("customer %s was not projected within %v, is the customers service running?", id, wait)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/giornetta/microshop/bootstrap"
+	"github.com/giornetta/microshop/config"
+	"github.com/giornetta/microshop/lifecycle"
+	"github.com/giornetta/microshop/log"
+)
+
+// serve runs one service, or every service in the same process. With all, each service reads its own
+// configuration, from -<service>-config and -<service>-set, and from environment variables prefixed
+// with its name, such as PRODUCTS_SERVER_PORT, on top of the unprefixed ones.
+func serve(args []string, logger *slog.Logger) error {
+	if len(args) == 0 {
+		return errors.New("usage: microshop serve products|customers|all [flags]")
+	}
+
+	names := []string{args[0]}
+	if args[0] == "all" {
+		names = serviceNames
+	}
+
+	fs := flag.NewFlagSet("serve "+args[0], flag.ExitOnError)
+	svcs, loaders, err := registerLoaders(fs, names)
+	if err != nil {
+		return err
+	}
+	fs.Parse(args[1:])
+
+	configs := make([]*config.Config, len(names))
+	var shutdownTimeout time.Duration
+	for i, l := range loaders {
+		cfg, err := l.Load()
+		if err != nil {
+			return fmt.Errorf("could not load %s config: %w", names[i], err)
+		}
+		configs[i] = cfg
+
+		shutdownTimeout = max(shutdownTimeout, cfg.Server.ShutdownTimeout)
+	}
+
+	if err := checkDistinct(names, configs); err != nil {
+		return err
+	}
+
+	logger, err = bootstrap.Logger(&configs[0].Log)
+	if err != nil {
+		return fmt.Errorf("could not set up logging: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tracingName := "microshop"
+	if len(svcs) == 1 {
+		tracingName = svcs[0].Name
+	}
+
+	shutdownTracing, err := bootstrap.Tracing(tracingName, &configs[0].Tracing, ctx)
+	if err != nil {
+		return fmt.Errorf("could not set up tracing: %w", err)
+	}
+
+	runner := lifecycle.New(log.Named(logger, "lifecycle"), shutdownTimeout)
+	for i, svc := range svcs {
+		svcLogger := logger
+		if len(svcs) > 1 {
+			svcLogger = logger.With("service", svc.Name)
+		}
+
+		if err := bootstrap.Start(&svc.Service, configs[i], svcLogger, runner, ctx); err != nil {
+			shutdownTracing(context.Background())
+			return fmt.Errorf("could not start %s: %w", svc.Name, err)
+		}
+	}
+	runner.Cleanup("tracing", shutdownTracing)
+
+	return runner.Run(ctx)
+}
+
+// registerLoaders adds to fs the configuration flags of the named services. A single service reads
+// -config and -set, while several services read their own -<service>-config and -<service>-set,
+// and environment variables prefixed with their name.
+func registerLoaders(fs *flag.FlagSet, names []string) ([]*service, []*config.Loader, error) {
+	svcs := make([]*service, len(names))
+	loaders := make([]*config.Loader, len(names))
+	for i, name := range names {
+		svc, err := lookupService(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		svcs[i] = svc
+
+		loaders[i] = &config.Loader{Defaults: svc.defaults}
+		if len(names) == 1 {
+			loaders[i].RegisterFlags(fs)
+		} else {
+			loaders[i].EnvPrefix = strings.ToUpper(name) + "_"
+			loaders[i].RegisterPrefixedFlags(fs, name)
+		}
+	}
+
+	return svcs, loaders, nil
+}
+
+// checkDistinct fails when two of the services would listen on the same port, share a database
+// or join the same consumer group, which they cannot do without breaking each other.
+func checkDistinct(names []string, configs []*config.Config) error {
+	resources := []struct {
+		name string
+		of   func(cfg *config.Config) string
+	}{
+		{"port", func(cfg *config.Config) string { return strconv.Itoa(cfg.Server.Port) }},
+		{"database", func(cfg *config.Config) string {
+			return fmt.Sprintf("%s:%d/%s", cfg.Postgres.Host, cfg.Postgres.Port, cfg.Postgres.Database)
+		}},
+		{"consumer group", func(cfg *config.Config) string { return cfg.Kafka.ConsumerGroup }},
+	}
+
+	for _, r := range resources {
+		usedBy := make(map[string]string)
+		for i, cfg := range configs {
+			value := r.of(cfg)
+			if other, ok := usedBy[value]; ok {
+				return fmt.Errorf("%s and %s are both configured with %s %s", other, names[i], r.name, value)
+			}
+			usedBy[value] = names[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/giornetta/microshop/bootstrap"
+	"github.com/giornetta/microshop/config"
+	"github.com/giornetta/microshop/customers"
+	customersPg "github.com/giornetta/microshop/customers/pg"
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/products"
+	productsPg "github.com/giornetta/microshop/products/pg"
+	"github.com/giornetta/microshop/services"
+)
+
+// service adds to the wiring of a service the projection rebuilt by replay, and its default configuration.
+type service struct {
+	bootstrap.Service
+
+	// tables are the tables of the projection, which replay rebuilds and swaps together.
+	tables []string
+	// projection returns the handler writing the projection to the tables named by table,
+	// which maps every one of tables to the table to write instead.
+	projection func(pool *pgxpool.Pool, table func(name string) string) events.Handler
+
+	// defaults gives the service its own port, database, migrations table and consumer group,
+	// matching docker-compose.yml, so that serve all runs every service with no configuration.
+	defaults func(cfg *config.Config)
+}
+
+// serviceNames lists the services in the order they are started by serve all.
+var serviceNames = []string{"products", "customers"}
+
+var knownServices = map[string]*service{
+	"products": {
+		Service: services.Products(services.PgProductStorage),
+		tables:  []string{"products", "price_history"},
+		projection: func(pool *pgxpool.Pool, table func(name string) string) events.Handler {
+			return events.Handlers(
+				products.NewProductHandler(productsPg.NewProductRepositoryWithTable(pool, table("products"))),
+				products.NewPriceHistoryHandler(productsPg.NewPriceHistoryRepositoryWithTable(pool, table("price_history"))),
+			)
+		},
+		defaults: func(cfg *config.Config) {
+			cfg.Server.Port = 8000
+			cfg.Postgres.Port = 5432
+			cfg.Postgres.Database = "products"
+			cfg.Postgres.MigrationsTable = "products_schema_migrations"
+			cfg.Kafka.ConsumerGroup = "products-service"
+		},
+	},
+	"customers": {
+		Service: services.Customers(services.PgCustomerRepository),
+		tables:  []string{"customers"},
+		projection: func(pool *pgxpool.Pool, table func(name string) string) events.Handler {
+			return customers.NewCustomerHandler(customersPg.NewCustomerRepositoryWithTable(pool, table("customers")))
+		},
+		defaults: func(cfg *config.Config) {
+			cfg.Server.Port = 8001
+			cfg.Postgres.Port = 5433
+			cfg.Postgres.Database = "customers"
+			cfg.Postgres.MigrationsTable = "customers_schema_migrations"
+			cfg.Kafka.ConsumerGroup = "customers-service"
+		},
+	},
+}
+
+func lookupService(name string) (*service, error) {
+	svc, ok := knownServices[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown service %q: expected products or customers", name)
+	}
+
+	return svc, nil
+}
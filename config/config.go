@@ -2,16 +2,26 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/caarlos0/env/v9"
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
+	Server   ServerConfig   `yaml:"server" envPrefix:"SERVER_"`
 	Postgres PostgresConfig `yaml:"postgres" envPrefix:"POSTGRES_"`
 	Kafka    KafkaConfig    `yaml:"kafka" envPrefix:"KAFKA_"`
+
+	EventStore EventStoreConfig `yaml:"event-store" envPrefix:"EVENT_STORE_"`
+	Tracing    TracingConfig    `yaml:"tracing" envPrefix:"TRACING_"`
+	Log        LogConfig        `yaml:"log" envPrefix:"LOG_"`
+
+	Notifications NotificationsConfig `yaml:"notifications" envPrefix:"NOTIFICATIONS_"`
+	Pricing       PricingConfig       `yaml:"pricing" envPrefix:"PRICING_"`
 }
 
 func FromYaml(filename string) (*Config, error) {
@@ -42,29 +52,215 @@ func FromEnv() (*Config, error) {
 
 type ServerConfig struct {
 	Port int `yaml:"port"`
+
+	ReadTimeout       time.Duration `yaml:"read-timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read-header-timeout"`
+	WriteTimeout      time.Duration `yaml:"write-timeout"`
+	IdleTimeout       time.Duration `yaml:"idle-timeout"`
+	// ShutdownTimeout bounds how long in-flight requests and events are waited for on shutdown.
+	ShutdownTimeout time.Duration `yaml:"shutdown-timeout"`
+
+	TLS ServerTLSConfig `yaml:"tls" envPrefix:"TLS_"`
+
+	// EventsToken is the bearer token required to POST events to /api/v1/events, which is disabled when it is empty.
+	EventsToken Secret `yaml:"events-token"`
+	// EventsTokenFile is read into EventsToken, to load it from a mounted secret.
+	EventsTokenFile string `yaml:"events-token-file"`
+}
+
+// ServerTLSConfig enables HTTPS when both CertFile and KeyFile are set.
+// The files are reloaded when they change, so certificates can be rotated in place.
+type ServerTLSConfig struct {
+	CertFile string `yaml:"cert-file"`
+	KeyFile  string `yaml:"key-file"`
+	// ClientCAFile enables mutual TLS, requiring clients to present a certificate signed by these CAs.
+	ClientCAFile string `yaml:"client-ca-file"`
+}
+
+func (c *ServerTLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
 }
 
 type PostgresConfig struct {
 	Host     string `yaml:"host"`
 	Username string `yaml:"username"`
-	Password string `yaml:"password"`
-	Database string `yaml:"database"`
-	Port     int    `yaml:"port"`
-	SSL      bool   `yaml:"ssl"`
+	Password Secret `yaml:"password"`
+	// PasswordFile is read into Password, to load it from a mounted secret.
+	PasswordFile string `yaml:"password-file"`
+	Database     string `yaml:"database"`
+	Port         int    `yaml:"port"`
+	SSL          bool   `yaml:"ssl"`
+	// AutoMigrate applies pending migrations on startup. Disable it to run them with the migrate command instead.
+	AutoMigrate bool `yaml:"auto-migrate"`
+	// MigrationsTable is the table recording the applied migrations, schema_migrations when empty.
+	// Services sharing a database need their own, and databases migrated before services got their own
+	// default must set it back to schema_migrations.
+	MigrationsTable string `yaml:"migrations-table"`
+
+	ConnectTimeout    time.Duration `yaml:"connect-timeout"`
+	MaxConns          int32         `yaml:"max-conns"`
+	MinConns          int32         `yaml:"min-conns"`
+	MaxConnLifetime   time.Duration `yaml:"max-conn-lifetime"`
+	MaxConnIdleTime   time.Duration `yaml:"max-conn-idle-time"`
+	HealthCheckPeriod time.Duration `yaml:"health-check-period"`
 }
 
 func (c *PostgresConfig) ConnectionString() string {
 	var ssl string
 	if c.SSL {
-		ssl = "enable"
+		ssl = "require"
 	} else {
 		ssl = "disable"
 	}
 
-	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s", c.Username, c.Password, c.Host, c.Port, c.Database, ssl)
+	query := url.Values{}
+	query.Set("sslmode", ssl)
+
+	if c.ConnectTimeout > 0 {
+		query.Set("connect_timeout", strconv.Itoa(int(c.ConnectTimeout.Seconds())))
+	}
+	if c.MaxConns > 0 {
+		query.Set("pool_max_conns", strconv.Itoa(int(c.MaxConns)))
+	}
+	if c.MinConns > 0 {
+		query.Set("pool_min_conns", strconv.Itoa(int(c.MinConns)))
+	}
+	if c.MaxConnLifetime > 0 {
+		query.Set("pool_max_conn_lifetime", c.MaxConnLifetime.String())
+	}
+	if c.MaxConnIdleTime > 0 {
+		query.Set("pool_max_conn_idle_time", c.MaxConnIdleTime.String())
+	}
+	if c.HealthCheckPeriod > 0 {
+		query.Set("pool_health_check_period", c.HealthCheckPeriod.String())
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.Username, string(c.Password)),
+		Host:     fmt.Sprintf("%s:%d", c.Host, c.Port),
+		Path:     c.Database,
+		RawQuery: query.Encode(),
+	}
+
+	return u.String()
+}
+
+// MigrationsConnectionString returns the connection string of the database for the migrate driver,
+// which records the applied migrations in MigrationsTable.
+func (c *PostgresConfig) MigrationsConnectionString() string {
+	if c.MigrationsTable == "" {
+		return c.ConnectionString()
+	}
+
+	u, _ := url.Parse(c.ConnectionString())
+	query := u.Query()
+	query.Set("x-migrations-table", c.MigrationsTable)
+	u.RawQuery = query.Encode()
+
+	return u.String()
 }
 
 type KafkaConfig struct {
 	ConsumerGroup string   `yaml:"consumer-group" env:"CG"`
 	BrokerAddrs   []string `yaml:"brokers" env:"BROKERS"`
+	ClientID      string   `yaml:"client-id" env:"CLIENT_ID"`
+
+	// Encodings maps topics to the format of their payloads: json (default), protobuf or avro.
+	// In the environment it is a comma-separated list of topic:format pairs, such as
+	// KAFKA_ENCODINGS=Products:avro,Customers:protobuf.
+	Encodings map[string]string `yaml:"encodings" env:"ENCODINGS"`
+	// SchemaRegistry is the directory of the local schema registry used by binary formats.
+	SchemaRegistry string `yaml:"schema-registry" env:"SCHEMA_REGISTRY"`
+	// Binding publishes events as CloudEvents, using the binary or structured Kafka binding.
+	// When empty, events are published with the EventType header.
+	Binding string `yaml:"cloudevents-binding" env:"CLOUDEVENTS_BINDING"`
+	// MaxConsumerLag is the lag above which the service is reported as not ready. Zero disables the check.
+	MaxConsumerLag int64 `yaml:"max-consumer-lag" env:"MAX_CONSUMER_LAG"`
+
+	AutoCreateTopics bool          `yaml:"auto-create-topics" env:"AUTO_CREATE_TOPICS"`
+	SessionTimeout   time.Duration `yaml:"session-timeout" env:"SESSION_TIMEOUT"`
+	RebalanceTimeout time.Duration `yaml:"rebalance-timeout" env:"REBALANCE_TIMEOUT"`
+	FetchMaxWait     time.Duration `yaml:"fetch-max-wait" env:"FETCH_MAX_WAIT"`
+	FetchMaxBytes    int32         `yaml:"fetch-max-bytes" env:"FETCH_MAX_BYTES"`
+	// ProducerAcks is the acknowledgement required for produced records: all, leader or none.
+	ProducerAcks          string        `yaml:"producer-acks" env:"PRODUCER_ACKS"`
+	ProducerLinger        time.Duration `yaml:"producer-linger" env:"PRODUCER_LINGER"`
+	ProducerBatchMaxBytes int32         `yaml:"producer-batch-max-bytes" env:"PRODUCER_BATCH_MAX_BYTES"`
+
+	TLS  KafkaTLSConfig `yaml:"tls" envPrefix:"TLS_"`
+	SASL SASLConfig     `yaml:"sasl" envPrefix:"SASL_"`
+}
+
+type KafkaTLSConfig struct {
+	Enabled bool `yaml:"enabled" env:"ENABLED"`
+	// CAFile verifies brokers against these CAs instead of the system ones.
+	CAFile string `yaml:"ca-file" env:"CA_FILE"`
+	// CertFile and KeyFile are presented to brokers requiring mutual TLS.
+	CertFile           string `yaml:"cert-file" env:"CERT_FILE"`
+	KeyFile            string `yaml:"key-file" env:"KEY_FILE"`
+	InsecureSkipVerify bool   `yaml:"insecure-skip-verify" env:"INSECURE_SKIP_VERIFY"`
+}
+
+type SASLConfig struct {
+	// Mechanism is one of PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512. SASL is disabled when empty.
+	Mechanism string `yaml:"mechanism" env:"MECHANISM"`
+	Username  string `yaml:"username" env:"USERNAME"`
+	Password  Secret `yaml:"password" env:"PASSWORD"`
+	// PasswordFile is read into Password, to load it from a mounted secret.
+	PasswordFile string `yaml:"password-file" env:"PASSWORD_FILE"`
+}
+
+type EventStoreConfig struct {
+	// SnapshotEvery is the number of events after which a snapshot of an aggregate is taken.
+	SnapshotEvery int `yaml:"snapshot-every" env:"SNAPSHOT_EVERY"`
+	// RelayInterval is how often the outbox of the event store is drained, bounding how late events are published.
+	RelayInterval time.Duration `yaml:"relay-interval" env:"RELAY_INTERVAL"`
+}
+
+type PricingConfig struct {
+	// ScheduleInterval is how often the price schedules are checked, bounding how late they are applied.
+	ScheduleInterval time.Duration `yaml:"schedule-interval" env:"SCHEDULE_INTERVAL"`
+}
+
+type TracingConfig struct {
+	// Exporter is where spans are sent: stdout, otlp, or nowhere when empty.
+	Exporter string `yaml:"exporter" env:"EXPORTER"`
+	Endpoint string `yaml:"endpoint" env:"ENDPOINT"`
+	Insecure bool   `yaml:"insecure" env:"INSECURE"`
+}
+
+type LogConfig struct {
+	// Format is text or json.
+	Format string `yaml:"format" env:"FORMAT"`
+	// Level is debug, info, warn or error.
+	Level string `yaml:"level" env:"LEVEL"`
+	// Levels overrides Level for named loggers, such as http, lifecycle or products-service.
+	Levels map[string]string `yaml:"levels" env:"LEVELS"`
+}
+
+// NotificationsConfig configures where stock alerts are delivered.
+type NotificationsConfig struct {
+	// Sinks are log, webhook and email. Alerts are not delivered when empty.
+	Sinks   []string      `yaml:"sinks" env:"SINKS"`
+	Webhook WebhookConfig `yaml:"webhook" envPrefix:"WEBHOOK_"`
+	Email   EmailConfig   `yaml:"email" envPrefix:"EMAIL_"`
+}
+
+type WebhookConfig struct {
+	URL     string        `yaml:"url" env:"URL"`
+	Timeout time.Duration `yaml:"timeout" env:"TIMEOUT"`
+}
+
+type EmailConfig struct {
+	// SMTPAddr is the host:port of the SMTP server, such as the mailpit service of docker-compose.
+	SMTPAddr string   `yaml:"smtp-addr" env:"SMTP_ADDR"`
+	From     string   `yaml:"from" env:"FROM"`
+	To       []string `yaml:"to" env:"TO"`
+	// Username and Password authenticate with PLAIN auth when set.
+	Username string `yaml:"username" env:"USERNAME"`
+	Password Secret `yaml:"password" env:"PASSWORD"`
+	// PasswordFile is read into Password, to load it from a mounted secret.
+	PasswordFile string        `yaml:"password-file" env:"PASSWORD_FILE"`
+	Timeout      time.Duration `yaml:"timeout" env:"TIMEOUT"`
 }
@@ -0,0 +1,285 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/caarlos0/env/v9"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFile is the configuration file read when none is given. Unlike an explicitly given file, it may be missing.
+const DefaultFile = "./config.yml"
+
+// Default returns the configuration every layer is applied on top of.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:              8080,
+			ReadTimeout:       5 * time.Second,
+			ReadHeaderTimeout: 2 * time.Second,
+			WriteTimeout:      5 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			ShutdownTimeout:   15 * time.Second,
+		},
+		Postgres: PostgresConfig{
+			Host:              "localhost",
+			Port:              5432,
+			AutoMigrate:       true,
+			ConnectTimeout:    5 * time.Second,
+			MaxConns:          10,
+			MaxConnLifetime:   time.Hour,
+			MaxConnIdleTime:   30 * time.Minute,
+			HealthCheckPeriod: time.Minute,
+		},
+		Kafka: KafkaConfig{
+			BrokerAddrs:      []string{"localhost:9092"},
+			AutoCreateTopics: true,
+			SessionTimeout:   45 * time.Second,
+			RebalanceTimeout: time.Minute,
+			FetchMaxWait:     5 * time.Second,
+			ProducerAcks:     "all",
+		},
+		EventStore: EventStoreConfig{
+			SnapshotEvery: 100,
+			RelayInterval: 100 * time.Millisecond,
+		},
+		Log: LogConfig{
+			Format: "text",
+			Level:  "info",
+		},
+		Notifications: NotificationsConfig{
+			Webhook: WebhookConfig{
+				Timeout: 5 * time.Second,
+			},
+			Email: EmailConfig{
+				Timeout: 10 * time.Second,
+			},
+		},
+		Pricing: PricingConfig{
+			ScheduleInterval: 10 * time.Second,
+		},
+	}
+}
+
+// Loader builds a Config by layering, in order, the defaults, a YAML file, environment variables
+// and flag overrides, then reads secret files and validates the result.
+type Loader struct {
+	File      string
+	Overrides []string
+
+	// EnvPrefix adds a layer of environment variables with this prefix, such as PRODUCTS_, applied after
+	// the unprefixed ones. It lets services running in the same process share some variables and not others.
+	EnvPrefix string
+
+	// Defaults adjusts the defaults before the other layers are applied, giving a service its own,
+	// such as its port and database, so that services running in the same process do not collide.
+	Defaults func(cfg *Config)
+
+	defaultFile string
+}
+
+// RegisterFlags adds the -config and -set flags to fs.
+func (l *Loader) RegisterFlags(fs *flag.FlagSet) {
+	l.defaultFile = DefaultFile
+	fs.StringVar(&l.File, "config", DefaultFile, "YAML configuration file")
+	fs.Func("set", "override a configuration key, such as server.port=9000 (repeatable)", func(s string) error {
+		l.Overrides = append(l.Overrides, s)
+		return nil
+	})
+}
+
+// RegisterPrefixedFlags adds the -<prefix>-config and -<prefix>-set flags to fs,
+// for a configuration whose file defaults to ./<prefix>.yml.
+func (l *Loader) RegisterPrefixedFlags(fs *flag.FlagSet, prefix string) {
+	l.defaultFile = fmt.Sprintf("./%s.yml", prefix)
+	fs.StringVar(&l.File, prefix+"-config", l.defaultFile, "YAML configuration file of "+prefix)
+	fs.Func(prefix+"-set", "override a configuration key of "+prefix+", such as server.port=9000 (repeatable)", func(s string) error {
+		l.Overrides = append(l.Overrides, s)
+		return nil
+	})
+}
+
+func (l *Loader) Load() (*Config, error) {
+	cfg, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// LoadKafka loads the configuration like Load, only validating its kafka section,
+// for tools that do not need the rest.
+func (l *Loader) LoadKafka() (*KafkaConfig, error) {
+	cfg, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yamlNames(validation.Errors{"Kafka": cfg.Kafka.Validate()}.Filter(), reflect.TypeOf(*cfg)); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &cfg.Kafka, nil
+}
+
+func (l *Loader) load() (*Config, error) {
+	cfg := Default()
+	if l.Defaults != nil {
+		l.Defaults(cfg)
+	}
+
+	if err := cfg.applyFile(l.File, l.File == l.defaultFile); err != nil {
+		return nil, err
+	}
+
+	if err := env.ParseWithOptions(cfg, env.Options{UseFieldNameByDefault: true}); err != nil {
+		return nil, fmt.Errorf("could not read environment: %w", err)
+	}
+
+	if l.EnvPrefix != "" {
+		if err := env.ParseWithOptions(cfg, env.Options{UseFieldNameByDefault: true, Prefix: l.EnvPrefix}); err != nil {
+			return nil, fmt.Errorf("could not read environment: %w", err)
+		}
+	}
+
+	for _, o := range l.Overrides {
+		if err := cfg.apply(o); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.readSecrets(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// applyFile decodes filename over c. An optional file may be missing.
+func (c *Config) applyFile(filename string, optional bool) error {
+	if filename == "" {
+		return nil
+	}
+
+	yfile, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) && optional {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := decodeStrict(yfile, c); err != nil {
+		return fmt.Errorf("could not parse %s: %w", filename, err)
+	}
+
+	return nil
+}
+
+// apply sets the key of a key=value override, given as the dot separated path of its YAML keys.
+// The value is parsed as YAML, so lists can be given as [a, b].
+func (c *Config) apply(override string) error {
+	path, value, ok := strings.Cut(override, "=")
+	if !ok {
+		return fmt.Errorf("invalid override %q: expected key=value", override)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &doc); err != nil {
+		return fmt.Errorf("invalid override %q: %w", override, err)
+	}
+
+	node := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	if len(doc.Content) > 0 && doc.Content[0].Kind != yaml.ScalarNode {
+		node = doc.Content[0]
+	}
+
+	keys := strings.Split(path, ".")
+	for i := len(keys) - 1; i >= 0; i-- {
+		node = &yaml.Node{
+			Kind:    yaml.MappingNode,
+			Content: []*yaml.Node{{Kind: yaml.ScalarNode, Value: keys[i]}, node},
+		}
+	}
+
+	b, err := yaml.Marshal(node)
+	if err != nil {
+		return err
+	}
+
+	if err := decodeStrict(b, c); err != nil {
+		return fmt.Errorf("invalid override %q: %w", override, err)
+	}
+
+	return nil
+}
+
+func decodeStrict(b []byte, v any) error {
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+
+	if err := dec.Decode(v); err != nil && err != io.EOF {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Config) readSecrets() error {
+	if err := readSecret(&c.Server.EventsToken, c.Server.EventsTokenFile); err != nil {
+		return err
+	}
+
+	if err := readSecret(&c.Postgres.Password, c.Postgres.PasswordFile); err != nil {
+		return err
+	}
+
+	if err := readSecret(&c.Kafka.SASL.Password, c.Kafka.SASL.PasswordFile); err != nil {
+		return err
+	}
+
+	return readSecret(&c.Notifications.Email.Password, c.Notifications.Email.PasswordFile)
+}
+
+func readSecret(s *Secret, filename string) error {
+	if filename == "" {
+		return nil
+	}
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("could not read secret: %w", err)
+	}
+
+	*s = Secret(strings.TrimRight(string(b), "\r\n"))
+	return nil
+}
+
+// Secret is a configuration value, such as a password, that is redacted when the configuration is printed.
+type Secret string
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+
+	return "REDACTED"
+}
+
+func (s Secret) MarshalYAML() (any, error) {
+	return s.String(), nil
+}
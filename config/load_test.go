@@ -0,0 +1,234 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// writeFile writes content to a file in a temporary directory and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return filename
+}
+
+const minimalFile = `
+postgres:
+  username: microshop
+  database: microshop
+`
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		env       map[string]string
+		prefix    string
+		overrides []string
+		want      int
+	}{
+		{name: "defaults", want: 8080},
+		{name: "file over defaults", file: "server:\n  port: 8081\n", want: 8081},
+		{name: "env over file", file: "server:\n  port: 8081\n", env: map[string]string{"SERVER_PORT": "8082"}, want: 8082},
+		{
+			name:   "prefixed env over env",
+			file:   "server:\n  port: 8081\n",
+			env:    map[string]string{"SERVER_PORT": "8082", "PRODUCTS_SERVER_PORT": "8083"},
+			prefix: "PRODUCTS_",
+			want:   8083,
+		},
+		{
+			name:      "set over env",
+			file:      "server:\n  port: 8081\n",
+			env:       map[string]string{"SERVER_PORT": "8082"},
+			overrides: []string{"server.port=8084"},
+			want:      8084,
+		},
+		{name: "last set wins", overrides: []string{"server.port=8084", "server.port=8085"}, want: 8085},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			l := Loader{
+				File:      writeFile(t, "config.yml", minimalFile+tt.file),
+				Overrides: tt.overrides,
+				EnvPrefix: tt.prefix,
+			}
+
+			cfg, err := l.Load()
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+
+			if cfg.Server.Port != tt.want {
+				t.Errorf("got port %d, want %d", cfg.Server.Port, tt.want)
+			}
+
+			// Layers only replace the keys they set.
+			if cfg.Server.ShutdownTimeout != 15*time.Second || cfg.Postgres.Username != "microshop" {
+				t.Errorf("layers replaced keys they did not set: %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestLoadServiceDefaults(t *testing.T) {
+	t.Setenv("SERVER_PORT", "8082")
+
+	l := Loader{
+		File: writeFile(t, "config.yml", minimalFile),
+		Defaults: func(cfg *Config) {
+			cfg.Server.Port = 8090
+			cfg.Postgres.Database = "products"
+		},
+	}
+
+	cfg, err := l.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	// Service defaults are defaults all the same, under the file and the environment.
+	if cfg.Server.Port != 8082 || cfg.Postgres.Database != "microshop" {
+		t.Errorf("got port %d and database %q, want 8082 and microshop", cfg.Server.Port, cfg.Postgres.Database)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	tests := []struct {
+		name      string
+		file      string
+		overrides []string
+		env       map[string]string
+		want      string
+	}{
+		{name: "unknown key", file: "server:\n  prot: 9000\n", want: "field prot not found"},
+		{name: "unknown section", file: "sever:\n  port: 9000\n", want: "field sever not found"},
+		{name: "mistyped value", file: "server:\n  port: high\n", want: "could not parse"},
+		{name: "unknown set path", overrides: []string{"server.prot=9000"}, want: `invalid override "server.prot=9000"`},
+		{name: "set path through a value", overrides: []string{"server.port.number=9000"}, want: `invalid override "server.port.number=9000"`},
+		{name: "set without value", overrides: []string{"server.port"}, want: "expected key=value"},
+		{name: "malformed env", env: map[string]string{"SERVER_PORT": "high"}, want: "could not read environment"},
+		{name: "missing secret file", env: map[string]string{"POSTGRES_PASSWORD_FILE": missing}, want: "could not read secret"},
+		// A directory stands for an unreadable file, as permissions do not stop tests running as root.
+		{name: "unreadable secret file", overrides: []string{"server.events-token-file=" + t.TempDir()}, want: "could not read secret"},
+		{name: "invalid value", overrides: []string{"server.port=70000"}, want: "invalid configuration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			l := Loader{
+				File:      writeFile(t, "config.yml", minimalFile+tt.file),
+				Overrides: tt.overrides,
+			}
+
+			_, err := l.Load()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+
+	// The default file may be missing, unlike one given explicitly.
+	l := Loader{
+		File:        filepath.Join(dir, "products.yml"),
+		Overrides:   []string{"postgres.username=microshop", "postgres.database=microshop"},
+		defaultFile: filepath.Join(dir, "products.yml"),
+	}
+	if _, err := l.Load(); err != nil {
+		t.Errorf("Load without the default file: %v", err)
+	}
+
+	l.defaultFile = "./products.yml"
+	if _, err := l.Load(); err == nil {
+		t.Error("Load succeeded without the given file")
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	secrets := map[string]string{
+		"events-token": "token-from-file",
+		"postgres":     "postgres-from-file",
+		"kafka":        "kafka-from-env",
+		"email":        "email-from-set",
+	}
+
+	t.Setenv("KAFKA_SASL_PASSWORD", secrets["kafka"])
+	t.Setenv("POSTGRES_PASSWORD_FILE", writeFile(t, "postgres", secrets["postgres"]+"\n"))
+
+	l := Loader{
+		File: writeFile(t, "config.yml", minimalFile+`
+server:
+  events-token-file: `+writeFile(t, "token", secrets["events-token"]+"\r\n")+`
+kafka:
+  sasl:
+    mechanism: PLAIN
+    username: microshop
+`),
+		Overrides: []string{"notifications.email.username=mailer", "notifications.email.password=" + secrets["email"]},
+	}
+
+	cfg, err := l.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	// Secret files are read without their trailing newline.
+	for name, got := range map[string]Secret{
+		"events-token": cfg.Server.EventsToken,
+		"postgres":     cfg.Postgres.Password,
+		"kafka":        cfg.Kafka.SASL.Password,
+		"email":        cfg.Notifications.Email.Password,
+	} {
+		if string(got) != secrets[name] {
+			t.Errorf("got %s secret %q, want %q", name, string(got), secrets[name])
+		}
+	}
+
+	var dumped bytes.Buffer
+	if err := yaml.NewEncoder(&dumped).Encode(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, secret := range secrets {
+		if strings.Contains(dumped.String(), secret) {
+			t.Errorf("the dumped configuration holds the %s secret:\n%s", name, dumped.String())
+		}
+	}
+	if n := strings.Count(dumped.String(), "REDACTED"); n != 4 {
+		t.Errorf("the dumped configuration redacts %d secrets, want 4:\n%s", n, dumped.String())
+	}
+
+	// Only the secrets are redacted, and unset ones stay empty rather than hint at a value.
+	if !strings.Contains(dumped.String(), "username: mailer") {
+		t.Errorf("the dumped configuration lacks the email username:\n%s", dumped.String())
+	}
+	if Secret("").String() != "" {
+		t.Errorf("an empty secret is printed as %q", Secret("").String())
+	}
+}
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+// Validate reports the invalid fields of c, named by their YAML keys.
+func (c *Config) Validate() error {
+	return yamlNames(validation.ValidateStruct(c,
+		validation.Field(&c.Server),
+		validation.Field(&c.Postgres),
+		validation.Field(&c.Kafka),
+		validation.Field(&c.EventStore),
+		validation.Field(&c.Tracing),
+		validation.Field(&c.Log),
+		validation.Field(&c.Notifications),
+		validation.Field(&c.Pricing),
+	), reflect.TypeOf(*c))
+}
+
+// yamlNames renames the fields of validation errors, which are the Go field names, to the YAML keys of t.
+func yamlNames(err error, t reflect.Type) error {
+	errs, ok := err.(validation.Errors)
+	if !ok {
+		return err
+	}
+
+	renamed := make(validation.Errors, len(errs))
+	for name, fieldErr := range errs {
+		f, ok := t.FieldByName(name)
+		if !ok {
+			renamed[name] = fieldErr
+			continue
+		}
+
+		if f.Type.Kind() == reflect.Struct {
+			fieldErr = yamlNames(fieldErr, f.Type)
+		}
+
+		key, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		renamed[key] = fieldErr
+	}
+
+	return renamed
+}
+
+func (c ServerConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Port, validation.Required, validation.Min(1), validation.Max(65535)),
+		validation.Field(&c.ReadTimeout, validation.Min(0)),
+		validation.Field(&c.ReadHeaderTimeout, validation.Min(0)),
+		validation.Field(&c.WriteTimeout, validation.Min(0)),
+		validation.Field(&c.IdleTimeout, validation.Min(0)),
+		validation.Field(&c.ShutdownTimeout, validation.Min(0)),
+		validation.Field(&c.TLS),
+	)
+}
+
+func (c ServerTLSConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.CertFile, validation.When(c.KeyFile != "" || c.ClientCAFile != "", validation.Required)),
+		validation.Field(&c.KeyFile, validation.When(c.CertFile != "", validation.Required)),
+	)
+}
+
+func (c PostgresConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Host, validation.Required),
+		validation.Field(&c.Port, validation.Required, validation.Min(1), validation.Max(65535)),
+		validation.Field(&c.Username, validation.Required),
+		validation.Field(&c.Database, validation.Required),
+		validation.Field(&c.ConnectTimeout, validation.Min(0)),
+		validation.Field(&c.MaxConns, validation.Required, validation.Min(int32(1))),
+		validation.Field(&c.MinConns, validation.Min(int32(0)), validation.Max(c.MaxConns)),
+		validation.Field(&c.MaxConnLifetime, validation.Min(0)),
+		validation.Field(&c.MaxConnIdleTime, validation.Min(0)),
+		validation.Field(&c.HealthCheckPeriod, validation.Min(0)),
+	)
+}
+
+func (c KafkaConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.BrokerAddrs, validation.Required),
+		validation.Field(&c.Encodings, validation.Each(validation.In("json", "protobuf", "avro"))),
+		validation.Field(&c.SchemaRegistry, validation.When(usesBinaryEncoding(c.Encodings), validation.Required)),
+		validation.Field(&c.Binding, validation.In("binary", "structured")),
+		validation.Field(&c.MaxConsumerLag, validation.Min(int64(0))),
+		validation.Field(&c.SessionTimeout, validation.Min(0)),
+		validation.Field(&c.RebalanceTimeout, validation.Min(0)),
+		validation.Field(&c.FetchMaxWait, validation.Min(0)),
+		validation.Field(&c.FetchMaxBytes, validation.Min(int32(0))),
+		validation.Field(&c.ProducerAcks, validation.In("all", "leader", "none")),
+		validation.Field(&c.ProducerLinger, validation.Min(0)),
+		validation.Field(&c.ProducerBatchMaxBytes, validation.Min(int32(0))),
+		validation.Field(&c.TLS),
+		validation.Field(&c.SASL),
+	)
+}
+
+func usesBinaryEncoding(encodings map[string]string) bool {
+	for _, e := range encodings {
+		if e != "json" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c KafkaTLSConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.CertFile, validation.When(c.KeyFile != "", validation.Required)),
+		validation.Field(&c.KeyFile, validation.When(c.CertFile != "", validation.Required)),
+	)
+}
+
+func (c SASLConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Mechanism, validation.By(func(any) error {
+			switch strings.ToUpper(c.Mechanism) {
+			case "", "PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512":
+				return nil
+			default:
+				return errors.New("must be PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512")
+			}
+		})),
+		validation.Field(&c.Username, validation.When(c.Mechanism != "", validation.Required)),
+		validation.Field(&c.Password, validation.When(c.Mechanism != "", validation.Required)),
+	)
+}
+
+func (c EventStoreConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.SnapshotEvery, validation.Min(0)),
+		validation.Field(&c.RelayInterval, validation.Required, validation.Min(10*time.Millisecond)),
+	)
+}
+
+func (c PricingConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.ScheduleInterval, validation.Required, validation.Min(time.Second)),
+	)
+}
+
+func (c TracingConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Exporter, validation.In("stdout", "otlp")),
+	)
+}
+
+var logLevels = []any{"debug", "DEBUG", "info", "INFO", "warn", "WARN", "error", "ERROR"}
+
+func (c LogConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Format, validation.In("text", "json")),
+		validation.Field(&c.Level, validation.In(logLevels...)),
+		validation.Field(&c.Levels, validation.Each(validation.In(logLevels...))),
+	)
+}
+
+// Validate only checks the settings of the enabled sinks.
+func (c NotificationsConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Sinks, validation.Each(validation.In("log", "webhook", "email"))),
+		validation.Field(&c.Webhook, validation.Skip.When(!slices.Contains(c.Sinks, "webhook"))),
+		validation.Field(&c.Email, validation.Skip.When(!slices.Contains(c.Sinks, "email"))),
+	)
+}
+
+func (c WebhookConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.URL, validation.Required, is.URL),
+		validation.Field(&c.Timeout, validation.Min(0)),
+	)
+}
+
+func (c EmailConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.SMTPAddr, validation.Required, is.DialString),
+		validation.Field(&c.From, validation.Required, is.EmailFormat),
+		validation.Field(&c.To, validation.Required, validation.Each(is.EmailFormat)),
+		validation.Field(&c.Password, validation.When(c.Username != "", validation.Required)),
+		validation.Field(&c.Timeout, validation.Min(0)),
+	)
+}
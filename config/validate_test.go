@@ -0,0 +1,64 @@
+package config
+
+import (
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+func TestValidate(t *testing.T) {
+	valid := func() *Config {
+		cfg := Default()
+		cfg.Postgres.Username = "microshop"
+		cfg.Postgres.Database = "microshop"
+		return cfg
+	}
+
+	tests := []struct {
+		name string
+		edit func(cfg *Config)
+		// want is the path of YAML keys of the invalid field, empty when the configuration is valid.
+		want []string
+	}{
+		{name: "defaults with a database", edit: func(cfg *Config) {}},
+		{name: "port out of range", edit: func(cfg *Config) { cfg.Server.Port = 70000 }, want: []string{"server", "port"}},
+		{name: "missing username", edit: func(cfg *Config) { cfg.Postgres.Username = "" }, want: []string{"postgres", "username"}},
+		{name: "more min than max conns", edit: func(cfg *Config) { cfg.Postgres.MinConns = 20 }, want: []string{"postgres", "min-conns"}},
+		{name: "tls key without certificate", edit: func(cfg *Config) { cfg.Server.TLS.KeyFile = "tls.key" }, want: []string{"server", "tls", "cert-file"}},
+		{
+			name: "binary encoding without schema registry",
+			edit: func(cfg *Config) { cfg.Kafka.Encodings = map[string]string{"products": "avro"} },
+			want: []string{"kafka", "schema-registry"},
+		},
+		{name: "unknown encoding", edit: func(cfg *Config) { cfg.Kafka.Encodings = map[string]string{"products": "xml"} }, want: []string{"kafka", "encodings"}},
+		{name: "sasl without password", edit: func(cfg *Config) { cfg.Kafka.SASL = SASLConfig{Mechanism: "PLAIN", Username: "microshop"} }, want: []string{"kafka", "sasl", "password"}},
+		{name: "unknown log level", edit: func(cfg *Config) { cfg.Log.Level = "trace" }, want: []string{"log", "level"}},
+		{name: "short relay interval", edit: func(cfg *Config) { cfg.EventStore.RelayInterval = 0 }, want: []string{"event-store", "relay-interval"}},
+		{name: "disabled webhook without url", edit: func(cfg *Config) { cfg.Notifications.Sinks = []string{"log"} }},
+		{name: "webhook without url", edit: func(cfg *Config) { cfg.Notifications.Sinks = []string{"webhook"} }, want: []string{"notifications", "webhook", "url"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.edit(cfg)
+
+			err := cfg.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+
+			// Errors are nested by section, keyed by the YAML keys users write.
+			for _, key := range tt.want {
+				errs, ok := err.(validation.Errors)
+				if !ok || errs[key] == nil {
+					t.Fatalf("got error %v, want one for %v", cfg.Validate(), tt.want)
+				}
+				err = errs[key]
+			}
+		})
+	}
+}
@@ -29,17 +29,31 @@ type ShippingAddress struct {
 	Street  string `json:"street,omitempty"`
 }
 
+// CustomerQuerier reads the projection of the customers. The customers it returns always have
+// a ShippingAddress, empty until it is set.
+// Every method fails with *errors.ErrInternal when the underlying storage does.
 type CustomerQuerier interface {
+	// FindById fails with *ErrNotFound if there is no customer with the given id.
 	FindById(id CustomerId, ctx context.Context) (*Customer, error)
+	// FindByEmail fails with *ErrNotFound if there is no customer with the given email.
 	FindByEmail(email string, ctx context.Context) (*Customer, error)
 }
 
+// CustomerStorer writes the projection of the customers, as its events are handled.
+// Every method fails with *errors.ErrInternal when the underlying storage does.
 type CustomerStorer interface {
+	// Store adds a customer, ignoring its shipping address, and fails with *ErrAlreadyExists
+	// if its id or email is already taken.
 	Store(customer *Customer, ctx context.Context) error
+	// UpdateShippingAddress replaces the address of the customer. Updating a missing customer does nothing,
+	// so that the events of deleted customers can be handled again.
 	UpdateShippingAddress(id CustomerId, addr *ShippingAddress, ctx context.Context) error
+	// Delete removes the customer with the given id. Deleting a missing customer does nothing.
 	Delete(id CustomerId, ctx context.Context) error
 }
 
+// CustomerRepository is implemented by pg and memory, which must both pass the contract
+// of customerstest.TestRepository.
 type CustomerRepository interface {
 	CustomerQuerier
 	CustomerStorer
@@ -43,6 +43,16 @@ func (h *productHandler) handleCreated(evt events.CustomerCreated, ctx context.C
 	}
 
 	if err := h.repository.Store(p, ctx); err != nil {
+		// Events are published at least once: a creation handled already is ignored, while the creation of
+		// another customer with the same email can never be handled.
+		if exists, ok := err.(*ErrAlreadyExists); ok {
+			if exists.CustomerId == p.Id {
+				return nil
+			}
+
+			return &events.ErrRejected{Err: err}
+		}
+
 		return err
 	}
 
@@ -0,0 +1,241 @@
+// Package customerstest implements the contract every customers.CustomerRepository must satisfy.
+package customerstest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/giornetta/microshop/customers"
+)
+
+// TestRepository checks the behaviour of the repositories returned by newRepository,
+// documented on customers.CustomerQuerier and customers.CustomerStorer.
+// Every subtest gets a new, empty repository.
+func TestRepository(t *testing.T, newRepository func(t *testing.T) customers.CustomerRepository) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, r customers.CustomerRepository)
+	}{
+		{"FindMissing", testFindMissing},
+		{"StoreAndFind", testStoreAndFind},
+		{"StoreIgnoresShippingAddress", testStoreIgnoresShippingAddress},
+		{"StoreDuplicateId", testStoreDuplicateId},
+		{"StoreDuplicateEmail", testStoreDuplicateEmail},
+		{"UpdateShippingAddress", testUpdateShippingAddress},
+		{"UpdateShippingAddressMissing", testUpdateShippingAddressMissing},
+		{"Delete", testDelete},
+		{"DeleteMissing", testDeleteMissing},
+		{"ReturnsCopies", testReturnsCopies},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepository(t))
+		})
+	}
+}
+
+func newCustomer(email string) *customers.Customer {
+	return &customers.Customer{
+		Id:              customers.CustomerId(uuid.NewString()),
+		FirstName:       "Ada",
+		LastName:        "Lovelace",
+		Email:           email,
+		ShippingAddress: &customers.ShippingAddress{},
+	}
+}
+
+func store(t *testing.T, r customers.CustomerRepository, c *customers.Customer) {
+	t.Helper()
+
+	if err := r.Store(c, context.Background()); err != nil {
+		t.Fatalf("Store(%+v): %v", c, err)
+	}
+}
+
+func findById(t *testing.T, r customers.CustomerRepository, id customers.CustomerId) *customers.Customer {
+	t.Helper()
+
+	c, err := r.FindById(id, context.Background())
+	if err != nil {
+		t.Fatalf("FindById(%s): %v", id, err)
+	}
+	if c.ShippingAddress == nil {
+		t.Fatalf("FindById(%s) returned a customer without a shipping address", id)
+	}
+
+	return c
+}
+
+func assertEqual(t *testing.T, got, want *customers.Customer) {
+	t.Helper()
+
+	if got.Id != want.Id || got.FirstName != want.FirstName || got.LastName != want.LastName || got.Email != want.Email ||
+		*got.ShippingAddress != *want.ShippingAddress {
+		t.Errorf("got %+v with address %+v, want %+v with address %+v", got, got.ShippingAddress, want, want.ShippingAddress)
+	}
+}
+
+func assertNotFound(t *testing.T, err error, want customers.ErrNotFound) {
+	t.Helper()
+
+	var notFound *customers.ErrNotFound
+	if !errors.As(err, &notFound) {
+		t.Fatalf("got error %v, want *ErrNotFound", err)
+	}
+	if *notFound != want {
+		t.Errorf("got %+v, want %+v", *notFound, want)
+	}
+}
+
+func assertAlreadyExists(t *testing.T, err error, want customers.ErrAlreadyExists) {
+	t.Helper()
+
+	var exists *customers.ErrAlreadyExists
+	if !errors.As(err, &exists) {
+		t.Fatalf("got error %v, want *ErrAlreadyExists", err)
+	}
+	if *exists != want {
+		t.Errorf("got %+v, want %+v", *exists, want)
+	}
+}
+
+func testFindMissing(t *testing.T, r customers.CustomerRepository) {
+	id := customers.CustomerId(uuid.NewString())
+
+	_, err := r.FindById(id, context.Background())
+	assertNotFound(t, err, customers.ErrNotFound{CustomerId: id})
+
+	_, err = r.FindByEmail("missing@example.com", context.Background())
+	assertNotFound(t, err, customers.ErrNotFound{Email: "missing@example.com"})
+}
+
+func testStoreAndFind(t *testing.T, r customers.CustomerRepository) {
+	want := newCustomer("ada@example.com")
+	store(t, r, want)
+
+	assertEqual(t, findById(t, r, want.Id), want)
+
+	got, err := r.FindByEmail(want.Email, context.Background())
+	if err != nil {
+		t.Fatalf("FindByEmail: %v", err)
+	}
+	if got.ShippingAddress == nil {
+		t.Fatalf("FindByEmail returned a customer without a shipping address")
+	}
+	assertEqual(t, got, want)
+}
+
+func testStoreIgnoresShippingAddress(t *testing.T, r customers.CustomerRepository) {
+	c := newCustomer("ada@example.com")
+	c.ShippingAddress = &customers.ShippingAddress{Country: "England", City: "London", ZipCode: "12345", Street: "St James's Square"}
+	store(t, r, c)
+
+	if got := findById(t, r, c.Id); *got.ShippingAddress != (customers.ShippingAddress{}) {
+		t.Errorf("Store set the shipping address to %+v", got.ShippingAddress)
+	}
+}
+
+func testStoreDuplicateId(t *testing.T, r customers.CustomerRepository) {
+	original := newCustomer("ada@example.com")
+	store(t, r, original)
+
+	duplicate := newCustomer("augusta@example.com")
+	duplicate.Id = original.Id
+
+	err := r.Store(duplicate, context.Background())
+	assertAlreadyExists(t, err, customers.ErrAlreadyExists{CustomerId: original.Id})
+
+	assertEqual(t, findById(t, r, original.Id), original)
+}
+
+func testStoreDuplicateEmail(t *testing.T, r customers.CustomerRepository) {
+	original := newCustomer("ada@example.com")
+	store(t, r, original)
+
+	duplicate := newCustomer("ada@example.com")
+
+	err := r.Store(duplicate, context.Background())
+	assertAlreadyExists(t, err, customers.ErrAlreadyExists{Email: original.Email})
+
+	_, err = r.FindById(duplicate.Id, context.Background())
+	assertNotFound(t, err, customers.ErrNotFound{CustomerId: duplicate.Id})
+}
+
+func testUpdateShippingAddress(t *testing.T, r customers.CustomerRepository) {
+	c := newCustomer("ada@example.com")
+	store(t, r, c)
+
+	addr := &customers.ShippingAddress{Country: "England", City: "London", ZipCode: "12345", Street: "St James's Square"}
+	if err := r.UpdateShippingAddress(c.Id, addr, context.Background()); err != nil {
+		t.Fatalf("UpdateShippingAddress: %v", err)
+	}
+
+	want := *c
+	want.ShippingAddress = addr
+	assertEqual(t, findById(t, r, c.Id), &want)
+}
+
+func testUpdateShippingAddressMissing(t *testing.T, r customers.CustomerRepository) {
+	id := customers.CustomerId(uuid.NewString())
+
+	addr := &customers.ShippingAddress{Country: "England", City: "London", ZipCode: "12345", Street: "St James's Square"}
+	if err := r.UpdateShippingAddress(id, addr, context.Background()); err != nil {
+		t.Fatalf("UpdateShippingAddress of a missing customer: %v", err)
+	}
+
+	_, err := r.FindById(id, context.Background())
+	assertNotFound(t, err, customers.ErrNotFound{CustomerId: id})
+}
+
+func testDelete(t *testing.T, r customers.CustomerRepository) {
+	c := newCustomer("ada@example.com")
+	store(t, r, c)
+	other := newCustomer("augusta@example.com")
+	store(t, r, other)
+
+	if err := r.Delete(c.Id, context.Background()); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	_, err := r.FindById(c.Id, context.Background())
+	assertNotFound(t, err, customers.ErrNotFound{CustomerId: c.Id})
+
+	findById(t, r, other.Id)
+
+	// The email of a deleted customer can be taken again.
+	store(t, r, newCustomer(c.Email))
+}
+
+func testDeleteMissing(t *testing.T, r customers.CustomerRepository) {
+	if err := r.Delete(customers.CustomerId(uuid.NewString()), context.Background()); err != nil {
+		t.Fatalf("Delete of a missing customer: %v", err)
+	}
+}
+
+func testReturnsCopies(t *testing.T, r customers.CustomerRepository) {
+	c := newCustomer("ada@example.com")
+	store(t, r, c)
+
+	addr := &customers.ShippingAddress{Country: "England", City: "London", ZipCode: "12345", Street: "St James's Square"}
+	if err := r.UpdateShippingAddress(c.Id, addr, context.Background()); err != nil {
+		t.Fatalf("UpdateShippingAddress: %v", err)
+	}
+	want := *c
+	want.ShippingAddress = &customers.ShippingAddress{}
+	*want.ShippingAddress = *addr
+
+	c.FirstName = "Augusta"
+	addr.City = "Paris"
+
+	got := findById(t, r, c.Id)
+	got.LastName = "King"
+	got.ShippingAddress.Street = "Rue de Rivoli"
+
+	assertEqual(t, findById(t, r, c.Id), &want)
+}
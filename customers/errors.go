@@ -27,10 +27,15 @@ func (err *ErrNotFound) StatusCode() int {
 }
 
 type ErrAlreadyExists struct {
-	Email string
+	CustomerId CustomerId
+	Email      string
 }
 
 func (err *ErrAlreadyExists) Error() string {
+	if err.CustomerId != "" {
+		return fmt.Sprintf("customer with id=%s already exists", err.CustomerId.String())
+	}
+
 	return fmt.Sprintf("customer with email=%s already exists", err.Email)
 }
 
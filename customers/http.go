@@ -22,7 +22,6 @@ func NewRouter(service Service) http.Handler {
 	router := chi.NewRouter()
 
 	router.Use(
-		middleware.Logger,
 		middleware.Recoverer,
 	)
 
@@ -0,0 +1,137 @@
+package customers
+
+import (
+	"context"
+	"fmt"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+
+	"github.com/giornetta/microshop/cloudevents"
+	"github.com/giornetta/microshop/errors"
+	"github.com/giornetta/microshop/events"
+)
+
+type eventIngester struct {
+	querier   CustomerQuerier
+	publisher events.Publisher
+}
+
+// NewEventIngester returns an Ingester validating the events it is given like the requests of the Service,
+// and publishing them only if they are all valid. The emails of the customers they create must not be taken,
+// either in the projection or by the events ingested before them.
+// Events are published one at a time, keeping the attributes of the CloudEvents they were received as,
+// so that a failure leaves the following events unpublished.
+func NewEventIngester(querier CustomerQuerier, publisher events.Publisher) cloudevents.Ingester {
+	return &eventIngester{
+		querier:   querier,
+		publisher: publisher,
+	}
+}
+
+func (i *eventIngester) Ingest(received []cloudevents.Received, ctx context.Context) error {
+	// customers holds the customers as the events ingested so far leave them, nil once deleted.
+	customers := make(map[CustomerId]*Customer)
+
+	evts := make([]events.Event, len(received))
+	for n, r := range received {
+		var err error
+		if evts[n], err = i.validate(r.Event, customers, ctx); err != nil {
+			return err
+		}
+	}
+
+	for n, r := range received {
+		if err := i.publisher.Publish(evts[n], events.WithCloudEvent(ctx, r.CloudEvent)); err != nil {
+			return &cloudevents.ErrPartiallyIngested{Ingested: n, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// validate checks the event against the customers, and returns it with the spaces around its values trimmed,
+// as the Service would publish it.
+func (i *eventIngester) validate(evt events.Event, customers map[CustomerId]*Customer, ctx context.Context) (events.Event, error) {
+	id := CustomerId(evt.Key())
+	if err := validation.Validate(id.String(), is.UUID); err != nil {
+		return nil, &errors.ErrBadRequest{Err: fmt.Errorf("invalid customer id %s: %w", id, err)}
+	}
+
+	c, ok := customers[id]
+	if !ok {
+		var err error
+		if c, err = i.querier.FindById(id, ctx); err != nil {
+			if _, ok := err.(*ErrNotFound); !ok {
+				return nil, err
+			}
+		}
+	}
+
+	switch e := evt.(type) {
+	case events.CustomerCreated:
+		if c != nil {
+			return nil, &ErrAlreadyExists{CustomerId: id}
+		}
+
+		req := &CreateCustomerRequest{FirstName: e.FirstName, LastName: e.LastName, Email: e.Email}
+		if err := req.Validate(); err != nil {
+			return nil, &errors.ErrBadRequest{Err: err}
+		}
+
+		if err := i.checkEmail(req.Email, customers, ctx); err != nil {
+			return nil, err
+		}
+
+		c = &Customer{Id: id, FirstName: req.FirstName, LastName: req.LastName, Email: req.Email}
+		e.FirstName, e.LastName, e.Email = req.FirstName, req.LastName, req.Email
+		evt = e
+	case events.CustomerShippingAddressUpdated:
+		if c == nil {
+			return nil, &ErrNotFound{CustomerId: id}
+		}
+
+		req := &UpdateShippingAddressRequest{Id: id, Country: e.Country, City: e.City, ZipCode: e.ZipCode, Street: e.Street}
+		if err := req.Validate(); err != nil {
+			return nil, &errors.ErrBadRequest{Err: err}
+		}
+		e.Country, e.City, e.ZipCode, e.Street = req.Country, req.City, req.ZipCode, req.Street
+		evt = e
+	case events.CustomerDeleted:
+		if c == nil {
+			return nil, &ErrNotFound{CustomerId: id}
+		}
+
+		c = nil
+	default:
+		return nil, &errors.ErrBadRequest{Err: fmt.Errorf("%s events cannot be ingested", evt.Type())}
+	}
+
+	customers[id] = c
+	return evt, nil
+}
+
+// checkEmail checks that no customer has the email, comparing those of the projection changed by the events
+// ingested so far in the state the events left them in.
+func (i *eventIngester) checkEmail(email string, customers map[CustomerId]*Customer, ctx context.Context) error {
+	for _, c := range customers {
+		if c != nil && c.Email == email {
+			return &ErrAlreadyExists{Email: email}
+		}
+	}
+
+	c, err := i.querier.FindByEmail(email, ctx)
+	if err != nil {
+		if _, ok := err.(*ErrNotFound); ok {
+			return nil
+		}
+
+		return err
+	}
+
+	if _, ok := customers[c.Id]; !ok {
+		return &ErrAlreadyExists{Email: email}
+	}
+
+	return nil
+}
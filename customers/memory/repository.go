@@ -0,0 +1,99 @@
+// Package memory implements a CustomerRepository in memory, standing in for Postgres where a database
+// is not available, such as in tests.
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/giornetta/microshop/customers"
+)
+
+type repository struct {
+	lock      sync.RWMutex
+	customers map[customers.CustomerId]customers.Customer
+}
+
+func NewCustomerRepository() customers.CustomerRepository {
+	return &repository{
+		customers: make(map[customers.CustomerId]customers.Customer),
+	}
+}
+
+func (r *repository) FindById(id customers.CustomerId, ctx context.Context) (*customers.Customer, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	c, ok := r.customers[id]
+	if !ok {
+		return nil, &customers.ErrNotFound{CustomerId: id}
+	}
+
+	return copyCustomer(c), nil
+}
+
+func (r *repository) FindByEmail(email string, ctx context.Context) (*customers.Customer, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for _, c := range r.customers {
+		if c.Email == email {
+			return copyCustomer(c), nil
+		}
+	}
+
+	return nil, &customers.ErrNotFound{Email: email}
+}
+
+func (r *repository) Store(customer *customers.Customer, ctx context.Context) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.customers[customer.Id]; ok {
+		return &customers.ErrAlreadyExists{CustomerId: customer.Id}
+	}
+
+	for _, c := range r.customers {
+		if c.Email == customer.Email {
+			return &customers.ErrAlreadyExists{Email: customer.Email}
+		}
+	}
+
+	c := *customer
+	c.ShippingAddress = &customers.ShippingAddress{}
+	r.customers[c.Id] = c
+
+	return nil
+}
+
+func (r *repository) UpdateShippingAddress(id customers.CustomerId, addr *customers.ShippingAddress, ctx context.Context) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	c, ok := r.customers[id]
+	if !ok {
+		return nil
+	}
+
+	a := *addr
+	c.ShippingAddress = &a
+	r.customers[id] = c
+
+	return nil
+}
+
+func (r *repository) Delete(id customers.CustomerId, ctx context.Context) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.customers, id)
+	return nil
+}
+
+// copyCustomer returns a copy of c not sharing its shipping address with the stored one.
+func copyCustomer(c customers.Customer) *customers.Customer {
+	addr := *c.ShippingAddress
+	c.ShippingAddress = &addr
+
+	return &c
+}
@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/giornetta/microshop/customers"
+	"github.com/giornetta/microshop/customers/customerstest"
+)
+
+func TestRepository(t *testing.T) {
+	customerstest.TestRepository(t, func(t *testing.T) customers.CustomerRepository {
+		return NewCustomerRepository()
+	})
+}
@@ -0,0 +1,8 @@
+package pg
+
+import "embed"
+
+// Migrations holds the schema of the customers database, in its migrations directory.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
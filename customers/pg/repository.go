@@ -3,9 +3,11 @@ package pg
 import (
 	"context"
 	"database/sql"
+	"fmt"
 
 	"github.com/giornetta/microshop/customers"
 	"github.com/giornetta/microshop/errors"
+	"github.com/giornetta/microshop/postgres"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -39,24 +41,40 @@ func (m customerModel) ToCustomer() *customers.Customer {
 }
 
 type repository struct {
-	pool *pgxpool.Pool
+	pool  *pgxpool.Pool
+	table string
 }
 
 func NewCustomerRepository(pool *pgxpool.Pool) customers.CustomerRepository {
+	return NewCustomerRepositoryWithTable(pool, "customers")
+}
+
+// NewCustomerRepositoryWithTable returns a CustomerRepository backed by the given table,
+// which must have the same columns as the customers one.
+func NewCustomerRepositoryWithTable(pool *pgxpool.Pool, table string) customers.CustomerRepository {
 	return &repository{
-		pool: pool,
+		pool:  pool,
+		table: pgx.Identifier{table}.Sanitize(),
 	}
 }
 
 func (r *repository) Store(c *customers.Customer, ctx context.Context) error {
 	if _, err := r.pool.Exec(
 		ctx,
-		`INSERT INTO
-		customers(customer_id, first_name, last_name, email)
-		VALUES($1, $2, $3, $4);`,
+		fmt.Sprintf(`INSERT INTO
+		%s(customer_id, first_name, last_name, email)
+		VALUES($1, $2, $3, $4);`, r.table),
 		c.Id, c.FirstName, c.LastName, c.Email,
 	); err != nil {
-		return err
+		if violation, primaryKey := postgres.UniqueViolation(err); violation {
+			if primaryKey {
+				return &customers.ErrAlreadyExists{CustomerId: c.Id}
+			}
+
+			return &customers.ErrAlreadyExists{Email: c.Email}
+		}
+
+		return &errors.ErrInternal{Err: err}
 	}
 
 	return nil
@@ -67,8 +85,8 @@ func (r *repository) FindByEmail(email string, ctx context.Context) (*customers.
 
 	if err := r.pool.QueryRow(
 		ctx,
-		`SELECT customer_id, first_name, last_name, email, shipping_country, shipping_city, shipping_zipcode, shipping_street
-		FROM customers WHERE email = $1`,
+		fmt.Sprintf(`SELECT customer_id, first_name, last_name, email, shipping_country, shipping_city, shipping_zipcode, shipping_street
+		FROM %s WHERE email = $1`, r.table),
 		email,
 	).Scan(
 		&c.Id, &c.FirstName, &c.LastName, &c.Email,
@@ -89,8 +107,8 @@ func (r *repository) FindById(id customers.CustomerId, ctx context.Context) (*cu
 
 	if err := r.pool.QueryRow(
 		ctx,
-		`SELECT customer_id, first_name, last_name, email, shipping_country, shipping_city, shipping_zipcode, shipping_street
-		FROM customers WHERE customer_id = $1`,
+		fmt.Sprintf(`SELECT customer_id, first_name, last_name, email, shipping_country, shipping_city, shipping_zipcode, shipping_street
+		FROM %s WHERE customer_id = $1`, r.table),
 		id,
 	).Scan(
 		&c.Id, &c.FirstName, &c.LastName, &c.Email,
@@ -109,20 +127,20 @@ func (r *repository) FindById(id customers.CustomerId, ctx context.Context) (*cu
 func (r *repository) UpdateShippingAddress(id customers.CustomerId, addr *customers.ShippingAddress, ctx context.Context) error {
 	if _, err := r.pool.Exec(
 		ctx,
-		`UPDATE customers
+		fmt.Sprintf(`UPDATE %s
 		SET shipping_country = $1, shipping_city = $2, shipping_zipcode = $3, shipping_street = $4
-		WHERE customer_id = $5;`,
+		WHERE customer_id = $5;`, r.table),
 		addr.Country, addr.City, addr.ZipCode, addr.Street, id,
 	); err != nil {
-		return err
+		return &errors.ErrInternal{Err: err}
 	}
 
 	return nil
 }
 
 func (r *repository) Delete(id customers.CustomerId, ctx context.Context) error {
-	if _, err := r.pool.Exec(ctx, "DELETE FROM customers WHERE customer_id = $1;", id); err != nil {
-		return err
+	if _, err := r.pool.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE customer_id = $1;", r.table), id); err != nil {
+		return &errors.ErrInternal{Err: err}
 	}
 
 	return nil
@@ -0,0 +1,16 @@
+package pg
+
+import (
+	"testing"
+
+	"github.com/giornetta/microshop/customers"
+	"github.com/giornetta/microshop/customers/customerstest"
+	"github.com/giornetta/microshop/postgres/postgrestest"
+)
+
+// TestRepository runs against the server given by postgrestest.URLEnv, and is skipped without one.
+func TestRepository(t *testing.T) {
+	customerstest.TestRepository(t, func(t *testing.T) customers.CustomerRepository {
+		return NewCustomerRepository(postgrestest.Database(t, Migrations))
+	})
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/giornetta/microshop/errors"
 	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/log"
 	"github.com/google/uuid"
 	"golang.org/x/exp/slog"
 )
@@ -117,7 +118,7 @@ func (s *loggingService) Create(req *CreateCustomerRequest, ctx context.Context)
 	p, err := s.service.Create(req, ctx)
 	if err != nil {
 		if e, ok := err.(*errors.ErrInternal); ok {
-			s.logger.Error("could not create customer",
+			s.logger.ErrorCtx(ctx, "could not create customer",
 				slog.String("method", "Create"),
 				slog.String("err", e.Cause().Error()),
 			)
@@ -130,12 +131,12 @@ func (s *loggingService) Create(req *CreateCustomerRequest, ctx context.Context)
 }
 
 func (s *loggingService) GetById(customerId CustomerId, ctx context.Context) (*Customer, error) {
+	ctx = log.WithCustomerId(ctx, customerId.String())
 	p, err := s.service.GetById(customerId, ctx)
 	if err != nil {
 		if e, ok := err.(*errors.ErrInternal); ok {
-			s.logger.Error("could not find customer by id",
+			s.logger.ErrorCtx(ctx, "could not find customer by id",
 				slog.String("method", "GetById"),
-				slog.String("customer_id", customerId.String()),
 				slog.String("err", e.Cause().Error()),
 			)
 		}
@@ -147,12 +148,12 @@ func (s *loggingService) GetById(customerId CustomerId, ctx context.Context) (*C
 }
 
 func (s *loggingService) UpdateShippingAddress(req *UpdateShippingAddressRequest, ctx context.Context) error {
+	ctx = log.WithCustomerId(ctx, req.Id.String())
 	err := s.service.UpdateShippingAddress(req, ctx)
 	if err != nil {
 		if e, ok := err.(*errors.ErrInternal); ok {
-			s.logger.Error("could not update shipping address",
+			s.logger.ErrorCtx(ctx, "could not update shipping address",
 				slog.String("method", "UpdateShippingAddress"),
-				slog.String("customer_id", req.Id.String()),
 				slog.String("err", e.Cause().Error()),
 			)
 		}
@@ -164,12 +165,12 @@ func (s *loggingService) UpdateShippingAddress(req *UpdateShippingAddressRequest
 }
 
 func (s *loggingService) Delete(customerId CustomerId, ctx context.Context) error {
+	ctx = log.WithCustomerId(ctx, customerId.String())
 	err := s.service.Delete(customerId, ctx)
 	if err != nil {
 		if e, ok := err.(*errors.ErrInternal); ok {
-			s.logger.Error("could not delete customer",
+			s.logger.ErrorCtx(ctx, "could not delete customer",
 				slog.String("method", "Delete"),
-				slog.String("customer_id", customerId.String()),
 				slog.String("err", e.Cause().Error()),
 			)
 		}
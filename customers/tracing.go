@@ -0,0 +1,76 @@
+package customers
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/giornetta/microshop/tracing"
+)
+
+type tracingService struct {
+	service Service
+	tracer  trace.Tracer
+}
+
+func NewTracingService(tracer trace.Tracer, service Service) Service {
+	return &tracingService{
+		service: service,
+		tracer:  tracer,
+	}
+}
+
+func (s *tracingService) Create(req *CreateCustomerRequest, ctx context.Context) (*Customer, error) {
+	ctx, span := s.tracer.Start(ctx, "customers.Service/Create")
+	defer span.End()
+
+	c, err := s.service.Create(req, ctx)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.String("customer_id", c.Id.String()))
+
+	return c, nil
+}
+
+func (s *tracingService) GetById(customerId CustomerId, ctx context.Context) (*Customer, error) {
+	ctx, span := s.tracer.Start(ctx, "customers.Service/GetById", trace.WithAttributes(
+		attribute.String("customer_id", customerId.String()),
+	))
+	defer span.End()
+
+	c, err := s.service.GetById(customerId, ctx)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (s *tracingService) UpdateShippingAddress(req *UpdateShippingAddressRequest, ctx context.Context) error {
+	ctx, span := s.tracer.Start(ctx, "customers.Service/UpdateShippingAddress", trace.WithAttributes(
+		attribute.String("customer_id", req.Id.String()),
+	))
+	defer span.End()
+
+	err := s.service.UpdateShippingAddress(req, ctx)
+	tracing.RecordError(span, err)
+
+	return err
+}
+
+func (s *tracingService) Delete(customerId CustomerId, ctx context.Context) error {
+	ctx, span := s.tracer.Start(ctx, "customers.Service/Delete", trace.WithAttributes(
+		attribute.String("customer_id", customerId.String()),
+	))
+	defer span.End()
+
+	err := s.service.Delete(customerId, ctx)
+	tracing.RecordError(span, err)
+
+	return err
+}
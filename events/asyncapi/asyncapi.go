@@ -0,0 +1,118 @@
+// Package asyncapi generates an AsyncAPI document describing every event registered in the events package.
+package asyncapi
+
+import (
+	"strconv"
+
+	"github.com/giornetta/microshop/events"
+)
+
+const Version = "2.6.0"
+
+type Document struct {
+	AsyncAPI           string              `yaml:"asyncapi"`
+	Info               Info                `yaml:"info"`
+	DefaultContentType string              `yaml:"defaultContentType"`
+	Channels           map[string]*Channel `yaml:"channels"`
+	Components         Components          `yaml:"components"`
+}
+
+type Info struct {
+	Title       string `yaml:"title"`
+	Version     string `yaml:"version"`
+	Description string `yaml:"description,omitempty"`
+}
+
+type Channel struct {
+	Subscribe *Operation `yaml:"subscribe"`
+}
+
+type Operation struct {
+	OperationId string   `yaml:"operationId"`
+	Message     *Message `yaml:"message"`
+}
+
+type Message struct {
+	Ref      string           `yaml:"$ref,omitempty"`
+	OneOf    []*Message       `yaml:"oneOf,omitempty"`
+	Name     string           `yaml:"name,omitempty"`
+	Title    string           `yaml:"title,omitempty"`
+	Headers  *events.Schema   `yaml:"headers,omitempty"`
+	Payload  *Ref             `yaml:"payload,omitempty"`
+	Bindings *MessageBindings `yaml:"bindings,omitempty"`
+}
+
+type MessageBindings struct {
+	Kafka KafkaMessageBinding `yaml:"kafka"`
+}
+
+type KafkaMessageBinding struct {
+	Key            *events.Schema `yaml:"key"`
+	BindingVersion string         `yaml:"bindingVersion"`
+}
+
+type Ref struct {
+	Ref string `yaml:"$ref"`
+}
+
+type Components struct {
+	Messages map[string]*Message       `yaml:"messages"`
+	Schemas  map[string]*events.Schema `yaml:"schemas"`
+}
+
+// Generate builds the AsyncAPI document of the given registrations,
+// with a channel per topic and a message per event type.
+func Generate(info Info, regs []*events.Registration) *Document {
+	doc := &Document{
+		AsyncAPI:           Version,
+		Info:               info,
+		DefaultContentType: "application/json",
+		Channels:           make(map[string]*Channel),
+		Components: Components{
+			Messages: make(map[string]*Message),
+			Schemas:  make(map[string]*events.Schema),
+		},
+	}
+
+	for _, reg := range regs {
+		name := reg.Type.String()
+
+		ch, ok := doc.Channels[reg.Topic.String()]
+		if !ok {
+			ch = &Channel{
+				Subscribe: &Operation{
+					OperationId: "consume" + reg.Topic.String(),
+					Message:     &Message{},
+				},
+			}
+			doc.Channels[reg.Topic.String()] = ch
+		}
+		ch.Subscribe.Message.OneOf = append(ch.Subscribe.Message.OneOf, &Message{Ref: "#/components/messages/" + name})
+
+		doc.Components.Messages[name] = &Message{
+			Name:  name,
+			Title: reg.GoType.Name(),
+			Headers: &events.Schema{
+				Type: "object",
+				Properties: map[string]*events.Schema{
+					"EventType":    {Type: "string", Enum: []string{name}},
+					"EventVersion": {Type: "string", Enum: []string{strconv.Itoa(reg.Version())}},
+				},
+				Required: []string{"EventType"},
+			},
+			Payload: &Ref{Ref: "#/components/schemas/" + name},
+			Bindings: &MessageBindings{
+				Kafka: KafkaMessageBinding{
+					Key:            &events.Schema{Type: "string"},
+					BindingVersion: "0.4.0",
+				},
+			},
+		}
+
+		schema := *reg.Schema
+		schema.Title = name
+		doc.Components.Schemas[name] = &schema
+	}
+
+	return doc
+}
@@ -0,0 +1,228 @@
+// Package avro implements an events.Codec encoding payloads with Apache Avro,
+// registering the schema of every event type in a schema registry.
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+
+	hamba "github.com/hamba/avro/v2"
+
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/schemaregistry"
+)
+
+const ContentType = "avro/binary"
+
+const namespace = "microshop.events"
+
+type codec struct {
+	registry schemaregistry.Registry
+
+	lock    sync.Mutex
+	writers map[events.Type]int
+	schemas map[int]hamba.Schema
+}
+
+// NewCodec returns a Codec encoding events with Avro, framing every payload with the ID of its schema.
+func NewCodec(registry schemaregistry.Registry) events.Codec {
+	return &codec{
+		registry: registry,
+		writers:  make(map[events.Type]int),
+		schemas:  make(map[int]hamba.Schema),
+	}
+}
+
+func (c *codec) ContentType() string { return ContentType }
+
+func (c *codec) Encode(e events.Event) ([]byte, error) {
+	id, schema, err := c.writerSchema(e.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := marshal(schema, e)
+	if err != nil {
+		return nil, err
+	}
+
+	return schemaregistry.Frame(id, payload), nil
+}
+
+// marshal encodes the fields of v with schema. Fields holds every integer as an int64 or uint64,
+// matching the longs of the schemas built by recordOf, as Avro has no unsigned types.
+func marshal(schema hamba.Schema, v any) ([]byte, error) {
+	fields, err := longs(events.Fields(v))
+	if err != nil {
+		return nil, err
+	}
+
+	return hamba.Marshal(schema, fields)
+}
+
+// longs returns v with its unsigned integers converted to int64, failing for those that overflow.
+func longs(v any) (any, error) {
+	switch v := v.(type) {
+	case uint64:
+		if v > math.MaxInt64 {
+			return nil, fmt.Errorf("%d does not fit an avro long", v)
+		}
+
+		return int64(v), nil
+	case map[string]any:
+		for k, e := range v {
+			var err error
+			if v[k], err = longs(e); err != nil {
+				return nil, err
+			}
+		}
+	case []any:
+		for i, e := range v {
+			var err error
+			if v[i], err = longs(e); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return v, nil
+}
+
+func (c *codec) Decode(t events.Type, version int, framed []byte) (events.Event, error) {
+	id, payload, err := schemaregistry.Unframe(framed)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := c.schema(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+	if err := hamba.Unmarshal(schema, payload, &fields); err != nil {
+		return nil, err
+	}
+
+	return events.DecodeMap(t, version, fields)
+}
+
+func (c *codec) writerSchema(t events.Type) (int, hamba.Schema, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if id, ok := c.writers[t]; ok {
+		return id, c.schemas[id], nil
+	}
+
+	reg, ok := events.Lookup(t)
+	if !ok {
+		return 0, nil, fmt.Errorf("event type %s is not registered", t)
+	}
+
+	definition, err := json.Marshal(recordOf(reg.GoType))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	schema, err := hamba.Parse(string(definition))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	id, err := c.registry.Register(fmt.Sprintf("%s-%s", reg.Topic, reg.Type), schemaregistry.Avro, string(definition))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	c.writers[t] = id
+	c.schemas[id] = schema
+
+	return id, schema, nil
+}
+
+func (c *codec) schema(id int) (hamba.Schema, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if schema, ok := c.schemas[id]; ok {
+		return schema, nil
+	}
+
+	s, err := c.registry.Lookup(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Format != schemaregistry.Avro {
+		return nil, fmt.Errorf("schema with id=%d is not an avro schema", id)
+	}
+
+	schema, err := hamba.Parse(s.Definition)
+	if err != nil {
+		return nil, err
+	}
+
+	c.schemas[id] = schema
+
+	return schema, nil
+}
+
+type field struct {
+	Name string `json:"name"`
+	Type any    `json:"type"`
+}
+
+func recordOf(t reflect.Type) map[string]any {
+	var fields []field
+	addFields(&fields, t)
+
+	return map[string]any{
+		"type":      "record",
+		"name":      t.Name(),
+		"namespace": namespace,
+		"fields":    fields,
+	}
+}
+
+func addFields(fields *[]field, t reflect.Type) {
+	for _, f := range events.FieldsOf(t) {
+		if f.Embedded {
+			addFields(fields, f.Type)
+			continue
+		}
+
+		*fields = append(*fields, field{Name: f.Name, Type: typeOf(f.Type)})
+	}
+}
+
+func typeOf(t reflect.Type) any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return []any{"null", typeOf(t.Elem())}
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "long"
+	case reflect.Float32:
+		return "float"
+	case reflect.Float64:
+		return "double"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytes"
+		}
+
+		return map[string]any{"type": "array", "items": typeOf(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "map", "values": typeOf(t.Elem())}
+	case reflect.Struct:
+		return recordOf(t)
+	default:
+		return "string"
+	}
+}
@@ -0,0 +1,116 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	hamba "github.com/hamba/avro/v2"
+
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/events/codectest"
+	"github.com/giornetta/microshop/schemaregistry"
+)
+
+func newCodec(t *testing.T) codectest.Codec {
+	t.Helper()
+
+	registry, err := schemaregistry.NewFileRegistry(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewCodec(registry)
+
+	return codectest.Codec{
+		Codec: c,
+		EncodeAs: func(v any, t events.Type) ([]byte, error) {
+			reg, ok := events.Lookup(t)
+			if !ok {
+				return nil, fmt.Errorf("event type %s is not registered", t)
+			}
+
+			// Readers only resolve writer records with their own name.
+			record := recordOf(reflect.TypeOf(v))
+			record["name"] = reg.GoType.Name()
+			definition, err := json.Marshal(record)
+			if err != nil {
+				return nil, err
+			}
+
+			schema, err := hamba.Parse(string(definition))
+			if err != nil {
+				return nil, err
+			}
+
+			id, err := registry.Register(fmt.Sprintf("%s-%s", reg.Topic, reg.Type), schemaregistry.Avro, string(definition))
+			if err != nil {
+				return nil, err
+			}
+
+			payload, err := marshal(schema, v)
+			if err != nil {
+				return nil, err
+			}
+
+			return schemaregistry.Frame(id, payload), nil
+		},
+	}
+}
+
+func TestCodec(t *testing.T) {
+	codectest.TestCodec(t, newCodec)
+}
+
+type narrow struct {
+	Int8   int8   `json:"int8"`
+	Int16  int16  `json:"int16"`
+	Int32  int32  `json:"int32"`
+	Uint8  uint8  `json:"uint8"`
+	Uint16 uint16 `json:"uint16"`
+	Uint32 uint32 `json:"uint32"`
+	Uint64 uint64 `json:"uint64"`
+	Int    int    `json:"int"`
+}
+
+func TestNarrowIntegers(t *testing.T) {
+	definition, err := json.Marshal(recordOf(reflect.TypeOf(narrow{})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := hamba.Parse(string(definition))
+	if err != nil {
+		t.Fatalf("Parse(%s): %v", definition, err)
+	}
+
+	want := narrow{Int8: -8, Int16: -16, Int32: math.MinInt32, Uint8: 8, Uint16: 16, Uint32: math.MaxUint32, Uint64: 64, Int: math.MaxInt64}
+
+	payload, err := marshal(schema, want)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	var fields map[string]any
+	if err := hamba.Unmarshal(schema, payload, &fields); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	// Decoded fields are read into events through JSON, as DecodeMap does.
+	b, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got narrow
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got != want {
+		t.Errorf("decoded %+v, want %+v", got, want)
+	}
+
+	if _, err := marshal(schema, narrow{Uint64: math.MaxUint64}); err == nil {
+		t.Error("encoding an unsigned integer overflowing a long succeeded")
+	}
+}
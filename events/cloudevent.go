@@ -0,0 +1,191 @@
+package events
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	CloudEventsSpecVersion = "1.0"
+
+	// CloudEventsJSONContentType is the content type of events in the structured JSON format.
+	CloudEventsJSONContentType = "application/cloudevents+json"
+	// CloudEventsBatchContentType is the content type of a JSON array of structured events.
+	CloudEventsBatchContentType = "application/cloudevents-batch+json"
+
+	// VersionExtension is the CloudEvents extension attribute carrying the version of the event payload.
+	VersionExtension = "eventversion"
+)
+
+// CloudEvent maps an Event to the attributes defined by the CloudEvents specification.
+// The type attribute is the event Type and the subject is its Key.
+type CloudEvent struct {
+	ID              string
+	Source          string
+	Type            Type
+	Subject         string
+	Time            time.Time
+	DataContentType string
+	Version         int
+	Data            []byte
+}
+
+// NewCloudEvent returns the CloudEvent of the given event, encoding its payload with the codec.
+// If the context carries a CloudEvent, as set by WithCloudEvent, its id, source and time are kept.
+func NewCloudEvent(e Event, source string, codec Codec, ctx context.Context) (*CloudEvent, error) {
+	data, err := codec.Encode(e)
+	if err != nil {
+		return nil, err
+	}
+
+	ce := &CloudEvent{
+		ID:              uuid.NewString(),
+		Source:          source,
+		Type:            e.Type(),
+		Subject:         e.Key().String(),
+		Time:            time.Now().UTC(),
+		DataContentType: codec.ContentType(),
+		Version:         VersionOf(e.Type()),
+		Data:            data,
+	}
+
+	if origin, ok := CloudEventFromContext(ctx); ok {
+		ce.ID = origin.ID
+		ce.Source = origin.Source
+		if !origin.Time.IsZero() {
+			ce.Time = origin.Time
+		}
+	}
+
+	return ce, nil
+}
+
+// Validate checks that the required CloudEvents attributes are set.
+func (ce *CloudEvent) Validate() error {
+	switch {
+	case ce.ID == "":
+		return errors.New("cloudevent is missing the id attribute")
+	case ce.Source == "":
+		return errors.New("cloudevent is missing the source attribute")
+	case ce.Type == "":
+		return errors.New("cloudevent is missing the type attribute")
+	}
+
+	return nil
+}
+
+// Event decodes the payload of the CloudEvent with the codec matching its data content type.
+func (ce *CloudEvent) Event(codecs *Codecs) (Event, error) {
+	codec, err := codecs.ForContentType(ce.DataContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	return codec.Decode(ce.Type, ce.Version, ce.Data)
+}
+
+type structuredCloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            *time.Time      `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Version         string          `json:"eventversion,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      string          `json:"data_base64,omitempty"`
+}
+
+// MarshalJSON encodes the CloudEvent in the structured JSON format.
+// JSON payloads are embedded as they are, while other formats are base64 encoded.
+func (ce *CloudEvent) MarshalJSON() ([]byte, error) {
+	s := structuredCloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              ce.ID,
+		Source:          ce.Source,
+		Type:            ce.Type.String(),
+		Subject:         ce.Subject,
+		DataContentType: ce.DataContentType,
+	}
+
+	if !ce.Time.IsZero() {
+		s.Time = &ce.Time
+	}
+
+	if ce.Version != 0 {
+		s.Version = strconv.Itoa(ce.Version)
+	}
+
+	if ce.DataContentType == "" || ce.DataContentType == JSONContentType {
+		s.Data = ce.Data
+	} else {
+		s.DataBase64 = base64.StdEncoding.EncodeToString(ce.Data)
+	}
+
+	return json.Marshal(s)
+}
+
+// UnmarshalJSON decodes a CloudEvent in the structured JSON format.
+func (ce *CloudEvent) UnmarshalJSON(payload []byte) error {
+	var s structuredCloudEvent
+	if err := json.Unmarshal(payload, &s); err != nil {
+		return err
+	}
+
+	if s.SpecVersion != CloudEventsSpecVersion {
+		return fmt.Errorf("cloudevents spec version %q is not supported", s.SpecVersion)
+	}
+
+	*ce = CloudEvent{
+		ID:              s.ID,
+		Source:          s.Source,
+		Type:            Type(s.Type),
+		Subject:         s.Subject,
+		DataContentType: s.DataContentType,
+		Data:            s.Data,
+	}
+
+	if s.Time != nil {
+		ce.Time = *s.Time
+	}
+
+	if s.Version != "" {
+		version, err := strconv.Atoi(s.Version)
+		if err != nil {
+			return fmt.Errorf("invalid %s attribute: %w", VersionExtension, err)
+		}
+		ce.Version = version
+	}
+
+	if s.DataBase64 != "" {
+		data, err := base64.StdEncoding.DecodeString(s.DataBase64)
+		if err != nil {
+			return fmt.Errorf("invalid data_base64 attribute: %w", err)
+		}
+		ce.Data = data
+	}
+
+	return nil
+}
+
+type cloudEventKey struct{}
+
+// WithCloudEvent returns a context carrying the CloudEvent an event was received as,
+// so that publishers can preserve its attributes.
+func WithCloudEvent(ctx context.Context, ce *CloudEvent) context.Context {
+	return context.WithValue(ctx, cloudEventKey{}, ce)
+}
+
+// CloudEventFromContext returns the CloudEvent stored in the context by WithCloudEvent.
+func CloudEventFromContext(ctx context.Context) (*CloudEvent, bool) {
+	ce, ok := ctx.Value(cloudEventKey{}).(*CloudEvent)
+	return ce, ok
+}
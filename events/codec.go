@@ -0,0 +1,100 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Codec encodes and decodes event payloads in a specific format,
+// identified by its content type.
+type Codec interface {
+	ContentType() string
+	Encode(e Event) ([]byte, error)
+	Decode(t Type, version int, payload []byte) (Event, error)
+}
+
+const JSONContentType = "application/json"
+
+type jsonCodec struct{}
+
+// JSON is the default codec, encoding events with encoding/json.
+var JSON Codec = jsonCodec{}
+
+func (jsonCodec) ContentType() string { return JSONContentType }
+
+func (jsonCodec) Encode(e Event) ([]byte, error) { return json.Marshal(e) }
+
+func (jsonCodec) Decode(t Type, version int, payload []byte) (Event, error) {
+	return Decode(t, version, payload)
+}
+
+// DecodeMap decodes an event from its generic representation, as produced by Fields,
+// upcasting it to the current version of the event if needed.
+// It allows binary codecs to reuse the JSON decoders and upcasters.
+func DecodeMap(t Type, version int, fields map[string]any) (Event, error) {
+	payload, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return Decode(t, version, payload)
+}
+
+// Codecs selects the codec used to encode the events of each topic,
+// and the one used to decode a payload given its content type.
+// JSON is always available and is used for topics without an explicit codec.
+type Codecs struct {
+	byContentType map[string]Codec
+	byTopic       map[Topic]Codec
+}
+
+func NewCodecs() *Codecs {
+	return &Codecs{
+		byContentType: map[string]Codec{JSONContentType: JSON},
+		byTopic:       make(map[Topic]Codec),
+	}
+}
+
+// Register makes the given codec available for decoding.
+func (c *Codecs) Register(codec Codec) {
+	c.byContentType[codec.ContentType()] = codec
+}
+
+// Use encodes the events of the given topic with the codec, registering it for decoding as well.
+func (c *Codecs) Use(topic Topic, codec Codec) {
+	c.Register(codec)
+	c.byTopic[topic] = codec
+}
+
+// ForTopic returns the codec used to encode the events of the given topic.
+func (c *Codecs) ForTopic(topic Topic) Codec {
+	if c == nil {
+		return JSON
+	}
+
+	codec, ok := c.byTopic[topic]
+	if !ok {
+		return JSON
+	}
+
+	return codec
+}
+
+// ForContentType returns the codec able to decode payloads of the given content type.
+// An empty content type is treated as JSON.
+func (c *Codecs) ForContentType(contentType string) (Codec, error) {
+	if contentType == "" {
+		return JSON, nil
+	}
+
+	if c == nil {
+		c = NewCodecs()
+	}
+
+	codec, ok := c.byContentType[contentType]
+	if !ok {
+		return nil, fmt.Errorf("content type %q is not supported", contentType)
+	}
+
+	return codec, nil
+}
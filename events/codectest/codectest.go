@@ -0,0 +1,170 @@
+// Package codectest checks that implementations of events.Codec framing their payloads with the ID
+// of a schema registered in a schemaregistry.Registry encode every registered event without losing
+// any of its fields, and read the payloads written with older schemas.
+package codectest
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/schemaregistry"
+)
+
+// Codec is a codec under test, along with what the cases need to know of its schemas.
+type Codec struct {
+	events.Codec
+
+	// EncodeAs encodes v, a struct holding an older shape of the events of type t, with the writer schema
+	// derived from its type and registered for t, as a service still publishing that shape would.
+	EncodeAs func(v any, t events.Type) ([]byte, error)
+}
+
+// TestCodec runs every case against the codecs returned by newCodec, each case getting its own.
+func TestCodec(t *testing.T, newCodec func(t *testing.T) Codec) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, c Codec)
+	}{
+		{name: "RoundTrip", test: testRoundTrip},
+		{name: "DecodeOlderWriterSchema", test: testDecodeOlderWriterSchema},
+		{name: "DecodeErrors", test: testDecodeErrors},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newCodec(t))
+		})
+	}
+}
+
+// testRoundTrip encodes a sample of every registered event type, decodes it back and checks that
+// it is unchanged. Every field of the samples is set, so that a field the codec drops or mangles
+// is told apart from one left to its zero value.
+func testRoundTrip(t *testing.T, c Codec) {
+	for _, reg := range events.Registrations() {
+		reg := reg
+
+		t.Run(reg.Type.String(), func(t *testing.T) {
+			want := Sample(reg.GoType).(events.Event)
+
+			payload, err := c.Encode(want)
+			if err != nil {
+				t.Fatalf("Encode(%+v): %v", want, err)
+			}
+
+			got, err := c.Decode(reg.Type, reg.Version(), payload)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("decoded %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+// productCreatedV1 is ProductCreated before the SKU, reorder threshold and stock were appended to it.
+type productCreatedV1 struct {
+	events.ProductEvent
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Price       float32 `json:"price"`
+	Amount      int     `json:"amount"`
+}
+
+func testDecodeOlderWriterSchema(t *testing.T, c Codec) {
+	// The codec writes with the current schema, and must still read what was written with the old one.
+	current := Sample(reflect.TypeOf(events.ProductCreated{})).(events.ProductCreated)
+	if _, err := c.Encode(current); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	old := productCreatedV1{
+		ProductEvent: events.ProductEvent{ProductId: "4f1c2a5e-8f7b-4a53-9f0e-2f7f5a1b9c10"},
+		Name:         "Keyboard",
+		Description:  "Mechanical keyboard",
+		Price:        49.5,
+		Amount:       10,
+	}
+
+	payload, err := c.EncodeAs(old, events.ProductCreatedType)
+	if err != nil {
+		t.Fatalf("EncodeAs: %v", err)
+	}
+
+	got, err := c.Decode(events.ProductCreatedType, events.VersionOf(events.ProductCreatedType), payload)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	want := events.ProductCreated{
+		ProductEvent: old.ProductEvent,
+		Name:         old.Name,
+		Description:  old.Description,
+		Price:        old.Price,
+		Amount:       old.Amount,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded %+v, want %+v", got, want)
+	}
+}
+
+func testDecodeErrors(t *testing.T, c Codec) {
+	payload, err := c.Encode(Sample(reflect.TypeOf(events.ProductDeleted{})).(events.Event))
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	badMagic := append([]byte{1}, payload[1:]...)
+	unknownSchema := schemaregistry.Frame(42, payload[5:])
+
+	for name, framed := range map[string][]byte{"a bad magic byte": badMagic, "an unknown schema": unknownSchema, "no schema id": payload[:3]} {
+		if _, err := c.Decode(events.ProductDeletedType, 1, framed); err == nil {
+			t.Errorf("decoding a payload with %s succeeded", name)
+		}
+	}
+}
+
+// Sample returns a value of t with every field set to a value derived from its position,
+// and slices holding two elements.
+func Sample(t reflect.Type) any {
+	n := 0
+	return sample(t, &n).Interface()
+}
+
+func sample(t reflect.Type, n *int) reflect.Value {
+	*n++
+	v := reflect.New(t).Elem()
+
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).IsExported() {
+				v.Field(i).Set(sample(t.Field(i).Type, n))
+			}
+		}
+	case reflect.Pointer:
+		v.Set(reflect.New(t.Elem()))
+		v.Elem().Set(sample(t.Elem(), n))
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(t, 2, 2))
+		for i := 0; i < 2; i++ {
+			v.Index(i).Set(sample(t.Elem(), n))
+		}
+	case reflect.String:
+		v.SetString(fmt.Sprintf("value-%d", *n))
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(*n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(uint64(*n))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(*n) + 0.5)
+	}
+
+	return v
+}
@@ -0,0 +1,100 @@
+package events
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Incompatibility describes a schema change that would break consumers
+// built against the previous version of an event.
+type Incompatibility struct {
+	Path   string
+	Reason string
+}
+
+func (i Incompatibility) String() string {
+	return fmt.Sprintf("%s: %s", i.Path, i.Reason)
+}
+
+// CheckCompatibility compares the schema consumers were built against (old)
+// with the one producers will now emit (new), returning every change that old
+// consumers would not be able to handle: removed required properties,
+// properties that became optional and type changes. Adding optional properties is allowed,
+// but not required ones, which the events already published do not have.
+func CheckCompatibility(old, new *Schema) []Incompatibility {
+	return checkCompatibility("$", old, new)
+}
+
+func checkCompatibility(path string, old, new *Schema) []Incompatibility {
+	var issues []Incompatibility
+
+	if old.Type != new.Type {
+		return append(issues, Incompatibility{
+			Path:   path,
+			Reason: fmt.Sprintf("type changed from %q to %q", old.Type, new.Type),
+		})
+	}
+
+	if old.Format != new.Format {
+		issues = append(issues, Incompatibility{
+			Path:   path,
+			Reason: fmt.Sprintf("format changed from %q to %q", old.Format, new.Format),
+		})
+	}
+
+	oldRequired := make(map[string]bool, len(old.Required))
+	for _, name := range old.Required {
+		oldRequired[name] = true
+	}
+
+	newRequired := make(map[string]bool, len(new.Required))
+	for _, name := range new.Required {
+		newRequired[name] = true
+	}
+
+	names := make([]string, 0, len(old.Properties))
+	for name := range old.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop, ok := new.Properties[name]
+		switch {
+		case !ok && oldRequired[name]:
+			issues = append(issues, Incompatibility{Path: path + "." + name, Reason: "required property was removed"})
+		case !ok:
+			continue
+		case oldRequired[name] && !newRequired[name]:
+			issues = append(issues, Incompatibility{Path: path + "." + name, Reason: "is no longer required"})
+			fallthrough
+		default:
+			issues = append(issues, checkCompatibility(path+"."+name, old.Properties[name], prop)...)
+		}
+	}
+
+	names = names[:0]
+	for name := range newRequired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		switch _, existed := old.Properties[name]; {
+		case !existed:
+			issues = append(issues, Incompatibility{Path: path + "." + name, Reason: "new property is required"})
+		case !oldRequired[name]:
+			issues = append(issues, Incompatibility{Path: path + "." + name, Reason: "became required"})
+		}
+	}
+
+	if old.Items != nil && new.Items != nil {
+		issues = append(issues, checkCompatibility(path+"[]", old.Items, new.Items)...)
+	}
+
+	if old.AdditionalProperties != nil && new.AdditionalProperties != nil {
+		issues = append(issues, checkCompatibility(path+"{}", old.AdditionalProperties, new.AdditionalProperties)...)
+	}
+
+	return issues
+}
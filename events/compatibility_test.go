@@ -0,0 +1,122 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckCompatibility(t *testing.T) {
+	// product returns the schema of an object with a required id, and an optional sku unless required says otherwise.
+	product := func(required ...string) *Schema {
+		return &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"product_id": {Type: "string"},
+				"sku":        {Type: "string"},
+				"stock": {Type: "array", Items: &Schema{
+					Type:       "object",
+					Properties: map[string]*Schema{"amount": {Type: "integer"}},
+					Required:   []string{"amount"},
+				}},
+			},
+			Required: append([]string{"product_id"}, required...),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func(s *Schema)
+		want   []Incompatibility
+	}{
+		{
+			name:   "unchanged",
+			change: func(s *Schema) {},
+		},
+		{
+			name: "optional property added",
+			change: func(s *Schema) {
+				s.Properties["reorder_threshold"] = &Schema{Type: "integer"}
+			},
+		},
+		{
+			name: "optional property removed",
+			change: func(s *Schema) {
+				delete(s.Properties, "sku")
+			},
+		},
+		{
+			name: "required property removed",
+			change: func(s *Schema) {
+				delete(s.Properties, "product_id")
+				s.Required = nil
+			},
+			want: []Incompatibility{{Path: "$.product_id", Reason: "required property was removed"}},
+		},
+		{
+			name: "required property became optional",
+			change: func(s *Schema) {
+				s.Required = nil
+			},
+			want: []Incompatibility{{Path: "$.product_id", Reason: "is no longer required"}},
+		},
+		{
+			name: "required property added",
+			change: func(s *Schema) {
+				s.Properties["name"] = &Schema{Type: "string"}
+				s.Required = append(s.Required, "name")
+			},
+			want: []Incompatibility{{Path: "$.name", Reason: "new property is required"}},
+		},
+		{
+			name: "optional property became required",
+			change: func(s *Schema) {
+				s.Required = append(s.Required, "sku")
+			},
+			want: []Incompatibility{{Path: "$.sku", Reason: "became required"}},
+		},
+		{
+			name: "type changed",
+			change: func(s *Schema) {
+				s.Properties["product_id"] = &Schema{Type: "integer"}
+			},
+			want: []Incompatibility{{Path: "$.product_id", Reason: `type changed from "string" to "integer"`}},
+		},
+		{
+			name: "format changed",
+			change: func(s *Schema) {
+				s.Properties["sku"] = &Schema{Type: "string", Format: "uuid"}
+			},
+			want: []Incompatibility{{Path: "$.sku", Reason: `format changed from "" to "uuid"`}},
+		},
+		{
+			name: "nested type changed",
+			change: func(s *Schema) {
+				s.Properties["stock"].Items.Properties["amount"] = &Schema{Type: "number"}
+			},
+			want: []Incompatibility{{Path: "$.stock[].amount", Reason: `type changed from "integer" to "number"`}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			new := product()
+			tt.change(new)
+
+			if got := CheckCompatibility(product(), new); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRegisteredSchemasAreCompatible checks that the schema of every event is compatible with itself,
+// so that the catalog check only reports actual changes.
+func TestRegisteredSchemasAreCompatible(t *testing.T) {
+	for _, reg := range Registrations() {
+		if issues := CheckCompatibility(reg.Schema, reg.Schema); len(issues) > 0 {
+			t.Errorf("%s is incompatible with itself: %v", reg.Type, issues)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Package encoding builds the set of event codecs used by a service.
+package encoding
+
+import (
+	"fmt"
+
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/events/avro"
+	"github.com/giornetta/microshop/events/protobuf"
+	"github.com/giornetta/microshop/schemaregistry"
+)
+
+const (
+	JSON     = "json"
+	Protobuf = "protobuf"
+	Avro     = "avro"
+)
+
+// NewCodecs returns the codecs able to decode every supported format, encoding each topic
+// with the format named in topicEncodings (json, protobuf or avro). Topics not listed use JSON.
+// Binary formats are only available when a schema registry directory is provided.
+func NewCodecs(registryDir string, topicEncodings map[string]string) (*events.Codecs, error) {
+	codecs := events.NewCodecs()
+
+	byName := map[string]events.Codec{JSON: events.JSON}
+
+	if registryDir != "" {
+		registry, err := schemaregistry.NewFileRegistry(registryDir)
+		if err != nil {
+			return nil, err
+		}
+
+		byName[Protobuf] = protobuf.NewCodec(registry)
+		byName[Avro] = avro.NewCodec(registry)
+
+		codecs.Register(byName[Protobuf])
+		codecs.Register(byName[Avro])
+	}
+
+	for topic, name := range topicEncodings {
+		codec, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("encoding %q of topic %s is not available", name, topic)
+		}
+
+		codecs.Use(events.Topic(topic), codec)
+	}
+
+	return codecs, nil
+}
@@ -34,10 +34,67 @@ type Publisher interface {
 	Publish(e Event, ctx context.Context) error
 }
 
+// BatchPublisher is a Publisher able to publish many events at once, waiting for all of them
+// instead of each one in turn.
+type BatchPublisher interface {
+	Publisher
+	// PublishBatch publishes the events, returning the error of each one, nil if it was published.
+	// Events with the same key keep their order.
+	PublishBatch(evts []Event, ctx context.Context) []error
+}
+
+// PublishBatch publishes the events with publisher, at once if it is a BatchPublisher, and returns
+// the error of each one. Other publishers publish the events one at a time, in order.
+func PublishBatch(publisher Publisher, evts []Event, ctx context.Context) []error {
+	if batch, ok := publisher.(BatchPublisher); ok {
+		return batch.PublishBatch(evts, ctx)
+	}
+
+	errs := make([]error, len(evts))
+	for i, e := range evts {
+		errs[i] = publisher.Publish(e, ctx)
+	}
+
+	return errs
+}
+
 type Handler interface {
 	Handle(e Event, ctx context.Context) error
 }
 
+// ErrRejected is returned by a Handler for an event it can never handle, such as the creation of a product
+// whose name is taken. Listeners skip rejected events instead of stopping, as handling them again would fail
+// the same way.
+type ErrRejected struct {
+	Err error
+}
+
+func (err *ErrRejected) Error() string {
+	return "event rejected: " + err.Err.Error()
+}
+
+func (err *ErrRejected) Unwrap() error {
+	return err.Err
+}
+
+type handlers []Handler
+
+// Handlers returns a Handler passing every event to each of the handlers in turn, stopping at the
+// first error. It lets a topic, which has a single handler, be handled by many.
+func Handlers(hs ...Handler) Handler {
+	return handlers(hs)
+}
+
+func (hs handlers) Handle(e Event, ctx context.Context) error {
+	for _, h := range hs {
+		if err := h.Handle(e, ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 type Decoder func(payload []byte) (Event, error)
 
 func fromJSON[T Event](payload []byte) (Event, error) {
@@ -49,17 +106,14 @@ func fromJSON[T Event](payload []byte) (Event, error) {
 	return evt, nil
 }
 
-var decoders = make(map[Type]Decoder)
-
-func registerEvent[T Event](eventType Type) {
-	decoders[eventType] = fromJSON[T]
-}
-
-func Decode(t Type, payload []byte) (Event, error) {
-	f, ok := decoders[t]
+// Decode decodes the payload of the given event type, upcasting it
+// to the current version of the event if it was encoded with an older one.
+// A version of 0 is treated as version 1, for events published before versioning was introduced.
+func Decode(t Type, version int, payload []byte) (Event, error) {
+	reg, ok := registry[t]
 	if !ok {
 		return nil, errors.New("the provided event type is not recognized")
 	}
 
-	return f(payload)
+	return reg.decode(version, payload)
 }
@@ -0,0 +1,119 @@
+package events
+
+import (
+	"reflect"
+)
+
+// Fields returns the generic representation of the given value: structs become maps keyed by
+// their JSON field names, with embedded structs flattened, slices become []any and other values
+// keep their Go type. It is the counterpart of DecodeMap.
+func Fields(v any) map[string]any {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+
+	fields := make(map[string]any)
+	addFieldValues(fields, rv)
+
+	return fields
+}
+
+// Field describes a struct field as seen by encoding/json.
+type Field struct {
+	Name      string
+	Index     int
+	Type      reflect.Type
+	OmitEmpty bool
+	// Embedded fields are structs whose fields are promoted into the parent.
+	Embedded bool
+}
+
+// FieldsOf returns the fields of the given struct type in declaration order,
+// as they are serialized by encoding/json.
+func FieldsOf(t reflect.Type) []Field {
+	var fields []Field
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, omitEmpty, skip := jsonField(f)
+		if skip {
+			continue
+		}
+
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			fields = append(fields, Field{Index: i, Type: f.Type, Embedded: true})
+			continue
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		fields = append(fields, Field{Name: name, Index: i, Type: f.Type, OmitEmpty: omitEmpty})
+	}
+
+	return fields
+}
+
+func addFieldValues(fields map[string]any, rv reflect.Value) {
+	for _, f := range FieldsOf(rv.Type()) {
+		if f.Embedded {
+			addFieldValues(fields, rv.Field(f.Index))
+			continue
+		}
+
+		fields[f.Name] = genericValue(rv.Field(f.Index))
+	}
+}
+
+func genericValue(rv reflect.Value) any {
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return nil
+		}
+
+		return genericValue(rv.Elem())
+	case reflect.Struct:
+		fields := make(map[string]any)
+		addFieldValues(fields, rv)
+
+		return fields
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return rv.Bytes()
+		}
+
+		values := make([]any, rv.Len())
+		for i := range values {
+			values[i] = genericValue(rv.Index(i))
+		}
+
+		return values
+	case reflect.Map:
+		values := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			values[iter.Key().String()] = genericValue(iter.Value())
+		}
+
+		return values
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint()
+	case reflect.Float32:
+		return float32(rv.Float())
+	case reflect.Float64:
+		return rv.Float()
+	default:
+		return rv.Interface()
+	}
+}
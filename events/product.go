@@ -6,12 +6,29 @@ func init() {
 	registerEvent[ProductCreated](ProductCreatedType)
 	registerEvent[ProductUpdated](ProductUpdatedType)
 	registerEvent[ProductDeleted](ProductDeletedType)
+	registerEvent[ProductStockLow](ProductStockLowType)
+	registerEvent[ProductOutOfStock](ProductOutOfStockType)
+	registerEvent[ProductStockChanged](ProductStockChangedType)
+	registerEvent[ProductStockTransferred](ProductStockTransferredType)
+	registerEvent[ProductPriceChanged](ProductPriceChangedType)
+
+	// Version 2 carries the stock of every warehouse holding the product.
+	registerUpcaster(ProductCreatedType, 1, stockInDefaultWarehouse)
+	registerUpcaster(ProductUpdatedType, 1, stockInDefaultWarehouse)
 }
 
 const (
 	ProductCreatedType Type = "Product.Created"
 	ProductUpdatedType Type = "Product.Updated"
 	ProductDeletedType Type = "Product.Deleted"
+
+	ProductStockLowType   Type = "Product.StockLow"
+	ProductOutOfStockType Type = "Product.OutOfStock"
+
+	ProductStockChangedType     Type = "Product.StockChanged"
+	ProductStockTransferredType Type = "Product.StockTransferred"
+
+	ProductPriceChangedType Type = "Product.PriceChanged"
 )
 
 type ProductEvent struct {
@@ -28,22 +45,109 @@ type ProductCreated struct {
 	Description string  `json:"description"`
 	Price       float32 `json:"price"`
 	Amount      int     `json:"amount"`
+	// SKU is optional, and empty in the events published before it was introduced.
+	SKU string `json:"sku,omitempty"`
+	// ReorderThreshold is the stock at or below which the product is low on stock, zero in older events.
+	ReorderThreshold int `json:"reorder_threshold,omitempty"`
+	// Stock is the stock of every warehouse holding the product, adding up to Amount. Older events have none,
+	// as their whole Amount was in the default warehouse.
+	Stock []WarehouseStock `json:"stock,omitempty"`
 }
 
 func (ProductCreated) Type() Type { return ProductCreatedType }
 
 type ProductUpdated struct {
 	ProductEvent
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float32 `json:"price"`
-	Amount      int     `json:"amount"`
+	Name             string           `json:"name"`
+	Description      string           `json:"description"`
+	Price            float32          `json:"price"`
+	Amount           int              `json:"amount"`
+	SKU              string           `json:"sku,omitempty"`
+	ReorderThreshold int              `json:"reorder_threshold,omitempty"`
+	Stock            []WarehouseStock `json:"stock,omitempty"`
 }
 
 func (ProductUpdated) Type() Type { return ProductUpdatedType }
 
+// DefaultWarehouseId is the warehouse that held the whole stock of the products before warehouses were introduced.
+const DefaultWarehouseId = "default"
+
+// stockInDefaultWarehouse upcasts the payloads of version 1, which have no stock if they were published
+// before warehouses were introduced, by placing their whole amount in the default warehouse.
+func stockInDefaultWarehouse(payload map[string]any) (map[string]any, error) {
+	if stock, ok := payload["stock"].([]any); ok && len(stock) > 0 {
+		return payload, nil
+	}
+
+	if amount, ok := payload["amount"].(float64); ok && amount > 0 {
+		payload["stock"] = []any{map[string]any{"warehouse_id": DefaultWarehouseId, "amount": amount}}
+	}
+
+	return payload, nil
+}
+
+type WarehouseStock struct {
+	WarehouseId string `json:"warehouse_id"`
+	Amount      int    `json:"amount"`
+}
+
 type ProductDeleted struct {
 	ProductEvent
 }
 
 func (ProductDeleted) Type() Type { return ProductDeletedType }
+
+// ProductStockLow is published when the stock of a product falls to its reorder threshold or below it,
+// without running out.
+type ProductStockLow struct {
+	ProductEvent
+	Name             string `json:"name"`
+	Amount           int    `json:"amount"`
+	ReorderThreshold int    `json:"reorder_threshold"`
+}
+
+func (ProductStockLow) Type() Type { return ProductStockLowType }
+
+// ProductOutOfStock is published when the last item of a product leaves the stock.
+type ProductOutOfStock struct {
+	ProductEvent
+	Name string `json:"name"`
+}
+
+func (ProductOutOfStock) Type() Type { return ProductOutOfStockType }
+
+// ProductStockChanged is published, after the update of the product, when the stock of a warehouse
+// is restocked or adjusted by Quantity, leaving it with Amount items.
+type ProductStockChanged struct {
+	ProductEvent
+	WarehouseId string `json:"warehouse_id"`
+	Quantity    int    `json:"quantity"`
+	Amount      int    `json:"amount"`
+}
+
+func (ProductStockChanged) Type() Type { return ProductStockChangedType }
+
+// ProductStockTransferred is published, after the update of the product, when Quantity items are moved
+// from a warehouse to another.
+type ProductStockTransferred struct {
+	ProductEvent
+	FromWarehouseId string `json:"from_warehouse_id"`
+	ToWarehouseId   string `json:"to_warehouse_id"`
+	Quantity        int    `json:"quantity"`
+}
+
+func (ProductStockTransferred) Type() Type { return ProductStockTransferredType }
+
+// ProductPriceChanged is published, after the creation or the update of the product, when its price is set.
+type ProductPriceChanged struct {
+	ProductEvent
+	Price float32 `json:"price"`
+	// PreviousPrice is zero when the price is set by the creation of the product.
+	PreviousPrice float32 `json:"previous_price"`
+	// ChangedAt is the time of the change, formatted as RFC 3339.
+	ChangedAt string `json:"changed_at"`
+	// ScheduleId is the price schedule that applied the change, empty if it was requested directly.
+	ScheduleId string `json:"schedule_id,omitempty"`
+}
+
+func (ProductPriceChanged) Type() Type { return ProductPriceChangedType }
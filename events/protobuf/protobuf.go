@@ -0,0 +1,324 @@
+// Package protobuf implements an events.Codec encoding payloads with Protocol Buffers.
+// Message descriptors are derived from the Go types of the events, numbering fields in
+// declaration order: new fields must therefore only be appended to event structs.
+package protobuf
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/schemaregistry"
+)
+
+const ContentType = "application/x-protobuf"
+
+const protoPackage = "microshop.events"
+
+type codec struct {
+	registry schemaregistry.Registry
+
+	lock        sync.Mutex
+	writers     map[events.Type]int
+	descriptors map[int]protoreflect.MessageDescriptor
+}
+
+// NewCodec returns a Codec encoding events with Protocol Buffers, framing every payload with the ID of its schema.
+func NewCodec(registry schemaregistry.Registry) events.Codec {
+	return &codec{
+		registry:    registry,
+		writers:     make(map[events.Type]int),
+		descriptors: make(map[int]protoreflect.MessageDescriptor),
+	}
+}
+
+func (c *codec) ContentType() string { return ContentType }
+
+func (c *codec) Encode(e events.Event) ([]byte, error) {
+	id, md, err := c.writerDescriptor(e.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := toMessage(md, events.Fields(e))
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return schemaregistry.Frame(id, payload), nil
+}
+
+func (c *codec) Decode(t events.Type, version int, framed []byte) (events.Event, error) {
+	id, payload, err := schemaregistry.Unframe(framed)
+	if err != nil {
+		return nil, err
+	}
+
+	md, err := c.descriptor(id)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return nil, err
+	}
+
+	return events.DecodeMap(t, version, fromMessage(msg))
+}
+
+func (c *codec) writerDescriptor(t events.Type) (int, protoreflect.MessageDescriptor, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if id, ok := c.writers[t]; ok {
+		return id, c.descriptors[id], nil
+	}
+
+	reg, ok := events.Lookup(t)
+	if !ok {
+		return 0, nil, fmt.Errorf("event type %s is not registered", t)
+	}
+
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:        proto.String(reg.Type.String() + ".proto"),
+		Package:     proto.String(protoPackage),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{messageOf(reg.GoType)},
+	}
+
+	md, err := messageDescriptor(fdp)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	definition, err := protojson.Marshal(fdp)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	id, err := c.registry.Register(fmt.Sprintf("%s-%s", reg.Topic, reg.Type), schemaregistry.Protobuf, string(definition))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	c.writers[t] = id
+	c.descriptors[id] = md
+
+	return id, md, nil
+}
+
+func (c *codec) descriptor(id int) (protoreflect.MessageDescriptor, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if md, ok := c.descriptors[id]; ok {
+		return md, nil
+	}
+
+	s, err := c.registry.Lookup(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Format != schemaregistry.Protobuf {
+		return nil, fmt.Errorf("schema with id=%d is not a protobuf schema", id)
+	}
+
+	var fdp descriptorpb.FileDescriptorProto
+	if err := protojson.Unmarshal([]byte(s.Definition), &fdp); err != nil {
+		return nil, err
+	}
+
+	md, err := messageDescriptor(&fdp)
+	if err != nil {
+		return nil, err
+	}
+
+	c.descriptors[id] = md
+
+	return md, nil
+}
+
+func messageDescriptor(fdp *descriptorpb.FileDescriptorProto) (protoreflect.MessageDescriptor, error) {
+	fd, err := protodesc.NewFile(fdp, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return fd.Messages().Get(0), nil
+}
+
+func messageOf(t reflect.Type) *descriptorpb.DescriptorProto {
+	msg := &descriptorpb.DescriptorProto{Name: proto.String(t.Name())}
+	addFields(msg, t)
+
+	return msg
+}
+
+func addFields(msg *descriptorpb.DescriptorProto, t reflect.Type) {
+	for _, f := range events.FieldsOf(t) {
+		if f.Embedded {
+			addFields(msg, f.Type)
+			continue
+		}
+
+		fieldType := f.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+		if (fieldType.Kind() == reflect.Slice || fieldType.Kind() == reflect.Array) && fieldType.Elem().Kind() != reflect.Uint8 {
+			label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+			fieldType = fieldType.Elem()
+		}
+
+		fdp := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(f.Name),
+			JsonName: proto.String(f.Name),
+			Number:   proto.Int32(int32(len(msg.Field) + 1)),
+			Label:    label.Enum(),
+			Type:     scalarType(fieldType).Enum(),
+		}
+
+		if fieldType.Kind() == reflect.Struct {
+			nested := messageOf(fieldType)
+			msg.NestedType = append(msg.NestedType, nested)
+			fdp.TypeName = proto.String(nested.GetName())
+		}
+
+		msg.Field = append(msg.Field, fdp)
+	}
+}
+
+func scalarType(t reflect.Type) descriptorpb.FieldDescriptorProto_Type {
+	switch t.Kind() {
+	case reflect.Bool:
+		return descriptorpb.FieldDescriptorProto_TYPE_BOOL
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return descriptorpb.FieldDescriptorProto_TYPE_SINT64
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return descriptorpb.FieldDescriptorProto_TYPE_UINT64
+	case reflect.Float32:
+		return descriptorpb.FieldDescriptorProto_TYPE_FLOAT
+	case reflect.Float64:
+		return descriptorpb.FieldDescriptorProto_TYPE_DOUBLE
+	case reflect.Slice, reflect.Array:
+		return descriptorpb.FieldDescriptorProto_TYPE_BYTES
+	case reflect.Struct:
+		return descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+	default:
+		return descriptorpb.FieldDescriptorProto_TYPE_STRING
+	}
+}
+
+func toMessage(md protoreflect.MessageDescriptor, fields map[string]any) (*dynamicpb.Message, error) {
+	msg := dynamicpb.NewMessage(md)
+
+	for i := 0; i < md.Fields().Len(); i++ {
+		fd := md.Fields().Get(i)
+
+		v, ok := fields[string(fd.Name())]
+		if !ok || v == nil {
+			continue
+		}
+
+		if fd.IsList() {
+			items, ok := v.([]any)
+			if !ok {
+				return nil, fmt.Errorf("field %s is not a list", fd.Name())
+			}
+
+			list := msg.Mutable(fd).List()
+			for _, item := range items {
+				pv, err := toValue(fd, item)
+				if err != nil {
+					return nil, err
+				}
+
+				list.Append(pv)
+			}
+
+			continue
+		}
+
+		pv, err := toValue(fd, v)
+		if err != nil {
+			return nil, err
+		}
+
+		msg.Set(fd, pv)
+	}
+
+	return msg, nil
+}
+
+func toValue(fd protoreflect.FieldDescriptor, v any) (protoreflect.Value, error) {
+	if fd.Kind() == protoreflect.MessageKind {
+		nested, ok := v.(map[string]any)
+		if !ok {
+			return protoreflect.Value{}, fmt.Errorf("field %s is not a message", fd.Name())
+		}
+
+		msg, err := toMessage(fd.Message(), nested)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+
+		return protoreflect.ValueOfMessage(msg), nil
+	}
+
+	switch v := v.(type) {
+	case bool, int64, uint64, float32, float64, string, []byte:
+		return protoreflect.ValueOf(v), nil
+	default:
+		return protoreflect.Value{}, fmt.Errorf("field %s has unsupported value %T", fd.Name(), v)
+	}
+}
+
+// fromMessage returns the generic representation of the message, including fields left to their default value.
+func fromMessage(msg protoreflect.Message) map[string]any {
+	fields := make(map[string]any)
+
+	md := msg.Descriptor()
+	for i := 0; i < md.Fields().Len(); i++ {
+		fd := md.Fields().Get(i)
+		v := msg.Get(fd)
+
+		switch {
+		case fd.IsList():
+			list := v.List()
+			items := make([]any, list.Len())
+			for j := range items {
+				items[j] = fromValue(fd, list.Get(j))
+			}
+			fields[string(fd.Name())] = items
+		default:
+			fields[string(fd.Name())] = fromValue(fd, v)
+		}
+	}
+
+	return fields
+}
+
+func fromValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) any {
+	if fd.Kind() == protoreflect.MessageKind {
+		return fromMessage(v.Message())
+	}
+
+	return v.Interface()
+}
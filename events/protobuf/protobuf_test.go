@@ -0,0 +1,69 @@
+package protobuf
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/events/codectest"
+	"github.com/giornetta/microshop/schemaregistry"
+)
+
+func newCodec(t *testing.T) codectest.Codec {
+	t.Helper()
+
+	registry, err := schemaregistry.NewFileRegistry(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return codectest.Codec{
+		Codec: NewCodec(registry),
+		EncodeAs: func(v any, t events.Type) ([]byte, error) {
+			reg, ok := events.Lookup(t)
+			if !ok {
+				return nil, fmt.Errorf("event type %s is not registered", t)
+			}
+
+			fdp := &descriptorpb.FileDescriptorProto{
+				Name:        proto.String(reg.Type.String() + ".old.proto"),
+				Package:     proto.String(protoPackage),
+				Syntax:      proto.String("proto3"),
+				MessageType: []*descriptorpb.DescriptorProto{messageOf(reflect.TypeOf(v))},
+			}
+			md, err := messageDescriptor(fdp)
+			if err != nil {
+				return nil, err
+			}
+			definition, err := protojson.Marshal(fdp)
+			if err != nil {
+				return nil, err
+			}
+
+			id, err := registry.Register(fmt.Sprintf("%s-%s", reg.Topic, reg.Type), schemaregistry.Protobuf, string(definition))
+			if err != nil {
+				return nil, err
+			}
+
+			msg, err := toMessage(md, events.Fields(v))
+			if err != nil {
+				return nil, err
+			}
+			payload, err := proto.Marshal(msg)
+			if err != nil {
+				return nil, err
+			}
+
+			return schemaregistry.Frame(id, payload), nil
+		},
+	}
+}
+
+func TestCodec(t *testing.T) {
+	codectest.TestCodec(t, newCodec)
+}
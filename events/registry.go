@@ -0,0 +1,123 @@
+package events
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Registration describes an event type known to the events package.
+type Registration struct {
+	Type   Type
+	Topic  Topic
+	GoType reflect.Type
+	Schema *Schema
+
+	decoder Decoder
+
+	// upcasters[i] transforms a payload of version i+1 into version i+2.
+	upcasters []Upcaster
+}
+
+// Version returns the current version of the event payload.
+// Every event starts at version 1, and each registered upcaster bumps it by one.
+func (r *Registration) Version() int {
+	return len(r.upcasters) + 1
+}
+
+var registry = make(map[Type]*Registration)
+
+func newRegistration[T Event](eventType Type) *Registration {
+	var evt T
+
+	goType := reflect.TypeOf(evt)
+
+	return &Registration{
+		Type:    eventType,
+		Topic:   evt.Topic(),
+		GoType:  goType,
+		Schema:  SchemaOf(goType),
+		decoder: fromJSON[T],
+	}
+}
+
+func registerEvent[T Event](eventType Type) {
+	registry[eventType] = newRegistration[T](eventType)
+}
+
+// registerUpcaster registers the function transforming payloads of the given event type
+// from version 'from' into version 'from+1'. Upcasters must be registered in order, starting from version 1.
+func registerUpcaster(eventType Type, from int, upcaster Upcaster) {
+	reg, ok := registry[eventType]
+	if !ok {
+		panic(fmt.Sprintf("cannot register upcaster for unknown event type %s", eventType))
+	}
+
+	reg.addUpcaster(from, upcaster)
+}
+
+func (r *Registration) addUpcaster(from int, upcaster Upcaster) {
+	if from != r.Version() {
+		panic(fmt.Sprintf("upcaster for %s must start from version %d, got %d", r.Type, r.Version(), from))
+	}
+
+	r.upcasters = append(r.upcasters, upcaster)
+}
+
+// Lookup returns the registration of the given event type, if any.
+func Lookup(t Type) (*Registration, bool) {
+	reg, ok := registry[t]
+	return reg, ok
+}
+
+// VersionOf returns the current version of the given event type, or 0 if it is not registered.
+func VersionOf(t Type) int {
+	reg, ok := registry[t]
+	if !ok {
+		return 0
+	}
+
+	return reg.Version()
+}
+
+// Registrations returns every registered event type, sorted by topic and type.
+func Registrations() []*Registration {
+	regs := make([]*Registration, 0, len(registry))
+	for _, reg := range registry {
+		regs = append(regs, reg)
+	}
+
+	sort.Slice(regs, func(i, j int) bool {
+		if regs[i].Topic != regs[j].Topic {
+			return regs[i].Topic < regs[j].Topic
+		}
+
+		return regs[i].Type < regs[j].Type
+	})
+
+	return regs
+}
+
+// Types returns the event types registered on the given topic, sorted by name.
+func Types(topic Topic) []Type {
+	var types []Type
+	for _, reg := range Registrations() {
+		if reg.Topic == topic {
+			types = append(types, reg.Type)
+		}
+	}
+
+	return types
+}
+
+// Topics returns every topic that has at least one registered event type.
+func Topics() []Topic {
+	var topics []Topic
+	for _, reg := range Registrations() {
+		if len(topics) == 0 || topics[len(topics)-1] != reg.Topic {
+			topics = append(topics, reg.Topic)
+		}
+	}
+
+	return topics
+}
@@ -0,0 +1,93 @@
+package events
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Schema is the subset of JSON Schema needed to describe event payloads.
+type Schema struct {
+	Title                string             `json:"title,omitempty" yaml:"title,omitempty"`
+	Type                 string             `json:"type,omitempty" yaml:"type,omitempty"`
+	Format               string             `json:"format,omitempty" yaml:"format,omitempty"`
+	Enum                 []string           `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty" yaml:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty" yaml:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+}
+
+// SchemaOf builds the JSON Schema of the given type, following encoding/json rules
+// for field names, embedded structs and omitempty.
+func SchemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+
+		return &Schema{Type: "array", Items: SchemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: SchemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.PkgPath() == "time" && t.Name() == "Time" {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		addFields(s, t)
+
+		return s
+	default:
+		return &Schema{}
+	}
+}
+
+func addFields(s *Schema, t reflect.Type) {
+	for _, f := range FieldsOf(t) {
+		if f.Embedded {
+			addFields(s, f.Type)
+			continue
+		}
+
+		s.Properties[f.Name] = SchemaOf(f.Type)
+		if !f.OmitEmpty && f.Type.Kind() != reflect.Pointer {
+			s.Required = append(s.Required, f.Name)
+		}
+	}
+}
+
+func jsonField(f reflect.StructField) (name string, omitEmpty bool, skip bool) {
+	tag, ok := f.Tag.Lookup("json")
+	if !ok {
+		return "", false, false
+	}
+
+	if tag == "-" {
+		return "", false, true
+	}
+
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+
+	return parts[0], omitEmpty, false
+}
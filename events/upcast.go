@@ -0,0 +1,44 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Upcaster transforms the payload of an event from one version into the next one.
+// Payloads are handled in their generic form, so that upcasters don't need to keep
+// the Go types of older versions around.
+type Upcaster func(payload map[string]any) (map[string]any, error)
+
+func (r *Registration) decode(version int, payload []byte) (Event, error) {
+	if version == 0 {
+		version = 1
+	}
+
+	if version == r.Version() {
+		return r.decoder(payload)
+	}
+
+	if version < 1 || version > r.Version() {
+		return nil, fmt.Errorf("version %d of %s is not supported, current version is %d", version, r.Type, r.Version())
+	}
+
+	var generic map[string]any
+	if err := json.Unmarshal(payload, &generic); err != nil {
+		return nil, err
+	}
+
+	for v := version; v < r.Version(); v++ {
+		var err error
+		if generic, err = r.upcasters[v-1](generic); err != nil {
+			return nil, fmt.Errorf("could not upcast %s from version %d: %w", r.Type, v, err)
+		}
+	}
+
+	upcasted, err := json.Marshal(generic)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.decoder(upcasted)
+}
@@ -0,0 +1,173 @@
+package events
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestDecodeFixtures replays a fixture of every version of every registered event,
+// found in testdata/<type>/v<version>.json. All the versions of an event must decode
+// to the same value as the fixture of its current version.
+func TestDecodeFixtures(t *testing.T) {
+	for _, reg := range Registrations() {
+		reg := reg
+
+		t.Run(reg.Type.String(), func(t *testing.T) {
+			want, err := Decode(reg.Type, reg.Version(), readFixture(t, reg.Type, reg.Version()))
+			if err != nil {
+				t.Fatalf("could not decode current version %d: %v", reg.Version(), err)
+			}
+
+			if reflect.TypeOf(want) != reg.GoType {
+				t.Fatalf("decoded %T, want %v", want, reg.GoType)
+			}
+
+			for v := 1; v < reg.Version(); v++ {
+				got, err := Decode(reg.Type, v, readFixture(t, reg.Type, v))
+				if err != nil {
+					t.Fatalf("could not decode version %d: %v", v, err)
+				}
+
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("version %d decoded to %+v, want %+v", v, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestDecodeUnversioned decodes a ProductCreated published before events were versioned, which is read as
+// version 1 and upcast with its whole amount in the default warehouse.
+func TestDecodeUnversioned(t *testing.T) {
+	got, err := Decode(ProductCreatedType, 0, readFixture(t, ProductCreatedType, 0))
+	if err != nil {
+		t.Fatalf("could not decode version 0: %v", err)
+	}
+
+	want := ProductCreated{
+		ProductEvent: ProductEvent{ProductId: "4f1c2a5e-8f7b-4a53-9f0e-2f7f5a1b9c10"},
+		Name:         "Keyboard",
+		Description:  "Mechanical keyboard",
+		Price:        49.9,
+		Amount:       10,
+		Stock:        []WarehouseStock{{WarehouseId: DefaultWarehouseId, Amount: 10}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded %+v, want %+v", got, want)
+	}
+}
+
+func TestUpcastStock(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    []WarehouseStock
+	}{
+		{name: "no stock", payload: `{"amount":4}`, want: []WarehouseStock{{WarehouseId: DefaultWarehouseId, Amount: 4}}},
+		{name: "out of stock", payload: `{"amount":0}`},
+		// Version 1 events published after warehouses were introduced already have their stock.
+		{
+			name:    "stock",
+			payload: `{"amount":4,"stock":[{"warehouse_id":"milan-1","amount":1},{"warehouse_id":"default","amount":3}]}`,
+			want:    []WarehouseStock{{WarehouseId: "milan-1", Amount: 1}, {WarehouseId: DefaultWarehouseId, Amount: 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, eventType := range []Type{ProductCreatedType, ProductUpdatedType} {
+				evt, err := Decode(eventType, 1, []byte(tt.payload))
+				if err != nil {
+					t.Fatalf("could not decode %s: %v", eventType, err)
+				}
+
+				var got []WarehouseStock
+				switch e := evt.(type) {
+				case ProductCreated:
+					got = e.Stock
+				case ProductUpdated:
+					got = e.Stock
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("%s has stock %+v, want %+v", eventType, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func readFixture(t *testing.T, eventType Type, version int) []byte {
+	t.Helper()
+
+	payload, err := os.ReadFile(filepath.Join("testdata", eventType.String(), fmt.Sprintf("v%d.json", version)))
+	if err != nil {
+		t.Fatalf("missing fixture for version %d: %v", version, err)
+	}
+
+	return payload
+}
+
+type upcastedEvent struct {
+	ProductEvent
+	Price struct {
+		Cents    int    `json:"cents"`
+		Currency string `json:"currency"`
+	} `json:"price"`
+}
+
+func (upcastedEvent) Type() Type { return "Test.Upcasted" }
+
+func TestUpcasterChain(t *testing.T) {
+	reg := newRegistration[upcastedEvent]("Test.Upcasted")
+
+	// v1 -> v2: price becomes an amount of cents.
+	reg.addUpcaster(1, func(payload map[string]any) (map[string]any, error) {
+		payload["price"] = int(payload["price"].(float64) * 100)
+		return payload, nil
+	})
+	// v2 -> v3: price gains a currency.
+	reg.addUpcaster(2, func(payload map[string]any) (map[string]any, error) {
+		payload["price"] = map[string]any{"cents": payload["price"], "currency": "EUR"}
+		return payload, nil
+	})
+
+	if reg.Version() != 3 {
+		t.Fatalf("got version %d, want 3", reg.Version())
+	}
+
+	payloads := map[int]string{
+		1: `{"product_id":"p1","price":12.5}`,
+		2: `{"product_id":"p1","price":1250}`,
+		3: `{"product_id":"p1","price":{"cents":1250,"currency":"EUR"}}`,
+	}
+
+	for version, payload := range payloads {
+		evt, err := reg.decode(version, []byte(payload))
+		if err != nil {
+			t.Fatalf("could not decode version %d: %v", version, err)
+		}
+
+		got := evt.(upcastedEvent)
+		if got.ProductId != "p1" || got.Price.Cents != 1250 || got.Price.Currency != "EUR" {
+			t.Errorf("version %d decoded to %+v", version, got)
+		}
+	}
+
+	if _, err := reg.decode(4, []byte(payloads[3])); err == nil {
+		t.Error("expected decoding a future version to fail")
+	}
+}
+
+func TestAddUpcasterOutOfOrder(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering an upcaster out of order to panic")
+		}
+	}()
+
+	reg := newRegistration[upcastedEvent]("Test.Upcasted")
+	reg.addUpcaster(2, func(payload map[string]any) (map[string]any, error) { return payload, nil })
+}
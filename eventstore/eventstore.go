@@ -0,0 +1,76 @@
+// Package eventstore defines an append-only store of event streams, one per aggregate,
+// used as the authoritative state of event-sourced aggregates.
+package eventstore
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/giornetta/microshop/events"
+)
+
+// StoredEvent is an event at a given position of its aggregate stream.
+// Versions start at 1 and grow by one with every appended event.
+type StoredEvent struct {
+	Version    int
+	Event      events.Event
+	RecordedAt time.Time
+}
+
+// Snapshot is the serialized state of an aggregate at a given version of its stream.
+type Snapshot struct {
+	AggregateId string
+	Version     int
+	State       []byte
+}
+
+// Append is an addition of events to the stream of an aggregate, as done by Store.Append.
+type Append struct {
+	AggregateId     string
+	ExpectedVersion int
+	Events          []events.Event
+}
+
+// Store keeps the event streams, along with an Outbox of the appended events: events appended
+// to a stream are held by the outbox in the same transaction, until a Relay publishes them.
+type Store interface {
+	Outbox
+
+	// Append adds the events to the stream of the aggregate, failing with ErrConcurrencyConflict
+	// if the stream is not at expectedVersion. An expectedVersion of 0 means the stream must not exist yet.
+	Append(aggregateId string, expectedVersion int, evts []events.Event, ctx context.Context) error
+	// AppendAll appends to many streams at once: either all the appends succeed, or none does,
+	// failing with ErrConcurrencyConflict for the first stream that is not at its expected version.
+	AppendAll(appends []Append, ctx context.Context) error
+	// Load returns the events of the aggregate stream with a version greater than afterVersion.
+	Load(aggregateId string, afterVersion int, ctx context.Context) ([]StoredEvent, error)
+
+	// SaveSnapshot stores the snapshot, replacing any previous one of the same aggregate.
+	SaveSnapshot(snapshot *Snapshot, ctx context.Context) error
+	// LoadSnapshot returns the latest snapshot of the aggregate, or nil if there is none.
+	LoadSnapshot(aggregateId string, ctx context.Context) (*Snapshot, error)
+}
+
+// Outbox holds the appended events that have not been published yet.
+type Outbox interface {
+	// Drain passes up to limit unpublished events to publish, in the order they were appended, and removes
+	// from the outbox the first n of them, n being the count publish returns. Concurrent drains wait for
+	// each other, so that every event is passed to a single one of them and the order of a stream is kept.
+	// It returns the number of removed events.
+	Drain(limit int, publish func(evts []events.Event) int, ctx context.Context) (int, error)
+}
+
+type ErrConcurrencyConflict struct {
+	AggregateId     string
+	ExpectedVersion int
+}
+
+func (err *ErrConcurrencyConflict) Error() string {
+	return fmt.Sprintf("stream of aggregate with id=%s is not at version %d", err.AggregateId, err.ExpectedVersion)
+}
+
+func (err *ErrConcurrencyConflict) StatusCode() int {
+	return http.StatusConflict
+}
@@ -0,0 +1,230 @@
+// Package eventstoretest checks that implementations of eventstore.Store behave the same,
+// so that the memory store used by tests stands in faithfully for the Postgres one.
+package eventstoretest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/eventstore"
+)
+
+// TestStore checks the behaviour of the stores returned by newStore, documented on eventstore.Store.
+// Every subtest gets a new store.
+func TestStore(t *testing.T, newStore func(t *testing.T) eventstore.Store) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s eventstore.Store)
+	}{
+		{"AppendAndLoad", testAppendAndLoad},
+		{"AppendConflict", testAppendConflict},
+		{"AppendAllConflict", testAppendAllConflict},
+		{"Snapshots", testSnapshots},
+		{"DrainInOrder", testDrainInOrder},
+		{"DrainPartially", testDrainPartially},
+		{"DrainWithLimit", testDrainWithLimit},
+		{"DrainSkipsConflicts", testDrainSkipsConflicts},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStore(t))
+		})
+	}
+}
+
+// stockChanged returns an event of the product, told apart from the others by quantity.
+func stockChanged(id string, quantity int) events.Event {
+	return events.ProductStockChanged{
+		ProductEvent: events.ProductEvent{ProductId: id},
+		WarehouseId:  "default",
+		Quantity:     quantity,
+		Amount:       quantity,
+	}
+}
+
+func appendEvents(t *testing.T, s eventstore.Store, id string, expectedVersion int, evts ...events.Event) {
+	t.Helper()
+
+	if err := s.Append(id, expectedVersion, evts, context.Background()); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+}
+
+// drain drains the outbox, accepting n of the events it is given, and returns them.
+func drain(t *testing.T, s eventstore.Store, limit, n int) []events.Event {
+	t.Helper()
+
+	var drained []events.Event
+	removed, err := s.Drain(limit, func(evts []events.Event) int {
+		drained = evts
+		return min(n, len(evts))
+	}, context.Background())
+	if err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if removed != min(n, len(drained)) {
+		t.Fatalf("Drain removed %d events, want %d", removed, min(n, len(drained)))
+	}
+
+	return drained
+}
+
+func testAppendAndLoad(t *testing.T, s eventstore.Store) {
+	id := uuid.NewString()
+	appendEvents(t, s, id, 0, stockChanged(id, 1), stockChanged(id, 2))
+	appendEvents(t, s, id, 2, stockChanged(id, 3))
+	appendEvents(t, s, uuid.NewString(), 0, stockChanged(id, 4))
+
+	stored, err := s.Load(id, 1, context.Background())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(stored) != 2 {
+		t.Fatalf("Load returned %d events, want 2", len(stored))
+	}
+	for i, e := range stored {
+		if want := stockChanged(id, i+2); e.Version != i+2 || !reflect.DeepEqual(e.Event, want) {
+			t.Errorf("Load returned %+v at version %d, want %+v at version %d", e.Event, e.Version, want, i+2)
+		}
+	}
+
+	none, err := s.Load(uuid.NewString(), 0, context.Background())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(none) != 0 {
+		t.Errorf("Load returned %+v for a missing stream", none)
+	}
+}
+
+func testAppendConflict(t *testing.T, s eventstore.Store) {
+	id := uuid.NewString()
+	appendEvents(t, s, id, 0, stockChanged(id, 1))
+
+	for _, version := range []int{0, 2} {
+		err := s.Append(id, version, []events.Event{stockChanged(id, 2)}, context.Background())
+
+		var conflict *eventstore.ErrConcurrencyConflict
+		if !errors.As(err, &conflict) || conflict.AggregateId != id || conflict.ExpectedVersion != version {
+			t.Errorf("Append at version %d: got error %v, want *ErrConcurrencyConflict", version, err)
+		}
+	}
+}
+
+func testAppendAllConflict(t *testing.T, s eventstore.Store) {
+	first, second := uuid.NewString(), uuid.NewString()
+	appendEvents(t, s, second, 0, stockChanged(second, 1))
+
+	err := s.AppendAll([]eventstore.Append{
+		{AggregateId: first, ExpectedVersion: 0, Events: []events.Event{stockChanged(first, 1)}},
+		{AggregateId: second, ExpectedVersion: 0, Events: []events.Event{stockChanged(second, 2)}},
+	}, context.Background())
+
+	var conflict *eventstore.ErrConcurrencyConflict
+	if !errors.As(err, &conflict) || conflict.AggregateId != second {
+		t.Fatalf("got error %v, want *ErrConcurrencyConflict on %s", err, second)
+	}
+
+	stored, err := s.Load(first, 0, context.Background())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(stored) != 0 {
+		t.Errorf("AppendAll appended %+v to %s despite the conflict", stored, first)
+	}
+}
+
+func testSnapshots(t *testing.T, s eventstore.Store) {
+	id := uuid.NewString()
+
+	none, err := s.LoadSnapshot(id, context.Background())
+	if err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+	if none != nil {
+		t.Fatalf("LoadSnapshot returned %+v for an aggregate without snapshots", none)
+	}
+
+	want := &eventstore.Snapshot{AggregateId: id, Version: 4, State: []byte(`{"version": 4}`)}
+	for _, snapshot := range []*eventstore.Snapshot{
+		{AggregateId: id, Version: 2, State: []byte(`{"version": 2}`)},
+		want,
+		{AggregateId: id, Version: 3, State: []byte(`{"version": 3}`)},
+	} {
+		if err := s.SaveSnapshot(snapshot, context.Background()); err != nil {
+			t.Fatalf("SaveSnapshot: %v", err)
+		}
+	}
+
+	got, err := s.LoadSnapshot(id, context.Background())
+	if err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+	if got == nil || got.Version != want.Version {
+		t.Errorf("LoadSnapshot returned %+v, want the snapshot at version %d", got, want.Version)
+	}
+}
+
+func testDrainInOrder(t *testing.T, s eventstore.Store) {
+	first, second := uuid.NewString(), uuid.NewString()
+	appendEvents(t, s, first, 0, stockChanged(first, 1), stockChanged(first, 2))
+	appendEvents(t, s, second, 0, stockChanged(second, 1))
+	appendEvents(t, s, first, 2, stockChanged(first, 3))
+
+	want := []events.Event{stockChanged(first, 1), stockChanged(first, 2), stockChanged(second, 1), stockChanged(first, 3)}
+	if got := drain(t, s, 10, 10); !reflect.DeepEqual(got, want) {
+		t.Errorf("Drain passed %+v, want %+v", got, want)
+	}
+
+	if got := drain(t, s, 10, 10); len(got) != 0 {
+		t.Errorf("Drain passed %+v again", got)
+	}
+}
+
+func testDrainPartially(t *testing.T, s eventstore.Store) {
+	id := uuid.NewString()
+	appendEvents(t, s, id, 0, stockChanged(id, 1), stockChanged(id, 2), stockChanged(id, 3))
+
+	// The events publish did not accept are kept, for the next drain to pass them again.
+	drain(t, s, 10, 1)
+	if got, want := drain(t, s, 10, 0), []events.Event{stockChanged(id, 2), stockChanged(id, 3)}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Drain passed %+v after removing the first event, want %+v", got, want)
+	}
+	if got, want := drain(t, s, 10, 10), []events.Event{stockChanged(id, 2), stockChanged(id, 3)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Drain passed %+v after publishing none, want %+v", got, want)
+	}
+}
+
+func testDrainWithLimit(t *testing.T, s eventstore.Store) {
+	id := uuid.NewString()
+	appendEvents(t, s, id, 0, stockChanged(id, 1), stockChanged(id, 2), stockChanged(id, 3))
+
+	if got, want := drain(t, s, 2, 2), []events.Event{stockChanged(id, 1), stockChanged(id, 2)}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Drain passed %+v, want %+v", got, want)
+	}
+	if got, want := drain(t, s, 2, 2), []events.Event{stockChanged(id, 3)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Drain passed %+v, want %+v", got, want)
+	}
+}
+
+func testDrainSkipsConflicts(t *testing.T, s eventstore.Store) {
+	id := uuid.NewString()
+	appendEvents(t, s, id, 0, stockChanged(id, 1))
+
+	// Events that were not appended are not published either.
+	if err := s.Append(id, 0, []events.Event{stockChanged(id, 2)}, context.Background()); err == nil {
+		t.Fatal("Append succeeded at a stale version")
+	}
+
+	if got, want := drain(t, s, 10, 10), []events.Event{stockChanged(id, 1)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Drain passed %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,134 @@
+// Package memory implements an event Store in memory, standing in for Postgres where a database
+// is not available, such as in tests.
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/eventstore"
+)
+
+type store struct {
+	lock      sync.RWMutex
+	streams   map[string][]eventstore.StoredEvent
+	snapshots map[string]eventstore.Snapshot
+	outbox    []events.Event
+
+	// draining is held by Drain, so that concurrent drains wait for each other.
+	draining sync.Mutex
+}
+
+func NewEventStore() eventstore.Store {
+	return &store{
+		streams:   make(map[string][]eventstore.StoredEvent),
+		snapshots: make(map[string]eventstore.Snapshot),
+	}
+}
+
+func (s *store) Append(aggregateId string, expectedVersion int, evts []events.Event, ctx context.Context) error {
+	return s.AppendAll([]eventstore.Append{{AggregateId: aggregateId, ExpectedVersion: expectedVersion, Events: evts}}, ctx)
+}
+
+func (s *store) AppendAll(appends []eventstore.Append, ctx context.Context) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Appends to the same stream follow each other, as they would in a transaction.
+	versions := make(map[string]int)
+	for _, a := range appends {
+		current, ok := versions[a.AggregateId]
+		if !ok {
+			current = len(s.streams[a.AggregateId])
+		}
+
+		if current != a.ExpectedVersion {
+			return &eventstore.ErrConcurrencyConflict{AggregateId: a.AggregateId, ExpectedVersion: a.ExpectedVersion}
+		}
+
+		versions[a.AggregateId] = current + len(a.Events)
+	}
+
+	now := time.Now().UTC()
+	for _, a := range appends {
+		stream := s.streams[a.AggregateId]
+		for i, evt := range a.Events {
+			stream = append(stream, eventstore.StoredEvent{
+				Version:    a.ExpectedVersion + i + 1,
+				Event:      evt,
+				RecordedAt: now,
+			})
+		}
+		s.streams[a.AggregateId] = stream
+		s.outbox = append(s.outbox, a.Events...)
+	}
+
+	return nil
+}
+
+func (s *store) Load(aggregateId string, afterVersion int, ctx context.Context) ([]eventstore.StoredEvent, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	stream := s.streams[aggregateId]
+	if afterVersion >= len(stream) {
+		return nil, nil
+	}
+	if afterVersion < 0 {
+		afterVersion = 0
+	}
+
+	return append([]eventstore.StoredEvent(nil), stream[afterVersion:]...), nil
+}
+
+func (s *store) Drain(limit int, publish func(evts []events.Event) int, ctx context.Context) (int, error) {
+	s.draining.Lock()
+	defer s.draining.Unlock()
+
+	// Appends may go on while the events are published, only adding to the end of the outbox.
+	s.lock.RLock()
+	pending := append([]events.Event(nil), s.outbox[:min(limit, len(s.outbox))]...)
+	s.lock.RUnlock()
+
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	n := publish(pending)
+
+	s.lock.Lock()
+	s.outbox = s.outbox[n:]
+	s.lock.Unlock()
+
+	return n, nil
+}
+
+// SaveSnapshot replaces the snapshot of the aggregate unless the stored one is as recent.
+func (s *store) SaveSnapshot(snapshot *eventstore.Snapshot, ctx context.Context) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if current, ok := s.snapshots[snapshot.AggregateId]; ok && current.Version >= snapshot.Version {
+		return nil
+	}
+
+	snap := *snapshot
+	snap.State = append([]byte(nil), snapshot.State...)
+	s.snapshots[snapshot.AggregateId] = snap
+
+	return nil
+}
+
+func (s *store) LoadSnapshot(aggregateId string, ctx context.Context) (*eventstore.Snapshot, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	snapshot, ok := s.snapshots[aggregateId]
+	if !ok {
+		return nil, nil
+	}
+
+	return &snapshot, nil
+}
@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/giornetta/microshop/eventstore"
+	"github.com/giornetta/microshop/eventstore/eventstoretest"
+)
+
+func TestStore(t *testing.T) {
+	eventstoretest.TestStore(t, func(t *testing.T) eventstore.Store {
+		return NewEventStore()
+	})
+}
@@ -0,0 +1,234 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/eventstore"
+)
+
+const uniqueViolation = "23505"
+
+type store struct {
+	pool *pgxpool.Pool
+}
+
+// NewEventStore returns an event Store backed by the event_streams and event_snapshots tables.
+func NewEventStore(pool *pgxpool.Pool) eventstore.Store {
+	return &store{
+		pool: pool,
+	}
+}
+
+func (s *store) Append(aggregateId string, expectedVersion int, evts []events.Event, ctx context.Context) error {
+	return s.AppendAll([]eventstore.Append{{AggregateId: aggregateId, ExpectedVersion: expectedVersion, Events: evts}}, ctx)
+}
+
+func (s *store) AppendAll(appends []eventstore.Append, ctx context.Context) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, a := range appends {
+		if err := appendTx(tx, a, ctx); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func appendTx(tx pgx.Tx, a eventstore.Append, ctx context.Context) error {
+	var current int
+	if err := tx.QueryRow(
+		ctx,
+		"SELECT COALESCE(MAX(version), 0) FROM event_streams WHERE aggregate_id = $1",
+		a.AggregateId,
+	).Scan(&current); err != nil {
+		return err
+	}
+
+	if current != a.ExpectedVersion {
+		return &eventstore.ErrConcurrencyConflict{AggregateId: a.AggregateId, ExpectedVersion: a.ExpectedVersion}
+	}
+
+	for i, evt := range a.Events {
+		payload, err := json.Marshal(evt)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(
+			ctx,
+			`INSERT INTO event_streams(aggregate_id, version, event_type, event_version, payload, recorded_at)
+			VALUES($1, $2, $3, $4, $5, $6);`,
+			a.AggregateId, a.ExpectedVersion+i+1, evt.Type().String(), events.VersionOf(evt.Type()), payload, time.Now().UTC(),
+		); err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+				return &eventstore.ErrConcurrencyConflict{AggregateId: a.AggregateId, ExpectedVersion: a.ExpectedVersion}
+			}
+
+			return err
+		}
+
+		if _, err := tx.Exec(
+			ctx,
+			"INSERT INTO event_outbox(aggregate_id, version) VALUES($1, $2);",
+			a.AggregateId, a.ExpectedVersion+i+1,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Drain locks the rows it reads from the outbox until they are removed, which makes concurrent drains wait.
+func (s *store) Drain(limit int, publish func(evts []events.Event) int, ctx context.Context) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(
+		ctx,
+		`SELECT o.position, e.event_type, e.event_version, e.payload
+		FROM event_outbox o JOIN event_streams e ON e.aggregate_id = o.aggregate_id AND e.version = o.version
+		ORDER BY o.position LIMIT $1 FOR UPDATE OF o`,
+		limit,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		positions []int64
+		pending   []events.Event
+	)
+	for rows.Next() {
+		var (
+			position     int64
+			eventType    string
+			eventVersion int
+			payload      []byte
+		)
+
+		if err := rows.Scan(&position, &eventType, &eventVersion, &payload); err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		evt, err := events.Decode(events.Type(eventType), eventVersion, payload)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		positions = append(positions, position)
+		pending = append(pending, evt)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	n := publish(pending)
+	if n == 0 {
+		return 0, nil
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM event_outbox WHERE position = ANY($1);", positions[:n]); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func (s *store) Load(aggregateId string, afterVersion int, ctx context.Context) ([]eventstore.StoredEvent, error) {
+	rows, err := s.pool.Query(
+		ctx,
+		`SELECT version, event_type, event_version, payload, recorded_at
+		FROM event_streams WHERE aggregate_id = $1 AND version > $2 ORDER BY version`,
+		aggregateId, afterVersion,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stored []eventstore.StoredEvent
+	for rows.Next() {
+		var (
+			e            eventstore.StoredEvent
+			eventType    string
+			eventVersion int
+			payload      []byte
+		)
+
+		if err := rows.Scan(&e.Version, &eventType, &eventVersion, &payload, &e.RecordedAt); err != nil {
+			return nil, err
+		}
+
+		if e.Event, err = events.Decode(events.Type(eventType), eventVersion, payload); err != nil {
+			return nil, err
+		}
+
+		stored = append(stored, e)
+	}
+
+	return stored, rows.Err()
+}
+
+func (s *store) SaveSnapshot(snapshot *eventstore.Snapshot, ctx context.Context) error {
+	if _, err := s.pool.Exec(
+		ctx,
+		`INSERT INTO event_snapshots(aggregate_id, version, state, taken_at)
+		VALUES($1, $2, $3, $4)
+		ON CONFLICT (aggregate_id) DO UPDATE
+		SET version = EXCLUDED.version, state = EXCLUDED.state, taken_at = EXCLUDED.taken_at
+		WHERE event_snapshots.version < EXCLUDED.version;`,
+		snapshot.AggregateId, snapshot.Version, snapshot.State, time.Now().UTC(),
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *store) LoadSnapshot(aggregateId string, ctx context.Context) (*eventstore.Snapshot, error) {
+	snapshot := eventstore.Snapshot{AggregateId: aggregateId}
+
+	if err := s.pool.QueryRow(
+		ctx,
+		"SELECT version, state FROM event_snapshots WHERE aggregate_id = $1",
+		aggregateId,
+	).Scan(&snapshot.Version, &snapshot.State); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
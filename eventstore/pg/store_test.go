@@ -0,0 +1,18 @@
+package pg
+
+import (
+	"testing"
+
+	"github.com/giornetta/microshop/eventstore"
+	"github.com/giornetta/microshop/eventstore/eventstoretest"
+	"github.com/giornetta/microshop/postgres/postgrestest"
+	products "github.com/giornetta/microshop/products/pg"
+)
+
+// TestStore runs against the server given by postgrestest.URLEnv, and is skipped without one.
+// The event store tables are migrated along with the products database, which holds them.
+func TestStore(t *testing.T) {
+	eventstoretest.TestStore(t, func(t *testing.T) eventstore.Store {
+		return NewEventStore(postgrestest.Database(t, products.Migrations))
+	})
+}
@@ -0,0 +1,99 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/giornetta/microshop/events"
+)
+
+// relayBatchSize is the number of events a Relay takes from the outbox at once.
+const relayBatchSize = 100
+
+// Relay publishes the events held by an Outbox, removing them once they are published.
+//
+// Events are published at least once: those published right before their removal fails are published again,
+// so the handlers of the events must accept them twice. The events of a stream are published in order, and
+// those following an event that could not be published are kept until it is.
+type Relay struct {
+	outbox    Outbox
+	publisher events.Publisher
+	interval  time.Duration
+	logger    *slog.Logger
+}
+
+// NewRelay returns a Relay draining the outbox every interval.
+func NewRelay(outbox Outbox, publisher events.Publisher, interval time.Duration, logger *slog.Logger) *Relay {
+	return &Relay{
+		outbox:    outbox,
+		publisher: publisher,
+		interval:  interval,
+		logger:    logger,
+	}
+}
+
+// Run publishes the events of the outbox right away, then every interval, until ctx is canceled.
+// Events that cannot be published are logged and retried at the following tick, so Run returns nil.
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Flush(ctx); err != nil && ctx.Err() == nil {
+			r.logger.ErrorCtx(ctx, "could not relay events", slog.String("err", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush publishes batches of events until the outbox is empty, failing at the first event that cannot be
+// published. A batch that started is published even if ctx is canceled meanwhile, but no other one is started.
+func (r *Relay) Flush(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var failed error
+		n, err := r.outbox.Drain(relayBatchSize, func(evts []events.Event) int {
+			errs := events.PublishBatch(r.publisher, evts, context.WithoutCancel(ctx))
+
+			// Only the events published up to the first failed one are removed, each known to be published
+			// by its own result. An event without a result is not known to be, so it counts as failed.
+			published := 0
+			for published < len(evts) && published < len(errs) && errs[published] == nil {
+				published++
+			}
+
+			if published < len(evts) {
+				err := errors.New("no result was returned")
+				if published < len(errs) {
+					err = errs[published]
+				}
+				failed = fmt.Errorf("could not publish %s event of %s: %w", evts[published].Type(), evts[published].Key(), err)
+			}
+
+			return published
+		}, context.WithoutCancel(ctx))
+		if err != nil {
+			return fmt.Errorf("could not drain the outbox: %w", err)
+		}
+
+		if failed != nil {
+			return failed
+		}
+
+		if n < relayBatchSize {
+			return nil
+		}
+	}
+}
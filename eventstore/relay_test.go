@@ -0,0 +1,109 @@
+package eventstore_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"golang.org/x/exp/slog"
+
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/eventstore"
+	"github.com/giornetta/microshop/eventstore/memory"
+	"github.com/giornetta/microshop/kafka"
+	"github.com/giornetta/microshop/kafka/kafkatest"
+)
+
+// outboxed returns the keys of the events held by the outbox, leaving them there.
+func outboxed(t *testing.T, outbox eventstore.Outbox) map[events.Key]bool {
+	t.Helper()
+
+	keys := make(map[events.Key]bool)
+	if _, err := outbox.Drain(1000, func(evts []events.Event) int {
+		for _, e := range evts {
+			keys[e.Key()] = true
+		}
+		return 0
+	}, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return keys
+}
+
+// produced returns the keys of the records produced to the partition of the topic.
+func produced(t *testing.T, client *kgo.Client, topic string, partition int32) map[events.Key]bool {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ends, err := kadm.NewClient(client).ListEndOffsets(ctx, topic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	end, _ := ends.Lookup(topic, partition)
+
+	client.AddConsumePartitions(map[string]map[int32]kgo.Offset{topic: {partition: kgo.NewOffset().AtStart()}})
+
+	keys := make(map[events.Key]bool)
+	for offset := int64(0); offset < end.Offset; {
+		fetches := client.PollFetches(ctx)
+		if errs := fetches.Errors(); errs != nil {
+			t.Fatal(errs[0].Err)
+		}
+
+		fetches.EachRecord(func(r *kgo.Record) {
+			keys[events.Key(r.Key)] = true
+			offset = r.Offset + 1
+		})
+	}
+
+	return keys
+}
+
+func TestRelayKeepsUnpublishedEvents(t *testing.T) {
+	cluster := kafkatest.Cluster(t, 2)
+	store := memory.NewEventStore()
+	ctx := context.Background()
+
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("product-%d", i)
+		if err := store.Append(id, 0, []events.Event{events.ProductCreated{
+			ProductEvent: events.ProductEvent{ProductId: id},
+			Name:         "Product " + id,
+			Price:        10,
+		}}, ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	kafkatest.FailProduce(cluster, 1)
+
+	client := kafkatest.Client(t, cluster)
+	relay := eventstore.NewRelay(store, kafka.NewEventPublisher(client, events.NewCodecs()), time.Hour, slog.Default())
+
+	if err := relay.Flush(ctx); err == nil {
+		t.Fatal("Flush succeeded although a partition rejected its events")
+	}
+
+	published := produced(t, kafkatest.Client(t, cluster), events.ProductTopic.String(), 0)
+	if len(published) == 0 || len(published) == 20 {
+		t.Fatalf("published %d events to the working partition, want some of them", len(published))
+	}
+
+	// Every event removed from the outbox was published, while the others are retried.
+	remaining := outboxed(t, store)
+	for i := 0; i < 20; i++ {
+		key := events.Key(fmt.Sprintf("product-%d", i))
+		if !remaining[key] && !published[key] {
+			t.Errorf("event of %s was removed from the outbox without being published", key)
+		}
+	}
+	if len(remaining) < 20-len(published) {
+		t.Errorf("outbox holds %d events, want at least the %d unpublished ones", len(remaining), 20-len(published))
+	}
+}
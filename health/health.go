@@ -0,0 +1,152 @@
+// Package health exposes liveness and readiness endpoints,
+// aggregating the checkers registered by the other packages.
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/giornetta/microshop/respond"
+)
+
+const checkTimeout = 3 * time.Second
+
+// Checker reports whether a dependency or component of the service is healthy.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type checkerFunc struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// NewChecker returns a Checker with the given name, running the check function.
+func NewChecker(name string, check func(ctx context.Context) error) Checker {
+	return &checkerFunc{
+		name:  name,
+		check: check,
+	}
+}
+
+func (c *checkerFunc) Name() string { return c.name }
+
+func (c *checkerFunc) Check(ctx context.Context) error { return c.check(ctx) }
+
+// Registry holds the checkers deciding whether the service is alive, meaning it should be restarted
+// when they fail, and whether it is ready, meaning it can receive traffic.
+type Registry struct {
+	lock      sync.RWMutex
+	liveness  []Checker
+	readiness []Checker
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// RegisterLiveness adds checkers that must pass for the service to be considered alive.
+// Liveness checkers are also part of readiness.
+func (r *Registry) RegisterLiveness(checkers ...Checker) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.liveness = append(r.liveness, checkers...)
+}
+
+// RegisterReadiness adds checkers that must pass for the service to be considered ready.
+func (r *Registry) RegisterReadiness(checkers ...Checker) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.readiness = append(r.readiness, checkers...)
+}
+
+// LivenessHandler serves the result of the liveness checks.
+func (r *Registry) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.lock.RLock()
+		checkers := append([]Checker(nil), r.liveness...)
+		r.lock.RUnlock()
+
+		serve(w, req, checkers)
+	})
+}
+
+// ReadinessHandler serves the result of both the liveness and the readiness checks.
+func (r *Registry) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.lock.RLock()
+		checkers := append(append([]Checker(nil), r.liveness...), r.readiness...)
+		r.lock.RUnlock()
+
+		serve(w, req, checkers)
+	})
+}
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Run executes the checkers concurrently, each with a timeout.
+func Run(checkers []Checker, ctx context.Context) *Report {
+	report := &Report{
+		Status: StatusUp,
+		Checks: make(map[string]CheckResult, len(checkers)),
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, c := range checkers {
+		wg.Add(1)
+		go func(c Checker) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			result := CheckResult{Status: StatusUp}
+			if err := c.Check(ctx); err != nil {
+				result = CheckResult{Status: StatusDown, Error: err.Error()}
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			report.Checks[c.Name()] = result
+			if result.Status == StatusDown {
+				report.Status = StatusDown
+			}
+		}(c)
+	}
+
+	wg.Wait()
+
+	return report
+}
+
+func serve(w http.ResponseWriter, r *http.Request, checkers []Checker) {
+	report := Run(checkers, r.Context())
+
+	status := http.StatusOK
+	if report.Status != StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+
+	respond.JSON(w, status, report)
+}
@@ -0,0 +1,89 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func passing(name string) Checker {
+	return NewChecker(name, func(context.Context) error { return nil })
+}
+
+func failing(name string) Checker {
+	return NewChecker(name, func(context.Context) error { return fmt.Errorf("%s is unreachable", name) })
+}
+
+// get serves a request with h and returns the status code and the decoded report.
+func get(t *testing.T, h http.Handler) (int, *Report) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var report Report
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("could not decode report: %v", err)
+	}
+
+	return rec.Code, &report
+}
+
+func TestReadinessFails(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterLiveness(passing("server"))
+	r.RegisterReadiness(passing("kafka"))
+
+	if status, report := get(t, r.ReadinessHandler()); status != http.StatusOK || report.Status != StatusUp {
+		t.Fatalf("readiness is %d %s with passing checks, want 200 %s", status, report.Status, StatusUp)
+	}
+
+	// Checks can be registered while the service runs, as components start.
+	r.RegisterReadiness(failing("postgres"))
+
+	status, report := get(t, r.ReadinessHandler())
+	if status != http.StatusServiceUnavailable || report.Status != StatusDown {
+		t.Fatalf("readiness is %d %s with a failing check, want 503 %s", status, report.Status, StatusDown)
+	}
+
+	want := map[string]CheckResult{
+		"server":   {Status: StatusUp},
+		"kafka":    {Status: StatusUp},
+		"postgres": {Status: StatusDown, Error: "postgres is unreachable"},
+	}
+	if len(report.Checks) != len(want) {
+		t.Errorf("report has checks %v, want %v", report.Checks, want)
+	}
+	for name, result := range want {
+		if report.Checks[name] != result {
+			t.Errorf("check %s is %+v, want %+v", name, report.Checks[name], result)
+		}
+	}
+}
+
+func TestLivenessIgnoresReadiness(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterLiveness(passing("server"))
+	r.RegisterReadiness(failing("postgres"))
+
+	status, report := get(t, r.LivenessHandler())
+	if status != http.StatusOK || report.Status != StatusUp {
+		t.Fatalf("liveness is %d %s with a failing readiness check, want 200 %s", status, report.Status, StatusUp)
+	}
+	if _, ok := report.Checks["postgres"]; ok {
+		t.Errorf("liveness ran the readiness check: %v", report.Checks)
+	}
+
+	// Liveness checks are part of readiness, though.
+	r.RegisterLiveness(failing("scheduler"))
+
+	if status, _ := get(t, r.LivenessHandler()); status != http.StatusServiceUnavailable {
+		t.Errorf("liveness is %d with a failing liveness check, want 503", status)
+	}
+	if _, report := get(t, r.ReadinessHandler()); report.Checks["scheduler"].Status != StatusDown {
+		t.Errorf("readiness reports %+v for the failing liveness check", report.Checks["scheduler"])
+	}
+}
@@ -0,0 +1,95 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/products"
+)
+
+func TestStockAlerts(t *testing.T) {
+	h := newHarness(t, "")
+	base := h.products + "/api/v1/products"
+
+	do(t, http.MethodPost, base+"/", map[string]any{
+		"name": "Keyboard", "description": "A mechanical keyboard", "price": 49.5, "amount": 5, "reorder_threshold": -1,
+	}, http.StatusBadRequest, nil)
+
+	var keyboard, mouse products.Product
+	do(t, http.MethodPost, base+"/", map[string]any{
+		"name": "Keyboard", "description": "A mechanical keyboard", "price": 49.5, "amount": 5, "reorder_threshold": 3,
+	}, http.StatusCreated, &keyboard)
+	do(t, http.MethodPost, base+"/", map[string]any{
+		"name": "Wireless Mouse", "description": "A mouse without a cable", "price": 19.9, "amount": 2,
+	}, http.StatusCreated, &mouse)
+
+	for _, p := range []products.Product{keyboard, mouse} {
+		eventually(t, base+"/"+p.Id.String(), new(products.Product), func(status int, _ *products.Product) bool {
+			return status == http.StatusOK
+		})
+	}
+
+	var low []*products.Product
+	do(t, http.MethodGet, base+"/low-stock", nil, http.StatusOK, &low)
+	if len(low) != 0 {
+		t.Fatalf("listed %+v as low on stock, want none", low)
+	}
+
+	adjustOne := func(id products.ProductId, quantity int) {
+		t.Helper()
+		adjust(t, base, map[string]any{"adjustments": []map[string]any{{"product_id": id, "quantity": quantity}}}, http.StatusOK, nil)
+	}
+
+	// Reaching the threshold raises an alert, going further below it does not.
+	adjustOne(keyboard.Id, -2)
+	adjustOne(keyboard.Id, -1)
+	eventually(t, base+"/low-stock", &low, func(status int, low *[]*products.Product) bool {
+		return status == http.StatusOK && len(*low) == 1 && (*low)[0].Id == keyboard.Id && (*low)[0].Amount == 2
+	})
+
+	adjustOne(keyboard.Id, -2)
+	adjustOne(mouse.Id, -2)
+	eventually(t, base+"/low-stock", &low, func(status int, low *[]*products.Product) bool {
+		return status == http.StatusOK && len(*low) == 2
+	})
+
+	// Restocking clears the alert, and raising the threshold above the stock raises it again.
+	do(t, http.MethodPut, base+"/restock/"+keyboard.Id.String(), map[string]any{"amount": 10}, http.StatusOK, nil)
+	do(t, http.MethodPut, base+"/"+keyboard.Id.String(), map[string]any{"reorder_threshold": 10}, http.StatusOK, nil)
+
+	want := []struct {
+		typ       events.Type
+		productId products.ProductId
+	}{
+		{events.ProductStockLowType, keyboard.Id},
+		{events.ProductOutOfStockType, keyboard.Id},
+		{events.ProductOutOfStockType, mouse.Id},
+		{events.ProductStockLowType, keyboard.Id},
+	}
+
+	var alerts []alertPayload
+	eventually(t, h.alerts.URL, &alerts, func(status int, alerts *[]alertPayload) bool {
+		return status == http.StatusOK && len(*alerts) >= len(want)
+	})
+
+	if len(alerts) != len(want) {
+		t.Fatalf("got %d alerts, want %d: %+v", len(alerts), len(want), alerts)
+	}
+
+	for i, alert := range alerts {
+		var evt events.ProductEvent
+		if err := json.Unmarshal(alert.Event, &evt); err != nil {
+			t.Fatalf("alert %d: %v", i, err)
+		}
+
+		if alert.EventType != want[i].typ || products.ProductId(evt.ProductId) != want[i].productId || alert.Subject == "" {
+			t.Errorf("alert %d was %s of %s (%q), want %s of %s", i, alert.EventType, evt.ProductId, alert.Subject, want[i].typ, want[i].productId)
+		}
+	}
+
+	eventually(t, base+"/low-stock", &low, func(status int, low *[]*products.Product) bool {
+		return status == http.StatusOK && len(*low) == 2 && (*low)[0].Id == mouse.Id && (*low)[1].ReorderThreshold == 10
+	})
+}
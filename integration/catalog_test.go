@@ -0,0 +1,210 @@
+package integration
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/giornetta/microshop/products"
+)
+
+func TestProductImportExport(t *testing.T) {
+	h := newHarness(t, "")
+	base := h.products + "/api/v1/products"
+
+	var keyboard products.Product
+	do(t, http.MethodPost, base+"/", map[string]any{
+		"name":        "Keyboard",
+		"description": "A mechanical keyboard",
+		"price":       49.5,
+		"amount":      3,
+	}, http.StatusCreated, &keyboard)
+	eventually(t, base+"/"+keyboard.Id.String(), new(products.Product), func(status int, _ *products.Product) bool {
+		return status == http.StatusOK
+	})
+
+	catalog := strings.Join([]string{
+		"name,description,price,amount,sku",
+		"Keyboard,A mechanical keyboard,39.5,100,KB-1",
+		"Wireless Mouse,A mouse without a cable,19.9,5,MS-1",
+		"Monitor,A large monitor,not a price,1,MN-1",
+		"Wireless Mouse,The same mouse again,19.9,5,MS-2",
+	}, "\n")
+
+	wantActions := []products.ImportAction{products.ImportUpdated, products.ImportCreated, products.ImportFailed, products.ImportFailed}
+
+	dryRun := importCatalog(t, base, "?dry_run=true", "text/csv", catalog)
+	if !dryRun.DryRun || dryRun.Status != products.ImportCompleted || dryRun.Total != 4 {
+		t.Fatalf("dry run ended as %+v", dryRun)
+	}
+	assertActions(t, dryRun, wantActions)
+
+	var list []*products.Product
+	do(t, http.MethodGet, base+"/", nil, http.StatusOK, &list)
+	if len(list) != 1 || !reflect.DeepEqual(*list[0], keyboard) {
+		t.Fatalf("dry run changed the catalog to %+v", list)
+	}
+
+	job := importCatalog(t, base, "", "text/csv", catalog)
+	assertActions(t, job, wantActions)
+	if job.Rows[0].ProductId != keyboard.Id || job.Rows[1].ProductId == "" {
+		t.Fatalf("import reported rows %+v", job.Rows)
+	}
+
+	eventually(t, base+"/", &list, func(status int, list *[]*products.Product) bool {
+		return status == http.StatusOK && len(*list) == 2 && (*list)[0].SKU != "" && (*list)[1].SKU != ""
+	})
+
+	// Imported amounts only set the stock of created products.
+	want := map[string]products.Product{
+		"KB-1": {Id: keyboard.Id, Name: "Keyboard", Description: "A mechanical keyboard", Price: 39.5, Amount: 3, SKU: "KB-1", Stock: keyboard.Stock},
+		"MS-1": {
+			Id: job.Rows[1].ProductId, Name: "Wireless Mouse", Description: "A mouse without a cable", Price: 19.9, Amount: 5, SKU: "MS-1",
+			Stock: []products.WarehouseStock{{WarehouseId: products.DefaultWarehouse, Amount: 5}},
+		},
+	}
+	for _, p := range list {
+		if !reflect.DeepEqual(*p, want[p.SKU]) {
+			t.Errorf("imported %+v, want %+v", p, want[p.SKU])
+		}
+	}
+
+	renamed := importCatalog(t, base, "?key=sku", "application/x-ndjson", strings.Join([]string{
+		`{"sku":"MS-1","name":"Cordless Mouse","description":"A mouse without a cable","price":19.9}`,
+		`{"sku":"KB-1","name":"Keyboard","description":"A mechanical keyboard","price":39.5}`,
+		`{"sku":"MN-1","name":"Keyboard","description":"A large monitor","price":199}`,
+		`{"name":"Lamp","description":"A desk lamp","price":15}`,
+		`{"name":"Speaker","description":"A loud speaker","price":25,"colour":"red"}`,
+	}, "\n"))
+	assertActions(t, renamed, []products.ImportAction{
+		products.ImportUpdated, products.ImportUnchanged, products.ImportFailed, products.ImportFailed, products.ImportFailed,
+	})
+
+	mouseUrl := base + "/" + job.Rows[1].ProductId.String()
+	eventually(t, mouseUrl, new(products.Product), func(status int, p *products.Product) bool {
+		return status == http.StatusOK && p.Name == "Cordless Mouse"
+	})
+
+	// Renaming a product with the name of another fails the row, in dry runs and real imports alike.
+	colliding := `{"sku":"MS-1","name":"Keyboard","description":"A mouse without a cable","price":19.9}`
+	for _, query := range []string{"?key=sku&dry_run=true", "?key=sku"} {
+		assertActions(t, importCatalog(t, base, query, "application/x-ndjson", colliding), []products.ImportAction{products.ImportFailed})
+	}
+	do(t, http.MethodPut, mouseUrl, map[string]any{"name": "Keyboard"}, http.StatusBadRequest, nil)
+
+	csvExport := export(t, base+"/export", "", "text/csv; charset=utf-8")
+	wantCSV := "product_id,sku,name,description,price,amount\n" +
+		job.Rows[1].ProductId.String() + ",MS-1,Cordless Mouse,A mouse without a cable,19.9,5\n" +
+		keyboard.Id.String() + ",KB-1,Keyboard,A mechanical keyboard,39.5,3\n"
+	if csvExport != wantCSV {
+		t.Errorf("exported CSV\n%s\nwant\n%s", csvExport, wantCSV)
+	}
+
+	ndjsonExport := export(t, base+"/export", "application/x-ndjson", "application/x-ndjson")
+	lines := strings.Split(strings.TrimSpace(ndjsonExport), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("exported NDJSON\n%s\nwant 2 lines", ndjsonExport)
+	}
+
+	var first products.Product
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("exported NDJSON line %q: %v", lines[0], err)
+	}
+	if first.Name != "Cordless Mouse" || first.SKU != "MS-1" || first.Amount != 5 {
+		t.Errorf("exported %+v first", first)
+	}
+
+	// Exports can be imported back, changing nothing.
+	reimported := importCatalog(t, base, "", "text/csv", csvExport)
+	assertActions(t, reimported, []products.ImportAction{products.ImportUnchanged, products.ImportUnchanged})
+
+	do(t, http.MethodGet, base+"/import/missing", nil, http.StatusNotFound, nil)
+	if status := post(t, base+"/import", "text/csv", "name,colour\nKeyboard,red", nil); status != http.StatusBadRequest {
+		t.Errorf("import with an unknown column: got status %d, want %d", status, http.StatusBadRequest)
+	}
+	if status := post(t, base+"/import", "application/pdf", "", nil); status != http.StatusBadRequest {
+		t.Errorf("import of an unsupported format: got status %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+// importCatalog starts importing catalog with the given query, and waits for the import to finish.
+func importCatalog(t *testing.T, base, query, contentType, catalog string) *products.ImportJob {
+	t.Helper()
+
+	url := base + "/import" + query
+
+	var job products.ImportJob
+	if status := post(t, url, contentType, catalog, &job); status != http.StatusAccepted {
+		t.Fatalf("POST %s: got status %d, want %d", url, status, http.StatusAccepted)
+	}
+
+	eventually(t, base+"/import/"+job.Id, &job, func(status int, job *products.ImportJob) bool {
+		return status == http.StatusOK && job.Status != products.ImportRunning
+	})
+
+	return &job
+}
+
+func assertActions(t *testing.T, job *products.ImportJob, want []products.ImportAction) {
+	t.Helper()
+
+	if len(job.Rows) != len(want) {
+		t.Fatalf("import reported %d rows, want %d: %+v", len(job.Rows), len(want), job.Rows)
+	}
+
+	for i, row := range job.Rows {
+		if row.Action != want[i] {
+			t.Errorf("row %d was %s (%s), want %s", row.Row, row.Action, row.Error, want[i])
+		}
+	}
+}
+
+func post(t *testing.T, url, contentType, body string, out any) int {
+	t.Helper()
+
+	res, err := http.Post(url, contentType, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST %s: %v", url, err)
+	}
+	defer res.Body.Close()
+
+	if out != nil && res.StatusCode < 300 {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			t.Fatalf("POST %s: %v", url, err)
+		}
+	}
+
+	return res.StatusCode
+}
+
+func export(t *testing.T, url, accept, wantContentType string) string {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != wantContentType {
+		t.Fatalf("GET %s: got status %d and content type %q", url, res.StatusCode, res.Header.Get("Content-Type"))
+	}
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+
+	return string(b)
+}
@@ -0,0 +1,190 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/giornetta/microshop/customers"
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/products"
+)
+
+// cloudEvent returns the structured CloudEvent of an event with the given type and JSON payload.
+func cloudEvent(eventType events.Type, subject string, data any) map[string]any {
+	return map[string]any{
+		"specversion":     events.CloudEventsSpecVersion,
+		"id":              uuid.NewString(),
+		"source":          "/integration",
+		"type":            eventType,
+		"subject":         subject,
+		"datacontenttype": "application/json",
+		"data":            data,
+	}
+}
+
+// postEvents POSTs the events as a batch with the given bearer token, failing t unless the response has the wanted status.
+func postEvents(t *testing.T, url, token string, ces []map[string]any, want int) {
+	t.Helper()
+
+	body, err := json.Marshal(ces)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", events.CloudEventsBatchContentType)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST %s: %v", url, err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != want {
+		t.Fatalf("POST %s: got status %d, want %d", url, res.StatusCode, want)
+	}
+}
+
+func productCreated(id, name string, price float32) map[string]any {
+	return cloudEvent(events.ProductCreatedType, id, map[string]any{
+		"product_id": id, "name": name, "description": "An ingested product", "price": price, "amount": 2,
+	})
+}
+
+func TestIngestProductEvents(t *testing.T) {
+	h := newHarness(t, "")
+	base := h.products + "/api/v1/products"
+	eventsUrl := h.products + "/api/v1/events"
+
+	var keyboard products.Product
+	do(t, http.MethodPost, base+"/", map[string]any{
+		"name": "Keyboard", "description": "A mechanical keyboard", "price": 49.5, "amount": 3,
+	}, http.StatusCreated, &keyboard)
+	eventually(t, base+"/"+keyboard.Id.String(), new(products.Product), func(status int, _ *products.Product) bool {
+		return status == http.StatusOK
+	})
+
+	mouse := uuid.NewString()
+	batch := []map[string]any{
+		productCreated(mouse, "Mouse", 19.5),
+		cloudEvent(events.ProductUpdatedType, mouse, map[string]any{
+			"product_id": mouse, "name": "Wireless Mouse", "description": "An ingested product", "price": 24.5, "amount": 2,
+		}),
+	}
+
+	postEvents(t, eventsUrl, "", batch, http.StatusUnauthorized)
+	postEvents(t, eventsUrl, "wrong-token", batch, http.StatusUnauthorized)
+
+	// Every event of a batch is validated before any is stored.
+	monitor := uuid.NewString()
+	for _, invalid := range [][]map[string]any{
+		{productCreated(monitor, "Monitor", 99), productCreated(uuid.NewString(), "Keyboard", 10)},
+		{productCreated(monitor, "Monitor", 99), productCreated(uuid.NewString(), "Monitor", 10)},
+		{productCreated(monitor, "Monitor", 0)},
+		{productCreated("not-a-uuid", "Monitor", 99)},
+		{productCreated(keyboard.Id.String(), "Monitor", 99)},
+		{cloudEvent(events.ProductDeletedType, uuid.NewString(), map[string]any{"product_id": monitor})},
+		{cloudEvent(events.ProductStockLowType, keyboard.Id.String(), map[string]any{
+			"product_id": keyboard.Id, "name": "Keyboard", "amount": 1, "reorder_threshold": 2,
+		})},
+	} {
+		postEvents(t, eventsUrl, eventsToken, invalid, http.StatusBadRequest)
+	}
+
+	postEvents(t, eventsUrl, eventsToken, batch, http.StatusAccepted)
+
+	mouseUrl := base + "/" + mouse
+	var p products.Product
+	eventually(t, mouseUrl, &p, func(status int, p *products.Product) bool {
+		return status == http.StatusOK && p.Name == "Wireless Mouse"
+	})
+	if p.Price != 24.5 || p.Amount != 2 {
+		t.Fatalf("projected %+v, want the ingested update", p)
+	}
+
+	// Ingested events are stored with those of the service, which goes on from them.
+	do(t, http.MethodPut, base+"/restock/"+mouse, map[string]any{"amount": 3}, http.StatusOK, nil)
+	eventually(t, mouseUrl, &p, func(status int, p *products.Product) bool {
+		return status == http.StatusOK && p.Amount == 5
+	})
+
+	var history []products.PriceChange
+	eventually(t, mouseUrl+"/prices", &history, func(status int, history *[]products.PriceChange) bool {
+		return status == http.StatusOK && len(*history) == 2
+	})
+
+	do(t, http.MethodGet, base+"/"+monitor, nil, http.StatusNotFound, nil)
+}
+
+func TestIngestCustomerEvents(t *testing.T) {
+	h := newHarness(t, "")
+	base := h.customers + "/api/v1/customers"
+	eventsUrl := h.customers + "/api/v1/events"
+
+	created := func(id, email string) map[string]any {
+		return cloudEvent(events.CustomerCreatedType, id, map[string]any{
+			"customer_id": id, "first_name": "Ada", "last_name": "Lovelace", "email": email,
+		})
+	}
+
+	ada := uuid.NewString()
+	postEvents(t, eventsUrl, "", []map[string]any{created(ada, "ada@example.com")}, http.StatusUnauthorized)
+	postEvents(t, eventsUrl, eventsToken, []map[string]any{
+		created(ada, "ada@example.com"), created(uuid.NewString(), "ada@example.com"),
+	}, http.StatusBadRequest)
+	postEvents(t, eventsUrl, eventsToken, []map[string]any{created(ada, "not-an-email")}, http.StatusBadRequest)
+	postEvents(t, eventsUrl, eventsToken, []map[string]any{created(ada, "ada@example.com")}, http.StatusAccepted)
+
+	var c customers.Customer
+	eventually(t, base+"/"+ada, &c, func(status int, _ *customers.Customer) bool {
+		return status == http.StatusOK
+	})
+	if c.Email != "ada@example.com" {
+		t.Fatalf("projected %+v, want the ingested customer", c)
+	}
+
+	postEvents(t, eventsUrl, eventsToken, []map[string]any{created(uuid.NewString(), "ada@example.com")}, http.StatusBadRequest)
+}
+
+func TestListenerSkipsRejectedEvents(t *testing.T) {
+	h := newHarness(t, "")
+	base := h.products + "/api/v1/products"
+
+	var keyboard products.Product
+	do(t, http.MethodPost, base+"/", map[string]any{
+		"name": "Keyboard", "description": "A mechanical keyboard", "price": 49.5,
+	}, http.StatusCreated, &keyboard)
+	eventually(t, base+"/"+keyboard.Id.String(), new(products.Product), func(status int, _ *products.Product) bool {
+		return status == http.StatusOK
+	})
+
+	// Events published past the services may conflict with the projection, which rejects them
+	// and goes on with the following events.
+	duplicate, mouse := uuid.NewString(), uuid.NewString()
+	for _, evt := range []events.Event{
+		events.ProductCreated{ProductEvent: events.ProductEvent{ProductId: duplicate}, Name: "Keyboard", Description: "A duplicate keyboard", Price: 10},
+		events.ProductCreated{ProductEvent: events.ProductEvent{ProductId: mouse}, Name: "Mouse", Description: "A wireless mouse", Price: 19.5},
+	} {
+		if err := h.publisher.Publish(evt, context.Background()); err != nil {
+			t.Fatalf("could not publish: %v", err)
+		}
+	}
+
+	eventually(t, base+"/"+mouse, new(products.Product), func(status int, _ *products.Product) bool {
+		return status == http.StatusOK
+	})
+
+	// The topic has a single partition, so the duplicate was handled before the mouse.
+	do(t, http.MethodGet, base+"/"+duplicate, nil, http.StatusNotFound, nil)
+}
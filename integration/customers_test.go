@@ -0,0 +1,53 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/giornetta/microshop/customers"
+)
+
+func TestCustomerLifecycle(t *testing.T) {
+	forEachBinding(t, func(t *testing.T, h *harness) {
+		base := h.customers + "/api/v1/customers"
+
+		var created customers.Customer
+		do(t, http.MethodPost, base+"/", map[string]any{
+			"first_name": "Ada",
+			"last_name":  "Lovelace",
+			"email":      "ada@example.com",
+		}, http.StatusCreated, &created)
+
+		customerUrl := base + "/" + created.Id.String()
+
+		var c customers.Customer
+		eventually(t, customerUrl, &c, func(status int, c *customers.Customer) bool {
+			return status == http.StatusOK
+		})
+		if c.Id != created.Id || c.FirstName != "Ada" || c.LastName != "Lovelace" || c.Email != "ada@example.com" {
+			t.Fatalf("projected %+v, want %+v", c, created)
+		}
+
+		do(t, http.MethodPost, base+"/", map[string]any{
+			"first_name": "Augusta",
+			"last_name":  "King",
+			"email":      "ada@example.com",
+		}, http.StatusBadRequest, nil)
+
+		addr := customers.ShippingAddress{
+			Country: "England",
+			City:    "London",
+			ZipCode: "12345",
+			Street:  "St James's Square",
+		}
+		do(t, http.MethodPut, customerUrl+"/shipping", addr, http.StatusOK, nil)
+		eventually(t, customerUrl, &c, func(status int, c *customers.Customer) bool {
+			return status == http.StatusOK && c.ShippingAddress != nil && *c.ShippingAddress == addr
+		})
+
+		do(t, http.MethodDelete, customerUrl, nil, http.StatusOK, nil)
+		eventually(t, customerUrl, &c, func(status int, c *customers.Customer) bool {
+			return status == http.StatusNotFound
+		})
+	})
+}
@@ -0,0 +1,427 @@
+// Package integration runs the products and customers services end to end, over HTTP,
+// against an in-process Kafka cluster, checking that the events they publish reach their projections.
+// The services are wired by the services package and started by bootstrap.Start, as microshop serve does.
+//
+// There is no embedded or dockerised Postgres stand-in. Unless MICROSHOP_TEST_POSTGRES gives the URL of
+// a Postgres server, the services are started without one, keeping their data in the memory repositories,
+// so that the tests run offline with a plain go test. Such runs execute no SQL, and the memory repositories
+// only stand in for the pg ones as far as the productstest, customerstest and eventstoretest contracts check them.
+// With MICROSHOP_TEST_POSTGRES set, every service gets its own database, created with postgrestest and
+// migrated by bootstrap.Start, as the postgres CI job does.
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kfake"
+	"golang.org/x/exp/slog"
+
+	"github.com/giornetta/microshop/bootstrap"
+	"github.com/giornetta/microshop/config"
+	"github.com/giornetta/microshop/customers"
+	customersMemory "github.com/giornetta/microshop/customers/memory"
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/events/encoding"
+	"github.com/giornetta/microshop/eventstore"
+	eventstoreMemory "github.com/giornetta/microshop/eventstore/memory"
+	"github.com/giornetta/microshop/kafka"
+	"github.com/giornetta/microshop/lifecycle"
+	"github.com/giornetta/microshop/postgres/postgrestest"
+	"github.com/giornetta/microshop/products"
+	productsMemory "github.com/giornetta/microshop/products/memory"
+	"github.com/giornetta/microshop/services"
+)
+
+// eventuallyTimeout bounds how long a projection is waited for, including the time the listener
+// takes to join its consumer group.
+const eventuallyTimeout = 15 * time.Second
+
+// eventsToken is the bearer token of the /api/v1/events endpoints.
+const eventsToken = "integration-token"
+
+// bindings are the ways events are published to Kafka, every test running once per binding.
+var bindings = []string{"", string(kafka.BinaryMode), string(kafka.StructuredMode)}
+
+type harness struct {
+	// products and customers are the base URLs of the services.
+	products  string
+	customers string
+	// publisher publishes events to the cluster directly, bypassing the services.
+	publisher events.Publisher
+	// alerts is the webhook the stock alerts of products are posted to. A GET lists the alerts
+	// received so far as alertPayloads.
+	alerts *httptest.Server
+	// scheduler pauses the price scheduler of products, which applies no schedule while stopped,
+	// as it would not while the service restarts.
+	scheduler *pause
+	// relay pauses the relay publishing the events appended to the event store of products, which
+	// publishes nothing while stopped, as it would not while Kafka is unavailable.
+	relay *pause
+}
+
+// pause stops a background component of a service from doing any work until it is started again.
+type pause struct {
+	lock   sync.RWMutex
+	paused bool
+}
+
+// stop pauses the component, waiting for the work it is doing to complete.
+func (p *pause) stop() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.paused = true
+}
+
+func (p *pause) start() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.paused = false
+}
+
+// pausedOutbox drains nothing while its relay is paused.
+type pausedOutbox struct {
+	eventstore.Store
+	pause *pause
+}
+
+func (o *pausedOutbox) Drain(limit int, publish func(evts []events.Event) int, ctx context.Context) (int, error) {
+	o.pause.lock.RLock()
+	defer o.pause.lock.RUnlock()
+
+	if o.pause.paused {
+		return 0, nil
+	}
+
+	return o.Store.Drain(limit, publish, ctx)
+}
+
+// pausedSchedules lists no due schedule while its scheduler is paused.
+type pausedSchedules struct {
+	products.PriceScheduleRepository
+	pause *pause
+}
+
+func (r *pausedSchedules) ListDue(now time.Time, ctx context.Context) ([]*products.PriceSchedule, error) {
+	r.pause.lock.RLock()
+	defer r.pause.lock.RUnlock()
+
+	if r.pause.paused {
+		return nil, nil
+	}
+
+	return r.PriceScheduleRepository.ListDue(now, ctx)
+}
+
+// forEachBinding runs test against a new harness for every binding.
+func forEachBinding(t *testing.T, test func(t *testing.T, h *harness)) {
+	for _, binding := range bindings {
+		binding := binding
+
+		name := binding
+		if name == "" {
+			name = "headers"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			test(t, newHarness(t, binding))
+		})
+	}
+}
+
+// newHarness starts a Kafka cluster, then both services publishing their events with the given binding,
+// through bootstrap.Start. Everything is stopped when t ends.
+func newHarness(t *testing.T, binding string) *harness {
+	t.Helper()
+
+	cluster, err := kfake.NewCluster(
+		kfake.NumBrokers(1),
+		kfake.SeedTopics(1, events.ProductTopic.String(), events.CustomerTopic.String()),
+	)
+	if err != nil {
+		t.Fatalf("could not start kafka cluster: %v", err)
+	}
+	t.Cleanup(cluster.Close)
+
+	alerts := newAlertsWebhook()
+	t.Cleanup(alerts.Close)
+
+	h := &harness{
+		alerts:    alerts,
+		scheduler: &pause{},
+		relay:     &pause{},
+	}
+
+	productsSvc := services.Products(func(d *bootstrap.Deps) *services.ProductStorage {
+		storage := productStorage(d)
+		storage.Events = &pausedOutbox{Store: storage.Events, pause: h.relay}
+		storage.PriceSchedules = &pausedSchedules{PriceScheduleRepository: storage.PriceSchedules, pause: h.scheduler}
+		return storage
+	})
+	customersSvc := services.Customers(customerRepository)
+
+	productsCfg := serviceConfig(t, cluster, binding, "products")
+	productsCfg.Notifications.Sinks = []string{"webhook"}
+	productsCfg.Notifications.Webhook.URL = alerts.URL
+	customersCfg := serviceConfig(t, cluster, binding, "customers")
+
+	if !postgrestest.Available() {
+		productsSvc.Migrations = nil
+		customersSvc.Migrations = nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runner := lifecycle.New(slog.Default(), 5*time.Second)
+	done := make(chan error, 1)
+
+	for _, s := range []struct {
+		svc *bootstrap.Service
+		cfg *config.Config
+	}{{&productsSvc, productsCfg}, {&customersSvc, customersCfg}} {
+		if err := bootstrap.Start(s.svc, s.cfg, slog.Default(), runner, ctx); err != nil {
+			// Stops the services started so far.
+			cancel()
+			runner.Run(ctx)
+			t.Fatalf("could not start %s: %v", s.svc.Name, err)
+		}
+	}
+
+	go func() {
+		done <- runner.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		// Connections the client keeps, some of them never used, would hold up the shutdown of the servers.
+		http.DefaultClient.CloseIdleConnections()
+
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("services failed: %v", err)
+		}
+	})
+
+	h.products = "http://" + net.JoinHostPort("localhost", strconv.Itoa(productsCfg.Server.Port))
+	h.customers = "http://" + net.JoinHostPort("localhost", strconv.Itoa(customersCfg.Server.Port))
+	for _, base := range []string{h.products, h.customers} {
+		eventually(t, base+"/readyz", new(json.RawMessage), func(status int, _ *json.RawMessage) bool {
+			return status == http.StatusOK
+		})
+	}
+
+	// Events published by the tests themselves go through a client of their own, as another service's would.
+	kafkaCfg := productsCfg.Kafka
+	client, err := bootstrap.KafkaClient(&kafkaCfg)
+	if err != nil {
+		t.Fatalf("could not create kafka client: %v", err)
+	}
+	t.Cleanup(client.Close)
+
+	codecs, err := encoding.NewCodecs("", nil)
+	if err != nil {
+		t.Fatalf("could not set up codecs: %v", err)
+	}
+
+	h.publisher, err = bootstrap.Publisher(client, codecs, &kafkaCfg, "/microshop/integration")
+	if err != nil {
+		t.Fatalf("could not set up publisher: %v", err)
+	}
+
+	return h
+}
+
+// serviceConfig returns the configuration of the named service, connecting to the cluster and listening
+// on a free port. Its database is created on the server given through postgrestest.URLEnv, when there is one.
+func serviceConfig(t *testing.T, cluster *kfake.Cluster, binding, name string) *config.Config {
+	cfg := config.Default()
+
+	cfg.Server.Port = freePort(t)
+	cfg.Server.EventsToken = eventsToken
+	cfg.Server.ShutdownTimeout = time.Second
+
+	cfg.Kafka.BrokerAddrs = cluster.ListenAddrs()
+	cfg.Kafka.ConsumerGroup = "integration-" + name
+	cfg.Kafka.Binding = binding
+	cfg.Kafka.FetchMaxWait = 100 * time.Millisecond
+
+	// A small snapshot interval makes the lifecycle tests go through snapshots as well.
+	cfg.EventStore.SnapshotEvery = 2
+	cfg.EventStore.RelayInterval = 10 * time.Millisecond
+	// A small interval lets the price tests wait for the scheduler without slowing down.
+	cfg.Pricing.ScheduleInterval = 50 * time.Millisecond
+
+	if postgrestest.Available() {
+		cfg.Postgres = postgresConfig(t, postgrestest.URL(t))
+	}
+
+	return cfg
+}
+
+// postgresConfig returns the configuration connecting to the database at dbUrl.
+func postgresConfig(t *testing.T, dbUrl string) config.PostgresConfig {
+	u, err := url.Parse(dbUrl)
+	if err != nil {
+		t.Fatalf("invalid postgres url: %v", err)
+	}
+
+	cfg := config.Default().Postgres
+	cfg.Host = u.Hostname()
+	if port := u.Port(); port != "" {
+		if cfg.Port, err = strconv.Atoi(port); err != nil {
+			t.Fatalf("invalid postgres port: %v", err)
+		}
+	}
+	cfg.Username = u.User.Username()
+	password, _ := u.User.Password()
+	cfg.Password = config.Secret(password)
+	cfg.Database = strings.TrimPrefix(u.Path, "/")
+	cfg.SSL = u.Query().Get("sslmode") == "require"
+
+	return cfg
+}
+
+// freePort returns a port no server is listening on.
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// productStorage returns the storage of the products service, in memory when it has no database.
+func productStorage(d *bootstrap.Deps) *services.ProductStorage {
+	if d.Pool != nil {
+		return services.PgProductStorage(d)
+	}
+
+	return &services.ProductStorage{
+		Products:       productsMemory.NewProductRepository(),
+		Warehouses:     productsMemory.NewWarehouseRepository(),
+		PriceHistory:   productsMemory.NewPriceHistoryRepository(),
+		PriceSchedules: productsMemory.NewPriceScheduleRepository(),
+		Events:         eventstoreMemory.NewEventStore(),
+	}
+}
+
+// customerRepository returns the repository of the customers service, in memory when it has no database.
+func customerRepository(d *bootstrap.Deps) customers.CustomerRepository {
+	if d.Pool != nil {
+		return services.PgCustomerRepository(d)
+	}
+
+	return customersMemory.NewCustomerRepository()
+}
+
+// alertPayload is a notify.WebhookPayload, keeping its event undecoded.
+type alertPayload struct {
+	Subject   string          `json:"subject"`
+	Body      string          `json:"body"`
+	EventType events.Type     `json:"event_type"`
+	Event     json.RawMessage `json:"event"`
+}
+
+func newAlertsWebhook() *httptest.Server {
+	var (
+		lock     sync.Mutex
+		payloads = []alertPayload{}
+	)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if r.Method == http.MethodGet {
+			json.NewEncoder(w).Encode(payloads)
+			return
+		}
+
+		var p alertPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		payloads = append(payloads, p)
+	}))
+}
+
+// do sends a request with body encoded as JSON, failing t unless the response has the wanted status.
+// The response body is decoded into out, when given.
+func do(t *testing.T, method, url string, body any, want int, out any) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	status, err := request(method, url, &buf, out)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	if status != want {
+		t.Fatalf("%s %s: got status %d, want %d", method, url, status, want)
+	}
+}
+
+func request(method, url string, body *bytes.Buffer, out any) (int, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if out != nil && res.StatusCode < 300 {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			return res.StatusCode, err
+		}
+	}
+
+	return res.StatusCode, nil
+}
+
+// eventually polls url until cond accepts its status and decoded body, failing t after eventuallyTimeout.
+// out is decoded again on every attempt, so it holds the accepted body on return.
+func eventually[T any](t *testing.T, url string, out *T, cond func(status int, v *T) bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(eventuallyTimeout)
+
+	var (
+		status int
+		err    error
+	)
+	for time.Now().Before(deadline) {
+		*out = *new(T)
+
+		status, err = request(http.MethodGet, url, &bytes.Buffer{}, out)
+		if err == nil && cond(status, out) {
+			return
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	t.Fatalf("GET %s: condition not met after %v, last status %d, error %v, body %+v", url, eventuallyTimeout, status, err, *out)
+}
@@ -0,0 +1,213 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giornetta/microshop/products"
+)
+
+func TestPriceHistoryAndSchedules(t *testing.T) {
+	h := newHarness(t, "")
+	base := h.products + "/api/v1/products"
+
+	var keyboard products.Product
+	do(t, http.MethodPost, base+"/", map[string]any{
+		"name": "Keyboard", "description": "A mechanical keyboard", "price": 49.5, "amount": 3,
+	}, http.StatusCreated, &keyboard)
+
+	productUrl := base + "/" + keyboard.Id.String()
+	pricesUrl := productUrl + "/prices"
+	schedulesUrl := productUrl + "/price-schedules"
+
+	var history []products.PriceChange
+	eventually(t, pricesUrl, &history, func(status int, history *[]products.PriceChange) bool {
+		return status == http.StatusOK && len(*history) == 1
+	})
+	if history[0].Price != 49.5 || history[0].PreviousPrice != 0 || history[0].ScheduleId != "" {
+		t.Fatalf("creation recorded %+v, want a price of 49.5", history[0])
+	}
+
+	do(t, http.MethodPut, productUrl, map[string]any{"price": 39.5}, http.StatusOK, nil)
+	do(t, http.MethodPut, productUrl, map[string]any{"name": "Mechanical Keyboard"}, http.StatusOK, nil)
+	eventually(t, productUrl, new(products.Product), func(status int, p *products.Product) bool {
+		return status == http.StatusOK && p.Name == "Mechanical Keyboard"
+	})
+	eventually(t, pricesUrl, &history, func(status int, history *[]products.PriceChange) bool {
+		return status == http.StatusOK && len(*history) == 2
+	})
+	if history[1].Price != 39.5 || history[1].PreviousPrice != 49.5 {
+		t.Fatalf("update recorded %+v, want a change from 49.5 to 39.5", history[1])
+	}
+
+	now := time.Now()
+	do(t, http.MethodPost, schedulesUrl, map[string]any{
+		"price": 29.9, "effective_from": now.Add(time.Hour), "effective_until": now.Add(time.Minute),
+	}, http.StatusBadRequest, nil)
+	do(t, http.MethodPost, base+"/"+uuid.NewString()+"/price-schedules", map[string]any{
+		"price": 29.9, "effective_from": now,
+	}, http.StatusNotFound, nil)
+	do(t, http.MethodGet, base+"/"+uuid.NewString()+"/prices", nil, http.StatusNotFound, nil)
+
+	// A sale taking effect right away, and ending shortly after.
+	var sale products.PriceSchedule
+	do(t, http.MethodPost, schedulesUrl, map[string]any{
+		"price": 29.9, "effective_from": now, "effective_until": now.Add(time.Second),
+	}, http.StatusCreated, &sale)
+	if sale.Status != products.SchedulePending {
+		t.Fatalf("scheduled %+v, want a pending schedule", sale)
+	}
+
+	do(t, http.MethodPost, schedulesUrl, map[string]any{
+		"price": 19.9, "effective_from": now.Add(500 * time.Millisecond),
+	}, http.StatusConflict, nil)
+
+	eventually(t, productUrl, new(products.Product), func(status int, p *products.Product) bool {
+		return status == http.StatusOK && p.Price == 29.9
+	})
+	eventually(t, productUrl, new(products.Product), func(status int, p *products.Product) bool {
+		return status == http.StatusOK && p.Price == 39.5
+	})
+
+	var schedules []products.PriceSchedule
+	eventually(t, schedulesUrl, &schedules, func(status int, schedules *[]products.PriceSchedule) bool {
+		return status == http.StatusOK && len(*schedules) == 1 && (*schedules)[0].Status == products.ScheduleCompleted
+	})
+	if previous := schedules[0].PreviousPrice; previous == nil || *previous != 39.5 {
+		t.Errorf("completed schedule %+v, want a previous price of 39.5", schedules[0])
+	}
+
+	eventually(t, pricesUrl, &history, func(status int, history *[]products.PriceChange) bool {
+		return status == http.StatusOK && len(*history) == 4
+	})
+	for i, want := range []float32{29.9, 39.5} {
+		if c := history[2+i]; c.Price != want || c.ScheduleId != sale.Id {
+			t.Errorf("schedule recorded %+v, want a price of %v set by %s", c, want, sale.Id)
+		}
+	}
+
+	// Schedules falling due while the scheduler is stopped are applied once it starts again.
+	h.scheduler.stop()
+
+	var increase products.PriceSchedule
+	do(t, http.MethodPost, schedulesUrl, map[string]any{
+		"price": 44.5, "effective_from": time.Now(),
+	}, http.StatusCreated, &increase)
+
+	time.Sleep(200 * time.Millisecond)
+	do(t, http.MethodGet, schedulesUrl, nil, http.StatusOK, &schedules)
+	if len(schedules) != 2 || schedules[1].Status != products.SchedulePending {
+		t.Fatalf("listed %+v while the scheduler was stopped, want %s pending", schedules, increase.Id)
+	}
+
+	h.scheduler.start()
+	eventually(t, productUrl, new(products.Product), func(status int, p *products.Product) bool {
+		return status == http.StatusOK && p.Price == 44.5
+	})
+	eventually(t, schedulesUrl, &schedules, func(status int, schedules *[]products.PriceSchedule) bool {
+		return status == http.StatusOK && len(*schedules) == 2 && (*schedules)[1].Status == products.ScheduleCompleted
+	})
+
+	var later products.PriceSchedule
+	do(t, http.MethodPost, schedulesUrl, map[string]any{
+		"price": 24.9, "effective_from": time.Now().Add(time.Hour), "effective_until": time.Now().Add(2 * time.Hour),
+	}, http.StatusCreated, &later)
+
+	var canceled products.PriceSchedule
+	do(t, http.MethodDelete, base+"/"+uuid.NewString()+"/price-schedules/"+later.Id.String(), nil, http.StatusNotFound, nil)
+	do(t, http.MethodDelete, schedulesUrl+"/"+later.Id.String(), nil, http.StatusOK, &canceled)
+	if canceled.Status != products.ScheduleCanceled {
+		t.Errorf("canceled %+v, want a canceled schedule", canceled)
+	}
+	do(t, http.MethodDelete, schedulesUrl+"/"+later.Id.String(), nil, http.StatusConflict, nil)
+	do(t, http.MethodDelete, schedulesUrl+"/"+increase.Id.String(), nil, http.StatusConflict, nil)
+}
+
+func TestCancelActivePriceSchedule(t *testing.T) {
+	h := newHarness(t, "")
+	base := h.products + "/api/v1/products"
+
+	var keyboard products.Product
+	do(t, http.MethodPost, base+"/", map[string]any{
+		"name": "Keyboard", "description": "A mechanical keyboard", "price": 49.5,
+	}, http.StatusCreated, &keyboard)
+
+	productUrl := base + "/" + keyboard.Id.String()
+	eventually(t, productUrl, new(products.Product), func(status int, _ *products.Product) bool {
+		return status == http.StatusOK
+	})
+
+	var sale products.PriceSchedule
+	do(t, http.MethodPost, productUrl+"/price-schedules", map[string]any{
+		"price": 29.9, "effective_from": time.Now(), "effective_until": time.Now().Add(time.Hour),
+	}, http.StatusCreated, &sale)
+
+	scheduleUrl := productUrl + "/price-schedules/" + sale.Id.String()
+	eventually(t, productUrl, new(products.Product), func(status int, p *products.Product) bool {
+		return status == http.StatusOK && p.Price == 29.9
+	})
+
+	// Canceling an active schedule ends it, restoring the previous price.
+	var canceled products.PriceSchedule
+	do(t, http.MethodDelete, scheduleUrl, nil, http.StatusOK, &canceled)
+	if canceled.Status != products.ScheduleActive || canceled.EffectiveUntil == nil || canceled.EffectiveUntil.After(time.Now()) {
+		t.Fatalf("canceled %+v, want an active schedule expiring now", canceled)
+	}
+
+	eventually(t, productUrl, new(products.Product), func(status int, p *products.Product) bool {
+		return status == http.StatusOK && p.Price == 49.5
+	})
+}
+
+func TestPriceSchedulesOutrunProjection(t *testing.T) {
+	h := newHarness(t, "")
+	base := h.products + "/api/v1/products"
+
+	var keyboard products.Product
+	do(t, http.MethodPost, base+"/", map[string]any{
+		"name": "Keyboard", "description": "A mechanical keyboard", "price": 49.5,
+	}, http.StatusCreated, &keyboard)
+
+	productUrl := base + "/" + keyboard.Id.String()
+	schedulesUrl := productUrl + "/price-schedules"
+	eventually(t, productUrl, new(products.Product), func(status int, _ *products.Product) bool {
+		return status == http.StatusOK
+	})
+
+	// With the relay stopped, the projection still has the price the product had before the update,
+	// which the scheduler must neither restore nor mistake for a change made during the sale.
+	h.relay.stop()
+	do(t, http.MethodPut, productUrl, map[string]any{"price": 39.5}, http.StatusOK, nil)
+
+	now := time.Now()
+	do(t, http.MethodPost, schedulesUrl, map[string]any{
+		"price": 29.9, "effective_from": now, "effective_until": now.Add(300 * time.Millisecond),
+	}, http.StatusCreated, nil)
+
+	var schedules []products.PriceSchedule
+	eventually(t, schedulesUrl, &schedules, func(status int, schedules *[]products.PriceSchedule) bool {
+		return status == http.StatusOK && len(*schedules) == 1 && (*schedules)[0].Status == products.ScheduleCompleted
+	})
+	if previous := schedules[0].PreviousPrice; previous == nil || *previous != 39.5 {
+		t.Errorf("completed schedule %+v, want a previous price of 39.5", schedules[0])
+	}
+
+	h.relay.start()
+	var history []products.PriceChange
+	eventually(t, productUrl+"/prices", &history, func(status int, history *[]products.PriceChange) bool {
+		return status == http.StatusOK && len(*history) == 4
+	})
+	for i, want := range []float32{49.5, 39.5, 29.9, 39.5} {
+		if history[i].Price != want {
+			t.Errorf("recorded %+v at %d, want a price of %v", history[i], i, want)
+		}
+	}
+
+	do(t, http.MethodGet, productUrl, nil, http.StatusOK, &keyboard)
+	if keyboard.Price != 39.5 {
+		t.Errorf("price is %v after the sale, want 39.5", keyboard.Price)
+	}
+}
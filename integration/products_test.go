@@ -0,0 +1,109 @@
+package integration
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giornetta/microshop/products"
+)
+
+func TestProductLifecycle(t *testing.T) {
+	forEachBinding(t, func(t *testing.T, h *harness) {
+		base := h.products + "/api/v1/products"
+
+		var created products.Product
+		do(t, http.MethodPost, base+"/", map[string]any{
+			"name":        "Keyboard",
+			"description": "A mechanical keyboard",
+			"price":       49.5,
+			"amount":      3,
+		}, http.StatusCreated, &created)
+
+		productUrl := base + "/" + created.Id.String()
+
+		var p products.Product
+		eventually(t, productUrl, &p, func(status int, p *products.Product) bool {
+			return status == http.StatusOK
+		})
+		if !reflect.DeepEqual(p, created) {
+			t.Fatalf("projected %+v, want %+v", p, created)
+		}
+
+		var list []*products.Product
+		do(t, http.MethodGet, base+"/", nil, http.StatusOK, &list)
+		if len(list) != 1 || !reflect.DeepEqual(*list[0], created) {
+			t.Fatalf("listed %+v, want only %+v", list, created)
+		}
+
+		do(t, http.MethodPost, base+"/", map[string]any{
+			"name":        "Keyboard",
+			"description": "Another mechanical keyboard",
+			"price":       10,
+		}, http.StatusBadRequest, nil)
+
+		do(t, http.MethodPut, productUrl, map[string]any{"price": 39.5}, http.StatusOK, nil)
+		eventually(t, productUrl, &p, func(status int, p *products.Product) bool {
+			return status == http.StatusOK && p.Price == 39.5
+		})
+
+		do(t, http.MethodPut, base+"/restock/"+created.Id.String(), map[string]any{"amount": 7}, http.StatusOK, nil)
+		eventually(t, productUrl, &p, func(status int, p *products.Product) bool {
+			return status == http.StatusOK && p.Amount == 10
+		})
+		if p.Name != created.Name || p.Description != created.Description || p.Price != 39.5 {
+			t.Fatalf("restock changed the product to %+v", p)
+		}
+
+		do(t, http.MethodDelete, productUrl, nil, http.StatusOK, nil)
+		eventually(t, productUrl, &p, func(status int, p *products.Product) bool {
+			return status == http.StatusNotFound
+		})
+
+		do(t, http.MethodDelete, productUrl, nil, http.StatusNotFound, nil)
+	})
+}
+
+func TestProductNotFound(t *testing.T) {
+	h := newHarness(t, "")
+	base := h.products + "/api/v1/products/"
+	id := uuid.NewString()
+
+	do(t, http.MethodGet, base+id, nil, http.StatusNotFound, nil)
+	do(t, http.MethodPut, base+id, map[string]any{"price": 10}, http.StatusNotFound, nil)
+	do(t, http.MethodPut, base+"restock/"+id, map[string]any{"amount": 1}, http.StatusNotFound, nil)
+}
+
+func TestEventsOutlivePublishingOutage(t *testing.T) {
+	h := newHarness(t, "")
+	base := h.products + "/api/v1/products"
+
+	var created products.Product
+	do(t, http.MethodPost, base+"/", map[string]any{
+		"name": "Keyboard", "description": "A mechanical keyboard", "price": 49.5, "amount": 3,
+	}, http.StatusCreated, &created)
+
+	productUrl := base + "/" + created.Id.String()
+	eventually(t, productUrl, new(products.Product), func(status int, _ *products.Product) bool {
+		return status == http.StatusOK
+	})
+
+	// Commands succeed once their events are stored, and are not applied twice when they are published later.
+	h.relay.stop()
+	do(t, http.MethodPut, base+"/restock/"+created.Id.String(), map[string]any{"amount": 2}, http.StatusOK, nil)
+
+	time.Sleep(200 * time.Millisecond)
+	var p products.Product
+	do(t, http.MethodGet, productUrl, nil, http.StatusOK, &p)
+	if p.Amount != 3 {
+		t.Fatalf("projected an amount of %d while nothing was published, want 3", p.Amount)
+	}
+
+	h.relay.start()
+	eventually(t, productUrl, &p, func(status int, p *products.Product) bool {
+		return status == http.StatusOK && p.Amount == 5
+	})
+}
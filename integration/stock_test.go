@@ -0,0 +1,121 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/giornetta/microshop/products"
+)
+
+type adjustStockResponse struct {
+	Mode    products.AdjustmentMode          `json:"mode"`
+	Applied int                              `json:"applied"`
+	Results []products.StockAdjustmentResult `json:"results"`
+}
+
+func TestStockAdjustments(t *testing.T) {
+	h := newHarness(t, "")
+	base := h.products + "/api/v1/products"
+
+	var keyboard, mouse products.Product
+	do(t, http.MethodPost, base+"/", map[string]any{
+		"name": "Keyboard", "description": "A mechanical keyboard", "price": 49.5, "amount": 3,
+	}, http.StatusCreated, &keyboard)
+	do(t, http.MethodPost, base+"/", map[string]any{
+		"name": "Wireless Mouse", "description": "A mouse without a cable", "price": 19.9,
+	}, http.StatusCreated, &mouse)
+
+	for _, p := range []products.Product{keyboard, mouse} {
+		eventually(t, base+"/"+p.Id.String(), new(products.Product), func(status int, _ *products.Product) bool {
+			return status == http.StatusOK
+		})
+	}
+
+	missing := uuid.NewString()
+	invalid := []map[string]any{
+		{"product_id": keyboard.Id, "quantity": 5},
+		{"product_id": mouse.Id, "quantity": -1},
+		{"product_id": missing, "quantity": 1},
+		{"product_id": keyboard.Id, "quantity": 0},
+	}
+
+	var res adjustStockResponse
+	adjust(t, base, map[string]any{"adjustments": invalid}, http.StatusUnprocessableEntity, &res)
+	assertStatuses(t, res, products.AllOrNothing, products.AdjustmentAborted, products.AdjustmentRejected, products.AdjustmentRejected, products.AdjustmentRejected)
+
+	adjust(t, base, map[string]any{"mode": "best_effort", "adjustments": invalid}, http.StatusMultiStatus, &res)
+	assertStatuses(t, res, products.BestEffort, products.AdjustmentApplied, products.AdjustmentRejected, products.AdjustmentRejected, products.AdjustmentRejected)
+	if res.Results[0].Amount != 8 {
+		t.Errorf("best effort adjustment left %d items, want 8", res.Results[0].Amount)
+	}
+
+	adjust(t, base, map[string]any{"mode": "all_or_nothing", "adjustments": []map[string]any{
+		{"product_id": keyboard.Id, "quantity": -2},
+		{"product_id": mouse.Id, "quantity": 4},
+		{"product_id": keyboard.Id, "quantity": -1},
+	}}, http.StatusOK, &res)
+	assertStatuses(t, res, products.AllOrNothing, products.AdjustmentApplied, products.AdjustmentApplied, products.AdjustmentApplied)
+	if res.Results[0].Amount != 6 || res.Results[2].Amount != 5 {
+		t.Errorf("adjustments of the same product left %d and %d items, want 6 and 5", res.Results[0].Amount, res.Results[2].Amount)
+	}
+
+	for id, want := range map[products.ProductId]int{keyboard.Id: 5, mouse.Id: 4} {
+		eventually(t, base+"/"+id.String(), new(products.Product), func(status int, p *products.Product) bool {
+			return status == http.StatusOK && p.Amount == want
+		})
+	}
+
+	adjust(t, base, map[string]any{"adjustments": []map[string]any{}}, http.StatusBadRequest, nil)
+	adjust(t, base, map[string]any{"mode": "most", "adjustments": invalid}, http.StatusBadRequest, nil)
+}
+
+// adjust posts a batch of adjustments, decoding the results into out whatever the status of the response,
+// as they are sent with every status but 400.
+func adjust(t *testing.T, base string, body any, want int, out *adjustStockResponse) {
+	t.Helper()
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url := base + "/stock-adjustments"
+	res, err := http.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("POST %s: %v", url, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != want {
+		t.Fatalf("POST %s: got status %d, want %d", url, res.StatusCode, want)
+	}
+
+	if out != nil {
+		*out = adjustStockResponse{}
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			t.Fatalf("POST %s: %v", url, err)
+		}
+	}
+}
+
+func assertStatuses(t *testing.T, res adjustStockResponse, mode products.AdjustmentMode, want ...products.AdjustmentStatus) {
+	t.Helper()
+
+	if res.Mode != mode {
+		t.Errorf("got mode %s, want %s", res.Mode, mode)
+	}
+
+	if len(res.Results) != len(want) {
+		t.Fatalf("got %d results, want %d: %+v", len(res.Results), len(want), res.Results)
+	}
+
+	for i, r := range res.Results {
+		if r.Status != want[i] {
+			t.Errorf("adjustment %d of %s by %d was %s (%s), want %s", i, r.ProductId, r.Quantity, r.Status, r.Error, want[i])
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package integration
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/giornetta/microshop/products"
+)
+
+func TestWarehouses(t *testing.T) {
+	h := newHarness(t, "")
+	base := h.products + "/api/v1/products"
+	warehouses := h.products + "/api/v1/warehouses"
+
+	var milan products.Warehouse
+	do(t, http.MethodPost, warehouses+"/", map[string]any{
+		"warehouse_id": "milan-1", "name": "Milan", "location": "Via Roma 1, Milan",
+	}, http.StatusCreated, &milan)
+	do(t, http.MethodPost, warehouses+"/", map[string]any{
+		"warehouse_id": "milan-1", "name": "Another Milan",
+	}, http.StatusBadRequest, nil)
+	do(t, http.MethodPost, warehouses+"/", map[string]any{
+		"warehouse_id": "Milan 2", "name": "Milan",
+	}, http.StatusBadRequest, nil)
+	do(t, http.MethodGet, warehouses+"/turin-1", nil, http.StatusNotFound, nil)
+
+	var list []products.Warehouse
+	do(t, http.MethodGet, warehouses+"/", nil, http.StatusOK, &list)
+	if len(list) != 2 || list[0].Id != products.DefaultWarehouse || list[1] != milan {
+		t.Fatalf("listed %+v, want the default warehouse and %+v", list, milan)
+	}
+
+	var keyboard products.Product
+	do(t, http.MethodPost, base+"/", map[string]any{
+		"name": "Keyboard", "description": "A mechanical keyboard", "price": 49.5, "amount": 3,
+	}, http.StatusCreated, &keyboard)
+
+	productUrl := base + "/" + keyboard.Id.String()
+	eventually(t, productUrl, new(products.Product), func(status int, _ *products.Product) bool {
+		return status == http.StatusOK
+	})
+
+	do(t, http.MethodPut, base+"/restock/"+keyboard.Id.String(), map[string]any{
+		"amount": 4, "warehouse_id": "milan-1",
+	}, http.StatusOK, nil)
+	do(t, http.MethodPut, base+"/restock/"+keyboard.Id.String(), map[string]any{
+		"amount": 4, "warehouse_id": "turin-1",
+	}, http.StatusNotFound, nil)
+
+	transfers := productUrl + "/transfers"
+	do(t, http.MethodPost, transfers, map[string]any{
+		"from_warehouse_id": "default", "to_warehouse_id": "milan-1", "quantity": 4,
+	}, http.StatusConflict, nil)
+	do(t, http.MethodPost, transfers, map[string]any{
+		"from_warehouse_id": "default", "to_warehouse_id": "turin-1", "quantity": 1,
+	}, http.StatusNotFound, nil)
+	do(t, http.MethodPost, transfers, map[string]any{
+		"from_warehouse_id": "milan-1", "to_warehouse_id": "milan-1", "quantity": 1,
+	}, http.StatusBadRequest, nil)
+	do(t, http.MethodPost, transfers, map[string]any{
+		"from_warehouse_id": "default", "to_warehouse_id": "milan-1", "quantity": 2,
+	}, http.StatusOK, nil)
+
+	var res adjustStockResponse
+	adjust(t, base, map[string]any{"adjustments": []map[string]any{
+		{"product_id": keyboard.Id, "warehouse_id": "milan-1", "quantity": -1},
+		{"product_id": keyboard.Id, "quantity": -1},
+	}}, http.StatusOK, &res)
+	assertStatuses(t, res, products.AllOrNothing, products.AdjustmentApplied, products.AdjustmentApplied)
+	if res.Results[0].WarehouseId != milan.Id || res.Results[0].Amount != 5 || res.Results[1].Amount != 0 {
+		t.Errorf("adjustments left %+v, want 5 items in %s and none in the default warehouse", res.Results, milan.Id)
+	}
+
+	adjust(t, base, map[string]any{"adjustments": []map[string]any{
+		{"product_id": keyboard.Id, "warehouse_id": "turin-1", "quantity": 1},
+	}}, http.StatusUnprocessableEntity, &res)
+	assertStatuses(t, res, products.AllOrNothing, products.AdjustmentRejected)
+
+	want := []products.WarehouseStock{{WarehouseId: milan.Id, Amount: 5}}
+	eventually(t, productUrl, new(products.Product), func(status int, p *products.Product) bool {
+		return status == http.StatusOK && p.Amount == 5 && reflect.DeepEqual(p.Stock, want)
+	})
+}
@@ -0,0 +1,174 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/giornetta/microshop/events"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// BindingMode selects how CloudEvents are mapped to Kafka records.
+type BindingMode string
+
+const (
+	// BinaryMode stores the CloudEvents attributes in ce_ prefixed headers, and the payload as the record value.
+	BinaryMode BindingMode = "binary"
+	// StructuredMode stores the whole CloudEvent, encoded as JSON, as the record value.
+	StructuredMode BindingMode = "structured"
+)
+
+func ParseBindingMode(s string) (BindingMode, error) {
+	switch mode := BindingMode(s); mode {
+	case BinaryMode, StructuredMode:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown cloudevents binding mode %q", s)
+	}
+}
+
+const (
+	cePrefix = "ce_"
+
+	ceSpecVersionHeader = cePrefix + "specversion"
+	ceIdHeader          = cePrefix + "id"
+	ceSourceHeader      = cePrefix + "source"
+	ceTypeHeader        = cePrefix + "type"
+	ceSubjectHeader     = cePrefix + "subject"
+	ceTimeHeader        = cePrefix + "time"
+	ceVersionHeader     = cePrefix + events.VersionExtension
+)
+
+type cloudEventPublisher struct {
+	client *kgo.Client
+	codecs *events.Codecs
+	source string
+	mode   BindingMode
+}
+
+// NewCloudEventPublisher returns a Publisher producing events to Kafka as CloudEvents,
+// using the given source attribute and protocol binding mode.
+func NewCloudEventPublisher(client *kgo.Client, codecs *events.Codecs, source string, mode BindingMode) events.Publisher {
+	return &cloudEventPublisher{
+		client: client,
+		codecs: codecs,
+		source: source,
+		mode:   mode,
+	}
+}
+
+func (p *cloudEventPublisher) Publish(e events.Event, ctx context.Context) error {
+	record, err := p.record(e, ctx)
+	if err != nil {
+		return err
+	}
+
+	return produce(p.client, []*kgo.Record{record}, ctx)[0]
+}
+
+func (p *cloudEventPublisher) PublishBatch(evts []events.Event, ctx context.Context) []error {
+	return produceBatch(p.client, evts, func(e events.Event) (*kgo.Record, error) {
+		return p.record(e, ctx)
+	}, ctx)
+}
+
+func (p *cloudEventPublisher) record(e events.Event, ctx context.Context) (*kgo.Record, error) {
+	ce, err := events.NewCloudEvent(e, p.source, p.codecs.ForTopic(e.Topic()), ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &kgo.Record{
+		Key:       []byte(e.Key()),
+		Timestamp: ce.Time,
+		Topic:     e.Topic().String(),
+	}
+
+	switch p.mode {
+	case StructuredMode:
+		if record.Value, err = json.Marshal(ce); err != nil {
+			return nil, err
+		}
+
+		record.Headers = []kgo.RecordHeader{
+			{Key: contentTypeHeader, Value: []byte(events.CloudEventsJSONContentType)},
+		}
+	default:
+		record.Value = ce.Data
+		record.Headers = binaryHeaders(ce)
+	}
+
+	return record, nil
+}
+
+func binaryHeaders(ce *events.CloudEvent) []kgo.RecordHeader {
+	headers := []kgo.RecordHeader{
+		{Key: ceSpecVersionHeader, Value: []byte(events.CloudEventsSpecVersion)},
+		{Key: ceIdHeader, Value: []byte(ce.ID)},
+		{Key: ceSourceHeader, Value: []byte(ce.Source)},
+		{Key: ceTypeHeader, Value: []byte(ce.Type)},
+		{Key: ceTimeHeader, Value: []byte(ce.Time.Format(time.RFC3339Nano))},
+		{Key: ceVersionHeader, Value: []byte(strconv.Itoa(ce.Version))},
+		{Key: contentTypeHeader, Value: []byte(ce.DataContentType)},
+	}
+
+	if ce.Subject != "" {
+		headers = append(headers, kgo.RecordHeader{Key: ceSubjectHeader, Value: []byte(ce.Subject)})
+	}
+
+	return headers
+}
+
+// cloudEventOf returns the CloudEvent contained in the record, if it was produced with one of the CloudEvents bindings.
+func cloudEventOf(record *kgo.Record) (*events.CloudEvent, bool, error) {
+	contentType, _ := header(record, contentTypeHeader)
+
+	if strings.HasPrefix(contentType, events.CloudEventsJSONContentType) {
+		var ce events.CloudEvent
+		if err := json.Unmarshal(record.Value, &ce); err != nil {
+			return nil, true, err
+		}
+
+		return &ce, true, ce.Validate()
+	}
+
+	if _, ok := header(record, ceSpecVersionHeader); !ok {
+		return nil, false, nil
+	}
+
+	ce := &events.CloudEvent{
+		DataContentType: contentType,
+		Data:            record.Value,
+	}
+
+	for _, h := range record.Headers {
+		switch h.Key {
+		case ceIdHeader:
+			ce.ID = string(h.Value)
+		case ceSourceHeader:
+			ce.Source = string(h.Value)
+		case ceTypeHeader:
+			ce.Type = events.Type(h.Value)
+		case ceSubjectHeader:
+			ce.Subject = string(h.Value)
+		case ceTimeHeader:
+			t, err := time.Parse(time.RFC3339Nano, string(h.Value))
+			if err != nil {
+				return nil, true, fmt.Errorf("invalid %s header: %w", ceTimeHeader, err)
+			}
+			ce.Time = t
+		case ceVersionHeader:
+			version, err := strconv.Atoi(string(h.Value))
+			if err != nil {
+				return nil, true, fmt.Errorf("invalid %s header: %w", ceVersionHeader, err)
+			}
+			ce.Version = version
+		}
+	}
+
+	return ce, true, ce.Validate()
+}
@@ -0,0 +1,45 @@
+package kafka
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+const (
+	eventTypeHeader    = "EventType"
+	eventVersionHeader = "EventVersion"
+	contentTypeHeader  = "content-type"
+)
+
+// header returns the value of the first header of the record with the given key.
+func header(record *kgo.Record, key string) (string, bool) {
+	for _, h := range record.Headers {
+		if h.Key == key {
+			return string(h.Value), true
+		}
+	}
+
+	return "", false
+}
+
+// eventVersion returns the version of the event contained in the record,
+// or 0 if the record was published without one.
+// A version header that is not a non-negative integer is an error, as the payload could not be decoded reliably.
+func eventVersion(record *kgo.Record) (int, error) {
+	v, ok := header(record, eventVersionHeader)
+	if !ok {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s header: %w", eventVersionHeader, err)
+	}
+	if version < 0 {
+		return 0, fmt.Errorf("invalid %s header: negative version %d", eventVersionHeader, version)
+	}
+
+	return version, nil
+}
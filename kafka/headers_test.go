@@ -0,0 +1,56 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/giornetta/microshop/events"
+)
+
+func TestEventVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []kgo.RecordHeader
+		want    int
+		wantErr bool
+	}{
+		{name: "missing", want: 0},
+		{name: "valid", headers: []kgo.RecordHeader{{Key: eventVersionHeader, Value: []byte("2")}}, want: 2},
+		{name: "zero", headers: []kgo.RecordHeader{{Key: eventVersionHeader, Value: []byte("0")}}, want: 0},
+		{name: "empty", headers: []kgo.RecordHeader{{Key: eventVersionHeader}}, wantErr: true},
+		{name: "not a number", headers: []kgo.RecordHeader{{Key: eventVersionHeader, Value: []byte("v2")}}, wantErr: true},
+		{name: "negative", headers: []kgo.RecordHeader{{Key: eventVersionHeader, Value: []byte("-1")}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := eventVersion(&kgo.Record{Headers: tt.headers})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("eventVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("eventVersion() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeRecordRejectsInvalidVersions(t *testing.T) {
+	record := &kgo.Record{
+		Value: []byte(`{"product_id":"product-1","name":"Keyboard","price":49.5}`),
+		Headers: []kgo.RecordHeader{
+			{Key: eventTypeHeader, Value: []byte(events.ProductCreatedType)},
+			{Key: eventVersionHeader, Value: []byte("two")},
+		},
+	}
+
+	if evt, _, err := DecodeRecord(record, events.NewCodecs()); err == nil {
+		t.Fatalf("DecodeRecord() = %+v, want an error", evt)
+	}
+
+	record.Headers[1].Value = []byte("1")
+	if _, _, err := DecodeRecord(record, events.NewCodecs()); err != nil {
+		t.Fatalf("DecodeRecord() with a valid version: %v", err)
+	}
+}
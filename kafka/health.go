@@ -0,0 +1,63 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/giornetta/microshop/health"
+)
+
+// NewBrokerChecker returns a Checker verifying that at least one broker is reachable.
+func NewBrokerChecker(client *kgo.Client) health.Checker {
+	return health.NewChecker("kafka", func(ctx context.Context) error {
+		return client.Ping(ctx)
+	})
+}
+
+// NewListenerChecker returns a Checker failing when the listener is not listening for events.
+func NewListenerChecker(l *Listener) health.Checker {
+	return health.NewChecker("listener", func(ctx context.Context) error {
+		if !l.Running() {
+			return errors.New("listener is not running")
+		}
+
+		return nil
+	})
+}
+
+// NewLagChecker returns a Checker failing when the consumer group lags behind
+// any partition by more than maxLag records.
+func NewLagChecker(client *kgo.Client, group string, maxLag int64) health.Checker {
+	admin := kadm.NewClient(client)
+
+	return health.NewChecker("consumer-lag", func(ctx context.Context) error {
+		lags, err := admin.Lag(ctx, group)
+		if err != nil {
+			return err
+		}
+
+		lag, ok := lags[group]
+		if !ok {
+			return fmt.Errorf("consumer group %s was not described", group)
+		}
+		if err := lag.Error(); err != nil {
+			return err
+		}
+
+		for _, l := range lag.Lag.Sorted() {
+			if l.Err != nil {
+				return l.Err
+			}
+
+			if l.Lag > maxLag {
+				return fmt.Errorf("lag of %s[%d] is %d, above %d", l.Topic, l.Partition, l.Lag, maxLag)
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,81 @@
+// Package kafkatest provides in-memory Kafka clusters to the tests of code producing and consuming events.
+package kafkatest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+
+	"github.com/giornetta/microshop/events"
+)
+
+// Cluster starts a cluster of as many brokers as partitions, seeded with the topics of the events.
+// Partition n of every topic is led by broker n, so that FailProduce can fail some partitions only.
+// The cluster is closed when t ends.
+func Cluster(t testing.TB, partitions int32) *kfake.Cluster {
+	t.Helper()
+
+	topics := []string{events.ProductTopic.String(), events.CustomerTopic.String()}
+
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(int(partitions)), kfake.SeedTopics(partitions, topics...))
+	if err != nil {
+		t.Fatalf("could not start kafka: %v", err)
+	}
+	t.Cleanup(cluster.Close)
+
+	for _, topic := range topics {
+		for p := int32(0); p < partitions; p++ {
+			if err := cluster.MoveTopicPartition(topic, p, p); err != nil {
+				t.Fatalf("could not move partition %d of %s: %v", p, topic, err)
+			}
+		}
+	}
+
+	return cluster
+}
+
+// Client returns a client of the cluster, closed when t ends.
+func Client(t testing.TB, cluster *kfake.Cluster, opts ...kgo.Opt) *kgo.Client {
+	t.Helper()
+
+	client, err := kgo.NewClient(append([]kgo.Opt{kgo.SeedBrokers(cluster.ListenAddrs()...)}, opts...)...)
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	t.Cleanup(client.Close)
+
+	return client
+}
+
+// FailProduce makes the partitions led by the given broker reject every record produced to them,
+// with an error that is not retried. The rejections are delayed, so that they come after the records
+// produced to the other brokers at the same time are acknowledged.
+func FailProduce(cluster *kfake.Cluster, broker int32) {
+	cluster.ControlKey(kmsg.Produce.Int16(), func(kreq kmsg.Request) (kmsg.Response, error, bool) {
+		if cluster.CurrentNode() != broker {
+			return nil, nil, false
+		}
+		cluster.KeepControl()
+		cluster.SleepControl(func() { time.Sleep(200 * time.Millisecond) })
+
+		req := kreq.(*kmsg.ProduceRequest)
+		resp := req.ResponseKind().(*kmsg.ProduceResponse)
+		for _, t := range req.Topics {
+			rt := kmsg.NewProduceResponseTopic()
+			rt.Topic, rt.TopicID = t.Topic, t.TopicID
+			for _, p := range t.Partitions {
+				rp := kmsg.NewProduceResponseTopicPartition()
+				rp.Partition = p.Partition
+				rp.ErrorCode = kerr.InvalidRecord.Code
+				rt.Partitions = append(rt.Partitions, rp)
+			}
+			resp.Topics = append(resp.Topics, rt)
+		}
+
+		return resp, nil, true
+	})
+}
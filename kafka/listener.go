@@ -3,24 +3,34 @@ package kafka
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/log"
+	"github.com/giornetta/microshop/tracing"
 	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/exp/slog"
 )
 
 // Listener fetches incoming Kafka messages, offering a simple API to specify handlers for them.
 type Listener struct {
 	client *kgo.Client
+	codecs *events.Codecs
 
 	lock     sync.RWMutex
 	handlers map[events.Topic]events.Handler
+
+	running atomic.Bool
 }
 
-// NewListener returns a new Kafka Listener.
-func NewListener(client *kgo.Client) *Listener {
+// NewListener returns a new Kafka Listener, decoding events with the codec matching their content type.
+func NewListener(client *kgo.Client, codecs *events.Codecs) *Listener {
 	l := &Listener{
 		client:   client,
+		codecs:   codecs,
 		handlers: make(map[events.Topic]events.Handler),
 	}
 
@@ -43,7 +53,15 @@ func (l *Listener) Handle(topic events.Topic, handler events.Handler) {
 
 // Listener is a blocking method that will fetch incoming kafka messages
 // until either an error occurs or the given context is canceled.
+// Once the context is canceled, the record being handled is allowed to complete and is committed,
+// while the remaining fetched records are left to be redelivered.
 func (l *Listener) Listen(ctx context.Context) error {
+	l.running.Store(true)
+	defer l.running.Store(false)
+
+	// Handlers and commits must not be interrupted halfway when the listener is stopped.
+	handleCtx := context.WithoutCancel(ctx)
+
 	for {
 		fetches := l.client.PollFetches(ctx)
 		if errs := fetches.Errors(); errs != nil {
@@ -56,22 +74,89 @@ func (l *Listener) Listen(ctx context.Context) error {
 
 		iter := fetches.RecordIter()
 		for !iter.Done() {
+			if ctx.Err() != nil {
+				return nil
+			}
+
 			record := iter.Next()
 
-			t := events.Type(record.Headers[0].Value)
-			event, err := events.Decode(t, record.Value)
-			if err != nil {
+			// Events are handled sequentially, in a blocking manner, to ensure ordering.
+			if err := l.handleRecord(record, handleCtx); err != nil {
 				return err
 			}
 
-			// Events are handled sequentially, in a blocking manner, to ensure ordering.
-			if err := l.handleEvent(event, ctx); err != nil {
+			if err := l.client.CommitRecords(handleCtx, record); err != nil {
 				return err
 			}
+		}
+	}
+}
+
+// Running reports whether Listen is fetching messages.
+func (l *Listener) Running() bool {
+	return l.running.Load()
+}
+
+func (l *Listener) handleRecord(record *kgo.Record, ctx context.Context) error {
+	ctx, span := tracing.StartConsume(record, ctx)
+	defer span.End()
+
+	event, id, err := DecodeRecord(record, l.codecs)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	ctx = log.WithAttrs(ctx, slog.String(log.EventIdKey, id))
+
+	span.SetAttributes(attribute.String("event.type", event.Type().String()))
 
-			l.client.CommitRecords(ctx, record)
+	if err := l.handleEvent(event, ctx); err != nil {
+		tracing.RecordError(span, err)
+
+		// Rejected events would fail again if redelivered, so they are committed and skipped.
+		var rejected *events.ErrRejected
+		if errors.As(err, &rejected) {
+			return nil
 		}
+
+		return err
+	}
+
+	return nil
+}
+
+// DecodeRecord returns the event contained in the record, which might have been published
+// either with the EventType header or with one of the CloudEvents bindings, and its ID:
+// the id attribute of CloudEvents, or the position of the record otherwise.
+func DecodeRecord(record *kgo.Record, codecs *events.Codecs) (events.Event, string, error) {
+	ce, ok, err := cloudEventOf(record)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if ok {
+		evt, err := ce.Event(codecs)
+		return evt, ce.ID, err
 	}
+
+	t, ok := header(record, eventTypeHeader)
+	if !ok {
+		return nil, "", errors.New("record is missing the event type header")
+	}
+
+	contentType, _ := header(record, contentTypeHeader)
+	codec, err := codecs.ForContentType(contentType)
+	if err != nil {
+		return nil, "", err
+	}
+
+	version, err := eventVersion(record)
+	if err != nil {
+		return nil, "", err
+	}
+
+	evt, err := codec.Decode(events.Type(t), version, record.Value)
+	return evt, fmt.Sprintf("%s-%d-%d", record.Topic, record.Partition, record.Offset), err
 }
 
 func (l *Listener) handleEvent(evt events.Event, ctx context.Context) error {
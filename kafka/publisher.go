@@ -2,42 +2,126 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
+	"strconv"
 	"time"
 
 	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/tracing"
 	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type eventPublisher struct {
 	client *kgo.Client
+	codecs *events.Codecs
 }
 
-func NewEventPublisher(client *kgo.Client) events.Publisher {
+// NewEventPublisher returns a Publisher producing events to Kafka,
+// encoding them with the codec configured for their topic.
+func NewEventPublisher(client *kgo.Client, codecs *events.Codecs) events.Publisher {
 	return &eventPublisher{
 		client: client,
+		codecs: codecs,
 	}
 }
 
 func (p *eventPublisher) Publish(e events.Event, ctx context.Context) error {
-	jsonPayload, _ := json.Marshal(e)
+	record, err := p.record(e)
+	if err != nil {
+		return err
+	}
+
+	return produce(p.client, []*kgo.Record{record}, ctx)[0]
+}
+
+func (p *eventPublisher) PublishBatch(evts []events.Event, ctx context.Context) []error {
+	return produceBatch(p.client, evts, func(e events.Event) (*kgo.Record, error) {
+		return p.record(e)
+	}, ctx)
+}
+
+func (p *eventPublisher) record(e events.Event) (*kgo.Record, error) {
+	codec := p.codecs.ForTopic(e.Topic())
+
+	payload, err := codec.Encode(e)
+	if err != nil {
+		return nil, err
+	}
 
 	record := &kgo.Record{
 		Key:   []byte(e.Key()),
-		Value: jsonPayload,
+		Value: payload,
 		Headers: []kgo.RecordHeader{
 			{
-				Key:   "EventType",
+				Key:   eventTypeHeader,
 				Value: []byte(e.Type()),
 			},
+			{
+				Key:   eventVersionHeader,
+				Value: []byte(strconv.Itoa(events.VersionOf(e.Type()))),
+			},
+			{
+				Key:   contentTypeHeader,
+				Value: []byte(codec.ContentType()),
+			},
 		},
 		Timestamp: time.Now(),
 		Topic:     e.Topic().String(),
 	}
 
-	if err := p.client.ProduceSync(ctx, record).FirstErr(); err != nil {
-		return err
+	return record, nil
+}
+
+// produceBatch builds the record of every event with record, and produces the ones it could build
+// at once, returning the error of each event.
+func produceBatch(client *kgo.Client, evts []events.Event, record func(e events.Event) (*kgo.Record, error), ctx context.Context) []error {
+	errs := make([]error, len(evts))
+	records := make([]*kgo.Record, 0, len(evts))
+	built := make([]int, 0, len(evts))
+
+	for i, e := range evts {
+		r, err := record(e)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		records = append(records, r)
+		built = append(built, i)
+	}
+
+	for j, err := range produce(client, records, ctx) {
+		errs[built[j]] = err
+	}
+
+	return errs
+}
+
+// produce produces the records, each with its own span, and waits for all of them to be acknowledged,
+// returning the error of each record.
+func produce(client *kgo.Client, records []*kgo.Record, ctx context.Context) []error {
+	errs := make([]error, len(records))
+	if len(records) == 0 {
+		return errs
+	}
+
+	spans := make([]trace.Span, len(records))
+	positions := make(map[*kgo.Record]int, len(records))
+	for i, r := range records {
+		// The context of each record is only used to propagate its span in the record headers.
+		_, spans[i] = tracing.StartProduce(r, ctx)
+		positions[r] = i
+	}
+
+	// Results come in the order the records were acknowledged, which differs from theirs across partitions.
+	for _, res := range client.ProduceSync(ctx, records...) {
+		i := positions[res.Record]
+		errs[i] = res.Err
+		if res.Err != nil {
+			tracing.RecordError(spans[i], res.Err)
+		}
+		spans[i].End()
 	}
 
-	return nil
+	return errs
 }
@@ -0,0 +1,40 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/kafka/kafkatest"
+)
+
+func TestProduceMatchesResultsToRecords(t *testing.T) {
+	cluster := kafkatest.Cluster(t, 2)
+	client := kafkatest.Client(t, cluster, kgo.RecordPartitioner(kgo.ManualPartitioner()))
+
+	// Warm up the metadata, so that the records of both partitions are produced at once.
+	if errs := produce(client, []*kgo.Record{{Topic: events.ProductTopic.String(), Partition: 0}}, context.Background()); errs[0] != nil {
+		t.Fatal(errs[0])
+	}
+
+	kafkatest.FailProduce(cluster, 1)
+
+	partitions := []int32{1, 0, 1, 0, 0}
+	records := make([]*kgo.Record, len(partitions))
+	for i, p := range partitions {
+		records[i] = &kgo.Record{Topic: events.ProductTopic.String(), Partition: p, Value: []byte("event")}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	errs := produce(client, records, ctx)
+	for i, err := range errs {
+		if failed := err != nil; failed != (partitions[i] == 1) {
+			t.Errorf("record %d of partition %d got error %v", i, partitions[i], err)
+		}
+	}
+}
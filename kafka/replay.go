@@ -0,0 +1,164 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/giornetta/microshop/events"
+)
+
+// ReplayProgress reports how many records of a topic have been replayed so far.
+type ReplayProgress struct {
+	Topic    events.Topic
+	Replayed int64
+	Total    int64
+}
+
+// Replay consumes the given topic from its first offset up to the end offsets it had when Replay was called,
+// passing every event to the handler in order and reporting progress after each record.
+// It returns the end offsets that were reached, so that consumer groups can be moved past the replayed records.
+// Events the handler rejects are skipped, as the Listener does.
+//
+// A partition is replayed once the position of its consumer reaches the end offset, even if no record sits right
+// before it, as happens when it is a transaction marker. The client must therefore keep the control records,
+// which carry the markers, and must not belong to a consumer group, nor consume any topic: Replay assigns the
+// partitions itself.
+func Replay(
+	client *kgo.Client,
+	codecs *events.Codecs,
+	topic events.Topic,
+	handler events.Handler,
+	progress func(ReplayProgress),
+	ctx context.Context,
+) (kadm.ListedOffsets, error) {
+	if keep, _ := client.OptValue(kgo.KeepControlRecords).(bool); !keep {
+		return nil, errors.New("the client must be created with kgo.KeepControlRecords to replay a topic")
+	}
+
+	adm := kadm.NewClient(client)
+
+	starts, err := adm.ListStartOffsets(ctx, topic.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := starts.Error(); err != nil {
+		return nil, err
+	}
+
+	ends, err := adm.ListEndOffsets(ctx, topic.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := ends.Error(); err != nil {
+		return nil, err
+	}
+
+	p := ReplayProgress{Topic: topic}
+	remaining := make(map[int32]int64)
+	positions := make(map[int32]int64)
+	assignments := make(map[int32]kgo.Offset)
+
+	ends.Each(func(end kadm.ListedOffset) {
+		start, _ := starts.Lookup(end.Topic, end.Partition)
+		if end.Offset <= start.Offset {
+			return
+		}
+
+		p.Total += end.Offset - start.Offset
+		remaining[end.Partition] = end.Offset
+		positions[end.Partition] = start.Offset
+		assignments[end.Partition] = kgo.NewOffset().At(start.Offset)
+	})
+
+	if progress != nil {
+		progress(p)
+	}
+
+	if len(remaining) == 0 {
+		return ends, nil
+	}
+
+	client.AddConsumePartitions(map[string]map[int32]kgo.Offset{topic.String(): assignments})
+	defer client.RemoveConsumePartitions(map[string][]int32{topic.String(): keys(assignments)})
+
+	// advance moves the position of the partition, which is replayed once it reaches the end offset.
+	advance := func(partition int32, position int64) {
+		p.Replayed += position - positions[partition]
+		positions[partition] = position
+		if position >= remaining[partition] {
+			delete(remaining, partition)
+		}
+
+		if progress != nil {
+			progress(p)
+		}
+	}
+
+	for len(remaining) > 0 {
+		fetches := client.PollFetches(ctx)
+		if errs := fetches.Errors(); errs != nil {
+			return nil, errs[0].Err
+		}
+
+		var err error
+		fetches.EachPartition(func(fp kgo.FetchTopicPartition) {
+			if err != nil {
+				return
+			}
+
+			for _, record := range fp.Records {
+				end, ok := remaining[record.Partition]
+				if !ok || record.Offset >= end {
+					continue
+				}
+
+				if !record.Attrs.IsControl() {
+					if err = replayRecord(record, codecs, handler, ctx); err != nil {
+						return
+					}
+				}
+
+				// Offsets missing from the log, such as those of compacted records, count as replayed once skipped.
+				advance(record.Partition, record.Offset+1)
+			}
+
+			// Records deleted past the end while being replayed will never be fetched.
+			if end, ok := remaining[fp.Partition]; ok && fp.LogStartOffset >= end {
+				advance(fp.Partition, end)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ends, nil
+}
+
+// replayRecord passes the event of the record to the handler, skipping it if rejected.
+func replayRecord(record *kgo.Record, codecs *events.Codecs, handler events.Handler, ctx context.Context) error {
+	event, _, err := DecodeRecord(record, codecs)
+	if err != nil {
+		return fmt.Errorf("could not decode record at partition %d, offset %d: %w", record.Partition, record.Offset, err)
+	}
+
+	var rejected *events.ErrRejected
+	if err := handler.Handle(event, ctx); err != nil && !errors.As(err, &rejected) {
+		return fmt.Errorf("could not handle record at partition %d, offset %d: %w", record.Partition, record.Offset, err)
+	}
+
+	return nil
+}
+
+func keys[K comparable, V any](m map[K]V) []K {
+	ks := make([]K, 0, len(m))
+	for k := range m {
+		ks = append(ks, k)
+	}
+
+	return ks
+}
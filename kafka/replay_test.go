@@ -0,0 +1,184 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/giornetta/microshop/events"
+)
+
+type handlerFunc func(e events.Event, ctx context.Context) error
+
+func (f handlerFunc) Handle(e events.Event, ctx context.Context) error { return f(e, ctx) }
+
+func newCluster(t *testing.T, partitions int32) *kfake.Cluster {
+	t.Helper()
+
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(partitions, events.ProductTopic.String()))
+	if err != nil {
+		t.Fatalf("could not start kafka: %v", err)
+	}
+	t.Cleanup(cluster.Close)
+
+	return cluster
+}
+
+func newClient(t *testing.T, cluster *kfake.Cluster, opts ...kgo.Opt) *kgo.Client {
+	t.Helper()
+
+	client, err := kgo.NewClient(append([]kgo.Opt{kgo.SeedBrokers(cluster.ListenAddrs()...)}, opts...)...)
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	t.Cleanup(client.Close)
+
+	return client
+}
+
+func created(n int) events.Event {
+	return events.ProductCreated{
+		ProductEvent: events.ProductEvent{ProductId: fmt.Sprintf("product-%d", n)},
+		Name:         fmt.Sprintf("Product %d", n),
+		Price:        float32(n) + 0.5,
+	}
+}
+
+// publish publishes n events, starting from the given one, in a transaction when transactional is set,
+// which leaves a commit marker as the last offset of the partitions they went to.
+func publish(t *testing.T, client *kgo.Client, from, n int, transactional bool) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	if transactional {
+		if err := client.BeginTransaction(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	publisher := NewEventPublisher(client, events.NewCodecs())
+	for i := from; i < from+n; i++ {
+		if err := publisher.Publish(created(i), ctx); err != nil {
+			t.Fatalf("could not publish: %v", err)
+		}
+	}
+
+	if transactional {
+		if err := client.EndTransaction(ctx, kgo.TryCommit); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	tests := []struct {
+		name       string
+		partitions int32
+		plain      int
+		committed  int
+	}{
+		{name: "empty topic", partitions: 2},
+		{name: "plain records", partitions: 2, plain: 5},
+		{name: "ending with a commit marker", partitions: 1, plain: 3, committed: 2},
+		{name: "only transactional records", partitions: 3, committed: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := newCluster(t, tt.partitions)
+
+			publish(t, newClient(t, cluster), 0, tt.plain, false)
+			if tt.committed > 0 {
+				publish(t, newClient(t, cluster, kgo.TransactionalID("replay-test")), tt.plain, tt.committed, true)
+			}
+
+			handled := make(map[events.Key]bool)
+			handler := handlerFunc(func(e events.Event, _ context.Context) error {
+				handled[e.Key()] = true
+				return nil
+			})
+
+			var last ReplayProgress
+			progress := func(p ReplayProgress) { last = p }
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			ends, err := Replay(newClient(t, cluster, kgo.KeepControlRecords()), events.NewCodecs(), events.ProductTopic, handler, progress, ctx)
+			if err != nil {
+				t.Fatalf("Replay: %v", err)
+			}
+
+			for i := 0; i < tt.plain+tt.committed; i++ {
+				if key := created(i).Key(); !handled[key] {
+					t.Errorf("event of %s was not replayed", key)
+				}
+			}
+			if len(handled) != tt.plain+tt.committed {
+				t.Errorf("replayed %d events, want %d", len(handled), tt.plain+tt.committed)
+			}
+
+			var total int64
+			ends.Each(func(o kadm.ListedOffset) { total += o.Offset })
+			if last.Total != total || last.Replayed != last.Total {
+				t.Errorf("last progress was %+v, want %d of %d offsets replayed", last, total, total)
+			}
+		})
+	}
+}
+
+func TestReplaySkipsRejectedEvents(t *testing.T) {
+	cluster := newCluster(t, 1)
+	publish(t, newClient(t, cluster), 0, 3, false)
+
+	var handled []events.Key
+	handler := handlerFunc(func(e events.Event, _ context.Context) error {
+		handled = append(handled, e.Key())
+		if e.Key() == created(1).Key() {
+			return &events.ErrRejected{Err: fmt.Errorf("duplicate")}
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := Replay(newClient(t, cluster, kgo.KeepControlRecords()), events.NewCodecs(), events.ProductTopic, handler, nil, ctx); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+
+	if len(handled) != 3 {
+		t.Errorf("handled %v, want the 3 events", handled)
+	}
+}
+
+func TestReplayStopsAtFailures(t *testing.T) {
+	cluster := newCluster(t, 1)
+	publish(t, newClient(t, cluster), 0, 3, false)
+
+	handler := handlerFunc(func(e events.Event, _ context.Context) error {
+		return fmt.Errorf("could not project %s", e.Key())
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := Replay(newClient(t, cluster, kgo.KeepControlRecords()), events.NewCodecs(), events.ProductTopic, handler, nil, ctx); err == nil {
+		t.Fatal("Replay succeeded although the handler failed")
+	}
+}
+
+func TestReplayRequiresControlRecords(t *testing.T) {
+	cluster := newCluster(t, 1)
+
+	handler := handlerFunc(func(events.Event, context.Context) error { return nil })
+	if _, err := Replay(newClient(t, cluster), events.NewCodecs(), events.ProductTopic, handler, nil, context.Background()); err == nil {
+		t.Fatal("Replay succeeded with a client dropping control records")
+	}
+}
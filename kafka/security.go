@@ -0,0 +1,115 @@
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+// SASL mechanisms supported by SecurityOptions.
+const (
+	SASLPlain       = "PLAIN"
+	SASLScramSHA256 = "SCRAM-SHA-256"
+	SASLScramSHA512 = "SCRAM-SHA-512"
+)
+
+// SecurityOptions configures how a client connects and authenticates to the brokers.
+type SecurityOptions struct {
+	TLS bool
+	// CAFile verifies brokers against these CAs instead of the system ones.
+	CAFile string
+	// CertFile and KeyFile are the client certificate presented to brokers requiring mutual TLS.
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+
+	// SASLMechanism is one of PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512. SASL is disabled when empty.
+	SASLMechanism string
+	Username      string
+	Password      string
+}
+
+// SecurityOpts returns the client options enabling TLS and SASL as described by opt.
+func SecurityOpts(opt *SecurityOptions) ([]kgo.Opt, error) {
+	var opts []kgo.Opt
+
+	if opt.TLS {
+		cfg, err := clientTLSConfig(opt)
+		if err != nil {
+			return nil, err
+		}
+
+		dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: 10 * time.Second}, Config: cfg}
+		opts = append(opts, kgo.Dialer(dialer.DialContext))
+	}
+
+	if opt.SASLMechanism != "" {
+		mechanism, err := saslMechanism(opt)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, kgo.SASL(mechanism))
+	}
+
+	return opts, nil
+}
+
+func clientTLSConfig(opt *SecurityOptions) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: opt.InsecureSkipVerify,
+	}
+
+	if opt.CAFile != "" {
+		pem, err := os.ReadFile(opt.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA file: %w", err)
+		}
+
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", opt.CAFile)
+		}
+	}
+
+	if opt.CertFile != "" || opt.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opt.CertFile, opt.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %w", err)
+		}
+
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+func saslMechanism(opt *SecurityOptions) (sasl.Mechanism, error) {
+	switch strings.ToUpper(opt.SASLMechanism) {
+	case SASLPlain:
+		return plain.Auth{User: opt.Username, Pass: opt.Password}.AsMechanism(), nil
+	case SASLScramSHA256:
+		return scram.Sha256(scramAuth(opt)), nil
+	case SASLScramSHA512:
+		return scram.Sha512(scramAuth(opt)), nil
+	default:
+		return nil, fmt.Errorf("unknown sasl mechanism %q", opt.SASLMechanism)
+	}
+}
+
+func scramAuth(opt *SecurityOptions) func(context.Context) (scram.Auth, error) {
+	return func(context.Context) (scram.Auth, error) {
+		return scram.Auth{User: opt.Username, Pass: opt.Password}, nil
+	}
+}
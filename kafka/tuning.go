@@ -0,0 +1,67 @@
+package kafka
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// TuningOptions adjusts how a client consumes and produces. Zero values keep the client defaults.
+type TuningOptions struct {
+	ClientID         string
+	AutoCreateTopics bool
+
+	SessionTimeout   time.Duration
+	RebalanceTimeout time.Duration
+	FetchMaxWait     time.Duration
+	FetchMaxBytes    int32
+
+	// ProducerAcks is all, leader or none.
+	ProducerAcks          string
+	ProducerLinger        time.Duration
+	ProducerBatchMaxBytes int32
+}
+
+// TuningOpts returns the client options described by opt.
+func TuningOpts(opt *TuningOptions) ([]kgo.Opt, error) {
+	var opts []kgo.Opt
+
+	if opt.ClientID != "" {
+		opts = append(opts, kgo.ClientID(opt.ClientID))
+	}
+	if opt.AutoCreateTopics {
+		opts = append(opts, kgo.AllowAutoTopicCreation())
+	}
+	if opt.SessionTimeout > 0 {
+		opts = append(opts, kgo.SessionTimeout(opt.SessionTimeout))
+	}
+	if opt.RebalanceTimeout > 0 {
+		opts = append(opts, kgo.RebalanceTimeout(opt.RebalanceTimeout))
+	}
+	if opt.FetchMaxWait > 0 {
+		opts = append(opts, kgo.FetchMaxWait(opt.FetchMaxWait))
+	}
+	if opt.FetchMaxBytes > 0 {
+		opts = append(opts, kgo.FetchMaxBytes(opt.FetchMaxBytes))
+	}
+	if opt.ProducerLinger > 0 {
+		opts = append(opts, kgo.ProducerLinger(opt.ProducerLinger))
+	}
+	if opt.ProducerBatchMaxBytes > 0 {
+		opts = append(opts, kgo.ProducerBatchMaxBytes(opt.ProducerBatchMaxBytes))
+	}
+
+	switch opt.ProducerAcks {
+	case "", "all":
+	case "leader":
+		// Idempotent writes require acknowledgements from all in-sync replicas.
+		opts = append(opts, kgo.RequiredAcks(kgo.LeaderAck()), kgo.DisableIdempotentWrite())
+	case "none":
+		opts = append(opts, kgo.RequiredAcks(kgo.NoAck()), kgo.DisableIdempotentWrite())
+	default:
+		return nil, fmt.Errorf("unknown producer acks %q", opt.ProducerAcks)
+	}
+
+	return opts, nil
+}
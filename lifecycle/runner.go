@@ -0,0 +1,176 @@
+// Package lifecycle runs the long-lived components of a service, such as HTTP servers and event listeners,
+// and shuts them down gracefully, in order, when the process is signaled or one of them stops.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+const DefaultShutdownTimeout = 15 * time.Second
+
+type component struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+type cleanup struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Runner starts components and, on shutdown, stops them one at a time in the order they were added,
+// then runs the cleanup functions, again in the order they were added.
+type Runner struct {
+	logger  *slog.Logger
+	timeout time.Duration
+
+	components []component
+	cleanups   []cleanup
+}
+
+// New returns a Runner giving every component and every cleanup function up to timeout to complete on shutdown.
+// A zero timeout uses DefaultShutdownTimeout.
+func New(logger *slog.Logger, timeout time.Duration) *Runner {
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+
+	return &Runner{
+		logger:  logger,
+		timeout: timeout,
+	}
+}
+
+// Go adds a component. Its run function must block until the context is canceled,
+// then return once its work is done.
+func (r *Runner) Go(name string, run func(ctx context.Context) error) {
+	r.components = append(r.components, component{name: name, run: run})
+}
+
+// Cleanup adds a function executed once every component has stopped, such as flushing producers or closing pools.
+func (r *Runner) Cleanup(name string, fn func(ctx context.Context) error) {
+	r.cleanups = append(r.cleanups, cleanup{name: name, fn: fn})
+}
+
+type running struct {
+	component
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// Run starts every component and blocks until the process receives SIGINT or SIGTERM, the given context
+// is canceled or a component stops on its own, then shuts everything down.
+// It returns the first error reported by a component or a cleanup function.
+func (r *Runner) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	stopped := make(chan string, len(r.components))
+
+	components := make([]*running, len(r.components))
+	for i, c := range r.components {
+		runCtx, cancel := context.WithCancel(context.Background())
+
+		rc := &running{component: c, cancel: cancel, done: make(chan struct{})}
+		components[i] = rc
+
+		go func() {
+			defer close(rc.done)
+
+			rc.err = rc.run(runCtx)
+			stopped <- rc.name
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		r.logger.Info("Shutting down", slog.String("reason", "signal received"))
+	case name := <-stopped:
+		r.logger.Info("Shutting down", slog.String("reason", name+" stopped"))
+	}
+	stop()
+
+	var errs []error
+
+	for _, rc := range components {
+		rc.cancel()
+
+		select {
+		case <-rc.done:
+		case <-time.After(r.timeout):
+			r.logger.Error("component did not stop in time", slog.String("component", rc.name))
+			errs = append(errs, errors.New(rc.name+" did not stop in time"))
+			continue
+		}
+
+		if rc.err != nil {
+			r.logger.Error("component failed", slog.String("component", rc.name), slog.String("err", rc.err.Error()))
+			errs = append(errs, rc.err)
+		}
+	}
+
+	for _, c := range r.cleanups {
+		ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+		err := c.fn(ctx)
+		cancel()
+
+		if err != nil {
+			r.logger.Error("cleanup failed", slog.String("cleanup", c.name), slog.String("err", err.Error()))
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// HTTPServer returns the run function of an HTTP server, serving HTTPS when its TLSConfig is set.
+// When stopped, the server stops accepting connections and waits up to drainTimeout
+// for in-flight requests, before closing the remaining ones.
+func HTTPServer(s *http.Server, drainTimeout time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var wg sync.WaitGroup
+		errs := make(chan error, 1)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.TLSConfig != nil {
+				errs <- s.ListenAndServeTLS("", "")
+			} else {
+				errs <- s.ListenAndServe()
+			}
+		}()
+
+		select {
+		case err := <-errs:
+			if err == http.ErrServerClosed {
+				return nil
+			}
+
+			return err
+		case <-ctx.Done():
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+		defer cancel()
+
+		err := s.Shutdown(shutdownCtx)
+		if err != nil {
+			s.Close()
+		}
+
+		wg.Wait()
+
+		return err
+	}
+}
@@ -0,0 +1,147 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// recorder records the order components and cleanup functions stop in.
+type recorder struct {
+	lock    sync.Mutex
+	stopped []string
+}
+
+func (r *recorder) record(name string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.stopped = append(r.stopped, name)
+}
+
+func (r *recorder) order() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return append([]string(nil), r.stopped...)
+}
+
+// component returns a run function blocking until its context is canceled, then recording its name.
+func (r *recorder) component(name string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		<-ctx.Done()
+		r.record(name)
+		return nil
+	}
+}
+
+func (r *recorder) cleanup(name string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		r.record(name)
+		return nil
+	}
+}
+
+func newRunner(timeout time.Duration) *Runner {
+	return New(slog.New(slog.NewTextHandler(io.Discard)), timeout)
+}
+
+// run runs r until ctx is canceled or a component stops, failing the test if it does not return in time.
+func run(t *testing.T, r *Runner, ctx context.Context) error {
+	t.Helper()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- r.Run(ctx)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return")
+		return nil
+	}
+}
+
+func TestShutdownOrder(t *testing.T) {
+	rec := &recorder{}
+
+	r := newRunner(time.Second)
+	r.Go("server", rec.component("server"))
+	r.Go("listener", rec.component("listener"))
+	r.Go("relay", rec.component("relay"))
+	r.Cleanup("producer", rec.cleanup("producer"))
+	r.Cleanup("pool", rec.cleanup("pool"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	if err := run(t, r, ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	// Servers stop taking requests before the listeners stop, and the pools they use are closed last.
+	want := []string{"server", "listener", "relay", "producer", "pool"}
+	if got := rec.order(); !reflect.DeepEqual(got, want) {
+		t.Errorf("stopped %v, want %v", got, want)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	rec := &recorder{}
+
+	stuck := make(chan struct{})
+	t.Cleanup(func() { close(stuck) })
+
+	r := newRunner(50 * time.Millisecond)
+	r.Go("stuck", func(ctx context.Context) error {
+		<-stuck
+		return nil
+	})
+	r.Go("listener", rec.component("listener"))
+	r.Cleanup("pool", rec.cleanup("pool"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := run(t, r, ctx)
+	if err == nil || !strings.Contains(err.Error(), "stuck did not stop in time") {
+		t.Fatalf("got error %v, want one for the stuck component", err)
+	}
+
+	// A stuck component does not keep the others from stopping.
+	if got, want := rec.order(), []string{"listener", "pool"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stopped %v, want %v", got, want)
+	}
+}
+
+func TestComponentError(t *testing.T) {
+	rec := &recorder{}
+	failure := errors.New("address already in use")
+
+	r := newRunner(time.Second)
+	r.Go("listener", rec.component("listener"))
+	r.Go("server", func(ctx context.Context) error {
+		return failure
+	})
+	r.Go("relay", rec.component("relay"))
+	r.Cleanup("pool", rec.cleanup("pool"))
+
+	// The context is never canceled: the failing component alone shuts the runner down.
+	err := run(t, r, context.Background())
+	if !errors.Is(err, failure) {
+		t.Fatalf("got error %v, want %v", err, failure)
+	}
+
+	if got, want := rec.order(), []string{"listener", "relay", "pool"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stopped %v, want %v", got, want)
+	}
+}
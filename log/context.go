@@ -0,0 +1,71 @@
+package log
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slog"
+)
+
+// Keys of the attributes added to records from their context.
+const (
+	RequestIdKey  = "request_id"
+	TraceIdKey    = "trace_id"
+	SpanIdKey     = "span_id"
+	EventIdKey    = "event_id"
+	EventTypeKey  = "event_type"
+	EventKeyKey   = "event_key"
+	ProductIdKey  = "product_id"
+	CustomerIdKey = "customer_id"
+)
+
+type attrsKey struct{}
+
+// WithAttrs returns a copy of ctx carrying attrs, which are added to every record logged with it.
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+
+	merged := make([]slog.Attr, 0, len(existing)+len(attrs))
+	merged = append(merged, existing...)
+	merged = append(merged, attrs...)
+
+	return context.WithValue(ctx, attrsKey{}, merged)
+}
+
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return WithAttrs(ctx, slog.String(RequestIdKey, id))
+}
+
+func WithProductId(ctx context.Context, id string) context.Context {
+	return WithAttrs(ctx, slog.String(ProductIdKey, id))
+}
+
+func WithCustomerId(ctx context.Context, id string) context.Context {
+	return WithAttrs(ctx, slog.String(CustomerIdKey, id))
+}
+
+// Attrs returns the attributes carried by ctx, followed by the IDs of its trace and span.
+func Attrs(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		attrs = append(attrs[:len(attrs):len(attrs)],
+			slog.String(TraceIdKey, sc.TraceID().String()),
+			slog.String(SpanIdKey, sc.SpanID().String()),
+		)
+	}
+
+	return attrs
+}
+
+// RequestId returns the ID of the request being served with ctx, if any.
+func RequestId(ctx context.Context) string {
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	for _, a := range attrs {
+		if a.Key == RequestIdKey {
+			return a.Value.String()
+		}
+	}
+
+	return ""
+}
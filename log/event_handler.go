@@ -2,6 +2,7 @@ package log
 
 import (
 	"context"
+	"time"
 
 	"github.com/giornetta/microshop/errors"
 	"github.com/giornetta/microshop/events"
@@ -13,6 +14,8 @@ type loggingHandler struct {
 	logger  *slog.Logger
 }
 
+// NewEventHandler returns a Handler adding the type and key of events to the context
+// of the wrapped handler, so that every record logged while handling them carries them.
 func NewEventHandler(logger *slog.Logger, handler events.Handler) events.Handler {
 	return &loggingHandler{
 		handler: handler,
@@ -21,15 +24,37 @@ func NewEventHandler(logger *slog.Logger, handler events.Handler) events.Handler
 }
 
 func (h *loggingHandler) Handle(evt events.Event, ctx context.Context) error {
+	ctx = withEvent(evt, ctx)
+	start := time.Now()
+
 	if err := h.handler.Handle(evt, ctx); err != nil {
-		if e, ok := err.(*errors.ErrInternal); ok {
-			h.logger.Error("could not handle event",
-				slog.String("type", evt.Type().String()),
+		switch e := err.(type) {
+		case *errors.ErrInternal:
+			h.logger.ErrorCtx(ctx, "could not handle event",
 				slog.String("err", e.Cause().Error()))
+		case *events.ErrRejected:
+			h.logger.WarnCtx(ctx, "Event rejected", slog.String("err", e.Err.Error()))
 		}
 
 		return err
 	}
 
+	h.logger.DebugCtx(ctx, "Event handled", slog.Duration("duration", time.Since(start)))
 	return nil
 }
+
+func withEvent(evt events.Event, ctx context.Context) context.Context {
+	attrs := []slog.Attr{
+		slog.String(EventTypeKey, evt.Type().String()),
+		slog.String(EventKeyKey, evt.Key().String()),
+	}
+
+	switch evt.Topic() {
+	case events.ProductTopic:
+		attrs = append(attrs, slog.String(ProductIdKey, evt.Key().String()))
+	case events.CustomerTopic:
+		attrs = append(attrs, slog.String(CustomerIdKey, evt.Key().String()))
+	}
+
+	return WithAttrs(ctx, attrs...)
+}
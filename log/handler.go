@@ -0,0 +1,176 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/exp/slog"
+)
+
+// LoggerKey is the attribute naming a logger, see Named. Its value selects the level of the logger.
+const LoggerKey = "logger"
+
+// Options configures the handler of New.
+type Options struct {
+	// Format is text (default) or json.
+	Format string
+	// Level is the minimum level of records, info when empty.
+	Level string
+	// Levels overrides Level for named loggers. A name also applies to the loggers below it,
+	// so kafka applies to kafka.listener unless it has its own level.
+	Levels map[string]string
+}
+
+// New returns a logger writing to w, adding to every record the attributes of its context.
+func New(w io.Writer, opt *Options) (*slog.Logger, error) {
+	level, err := ParseLevel(opt.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	levels := make(map[string]slog.Level, len(opt.Levels))
+	for name, l := range opt.Levels {
+		if levels[name], err = ParseLevel(l); err != nil {
+			return nil, fmt.Errorf("logger %s: %w", name, err)
+		}
+	}
+
+	// The inner handler logs everything: levels are enforced by the context handler.
+	handlerOpts := slog.HandlerOptions{Level: slog.Level(-100)}
+
+	var inner slog.Handler
+	switch opt.Format {
+	case "", "text":
+		inner = handlerOpts.NewTextHandler(w)
+	case "json":
+		inner = handlerOpts.NewJSONHandler(w)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", opt.Format)
+	}
+
+	lowest := level
+	for _, l := range levels {
+		lowest = min(lowest, l)
+	}
+
+	return slog.New(&contextHandler{
+		handler:  inner,
+		level:    level,
+		fallback: level,
+		lowest:   lowest,
+		levels:   levels,
+	}), nil
+}
+
+// Named returns a logger whose level is configured by name in Options.Levels.
+// Records of unnamed loggers can name theirs with a LoggerKey attribute as well, while a LoggerKey
+// attribute within a group names nothing.
+func Named(logger *slog.Logger, name string) *slog.Logger {
+	return logger.With(slog.String(LoggerKey, name))
+}
+
+// ParseLevel parses debug, info, warn or error, returning info for an empty string.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", s)
+	}
+
+	return level, nil
+}
+
+// contextHandler adds the attributes of the context to records, and filters them by the level of their logger.
+type contextHandler struct {
+	handler slog.Handler
+
+	level    slog.Level
+	fallback slog.Level
+	// lowest is the lowest of the configured levels, below which no logger is enabled.
+	lowest slog.Level
+	levels map[string]slog.Level
+
+	// named is set once the logger was named, fixing its level. Unnamed loggers resolve it for each record,
+	// which may name the logger itself, so they let through every record some logger would.
+	named bool
+	// grouped is set once attributes are added to a group, where they cannot name the logger.
+	grouped bool
+}
+
+func (h *contextHandler) Enabled(_ context.Context, level slog.Level) bool {
+	if h.named || h.grouped {
+		return level >= h.level
+	}
+
+	return level >= h.lowest
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !h.named && !h.grouped {
+		level := h.level
+		r.Attrs(func(a slog.Attr) {
+			if a.Key == LoggerKey {
+				level = h.levelOf(a.Value.String())
+			}
+		})
+
+		if r.Level < level {
+			return nil
+		}
+	}
+
+	if ctx != nil {
+		r.AddAttrs(Attrs(ctx)...)
+	}
+
+	return h.handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.handler = h.handler.WithAttrs(attrs)
+
+	if h.grouped {
+		return &c
+	}
+
+	for _, a := range attrs {
+		if a.Key == LoggerKey {
+			c.level = h.levelOf(a.Value.String())
+			c.named = true
+		}
+	}
+
+	return &c
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	c := *h
+	c.handler = h.handler.WithGroup(name)
+	c.grouped = true
+	return &c
+}
+
+// levelOf returns the level of the longest configured name that is name or one of its parents.
+func (h *contextHandler) levelOf(name string) slog.Level {
+	for {
+		if level, ok := h.levels[name]; ok {
+			return level
+		}
+
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			return h.fallback
+		}
+		name = name[:i]
+	}
+}
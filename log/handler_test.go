@@ -0,0 +1,176 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slog"
+)
+
+// newLogger returns a JSON logger configured with opt, and a function decoding the records it logged so far.
+func newLogger(t *testing.T, opt *Options) (*slog.Logger, func() []map[string]any) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	opt.Format = "json"
+
+	logger, err := New(&buf, opt)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	return logger, func() []map[string]any {
+		var records []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+
+			var record map[string]any
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				t.Fatalf("invalid record %q: %v", line, err)
+			}
+			records = append(records, record)
+		}
+		buf.Reset()
+
+		return records
+	}
+}
+
+func messages(records []map[string]any) []string {
+	msgs := make([]string, 0, len(records))
+	for _, r := range records {
+		msgs = append(msgs, r[slog.MessageKey].(string))
+	}
+
+	return msgs
+}
+
+func TestLevels(t *testing.T) {
+	logger, logged := newLogger(t, &Options{
+		Level:  "info",
+		Levels: map[string]string{"kafka": "debug", "kafka.listener": "error", "http": "warn"},
+	})
+
+	tests := []struct {
+		name   string
+		logger *slog.Logger
+		log    func(l *slog.Logger)
+		want   bool
+	}{
+		{name: "info at the default level", logger: logger, log: func(l *slog.Logger) { l.Info("logged") }, want: true},
+		{name: "debug below the default level", logger: logger, log: func(l *slog.Logger) { l.Debug("logged") }},
+		{name: "debug of a named logger", logger: Named(logger, "kafka"), log: func(l *slog.Logger) { l.Debug("logged") }, want: true},
+		{name: "debug of a child logger", logger: Named(logger, "kafka.publisher"), log: func(l *slog.Logger) { l.Debug("logged") }, want: true},
+		{name: "warn of a child logger with its own level", logger: Named(logger, "kafka.listener"), log: func(l *slog.Logger) { l.Warn("logged") }},
+		{name: "info of a logger with a higher level", logger: Named(logger, "http"), log: func(l *slog.Logger) { l.Info("logged") }},
+		{name: "debug of an unconfigured logger", logger: Named(logger, "lifecycle"), log: func(l *slog.Logger) { l.Debug("logged") }},
+		{name: "info of an unconfigured logger", logger: Named(logger, "lifecycle"), log: func(l *slog.Logger) { l.Info("logged") }, want: true},
+		{name: "renamed logger", logger: Named(Named(logger, "http"), "kafka"), log: func(l *slog.Logger) { l.Debug("logged") }, want: true},
+		{
+			name:   "record naming its logger",
+			logger: logger,
+			log:    func(l *slog.Logger) { l.Debug("logged", slog.String(LoggerKey, "kafka")) },
+			want:   true,
+		},
+		{
+			name:   "record naming a logger with a higher level",
+			logger: logger,
+			log:    func(l *slog.Logger) { l.Info("logged", slog.String(LoggerKey, "http")) },
+		},
+		{
+			name:   "record of a named logger naming another",
+			logger: Named(logger, "http"),
+			log:    func(l *slog.Logger) { l.Debug("logged", slog.String(LoggerKey, "kafka")) },
+		},
+		{
+			name:   "logger named within a group",
+			logger: logger.WithGroup("event").With(slog.String(LoggerKey, "kafka")),
+			log:    func(l *slog.Logger) { l.Debug("logged") },
+		},
+		{
+			name:   "record naming its logger within a group",
+			logger: logger.WithGroup("event"),
+			log:    func(l *slog.Logger) { l.Debug("logged", slog.String(LoggerKey, "kafka")) },
+		},
+		{
+			name:   "named logger with a group",
+			logger: Named(logger, "kafka").WithGroup("event"),
+			log:    func(l *slog.Logger) { l.Debug("logged") },
+			want:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.log(tt.logger)
+
+			if got := len(logged()) == 1; got != tt.want {
+				t.Errorf("logged = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContextAttrs(t *testing.T) {
+	logger, logged := newLogger(t, &Options{})
+
+	ctx := WithProductId(WithRequestId(context.Background(), "request-1"), "product-1")
+	Named(logger, "http").InfoCtx(ctx, "Request served", slog.Int("status", 200))
+
+	records := logged()
+	if len(records) != 1 {
+		t.Fatalf("logged %d records, want 1", len(records))
+	}
+
+	for key, want := range map[string]any{RequestIdKey: "request-1", ProductIdKey: "product-1", LoggerKey: "http", "status": 200.0} {
+		if got := records[0][key]; got != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+
+	if got := RequestId(ctx); got != "request-1" {
+		t.Errorf("RequestId() = %q, want request-1", got)
+	}
+	if got := RequestId(context.Background()); got != "" {
+		t.Errorf("RequestId() without a request = %q", got)
+	}
+}
+
+func TestWithAttrsKeepsParents(t *testing.T) {
+	parent := WithAttrs(context.Background(), slog.String("a", "1"))
+	first := WithAttrs(parent, slog.String("b", "2"))
+	second := WithAttrs(parent, slog.String("c", "3"))
+
+	if got, want := Attrs(first), []slog.Attr{slog.String("a", "1"), slog.String("b", "2")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Attrs(first) = %v, want %v", got, want)
+	}
+	if got, want := Attrs(second), []slog.Attr{slog.String("a", "1"), slog.String("c", "3")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Attrs(second) = %v, want %v", got, want)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	for _, opt := range []*Options{
+		{Format: "xml"},
+		{Level: "verbose"},
+		{Levels: map[string]string{"kafka": "loud"}},
+	} {
+		if _, err := New(&bytes.Buffer{}, opt); err == nil {
+			t.Errorf("New(%+v) succeeded", opt)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for s, want := range map[string]slog.Level{"": slog.LevelInfo, "debug": slog.LevelDebug, "WARN": slog.LevelWarn, "error": slog.LevelError} {
+		if got, err := ParseLevel(s); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+}
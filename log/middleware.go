@@ -0,0 +1,60 @@
+package log
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"golang.org/x/exp/slog"
+)
+
+// RequestIdHeader carries the ID of a request. An ID sent by the client is kept, so requests can be
+// correlated across services, otherwise one is generated. It is echoed in the response.
+const RequestIdHeader = "X-Request-Id"
+
+// Middleware adds the request ID to the context of requests and logs a record for every response.
+// Records of failed requests are logged as errors.
+func Middleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIdHeader)
+			if id == "" {
+				id = uuid.New().String()
+			}
+			w.Header().Set(RequestIdHeader, id)
+
+			ctx := WithRequestId(r.Context(), id)
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+
+			route := r.URL.Path
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+
+			logger.LogAttrs(ctx, level, "Request served",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("route", route),
+				slog.Int("status", status),
+				slog.Int("bytes", ww.BytesWritten()),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("remote", r.RemoteAddr),
+			)
+		})
+	}
+}
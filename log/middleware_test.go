@@ -0,0 +1,58 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	logger, logged := newLogger(t, &Options{})
+
+	var seen string
+	handler := Middleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestId(r.Context())
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	tests := []struct {
+		name      string
+		path      string
+		requestId string
+		level     string
+		status    float64
+	}{
+		{name: "generated request id", path: "/ok", level: "INFO", status: http.StatusOK},
+		{name: "request id of the client", path: "/ok", requestId: "request-1", level: "INFO", status: http.StatusOK},
+		{name: "failed request", path: "/fail", requestId: "request-2", level: "ERROR", status: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.requestId != "" {
+				req.Header.Set(RequestIdHeader, tt.requestId)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(RequestIdHeader)
+			if id == "" || id != seen || (tt.requestId != "" && id != tt.requestId) {
+				t.Errorf("echoed request id %q, handler saw %q, client sent %q", id, seen, tt.requestId)
+			}
+
+			records := logged()
+			if len(records) != 1 {
+				t.Fatalf("logged %d records, want 1", len(records))
+			}
+
+			r := records[0]
+			if r["level"] != tt.level || r["status"] != tt.status || r[RequestIdKey] != id || r["path"] != tt.path {
+				t.Errorf("logged %v", r)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/giornetta/microshop/events"
+)
+
+var (
+	publishDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "microshop_events_publish_duration_seconds",
+		Help:    "Duration of event publications, by topic and event type.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"topic", "type"})
+
+	publishFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "microshop_events_publish_failures_total",
+		Help: "Number of event publications that failed, by topic and event type.",
+	}, []string{"topic", "type"})
+
+	handleDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "microshop_events_handle_duration_seconds",
+		Help:    "Duration of event handling, by topic and event type.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"topic", "type"})
+
+	handleFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "microshop_events_handle_failures_total",
+		Help: "Number of events whose handling failed, by topic and event type.",
+	}, []string{"topic", "type"})
+)
+
+type instrumentingPublisher struct {
+	publisher events.Publisher
+}
+
+// NewPublisher returns a Publisher recording the latency and failures of the given one.
+func NewPublisher(publisher events.Publisher) events.Publisher {
+	return &instrumentingPublisher{
+		publisher: publisher,
+	}
+}
+
+func (p *instrumentingPublisher) Publish(e events.Event, ctx context.Context) error {
+	start := time.Now()
+	err := p.publisher.Publish(e, ctx)
+
+	publishDuration.WithLabelValues(e.Topic().String(), e.Type().String()).Observe(time.Since(start).Seconds())
+	if err != nil {
+		publishFailures.WithLabelValues(e.Topic().String(), e.Type().String()).Inc()
+	}
+
+	return err
+}
+
+// PublishBatch publishes the events at once when the wrapped publisher can, observing the duration
+// of the whole batch for each event.
+func (p *instrumentingPublisher) PublishBatch(evts []events.Event, ctx context.Context) []error {
+	start := time.Now()
+	errs := events.PublishBatch(p.publisher, evts, ctx)
+	elapsed := time.Since(start).Seconds()
+
+	for i, e := range evts {
+		publishDuration.WithLabelValues(e.Topic().String(), e.Type().String()).Observe(elapsed)
+		if errs[i] != nil {
+			publishFailures.WithLabelValues(e.Topic().String(), e.Type().String()).Inc()
+		}
+	}
+
+	return errs
+}
+
+type instrumentingHandler struct {
+	handler events.Handler
+}
+
+// NewEventHandler returns a Handler recording the duration and failures of the given one.
+func NewEventHandler(handler events.Handler) events.Handler {
+	return &instrumentingHandler{
+		handler: handler,
+	}
+}
+
+func (h *instrumentingHandler) Handle(e events.Event, ctx context.Context) error {
+	start := time.Now()
+	err := h.handler.Handle(e, ctx)
+
+	handleDuration.WithLabelValues(e.Topic().String(), e.Type().String()).Observe(time.Since(start).Seconds())
+	if err != nil {
+		handleFailures.WithLabelValues(e.Topic().String(), e.Type().String()).Inc()
+	}
+
+	return err
+}
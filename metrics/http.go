@@ -0,0 +1,80 @@
+// Package metrics exposes Prometheus metrics for the HTTP servers, Kafka clients and Postgres pools of the services.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "microshop_http_requests_total",
+		Help: "Number of HTTP requests handled, by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "microshop_http_request_duration_seconds",
+		Help:    "Duration of HTTP requests, by method, route pattern and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// Handler serves the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Middleware records the rate, errors and duration of requests, labelled by the chi route pattern
+// that handled them, so that path parameters don't blow up the cardinality of the metrics.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		labels := prometheus.Labels{
+			"method": r.Method,
+			"route":  route,
+			"status": strconv.Itoa(status),
+		}
+
+		httpRequests.With(labels).Inc()
+		httpDuration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
+
+// Register adds the collectors to the registry served by Handler.
+func Register(collectors ...prometheus.Collector) error {
+	for _, c := range collectors {
+		if err := prometheus.Register(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Unregister removes the collectors added by Register.
+func Unregister(collectors ...prometheus.Collector) {
+	for _, c := range collectors {
+		prometheus.Unregister(c)
+	}
+}
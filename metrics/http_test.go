@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddleware(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/products/{productId}", func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, "productId") == "missing" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+
+		// Handlers writing a body without a status code answer 200.
+		w.Write([]byte("{}"))
+	})
+	r.Post("/products", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/products/8f3c1a2e"},
+		{http.MethodGet, "/products/0b9d7e4f"},
+		{http.MethodGet, "/products/missing"},
+		{http.MethodPost, "/products"},
+		{http.MethodGet, "/customers"},
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, nil))
+	}
+
+	tests := []struct {
+		method, route, status string
+		want                  float64
+	}{
+		{http.MethodGet, "/products/{productId}", "200", 2},
+		{http.MethodGet, "/products/{productId}", "404", 1},
+		{http.MethodPost, "/products", "201", 1},
+		{http.MethodGet, "unmatched", "404", 1},
+	}
+
+	for _, tt := range tests {
+		labels := prometheus.Labels{"method": tt.method, "route": tt.route, "status": tt.status}
+		if got := testutil.ToFloat64(httpRequests.With(labels)); got != tt.want {
+			t.Errorf("counted %v requests for %v, want %v", got, labels, tt.want)
+		}
+	}
+
+	// Requests are only labelled by route pattern, never by the paths that were requested.
+	if n := testutil.CollectAndCount(httpRequests); n != len(tests) {
+		t.Errorf("recorded %d request series, want %d", n, len(tests))
+	}
+	if n := testutil.CollectAndCount(httpDuration); n != len(tests) {
+		t.Errorf("recorded %d duration series, want %d", n, len(tests))
+	}
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+const lagTimeout = 5 * time.Second
+
+type lagCollector struct {
+	admin *kadm.Client
+	group string
+	desc  *prometheus.Desc
+}
+
+// NewLagCollector returns a Collector querying the lag of the consumer group every time metrics are scraped.
+// The group is a constant label, so the lag of every consumer group in the process can be collected.
+func NewLagCollector(client *kgo.Client, group string) prometheus.Collector {
+	return &lagCollector{
+		admin: kadm.NewClient(client),
+		group: group,
+		desc: prometheus.NewDesc(
+			"microshop_kafka_consumer_lag",
+			"Number of records the consumer group still has to process, by topic and partition.",
+			[]string{"topic", "partition"}, prometheus.Labels{"group": group},
+		),
+	}
+}
+
+func (c *lagCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *lagCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), lagTimeout)
+	defer cancel()
+
+	lags, err := c.admin.Lag(ctx, c.group)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+
+	lags.Each(func(l kadm.DescribedGroupLag) {
+		if l.Error() != nil {
+			ch <- prometheus.NewInvalidMetric(c.desc, l.Error())
+			return
+		}
+
+		for _, lag := range l.Lag.Sorted() {
+			if lag.Err != nil {
+				continue
+			}
+
+			ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(lag.Lag),
+				lag.Topic, strconv.Itoa(int(lag.Partition)))
+		}
+	})
+}
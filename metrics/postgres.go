@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquired      *prometheus.Desc
+	idle          *prometheus.Desc
+	total         *prometheus.Desc
+	max           *prometheus.Desc
+	acquires      *prometheus.Desc
+	acquireTime   *prometheus.Desc
+	emptyAcquires *prometheus.Desc
+	canceled      *prometheus.Desc
+}
+
+// NewPoolCollector returns a Collector exposing the statistics of the pgx pool, labelled with the given database name.
+func NewPoolCollector(pool *pgxpool.Pool, database string) prometheus.Collector {
+	labels := prometheus.Labels{"database": database}
+
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc("microshop_pgxpool_"+name, help, nil, labels)
+	}
+
+	return &poolCollector{
+		pool:          pool,
+		acquired:      desc("acquired_conns", "Number of connections currently acquired from the pool."),
+		idle:          desc("idle_conns", "Number of idle connections in the pool."),
+		total:         desc("total_conns", "Number of connections in the pool."),
+		max:           desc("max_conns", "Maximum size of the pool."),
+		acquires:      desc("acquires_total", "Number of successful acquires from the pool."),
+		acquireTime:   desc("acquire_duration_seconds_total", "Total time spent acquiring connections from the pool."),
+		emptyAcquires: desc("empty_acquires_total", "Number of acquires that had to wait for a connection."),
+		canceled:      desc("canceled_acquires_total", "Number of acquires canceled by their context."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.acquired, c.idle, c.total, c.max, c.acquires, c.acquireTime, c.emptyAcquires, c.canceled} {
+		ch <- d
+	}
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(c.acquired, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireTime, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceled, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+}
@@ -0,0 +1,26 @@
+package notify
+
+import (
+	"context"
+
+	"golang.org/x/exp/slog"
+)
+
+type logSink struct {
+	logger *slog.Logger
+}
+
+// NewLogSink returns a Sink writing notifications to logger, at the warn level.
+func NewLogSink(logger *slog.Logger) Sink {
+	return &logSink{logger: logger}
+}
+
+func (s *logSink) Notify(n *Notification, ctx context.Context) error {
+	attrs := []any{slog.String("subject", n.Subject), slog.String("body", n.Body)}
+	if n.Event != nil {
+		attrs = append(attrs, slog.String("event_type", n.Event.Type().String()), slog.String("event_key", n.Event.Key().String()))
+	}
+
+	s.logger.WarnCtx(ctx, "Notification", attrs...)
+	return nil
+}
@@ -0,0 +1,68 @@
+// Package notify delivers notifications to people, such as the alerts raised by low stock,
+// through sinks writing them to the log, posting them to a webhook or sending them by email.
+package notify
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/giornetta/microshop/events"
+)
+
+// Notification is a message meant for people, raised by an event.
+type Notification struct {
+	Subject string
+	Body    string
+	// Event is the event the notification was raised by.
+	Event events.Event
+}
+
+// Sink delivers notifications. Sinks are called by event handlers, so they should not block for
+// longer than ctx allows.
+type Sink interface {
+	Notify(n *Notification, ctx context.Context) error
+}
+
+type multiSink []Sink
+
+// Multi returns a Sink delivering notifications to every one of sinks, even when some of them fail.
+func Multi(sinks ...Sink) Sink {
+	return multiSink(sinks)
+}
+
+func (m multiSink) Notify(n *Notification, ctx context.Context) error {
+	var errs []error
+	for _, s := range m {
+		if err := s.Notify(n, ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+type timeoutSink struct {
+	sink    Sink
+	timeout time.Duration
+}
+
+// WithTimeout returns a Sink giving up on the notifications that sink does not deliver within timeout.
+// A timeout of zero leaves sink unbounded.
+func WithTimeout(sink Sink, timeout time.Duration) Sink {
+	if timeout <= 0 {
+		return sink
+	}
+
+	return &timeoutSink{
+		sink:    sink,
+		timeout: timeout,
+	}
+}
+
+func (s *timeoutSink) Notify(n *Notification, ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.sink.Notify(n, ctx)
+}
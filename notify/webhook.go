@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/giornetta/microshop/events"
+)
+
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink returns a Sink posting notifications to url as WebhookPayload JSON objects,
+// failing unless it responds with a 2xx status.
+func NewWebhookSink(url string, client *http.Client) Sink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &webhookSink{
+		url:    url,
+		client: client,
+	}
+}
+
+// WebhookPayload is the body of the requests of webhook sinks.
+type WebhookPayload struct {
+	Subject   string       `json:"subject"`
+	Body      string       `json:"body"`
+	EventType events.Type  `json:"event_type,omitempty"`
+	Event     events.Event `json:"event,omitempty"`
+}
+
+func (s *webhookSink) Notify(n *Notification, ctx context.Context) error {
+	payload := WebhookPayload{
+		Subject: n.Subject,
+		Body:    n.Body,
+		Event:   n.Event,
+	}
+	if n.Event != nil {
+		payload.EventType = n.Event.Type()
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not post notification: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("could not post notification: webhook responded with status %d", res.StatusCode)
+	}
+
+	return nil
+}
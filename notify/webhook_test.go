@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giornetta/microshop/events"
+)
+
+var deleted = &Notification{
+	Subject: "Keyboard was deleted",
+	Body:    "Keyboard is no longer sold.",
+	Event:   events.ProductDeleted{ProductEvent: events.ProductEvent{ProductId: "2f9c5d3a-7b1e-4c8a-9e6f-0d4b3a2c1e5f"}},
+}
+
+func TestWebhookSink(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got a %s request of %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("could not decode payload: %v", err)
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	if err := NewWebhookSink(server.URL, nil).Notify(deleted, context.Background()); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	if got["subject"] != deleted.Subject || got["body"] != deleted.Body || got["event_type"] != events.ProductDeletedType.String() {
+		t.Errorf("posted %v, want the notification and its event type", got)
+	}
+	if event, ok := got["event"].(map[string]any); !ok || event["product_id"] != "2f9c5d3a-7b1e-4c8a-9e6f-0d4b3a2c1e5f" {
+		t.Errorf("posted event %v, want the deleted product", got["event"])
+	}
+}
+
+func TestWebhookSinkStatus(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(status)
+			}))
+			defer server.Close()
+
+			err := NewWebhookSink(server.URL, nil).Notify(deleted, context.Background())
+			if err == nil || !strings.Contains(err.Error(), "responded with status") {
+				t.Errorf("got error %v, want one for the %d response", err, status)
+			}
+		})
+	}
+}
+
+func TestWebhookSinkTimeout(t *testing.T) {
+	// The webhook hangs until the test ends, long after the sink gives up on it.
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	sink := WithTimeout(NewWebhookSink(server.URL, nil), 50*time.Millisecond)
+
+	start := time.Now()
+	err := sink.Notify(deleted, context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Notify returned after %v, long after its timeout", elapsed)
+	}
+}
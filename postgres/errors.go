@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	uniqueViolationCode    = "23505"
+	exclusionViolationCode = "23P01"
+)
+
+// UniqueViolation reports whether err comes from a statement violating a unique constraint,
+// and whether that constraint is the primary key of its table.
+func UniqueViolation(err error) (violation bool, primaryKey bool) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolationCode {
+		return false, false
+	}
+
+	// Tables copied with LIKE, such as shadow tables, get primary keys named <table>_pkey<n>.
+	return true, strings.Contains(pgErr.ConstraintName, "_pkey")
+}
+
+// UniqueViolationOn reports whether err comes from a statement violating the unique constraint
+// of column, relying on Postgres naming such constraints <table>_<column>_key<n>.
+func UniqueViolationOn(err error, column string) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolationCode {
+		return false
+	}
+
+	return strings.Contains(pgErr.ConstraintName, "_"+column+"_key")
+}
+
+// ExclusionViolation reports whether err comes from a statement violating an exclusion constraint.
+func ExclusionViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == exclusionViolationCode
+}
@@ -0,0 +1,16 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/giornetta/microshop/health"
+)
+
+// NewHealthChecker returns a Checker pinging the database through the pool.
+func NewHealthChecker(pool *pgxpool.Pool) health.Checker {
+	return health.NewChecker("postgres", func(ctx context.Context) error {
+		return pool.Ping(ctx)
+	})
+}
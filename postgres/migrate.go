@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// MigrationStatus describes the schema version of a database.
+type MigrationStatus struct {
+	// Version is the applied version, zero when no migration was ever applied.
+	Version uint
+	// Dirty reports that a migration failed halfway and the schema must be fixed, then forced to a version.
+	Dirty   bool
+	Latest  uint
+	Pending []uint
+}
+
+// Migrator applies the migrations embedded in a service binary.
+type Migrator struct {
+	source source.Driver
+	m      *migrate.Migrate
+}
+
+// NewMigrator returns a Migrator applying the migrations directory of migrations to the database at dbUrl.
+func NewMigrator(migrations fs.FS, dbUrl string) (*Migrator, error) {
+	src, err := iofs.New(migrations, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("could not read migrations: %w", err)
+	}
+
+	dbUrl, err = migrationURL(dbUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", src, dbUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{source: src, m: m}, nil
+}
+
+// migrationURL removes the pgxpool parameters from dbUrl, which the migration driver would send to the server.
+func migrationURL(dbUrl string) (string, error) {
+	u, err := url.Parse(dbUrl)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	for k := range query {
+		if strings.HasPrefix(k, "pool_") {
+			query.Del(k)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up() error {
+	return ignoreNoChange(m.m.Up())
+}
+
+// Down reverts the last steps migrations, or every migration when steps is zero.
+func (m *Migrator) Down(steps int) error {
+	if steps == 0 {
+		return ignoreNoChange(m.m.Down())
+	}
+
+	return ignoreNoChange(m.m.Steps(-steps))
+}
+
+// To migrates up or down to version.
+func (m *Migrator) To(version uint) error {
+	return ignoreNoChange(m.m.Migrate(version))
+}
+
+// Force sets the schema version without running migrations, clearing the dirty flag.
+// A negative version marks the database as having no migration applied.
+func (m *Migrator) Force(version int) error {
+	return m.m.Force(version)
+}
+
+func (m *Migrator) Status() (*MigrationStatus, error) {
+	var status MigrationStatus
+
+	version, dirty, err := m.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, err
+	}
+	status.Version, status.Dirty = version, dirty
+
+	v, err := m.source.First()
+	for err == nil {
+		if v > status.Version {
+			status.Pending = append(status.Pending, v)
+		}
+		status.Latest = v
+
+		v, err = m.source.Next(v)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.m.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+// Migrate applies every pending migration of migrations to the database at dbUrl.
+func Migrate(migrations fs.FS, dbUrl string) error {
+	m, err := NewMigrator(migrations, dbUrl)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	return m.Up()
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ShadowTable returns the name of the table used to rebuild the given projection.
+func ShadowTable(table string) string {
+	return table + "_shadow"
+}
+
+// ResetShadowTable (re)creates an empty shadow table with the same columns,
+// constraints and indexes of the given one.
+func ResetShadowTable(ctx context.Context, pool *pgxpool.Pool, table string) error {
+	shadow := pgx.Identifier{ShadowTable(table)}.Sanitize()
+
+	if _, err := pool.Exec(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s;", shadow)); err != nil {
+		return err
+	}
+
+	if _, err := pool.Exec(ctx, fmt.Sprintf(
+		"CREATE TABLE %s (LIKE %s INCLUDING ALL);", shadow, pgx.Identifier{table}.Sanitize(),
+	)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SwapShadowTables atomically replaces the given tables with their shadows, dropping the old ones,
+// so that the projections spanning several tables are swapped together.
+func SwapShadowTables(ctx context.Context, pool *pgxpool.Pool, tables ...string) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, table := range tables {
+		live := pgx.Identifier{table}.Sanitize()
+		old := pgx.Identifier{table + "_old"}.Sanitize()
+
+		for _, stmt := range []string{
+			fmt.Sprintf("LOCK TABLE %s IN ACCESS EXCLUSIVE MODE;", live),
+			fmt.Sprintf("DROP TABLE IF EXISTS %s;", old),
+			fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", live, old),
+			fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", pgx.Identifier{ShadowTable(table)}.Sanitize(), live),
+			fmt.Sprintf("DROP TABLE %s;", old),
+		} {
+			if _, err := tx.Exec(ctx, stmt); err != nil {
+				return err
+			}
+		}
+
+		if err := renameShadowIndexes(ctx, tx, table); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// renameShadowIndexes gives the indexes of a swapped table, which Postgres named after its shadow when copying them,
+// the names they had in the table it replaced, so that they are free for the next shadow and are found by the
+// constraint names errors are told apart by. Renaming the index of a constraint renames the constraint as well.
+func renameShadowIndexes(ctx context.Context, tx pgx.Tx, table string) error {
+	shadow := ShadowTable(table)
+
+	rows, err := tx.Query(ctx, `
+		SELECT indexname FROM pg_indexes
+		WHERE schemaname = current_schema() AND tablename = $1 AND starts_with(indexname, $2);`,
+		table, shadow,
+	)
+	if err != nil {
+		return err
+	}
+
+	indexes, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+
+	for _, index := range indexes {
+		if _, err := tx.Exec(ctx, fmt.Sprintf(
+			"ALTER INDEX %s RENAME TO %s;",
+			pgx.Identifier{index}.Sanitize(), pgx.Identifier{table + strings.TrimPrefix(index, shadow)}.Sanitize(),
+		)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package postgres_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/giornetta/microshop/postgres"
+	"github.com/giornetta/microshop/postgres/postgrestest"
+)
+
+var migrations = fstest.MapFS{
+	"migrations/1_items.up.sql": {Data: []byte(`
+		CREATE TABLE items (item_id INT PRIMARY KEY, name TEXT UNIQUE NOT NULL, price REAL CHECK (price > 0));
+		CREATE TABLE labels (item_id INT NOT NULL, label TEXT NOT NULL, PRIMARY KEY (item_id, label));
+		CREATE INDEX labels_label ON labels (label);
+	`)},
+	"migrations/1_items.down.sql": {Data: []byte(`DROP TABLE labels; DROP TABLE items;`)},
+}
+
+// TestResetShadowTable runs against the server given by postgrestest.URLEnv, and is skipped without one.
+func TestResetShadowTable(t *testing.T) {
+	pool := postgrestest.Database(t, migrations)
+	ctx := context.Background()
+
+	exec(t, pool, `INSERT INTO items VALUES (1, 'Keyboard', 49.5);`)
+
+	for i := 0; i < 2; i++ {
+		if err := postgres.ResetShadowTable(ctx, pool, "items"); err != nil {
+			t.Fatalf("ResetShadowTable: %v", err)
+		}
+
+		if n := count(t, pool, "items_shadow"); n != 0 {
+			t.Fatalf("reset shadow table holds %d rows, want none", n)
+		}
+
+		exec(t, pool, `INSERT INTO items_shadow VALUES (1, 'Keyboard', 49.5);`)
+	}
+
+	// The shadow table has the constraints of the live one.
+	for _, stmt := range []string{
+		`INSERT INTO items_shadow VALUES (1, 'Mouse', 19.5);`,
+		`INSERT INTO items_shadow VALUES (2, 'Keyboard', 19.5);`,
+		`INSERT INTO items_shadow VALUES (2, 'Mouse', 0);`,
+	} {
+		if _, err := pool.Exec(ctx, stmt); err == nil {
+			t.Errorf("%s succeeded on the shadow table", stmt)
+		}
+	}
+
+	_, err := pool.Exec(ctx, `INSERT INTO items_shadow VALUES (1, 'Mouse', 19.5);`)
+	if violation, primaryKey := postgres.UniqueViolation(err); !violation || !primaryKey {
+		t.Errorf("duplicate id on the shadow table: got %v, want a primary key violation", err)
+	}
+
+	if count(t, pool, "items") != 1 {
+		t.Error("ResetShadowTable changed the live table")
+	}
+}
+
+func TestSwapShadowTables(t *testing.T) {
+	pool := postgrestest.Database(t, migrations)
+	ctx := context.Background()
+
+	want := indexes(t, pool)
+
+	exec(t, pool, `INSERT INTO items VALUES (1, 'Keyboard', 49.5);`)
+
+	// Swapping twice checks that the first swap leaves the index names free for the next shadow tables.
+	for i := 1; i <= 2; i++ {
+		for _, table := range []string{"items", "labels"} {
+			if err := postgres.ResetShadowTable(ctx, pool, table); err != nil {
+				t.Fatalf("ResetShadowTable(%s): %v", table, err)
+			}
+		}
+
+		exec(t, pool, `INSERT INTO items_shadow VALUES (2, 'Mouse', 19.5), (3, 'Monitor', 199);`)
+		exec(t, pool, `INSERT INTO labels_shadow VALUES (2, 'wireless');`)
+
+		if err := postgres.SwapShadowTables(ctx, pool, "items", "labels"); err != nil {
+			t.Fatalf("swap %d: %v", i, err)
+		}
+
+		if n := count(t, pool, "items"); n != 2 {
+			t.Fatalf("swap %d: items holds %d rows, want those of its shadow", i, n)
+		}
+		if n := count(t, pool, "labels"); n != 1 {
+			t.Fatalf("swap %d: labels holds %d rows, want those of its shadow", i, n)
+		}
+
+		if got := indexes(t, pool); !reflect.DeepEqual(got, want) {
+			t.Fatalf("swap %d: indexes are %v, want %v", i, got, want)
+		}
+
+		var shadows int
+		if err := pool.QueryRow(ctx, `
+			SELECT count(*) FROM pg_tables
+			WHERE schemaname = current_schema() AND tablename IN ('items_shadow', 'labels_shadow', 'items_old', 'labels_old');`,
+		).Scan(&shadows); err != nil {
+			t.Fatal(err)
+		}
+		if shadows != 0 {
+			t.Fatalf("swap %d left %d shadow or old tables behind", i, shadows)
+		}
+	}
+
+	_, err := pool.Exec(ctx, `INSERT INTO items VALUES (4, 'Mouse', 19.5);`)
+	if !postgres.UniqueViolationOn(err, "name") {
+		t.Errorf("duplicate name after the swap: got %v, want a violation on name", err)
+	}
+}
+
+func TestSwapShadowTablesRollsBack(t *testing.T) {
+	pool := postgrestest.Database(t, migrations)
+	ctx := context.Background()
+
+	if err := postgres.ResetShadowTable(ctx, pool, "items"); err != nil {
+		t.Fatal(err)
+	}
+	exec(t, pool, `INSERT INTO items_shadow VALUES (1, 'Keyboard', 49.5);`)
+
+	// labels has no shadow, so no table is swapped.
+	if err := postgres.SwapShadowTables(ctx, pool, "items", "labels"); err == nil {
+		t.Fatal("swapping a table without a shadow succeeded")
+	}
+
+	if count(t, pool, "items") != 0 || count(t, pool, "items_shadow") != 1 {
+		t.Error("a failed swap replaced items")
+	}
+}
+
+func exec(t *testing.T, pool *pgxpool.Pool, sql string) {
+	t.Helper()
+
+	if _, err := pool.Exec(context.Background(), sql); err != nil {
+		t.Fatalf("%s: %v", sql, err)
+	}
+}
+
+func count(t *testing.T, pool *pgxpool.Pool, table string) int {
+	t.Helper()
+
+	var n int
+	if err := pool.QueryRow(context.Background(), "SELECT count(*) FROM "+pgx.Identifier{table}.Sanitize()).Scan(&n); err != nil {
+		t.Fatalf("could not count the rows of %s: %v", table, err)
+	}
+
+	return n
+}
+
+// indexes returns the names of the indexes of items and labels.
+func indexes(t *testing.T, pool *pgxpool.Pool) []string {
+	t.Helper()
+
+	rows, err := pool.Query(context.Background(), `
+		SELECT indexname FROM pg_indexes
+		WHERE schemaname = current_schema() AND tablename IN ('items', 'labels')
+		ORDER BY indexname;`,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return names
+}
@@ -0,0 +1,405 @@
+package products
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/eventstore"
+)
+
+// Aggregate is the authoritative state of a product, rebuilt from its event stream.
+// Commands are validated against it, and the events they produce are applied to it
+// before being appended to the stream.
+type Aggregate struct {
+	Product
+	Created bool
+	Deleted bool
+
+	// Version is the version of the stream the aggregate was loaded from.
+	Version int
+
+	changes []events.Event
+}
+
+// Exists reports whether the product was created and not deleted.
+func (a *Aggregate) Exists() bool {
+	return a.Created && !a.Deleted
+}
+
+// Changes returns the events recorded since the aggregate was loaded.
+func (a *Aggregate) Changes() []events.Event {
+	return a.changes
+}
+
+// Apply mutates the state of the aggregate according to the event.
+func (a *Aggregate) Apply(evt events.Event) {
+	switch e := evt.(type) {
+	case events.ProductCreated:
+		a.Product = Product{
+			Id:               ProductId(e.ProductId),
+			Name:             e.Name,
+			Description:      e.Description,
+			Price:            e.Price,
+			Amount:           e.Amount,
+			SKU:              e.SKU,
+			ReorderThreshold: e.ReorderThreshold,
+			Stock:            stockFromEvent(e.Stock, e.Amount),
+		}
+		a.Created = true
+		a.Deleted = false
+	case events.ProductUpdated:
+		a.Product = Product{
+			Id:               ProductId(e.ProductId),
+			Name:             e.Name,
+			Description:      e.Description,
+			Price:            e.Price,
+			Amount:           e.Amount,
+			SKU:              e.SKU,
+			ReorderThreshold: e.ReorderThreshold,
+			Stock:            stockFromEvent(e.Stock, e.Amount),
+		}
+		a.Created = true
+	case events.ProductDeleted:
+		a.Deleted = true
+	}
+}
+
+func (a *Aggregate) record(evt events.Event) {
+	a.Apply(evt)
+	a.changes = append(a.changes, evt)
+}
+
+func (a *Aggregate) updated() events.ProductUpdated {
+	return events.ProductUpdated{
+		ProductEvent:     events.ProductEvent{ProductId: a.Id.String()},
+		Name:             a.Name,
+		Description:      a.Description,
+		Price:            a.Price,
+		Amount:           a.Amount,
+		SKU:              a.SKU,
+		ReorderThreshold: a.ReorderThreshold,
+		Stock:            stockToEvent(a.Stock),
+	}
+}
+
+// recordUpdate records the current state of the product followed by evts, which describe the change,
+// and by the stock alerts raised by the change from the before state.
+func (a *Aggregate) recordUpdate(before Product, evts ...events.Event) {
+	a.record(a.updated())
+
+	for _, evt := range evts {
+		a.record(evt)
+	}
+
+	for _, alert := range stockAlerts(&before, &a.Product) {
+		a.record(alert)
+	}
+}
+
+// stockAlerts returns the alerts raised by a change of the product from before to after: OutOfStock when it runs out,
+// and StockLow when it reaches its reorder threshold otherwise. Products already low on stock raise no StockLow.
+func stockAlerts(before, after *Product) []events.Event {
+	switch {
+	case after.OutOfStock() && !before.OutOfStock():
+		return []events.Event{events.ProductOutOfStock{
+			ProductEvent: events.ProductEvent{ProductId: after.Id.String()},
+			Name:         after.Name,
+		}}
+	case after.StockLow() && !after.OutOfStock() && !before.StockLow():
+		return []events.Event{events.ProductStockLow{
+			ProductEvent:     events.ProductEvent{ProductId: after.Id.String()},
+			Name:             after.Name,
+			Amount:           after.Amount,
+			ReorderThreshold: after.ReorderThreshold,
+		}}
+	default:
+		return nil
+	}
+}
+
+// Create records the creation of the product.
+func (a *Aggregate) Create(req *CreateProductRequest) {
+	a.record(events.ProductCreated{
+		ProductEvent:     events.ProductEvent{ProductId: a.Id.String()},
+		Name:             req.Name,
+		Description:      req.Description,
+		Price:            req.Price,
+		Amount:           req.Amount,
+		SKU:              req.SKU,
+		ReorderThreshold: req.ReorderThreshold,
+		Stock:            stockToEvent(defaultStock(req.Amount)),
+	})
+	a.record(a.priceChanged(0, ""))
+}
+
+// Update records the changes to the details of the product.
+func (a *Aggregate) Update(req *UpdateProductRequest) {
+	before := a.Product
+
+	if req.Name != "" {
+		a.Name = req.Name
+	}
+
+	if req.Description != "" {
+		a.Description = req.Description
+	}
+
+	if req.Price != 0 {
+		a.Price = req.Price
+	}
+
+	if req.SKU != "" {
+		a.SKU = req.SKU
+	}
+
+	if req.ReorderThreshold != nil {
+		a.ReorderThreshold = *req.ReorderThreshold
+	}
+
+	if a.Price == before.Price {
+		a.recordUpdate(before)
+		return
+	}
+
+	a.recordUpdate(before, a.priceChanged(before.Price, ""))
+}
+
+// ChangePrice records the change of the price of the product, applied by a price schedule when it has one.
+// Nothing is recorded if the product already has the price, so that schedules can be applied again.
+func (a *Aggregate) ChangePrice(req *ChangePriceRequest) {
+	if a.Price == req.Price {
+		return
+	}
+
+	before := a.Product
+	a.Price = req.Price
+
+	a.recordUpdate(before, a.priceChanged(before.Price, req.ScheduleId))
+}
+
+// priceChanged returns the event following the change of the price from previous to the current one.
+// The time is truncated to microseconds, the precision of the price history.
+func (a *Aggregate) priceChanged(previous float32, schedule PriceScheduleId) events.ProductPriceChanged {
+	return events.ProductPriceChanged{
+		ProductEvent:  events.ProductEvent{ProductId: a.Id.String()},
+		Price:         a.Price,
+		PreviousPrice: previous,
+		ChangedAt:     time.Now().UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+		ScheduleId:    schedule.String(),
+	}
+}
+
+// Restock records an increase of the stock of a warehouse.
+func (a *Aggregate) Restock(req *RestockProductRequest) {
+	// Restocks only add items, so they cannot fail.
+	_ = a.AdjustStock(req.WarehouseId, req.Amount)
+}
+
+// AdjustStock records a change of the stock of a warehouse by quantity, which can be negative but
+// cannot take the stock of the warehouse below zero.
+func (a *Aggregate) AdjustStock(warehouse WarehouseId, quantity int) error {
+	before := a.Product
+	if err := a.addStock(warehouse, quantity); err != nil {
+		return err
+	}
+
+	a.recordUpdate(before, events.ProductStockChanged{
+		ProductEvent: events.ProductEvent{ProductId: a.Id.String()},
+		WarehouseId:  warehouse.String(),
+		Quantity:     quantity,
+		Amount:       a.StockIn(warehouse),
+	})
+
+	return nil
+}
+
+// TransferStock records the move of items from a warehouse to another, failing with *ErrInsufficientStock
+// if the source warehouse does not hold enough of them.
+func (a *Aggregate) TransferStock(req *TransferStockRequest) error {
+	before := a.Product
+	if err := a.addStock(req.From, -req.Quantity); err != nil {
+		return err
+	}
+	_ = a.addStock(req.To, req.Quantity)
+
+	a.recordUpdate(before, events.ProductStockTransferred{
+		ProductEvent:    events.ProductEvent{ProductId: a.Id.String()},
+		FromWarehouseId: req.From.String(),
+		ToWarehouseId:   req.To.String(),
+		Quantity:        req.Quantity,
+	})
+
+	return nil
+}
+
+// Delete records the deletion of the product.
+func (a *Aggregate) Delete() {
+	a.record(events.ProductDeleted{
+		ProductEvent: events.ProductEvent{ProductId: a.Id.String()},
+	})
+}
+
+type AggregateRepository interface {
+	// Load rebuilds the aggregate of the given product from its latest snapshot and the events following it.
+	Load(id ProductId, ctx context.Context) (*Aggregate, error)
+	// Save appends the changes of the aggregate to its stream, failing if the stream
+	// was modified since the aggregate was loaded.
+	Save(aggregate *Aggregate, ctx context.Context) error
+	// SaveAll saves the changes of every aggregate, or of none of them if any stream
+	// was modified since its aggregate was loaded.
+	SaveAll(aggregates []*Aggregate, ctx context.Context) error
+}
+
+type aggregateRepository struct {
+	store         eventstore.Store
+	querier       ProductQuerier
+	snapshotEvery int
+	logger        *slog.Logger
+}
+
+// NewAggregateRepository returns an AggregateRepository backed by the event store, taking a snapshot
+// of the aggregates every snapshotEvery events. Products created before the event store was introduced
+// have no stream: their aggregates are bootstrapped from the projection queried through querier.
+// Snapshots that cannot be taken are logged with logger.
+func NewAggregateRepository(store eventstore.Store, querier ProductQuerier, snapshotEvery int, logger *slog.Logger) AggregateRepository {
+	return &aggregateRepository{
+		store:         store,
+		querier:       querier,
+		snapshotEvery: snapshotEvery,
+		logger:        logger,
+	}
+}
+
+type aggregateSnapshot struct {
+	Product Product `json:"product"`
+	Deleted bool    `json:"deleted"`
+}
+
+func (r *aggregateRepository) Load(id ProductId, ctx context.Context) (*Aggregate, error) {
+	a := &Aggregate{Product: Product{Id: id}}
+
+	snapshot, err := r.store.LoadSnapshot(id.String(), ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if snapshot != nil {
+		var state aggregateSnapshot
+		if err := json.Unmarshal(snapshot.State, &state); err != nil {
+			return nil, err
+		}
+
+		a.Product = state.Product
+		a.Created = true
+		// Snapshots taken before warehouses were introduced have no stock.
+		if len(a.Stock) == 0 {
+			a.Stock = defaultStock(a.Amount)
+		}
+		a.Deleted = state.Deleted
+		a.Version = snapshot.Version
+	}
+
+	stored, err := r.store.Load(id.String(), a.Version, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range stored {
+		a.Apply(e.Event)
+		a.Version = e.Version
+	}
+
+	if a.Version == 0 {
+		return r.bootstrap(id, ctx)
+	}
+
+	return a, nil
+}
+
+// bootstrap returns an aggregate holding the projected state of a product without a stream.
+// Its changes will start the stream, and since ProductUpdated carries the whole state of the
+// product, the stream alone is enough to rebuild it afterwards.
+func (r *aggregateRepository) bootstrap(id ProductId, ctx context.Context) (*Aggregate, error) {
+	a := &Aggregate{Product: Product{Id: id}}
+
+	p, err := r.querier.FindById(id, ctx)
+	if err != nil {
+		if _, ok := err.(*ErrNotFound); ok {
+			return a, nil
+		}
+
+		return nil, err
+	}
+
+	a.Product = *p
+	a.Created = true
+
+	return a, nil
+}
+
+func (r *aggregateRepository) Save(a *Aggregate, ctx context.Context) error {
+	return r.SaveAll([]*Aggregate{a}, ctx)
+}
+
+func (r *aggregateRepository) SaveAll(aggregates []*Aggregate, ctx context.Context) error {
+	var appends []eventstore.Append
+	for _, a := range aggregates {
+		if len(a.changes) > 0 {
+			appends = append(appends, eventstore.Append{AggregateId: a.Id.String(), ExpectedVersion: a.Version, Events: a.changes})
+		}
+	}
+
+	if len(appends) == 0 {
+		return nil
+	}
+
+	if err := r.store.AppendAll(appends, ctx); err != nil {
+		return err
+	}
+
+	for _, a := range aggregates {
+		r.saved(a, ctx)
+	}
+
+	return nil
+}
+
+// saved moves the aggregate to the version following its appended changes, and takes a snapshot of it
+// when it crosses a multiple of snapshotEvery.
+func (r *aggregateRepository) saved(a *Aggregate, ctx context.Context) {
+	if len(a.changes) == 0 {
+		return
+	}
+
+	previous := a.Version
+	a.Version += len(a.changes)
+	a.changes = nil
+
+	if r.snapshotEvery > 0 && a.Version/r.snapshotEvery > previous/r.snapshotEvery {
+		// Snapshots are an optimization: failing to take one doesn't invalidate the appended events.
+		if err := r.snapshot(a, ctx); err != nil {
+			r.logger.WarnCtx(ctx, "could not take snapshot",
+				slog.String("product_id", a.Id.String()),
+				slog.Int("version", a.Version),
+				slog.String("err", err.Error()),
+			)
+		}
+	}
+}
+
+func (r *aggregateRepository) snapshot(a *Aggregate, ctx context.Context) error {
+	state, err := json.Marshal(aggregateSnapshot{Product: a.Product, Deleted: a.Deleted})
+	if err != nil {
+		return err
+	}
+
+	return r.store.SaveSnapshot(&eventstore.Snapshot{
+		AggregateId: a.Id.String(),
+		Version:     a.Version,
+		State:       state,
+	}, ctx)
+}
@@ -0,0 +1,161 @@
+package products
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/giornetta/microshop/events"
+)
+
+const (
+	keyboardId ProductId = "6b0f7a64-3a36-4b8f-9d0c-5f2d7a9b1e01"
+	mouseId    ProductId = "6b0f7a64-3a36-4b8f-9d0c-5f2d7a9b1e02"
+)
+
+// aggregate returns the aggregate of an existing product with the given stock, all held by the DefaultWarehouse.
+func aggregate(id ProductId, amount, threshold int) *Aggregate {
+	return &Aggregate{
+		Product: Product{
+			Id:               id,
+			Name:             "Product " + id.String(),
+			Price:            10,
+			Amount:           amount,
+			ReorderThreshold: threshold,
+			Stock:            defaultStock(amount),
+		},
+		Created: true,
+		Version: 1,
+	}
+}
+
+func types(evts []events.Event) []events.Type {
+	ts := make([]events.Type, len(evts))
+	for i, e := range evts {
+		ts[i] = e.Type()
+	}
+
+	return ts
+}
+
+func TestStockAlerts(t *testing.T) {
+	threshold := func(n int) *int { return &n }
+
+	tests := []struct {
+		name      string
+		amount    int
+		threshold int
+		change    func(a *Aggregate) error
+		want      []events.Type
+	}{
+		{
+			name:   "staying above the threshold",
+			amount: 10, threshold: 3,
+			change: func(a *Aggregate) error { return a.AdjustStock(DefaultWarehouse, -6) },
+			want:   []events.Type{events.ProductUpdatedType, events.ProductStockChangedType},
+		},
+		{
+			name:   "reaching the threshold",
+			amount: 10, threshold: 3,
+			change: func(a *Aggregate) error { return a.AdjustStock(DefaultWarehouse, -7) },
+			want:   []events.Type{events.ProductUpdatedType, events.ProductStockChangedType, events.ProductStockLowType},
+		},
+		{
+			name:   "already at the threshold",
+			amount: 3, threshold: 3,
+			change: func(a *Aggregate) error { return a.AdjustStock(DefaultWarehouse, -1) },
+			want:   []events.Type{events.ProductUpdatedType, events.ProductStockChangedType},
+		},
+		{
+			name:   "already below the threshold",
+			amount: 2, threshold: 3,
+			change: func(a *Aggregate) error { return a.AdjustStock(DefaultWarehouse, 1) },
+			want:   []events.Type{events.ProductUpdatedType, events.ProductStockChangedType},
+		},
+		{
+			name:   "dropping from above the threshold to 0",
+			amount: 10, threshold: 3,
+			change: func(a *Aggregate) error { return a.AdjustStock(DefaultWarehouse, -10) },
+			want:   []events.Type{events.ProductUpdatedType, events.ProductStockChangedType, events.ProductOutOfStockType},
+		},
+		{
+			name:   "dropping from below the threshold to 0",
+			amount: 2, threshold: 3,
+			change: func(a *Aggregate) error { return a.AdjustStock(DefaultWarehouse, -2) },
+			want:   []events.Type{events.ProductUpdatedType, events.ProductStockChangedType, events.ProductOutOfStockType},
+		},
+		{
+			name:   "restocking from 0 to below the threshold",
+			amount: 0, threshold: 3,
+			change: func(a *Aggregate) error { return a.AdjustStock(DefaultWarehouse, 1) },
+			want:   []events.Type{events.ProductUpdatedType, events.ProductStockChangedType},
+		},
+		{
+			name:   "transferring between warehouses",
+			amount: 3, threshold: 3,
+			change: func(a *Aggregate) error {
+				return a.TransferStock(&TransferStockRequest{From: DefaultWarehouse, To: "north", Quantity: 2})
+			},
+			want: []events.Type{events.ProductUpdatedType, events.ProductStockTransferredType},
+		},
+		{
+			name:   "raising the threshold above the stock",
+			amount: 5, threshold: 3,
+			change: func(a *Aggregate) error {
+				a.Update(&UpdateProductRequest{Id: a.Id, ReorderThreshold: threshold(8)})
+				return nil
+			},
+			want: []events.Type{events.ProductUpdatedType, events.ProductStockLowType},
+		},
+		{
+			name:   "raising the threshold of a product out of stock",
+			amount: 0, threshold: 3,
+			change: func(a *Aggregate) error {
+				a.Update(&UpdateProductRequest{Id: a.Id, ReorderThreshold: threshold(8)})
+				return nil
+			},
+			want: []events.Type{events.ProductUpdatedType},
+		},
+		{
+			name:   "lowering the threshold",
+			amount: 5, threshold: 8,
+			change: func(a *Aggregate) error {
+				a.Update(&UpdateProductRequest{Id: a.Id, ReorderThreshold: threshold(3)})
+				return nil
+			},
+			want: []events.Type{events.ProductUpdatedType},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := aggregate(keyboardId, tt.amount, tt.threshold)
+
+			if err := tt.change(a); err != nil {
+				t.Fatalf("change failed: %v", err)
+			}
+
+			if got := types(a.Changes()); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("recorded %v, want %v", got, tt.want)
+			}
+
+			for _, evt := range a.Changes() {
+				if low, ok := evt.(events.ProductStockLow); ok && (low.Amount != a.Amount || low.ReorderThreshold != a.ReorderThreshold) {
+					t.Errorf("recorded %+v, want the stock and threshold after the change", low)
+				}
+			}
+		})
+	}
+}
+
+func TestAdjustStockBelowZero(t *testing.T) {
+	a := aggregate(keyboardId, 2, 1)
+
+	err := a.AdjustStock(DefaultWarehouse, -3)
+	if _, ok := err.(*ErrInsufficientStock); !ok {
+		t.Fatalf("AdjustStock() error = %v, want *ErrInsufficientStock", err)
+	}
+
+	if len(a.Changes()) != 0 || a.Amount != 2 {
+		t.Errorf("failed adjustment left amount %d and changes %v", a.Amount, types(a.Changes()))
+	}
+}
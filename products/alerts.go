@@ -0,0 +1,56 @@
+package products
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/notify"
+)
+
+type stockAlertHandler struct {
+	sink   notify.Sink
+	logger *slog.Logger
+}
+
+// NewStockAlertHandler returns a Handler notifying sink of the Product.StockLow and Product.OutOfStock
+// events, and ignoring the others. Notifications that cannot be delivered are logged rather than
+// returned, as they are not worth stopping the handling of the topic for.
+func NewStockAlertHandler(sink notify.Sink, logger *slog.Logger) events.Handler {
+	return &stockAlertHandler{
+		sink:   sink,
+		logger: logger,
+	}
+}
+
+func (h *stockAlertHandler) Handle(evt events.Event, ctx context.Context) error {
+	var n *notify.Notification
+
+	switch e := evt.(type) {
+	case events.ProductStockLow:
+		n = &notify.Notification{
+			Subject: fmt.Sprintf("%s is low on stock", e.Name),
+			Body:    fmt.Sprintf("Product %s (%s) has %d items left, reaching its reorder threshold of %d.", e.Name, e.ProductId, e.Amount, e.ReorderThreshold),
+			Event:   evt,
+		}
+	case events.ProductOutOfStock:
+		n = &notify.Notification{
+			Subject: fmt.Sprintf("%s is out of stock", e.Name),
+			Body:    fmt.Sprintf("Product %s (%s) has no items left.", e.Name, e.ProductId),
+			Event:   evt,
+		}
+	default:
+		return nil
+	}
+
+	if err := h.sink.Notify(n, ctx); err != nil {
+		h.logger.ErrorCtx(ctx, "could not deliver stock alert",
+			slog.String("product_id", evt.Key().String()),
+			slog.String("err", err.Error()),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,244 @@
+package products
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Format is an encoding of the catalog, read by imports and written by exports.
+type Format string
+
+const (
+	// FormatCSV is a CSV file with a header naming its columns, in any order.
+	FormatCSV Format = "csv"
+	// FormatNDJSON holds a JSON object per line.
+	FormatNDJSON Format = "ndjson"
+)
+
+// ParseFormat returns the format named by s, or by the media type s, such as text/csv.
+func ParseFormat(s string) (Format, error) {
+	mediaType, _, _ := strings.Cut(s, ";")
+
+	switch strings.ToLower(strings.TrimSpace(mediaType)) {
+	case "csv", "text/csv":
+		return FormatCSV, nil
+	case "ndjson", "jsonl", "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return FormatNDJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported catalog format %q, want csv or ndjson", s)
+	}
+}
+
+func (f Format) ContentType() string {
+	if f == FormatNDJSON {
+		return "application/x-ndjson"
+	}
+
+	return "text/csv; charset=utf-8"
+}
+
+// catalogColumns are the columns of exported CSV files. Imported files need name, description
+// and price, while amount and sku are optional, and product_id is ignored so that exports can be
+// imported back.
+var catalogColumns = []string{"product_id", "sku", "name", "description", "price", "amount"}
+
+// catalogRecord is a product as found in a catalog file.
+type catalogRecord struct {
+	// Row is the line of the record in its file, counting the header of CSV files.
+	Row int `json:"-"`
+
+	ProductId   ProductId `json:"product_id,omitempty"`
+	SKU         string    `json:"sku,omitempty"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Price       float32   `json:"price"`
+	Amount      int       `json:"amount"`
+
+	// Err is set when the record could not be read, leaving its fields empty.
+	Err error `json:"-"`
+}
+
+// readCatalog reads every record of a catalog file. Records that cannot be read are returned
+// with their Err set, while the error is only returned when the file as a whole cannot be read.
+func readCatalog(r io.Reader, format Format) ([]*catalogRecord, error) {
+	if format == FormatNDJSON {
+		return readNDJSON(r)
+	}
+
+	return readCSV(r)
+}
+
+func readCSV(r io.Reader) ([]*catalogRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(catalogColumns, name) {
+			return nil, fmt.Errorf("unknown column %q, want some of %s", name, strings.Join(catalogColumns, ", "))
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("column %q appears more than once", name)
+		}
+
+		columns[name] = i
+	}
+
+	for _, name := range []string{"name", "description", "price"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	var records []*catalogRecord
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			// Quoting errors leave the reader unable to tell where the next record starts.
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		records = append(records, parseCSVRecord(line, fields, columns))
+	}
+}
+
+func parseCSVRecord(line int, fields []string, columns map[string]int) *catalogRecord {
+	rec := &catalogRecord{Row: line}
+	if len(fields) != len(columns) {
+		rec.Err = fmt.Errorf("has %d fields, want %d", len(fields), len(columns))
+		return rec
+	}
+
+	field := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(fields[i])
+		}
+
+		return ""
+	}
+
+	parsed := catalogRecord{
+		Row:         line,
+		ProductId:   ProductId(field("product_id")),
+		SKU:         field("sku"),
+		Name:        field("name"),
+		Description: field("description"),
+	}
+
+	if s := field("price"); s != "" {
+		price, err := strconv.ParseFloat(s, 32)
+		if err != nil {
+			rec.Err = fmt.Errorf("price: %q is not a number", s)
+			return rec
+		}
+
+		parsed.Price = float32(price)
+	}
+
+	if s := field("amount"); s != "" {
+		amount, err := strconv.Atoi(s)
+		if err != nil {
+			rec.Err = fmt.Errorf("amount: %q is not an integer", s)
+			return rec
+		}
+
+		parsed.Amount = amount
+	}
+
+	return &parsed
+}
+
+// maxNDJSONLine is the length of the longest line accepted in NDJSON files.
+const maxNDJSONLine = 1 << 20
+
+func readNDJSON(r io.Reader) ([]*catalogRecord, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLine)
+
+	var records []*catalogRecord
+	for line := 1; scanner.Scan(); line++ {
+		b := bytes.TrimSpace(scanner.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+
+		rec := &catalogRecord{}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(rec); err != nil {
+			rec = &catalogRecord{Err: err}
+		}
+		rec.Row = line
+
+		records = append(records, rec)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// WriteCatalog writes the products to w in the given format, one product at a time.
+func WriteCatalog(w io.Writer, format Format, products []*Product) error {
+	if format == FormatNDJSON {
+		enc := json.NewEncoder(w)
+		for _, p := range products {
+			if err := enc.Encode(catalogRecord{
+				ProductId:   p.Id,
+				SKU:         p.SKU,
+				Name:        p.Name,
+				Description: p.Description,
+				Price:       p.Price,
+				Amount:      p.Amount,
+			}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(catalogColumns); err != nil {
+		return err
+	}
+
+	for _, p := range products {
+		if err := writer.Write([]string{
+			p.Id.String(),
+			p.SKU,
+			p.Name,
+			p.Description,
+			strconv.FormatFloat(float64(p.Price), 'f', -1, 32),
+			strconv.Itoa(p.Amount),
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
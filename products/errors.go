@@ -8,6 +8,7 @@ import (
 type ErrNotFound struct {
 	ProductId ProductId
 	Name      string
+	SKU       string
 }
 
 func (err *ErrNotFound) Error() string {
@@ -19,6 +20,10 @@ func (err *ErrNotFound) Error() string {
 		return fmt.Sprintf("product with name=%s was not found", err.Name)
 	}
 
+	if err.SKU != "" {
+		return fmt.Sprintf("product with sku=%s was not found", err.SKU)
+	}
+
 	return "product was not found"
 }
 
@@ -27,13 +32,113 @@ func (err *ErrNotFound) StatusCode() int {
 }
 
 type ErrAlreadyExists struct {
-	Name string
+	ProductId ProductId
+	Name      string
+	SKU       string
 }
 
 func (err *ErrAlreadyExists) Error() string {
+	if err.ProductId != "" {
+		return fmt.Sprintf("product with id=%s already exists", err.ProductId.String())
+	}
+
+	if err.SKU != "" {
+		return fmt.Sprintf("product with sku=%s already exists", err.SKU)
+	}
+
 	return fmt.Sprintf("product with name=%s already exists", err.Name)
 }
 
 func (err *ErrAlreadyExists) StatusCode() int {
 	return http.StatusBadRequest
 }
+
+type ErrImportNotFound struct {
+	JobId string
+}
+
+func (err *ErrImportNotFound) Error() string {
+	return fmt.Sprintf("import with id=%s was not found", err.JobId)
+}
+
+func (err *ErrImportNotFound) StatusCode() int {
+	return http.StatusNotFound
+}
+
+type ErrInsufficientStock struct {
+	ProductId   ProductId
+	WarehouseId WarehouseId
+	Amount      int
+	Quantity    int
+}
+
+func (err *ErrInsufficientStock) Error() string {
+	return fmt.Sprintf("product with id=%s has %d items in warehouse %s, cannot remove %d", err.ProductId.String(), err.Amount, err.WarehouseId.String(), -err.Quantity)
+}
+
+func (err *ErrInsufficientStock) StatusCode() int {
+	return http.StatusConflict
+}
+
+type ErrWarehouseNotFound struct {
+	WarehouseId WarehouseId
+}
+
+func (err *ErrWarehouseNotFound) Error() string {
+	return fmt.Sprintf("warehouse with id=%s was not found", err.WarehouseId.String())
+}
+
+func (err *ErrWarehouseNotFound) StatusCode() int {
+	return http.StatusNotFound
+}
+
+type ErrWarehouseAlreadyExists struct {
+	WarehouseId WarehouseId
+}
+
+func (err *ErrWarehouseAlreadyExists) Error() string {
+	return fmt.Sprintf("warehouse with id=%s already exists", err.WarehouseId.String())
+}
+
+func (err *ErrWarehouseAlreadyExists) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+type ErrPriceScheduleNotFound struct {
+	ScheduleId PriceScheduleId
+}
+
+func (err *ErrPriceScheduleNotFound) Error() string {
+	return fmt.Sprintf("price schedule with id=%s was not found", err.ScheduleId.String())
+}
+
+func (err *ErrPriceScheduleNotFound) StatusCode() int {
+	return http.StatusNotFound
+}
+
+// ErrPriceScheduleOverlaps is returned when a price schedule would take effect, or expire, while another one is in effect.
+type ErrPriceScheduleOverlaps struct {
+	ScheduleId PriceScheduleId
+}
+
+func (err *ErrPriceScheduleOverlaps) Error() string {
+	return fmt.Sprintf("price schedule overlaps price schedule with id=%s", err.ScheduleId.String())
+}
+
+func (err *ErrPriceScheduleOverlaps) StatusCode() int {
+	return http.StatusConflict
+}
+
+// ErrPriceScheduleConflict is returned when a price schedule does not have the status a change requires.
+type ErrPriceScheduleConflict struct {
+	ScheduleId PriceScheduleId
+	Status     PriceScheduleStatus
+}
+
+func (err *ErrPriceScheduleConflict) Error() string {
+	return fmt.Sprintf("price schedule with id=%s is %s", err.ScheduleId.String(), err.Status)
+}
+
+func (err *ErrPriceScheduleConflict) StatusCode() int {
+	return http.StatusConflict
+}
@@ -0,0 +1,255 @@
+package products
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/giornetta/microshop/errors"
+	"github.com/giornetta/microshop/eventstore"
+)
+
+type eventSourcedService struct {
+	querier    ProductQuerier
+	warehouses WarehouseQuerier
+	aggregates AggregateRepository
+}
+
+// NewEventSourcedService returns a Service validating commands against the product aggregates,
+// rebuilt from the event store, instead of the eventually consistent projection.
+// Events are appended to the store, so that concurrent commands on the same product fail with a conflict
+// instead of overwriting each other, and published by the eventstore.Relay draining its outbox: a command
+// succeeds once its events are stored, and never has to be retried because Kafka was unavailable.
+// Queries and the uniqueness of product names and SKUs are still served by the projection.
+func NewEventSourcedService(querier ProductQuerier, warehouses WarehouseQuerier, aggregates AggregateRepository) Service {
+	return &eventSourcedService{
+		querier:    querier,
+		warehouses: warehouses,
+		aggregates: aggregates,
+	}
+}
+
+func (s *eventSourcedService) Create(req *CreateProductRequest, ctx context.Context) (*Product, error) {
+	if err := req.Validate(); err != nil {
+		return nil, &errors.ErrBadRequest{Err: err}
+	}
+
+	_, err := s.querier.FindByName(req.Name, ctx)
+	if err == nil {
+		return nil, &ErrAlreadyExists{Name: req.Name}
+	}
+
+	if _, ok := err.(*ErrNotFound); !ok {
+		return nil, err
+	}
+
+	if err := checkSKU(s.querier, req.SKU, "", ctx); err != nil {
+		return nil, err
+	}
+
+	a := &Aggregate{Product: Product{Id: ProductId(uuid.New().String())}}
+	a.Create(req)
+
+	if err := s.commit(a, ctx); err != nil {
+		return nil, err
+	}
+
+	return &a.Product, nil
+}
+
+func (s *eventSourcedService) GetById(productId ProductId, ctx context.Context) (*Product, error) {
+	p, err := s.querier.FindById(productId, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (s *eventSourcedService) List(ctx context.Context) ([]*Product, error) {
+	prods, err := s.querier.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return prods, nil
+}
+
+func (s *eventSourcedService) ListLowStock(ctx context.Context) ([]*Product, error) {
+	prods, err := s.querier.ListLowStock(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return prods, nil
+}
+
+func (s *eventSourcedService) Update(req *UpdateProductRequest, ctx context.Context) error {
+	if err := req.Validate(); err != nil {
+		return &errors.ErrBadRequest{Err: err}
+	}
+
+	if err := checkName(s.querier, req.Name, req.Id, ctx); err != nil {
+		return err
+	}
+
+	if err := checkSKU(s.querier, req.SKU, req.Id, ctx); err != nil {
+		return err
+	}
+
+	a, err := s.load(req.Id, ctx)
+	if err != nil {
+		return err
+	}
+
+	a.Update(req)
+
+	return s.commit(a, ctx)
+}
+
+func (s *eventSourcedService) Restock(req *RestockProductRequest, ctx context.Context) error {
+	if err := req.Validate(); err != nil {
+		return &errors.ErrBadRequest{Err: err}
+	}
+
+	if err := checkWarehouse(s.warehouses, req.WarehouseId, ctx); err != nil {
+		return err
+	}
+
+	a, err := s.load(req.Id, ctx)
+	if err != nil {
+		return err
+	}
+
+	a.Restock(req)
+
+	return s.commit(a, ctx)
+}
+
+func (s *eventSourcedService) TransferStock(req *TransferStockRequest, ctx context.Context) error {
+	if err := req.Validate(); err != nil {
+		return &errors.ErrBadRequest{Err: err}
+	}
+
+	for _, id := range []WarehouseId{req.From, req.To} {
+		if err := checkWarehouse(s.warehouses, id, ctx); err != nil {
+			return err
+		}
+	}
+
+	a, err := s.load(req.Id, ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := a.TransferStock(req); err != nil {
+		return err
+	}
+
+	return s.commit(a, ctx)
+}
+
+func (s *eventSourcedService) ChangePrice(req *ChangePriceRequest, ctx context.Context) error {
+	if err := req.Validate(); err != nil {
+		return &errors.ErrBadRequest{Err: err}
+	}
+
+	a, err := s.load(req.Id, ctx)
+	if err != nil {
+		return err
+	}
+
+	a.ChangePrice(req)
+
+	return s.commit(a, ctx)
+}
+
+// AdjustStock applies the valid adjustments of an AllOrNothing batch in a single append to the event store,
+// so that they are all rejected if any of their products changed concurrently. Those of a BestEffort batch
+// are saved product by product.
+func (s *eventSourcedService) AdjustStock(req *AdjustStockRequest, ctx context.Context) ([]StockAdjustmentResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, &errors.ErrBadRequest{Err: err}
+	}
+
+	b, err := newStockBatch(req, s.load, s.warehouses, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Mode == AllOrNothing {
+		if b.rejected {
+			b.abort()
+			return b.results, nil
+		}
+
+		if err := s.aggregates.SaveAll(b.aggregates, ctx); err != nil {
+			if _, ok := err.(*eventstore.ErrConcurrencyConflict); ok {
+				return nil, err
+			}
+
+			return nil, &errors.ErrInternal{Err: err}
+		}
+
+		b.apply(b.aggregates)
+		return b.results, nil
+	}
+
+	var saved []*Aggregate
+	for _, a := range b.aggregates {
+		if err := s.aggregates.Save(a, ctx); err != nil {
+			if _, ok := err.(*eventstore.ErrConcurrencyConflict); !ok {
+				err = &errors.ErrInternal{Err: err}
+			}
+
+			b.fail(a, err)
+			continue
+		}
+
+		saved = append(saved, a)
+	}
+
+	b.apply(saved)
+	return b.results, nil
+}
+
+func (s *eventSourcedService) Delete(productId ProductId, ctx context.Context) error {
+	a, err := s.load(productId, ctx)
+	if err != nil {
+		return err
+	}
+
+	a.Delete()
+
+	return s.commit(a, ctx)
+}
+
+func (s *eventSourcedService) load(productId ProductId, ctx context.Context) (*Aggregate, error) {
+	a, err := s.aggregates.Load(productId, ctx)
+	if err != nil {
+		if _, ok := err.(*errors.ErrInternal); ok {
+			return nil, err
+		}
+
+		return nil, &errors.ErrInternal{Err: err}
+	}
+
+	if !a.Exists() {
+		return nil, &ErrNotFound{ProductId: productId}
+	}
+
+	return a, nil
+}
+
+// commit appends the changes of the aggregate to its stream, whose outbox publishes them.
+func (s *eventSourcedService) commit(a *Aggregate, ctx context.Context) error {
+	if err := s.aggregates.Save(a, ctx); err != nil {
+		if _, ok := err.(*eventstore.ErrConcurrencyConflict); ok {
+			return err
+		}
+
+		return &errors.ErrInternal{Err: err}
+	}
+
+	return nil
+}
@@ -2,7 +2,12 @@ package products
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -12,38 +17,61 @@ import (
 )
 
 type handler struct {
-	Service Service
+	Service    Service
+	Importer   *Importer
+	Warehouses WarehouseService
+	Pricing    PricingService
 }
 
-func NewRouter(service Service) http.Handler {
+func NewRouter(service Service, importer *Importer, warehouses WarehouseService, pricing PricingService) http.Handler {
 	h := &handler{
-		Service: service,
+		Service:    service,
+		Importer:   importer,
+		Warehouses: warehouses,
+		Pricing:    pricing,
 	}
 
 	router := chi.NewRouter()
 
 	router.Use(
-		middleware.Logger,
 		middleware.Recoverer,
 	)
 
 	router.Route("/api/v1/products", func(r chi.Router) {
 		r.Post("/", h.handleCreateProduct)
 		r.Get("/", h.handleListProducts)
+		r.Post("/import", h.handleImportProducts)
+		r.Get("/import/{jobId}", h.handleGetImport)
+		r.Get("/export", h.handleExportProducts)
+		r.Get("/low-stock", h.handleListLowStock)
 		r.Get("/{id}", h.handleGetProduct)
 		r.Put("/{id}", h.handleUpdateProduct)
 		r.Put("/restock/{id}", h.handleRestockProduct)
+		r.Post("/{id}/transfers", h.handleTransferStock)
+		r.Get("/{id}/prices", h.handleGetPriceHistory)
+		r.Post("/{id}/price-schedules", h.handleSchedulePrice)
+		r.Get("/{id}/price-schedules", h.handleListPriceSchedules)
+		r.Delete("/{id}/price-schedules/{scheduleId}", h.handleCancelPriceSchedule)
+		r.Post("/stock-adjustments", h.handleAdjustStock)
 		r.Delete("/{id}", h.handleDeleteProduct)
 	})
 
+	router.Route("/api/v1/warehouses", func(r chi.Router) {
+		r.Post("/", h.handleCreateWarehouse)
+		r.Get("/", h.handleListWarehouses)
+		r.Get("/{id}", h.handleGetWarehouse)
+	})
+
 	return router
 }
 
 type createProductRequest struct {
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float32 `json:"price"`
-	Amount      int     `json:"amount"`
+	Name             string  `json:"name"`
+	Description      string  `json:"description"`
+	Price            float32 `json:"price"`
+	Amount           int     `json:"amount"`
+	SKU              string  `json:"sku"`
+	ReorderThreshold int     `json:"reorder_threshold"`
 }
 
 func (h *handler) handleCreateProduct(w http.ResponseWriter, r *http.Request) {
@@ -55,10 +83,12 @@ func (h *handler) handleCreateProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	p, err := h.Service.Create(&CreateProductRequest{
-		Name:        req.Name,
-		Description: req.Description,
-		Price:       req.Price,
-		Amount:      req.Amount,
+		Name:             req.Name,
+		Description:      req.Description,
+		Price:            req.Price,
+		Amount:           req.Amount,
+		SKU:              req.SKU,
+		ReorderThreshold: req.ReorderThreshold,
 	}, r.Context())
 	if err != nil {
 		respond.Err(w, err)
@@ -78,6 +108,26 @@ func (h *handler) handleListProducts(w http.ResponseWriter, r *http.Request) {
 	respond.JSON(w, http.StatusOK, products)
 }
 
+// handleListLowStock lists the products that reached their reorder threshold, those with the
+// smallest stock first.
+func (h *handler) handleListLowStock(w http.ResponseWriter, r *http.Request) {
+	products, err := h.Service.ListLowStock(r.Context())
+	if err != nil {
+		respond.Err(w, err)
+		return
+	}
+
+	slices.SortFunc(products, func(a, b *Product) int {
+		if a.Amount != b.Amount {
+			return a.Amount - b.Amount
+		}
+
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	respond.JSON(w, http.StatusOK, products)
+}
+
 func (h *handler) handleGetProduct(w http.ResponseWriter, r *http.Request) {
 	productId := chi.URLParam(r, "id")
 
@@ -94,6 +144,9 @@ type updateProductRequest struct {
 	Name        string  `json:"name"`
 	Description string  `json:"description"`
 	Price       float32 `json:"price"`
+	SKU         string  `json:"sku"`
+	// ReorderThreshold is only changed when given, as zero is a valid threshold.
+	ReorderThreshold *int `json:"reorder_threshold"`
 }
 
 func (h *handler) handleUpdateProduct(w http.ResponseWriter, r *http.Request) {
@@ -106,10 +159,12 @@ func (h *handler) handleUpdateProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.Service.Update(&UpdateProductRequest{
-		Id:          ProductId(id),
-		Name:        req.Name,
-		Description: req.Description,
-		Price:       req.Price,
+		Id:               ProductId(id),
+		Name:             req.Name,
+		Description:      req.Description,
+		Price:            req.Price,
+		SKU:              req.SKU,
+		ReorderThreshold: req.ReorderThreshold,
 	}, r.Context()); err != nil {
 		respond.Err(w, err)
 		return
@@ -119,7 +174,8 @@ func (h *handler) handleUpdateProduct(w http.ResponseWriter, r *http.Request) {
 }
 
 type restockProductRequest struct {
-	Amount uint `json:"amount"`
+	Amount      uint        `json:"amount"`
+	WarehouseId WarehouseId `json:"warehouse_id"`
 }
 
 func (h *handler) handleRestockProduct(w http.ResponseWriter, r *http.Request) {
@@ -132,8 +188,9 @@ func (h *handler) handleRestockProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.Service.Restock(&RestockProductRequest{
-		Id:     ProductId(id),
-		Amount: int(req.Amount),
+		Id:          ProductId(id),
+		Amount:      int(req.Amount),
+		WarehouseId: req.WarehouseId,
 	}, r.Context()); err != nil {
 		respond.Err(w, err)
 		return
@@ -142,6 +199,90 @@ func (h *handler) handleRestockProduct(w http.ResponseWriter, r *http.Request) {
 	respond.JSON(w, http.StatusOK, nil)
 }
 
+type transferStockRequest struct {
+	FromWarehouseId WarehouseId `json:"from_warehouse_id"`
+	ToWarehouseId   WarehouseId `json:"to_warehouse_id"`
+	Quantity        int         `json:"quantity"`
+}
+
+func (h *handler) handleTransferStock(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req transferStockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Err(w, &errors.ErrBadRequest{})
+		return
+	}
+
+	if err := h.Service.TransferStock(&TransferStockRequest{
+		Id:       ProductId(id),
+		From:     req.FromWarehouseId,
+		To:       req.ToWarehouseId,
+		Quantity: req.Quantity,
+	}, r.Context()); err != nil {
+		respond.Err(w, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, nil)
+}
+
+type stockAdjustment struct {
+	ProductId   ProductId   `json:"product_id"`
+	WarehouseId WarehouseId `json:"warehouse_id"`
+	Quantity    int         `json:"quantity"`
+}
+
+type adjustStockRequest struct {
+	Mode        AdjustmentMode    `json:"mode"`
+	Adjustments []stockAdjustment `json:"adjustments"`
+}
+
+type adjustStockResponse struct {
+	Mode    AdjustmentMode          `json:"mode"`
+	Applied int                     `json:"applied"`
+	Results []StockAdjustmentResult `json:"results"`
+}
+
+// handleAdjustStock applies a batch of stock adjustments. It responds with 200 when every adjustment
+// was applied, 207 when only some were, and 422 when none was, with the result of each one.
+func (h *handler) handleAdjustStock(w http.ResponseWriter, r *http.Request) {
+	var req adjustStockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Err(w, &errors.ErrBadRequest{})
+		return
+	}
+
+	adjustments := make([]StockAdjustment, len(req.Adjustments))
+	for i, adj := range req.Adjustments {
+		adjustments[i] = StockAdjustment{Id: adj.ProductId, Quantity: adj.Quantity, WarehouseId: adj.WarehouseId}
+	}
+
+	serviceReq := &AdjustStockRequest{Adjustments: adjustments, Mode: req.Mode}
+	results, err := h.Service.AdjustStock(serviceReq, r.Context())
+	if err != nil {
+		respond.Err(w, err)
+		return
+	}
+
+	res := adjustStockResponse{Mode: serviceReq.Mode, Results: results}
+	for _, result := range results {
+		if result.Status == AdjustmentApplied {
+			res.Applied++
+		}
+	}
+
+	status := http.StatusMultiStatus
+	switch res.Applied {
+	case len(results):
+		status = http.StatusOK
+	case 0:
+		status = http.StatusUnprocessableEntity
+	}
+
+	respond.JSON(w, status, res)
+}
+
 func (h *handler) handleDeleteProduct(w http.ResponseWriter, r *http.Request) {
 	productId := chi.URLParam(r, "id")
 
@@ -152,3 +293,201 @@ func (h *handler) handleDeleteProduct(w http.ResponseWriter, r *http.Request) {
 
 	respond.JSON(w, http.StatusOK, nil)
 }
+
+// maxImportSize is the size of the largest catalog file accepted by imports.
+const maxImportSize = 32 << 20
+
+// handleImportProducts starts importing the catalog file in the body, whose format is given by the
+// format query parameter or the Content-Type header. Records are matched to products by the field
+// named by the key query parameter, name by default, and nothing is changed when dry_run is true.
+func (h *handler) handleImportProducts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	formatName := query.Get("format")
+	if formatName == "" {
+		formatName = r.Header.Get("Content-Type")
+	}
+	format, err := ParseFormat(formatName)
+	if err != nil {
+		respond.Err(w, &errors.ErrBadRequest{Err: err})
+		return
+	}
+
+	key := ImportKey(query.Get("key"))
+	if key == "" {
+		key = ImportByName
+	}
+
+	var dryRun bool
+	if s := query.Get("dry_run"); s != "" {
+		if dryRun, err = strconv.ParseBool(s); err != nil {
+			respond.Err(w, &errors.ErrBadRequest{Err: fmt.Errorf("dry_run: %q is not a boolean", s)})
+			return
+		}
+	}
+
+	job, err := h.Importer.Start(&ImportRequest{
+		Format: format,
+		Key:    key,
+		DryRun: dryRun,
+		Body:   http.MaxBytesReader(w, r.Body, maxImportSize),
+	}, r.Context())
+	if err != nil {
+		respond.Err(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/products/import/"+job.Id)
+	respond.JSON(w, http.StatusAccepted, job)
+}
+
+func (h *handler) handleGetImport(w http.ResponseWriter, r *http.Request) {
+	job, err := h.Importer.Job(chi.URLParam(r, "jobId"))
+	if err != nil {
+		respond.Err(w, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, job)
+}
+
+// handleExportProducts writes the catalog, sorted by name, in the format given by the format
+// query parameter or the Accept header, CSV by default.
+func (h *handler) handleExportProducts(w http.ResponseWriter, r *http.Request) {
+	format := FormatCSV
+	if s := r.URL.Query().Get("format"); s != "" {
+		f, err := ParseFormat(s)
+		if err != nil {
+			respond.Err(w, &errors.ErrBadRequest{Err: err})
+			return
+		}
+
+		format = f
+	} else if f, err := ParseFormat(r.Header.Get("Accept")); err == nil {
+		format = f
+	}
+
+	products, err := h.Service.List(r.Context())
+	if err != nil {
+		respond.Err(w, err)
+		return
+	}
+
+	slices.SortFunc(products, func(a, b *Product) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="products.%s"`, format))
+	w.WriteHeader(http.StatusOK)
+
+	// The status is already sent, so a failing export can only be noticed by its truncated body.
+	_ = WriteCatalog(w, format, products)
+}
+
+type createWarehouseRequest struct {
+	Id       WarehouseId `json:"warehouse_id"`
+	Name     string      `json:"name"`
+	Location string      `json:"location"`
+}
+
+func (h *handler) handleCreateWarehouse(w http.ResponseWriter, r *http.Request) {
+	var req createWarehouseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Err(w, &errors.ErrBadRequest{})
+		return
+	}
+
+	warehouse, err := h.Warehouses.Create(&CreateWarehouseRequest{
+		Id:       req.Id,
+		Name:     req.Name,
+		Location: req.Location,
+	}, r.Context())
+	if err != nil {
+		respond.Err(w, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusCreated, warehouse)
+}
+
+func (h *handler) handleListWarehouses(w http.ResponseWriter, r *http.Request) {
+	warehouses, err := h.Warehouses.List(r.Context())
+	if err != nil {
+		respond.Err(w, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, warehouses)
+}
+
+func (h *handler) handleGetWarehouse(w http.ResponseWriter, r *http.Request) {
+	warehouse, err := h.Warehouses.GetById(WarehouseId(chi.URLParam(r, "id")), r.Context())
+	if err != nil {
+		respond.Err(w, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, warehouse)
+}
+
+func (h *handler) handleGetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	history, err := h.Pricing.History(ProductId(chi.URLParam(r, "id")), r.Context())
+	if err != nil {
+		respond.Err(w, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, history)
+}
+
+type schedulePriceRequest struct {
+	Price          float32    `json:"price"`
+	EffectiveFrom  time.Time  `json:"effective_from"`
+	EffectiveUntil *time.Time `json:"effective_until"`
+}
+
+func (h *handler) handleSchedulePrice(w http.ResponseWriter, r *http.Request) {
+	var req schedulePriceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Err(w, &errors.ErrBadRequest{})
+		return
+	}
+
+	schedule, err := h.Pricing.Schedule(&SchedulePriceRequest{
+		ProductId:      ProductId(chi.URLParam(r, "id")),
+		Price:          req.Price,
+		EffectiveFrom:  req.EffectiveFrom,
+		EffectiveUntil: req.EffectiveUntil,
+	}, r.Context())
+	if err != nil {
+		respond.Err(w, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusCreated, schedule)
+}
+
+func (h *handler) handleListPriceSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules, err := h.Pricing.ListSchedules(ProductId(chi.URLParam(r, "id")), r.Context())
+	if err != nil {
+		respond.Err(w, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, schedules)
+}
+
+func (h *handler) handleCancelPriceSchedule(w http.ResponseWriter, r *http.Request) {
+	schedule, err := h.Pricing.CancelSchedule(
+		ProductId(chi.URLParam(r, "id")),
+		PriceScheduleId(chi.URLParam(r, "scheduleId")),
+		r.Context(),
+	)
+	if err != nil {
+		respond.Err(w, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, schedule)
+}
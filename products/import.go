@@ -0,0 +1,347 @@
+package products
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giornetta/microshop/errors"
+)
+
+// ImportKey is the field matching the records of an import to existing products.
+type ImportKey string
+
+const (
+	ImportByName ImportKey = "name"
+	ImportBySKU  ImportKey = "sku"
+)
+
+// ImportAction is the outcome of a record of an import. Dry runs report the outcome
+// the record would have had.
+type ImportAction string
+
+const (
+	ImportCreated   ImportAction = "created"
+	ImportUpdated   ImportAction = "updated"
+	ImportUnchanged ImportAction = "unchanged"
+	ImportFailed    ImportAction = "failed"
+	// ImportSkipped records were not processed because the job failed before reaching them.
+	ImportSkipped ImportAction = "skipped"
+)
+
+type ImportStatus string
+
+const (
+	ImportRunning   ImportStatus = "running"
+	ImportCompleted ImportStatus = "completed"
+	// ImportAborted jobs stopped at the first internal error, as the following records would fail the same way.
+	ImportAborted ImportStatus = "aborted"
+)
+
+// ImportRowResult is the outcome of a record of an import.
+type ImportRowResult struct {
+	Row       int          `json:"row"`
+	Key       string       `json:"key,omitempty"`
+	Action    ImportAction `json:"action"`
+	ProductId ProductId    `json:"product_id,omitempty"`
+	Error     string       `json:"error,omitempty"`
+}
+
+// ImportJob tracks an import, whose records are processed in the background.
+type ImportJob struct {
+	Id         string               `json:"job_id"`
+	Status     ImportStatus         `json:"status"`
+	DryRun     bool                 `json:"dry_run"`
+	Key        ImportKey            `json:"key"`
+	Format     Format               `json:"format"`
+	Total      int                  `json:"total"`
+	Processed  int                  `json:"processed"`
+	Summary    map[ImportAction]int `json:"summary"`
+	Rows       []ImportRowResult    `json:"rows"`
+	Error      string               `json:"error,omitempty"`
+	StartedAt  time.Time            `json:"started_at"`
+	FinishedAt *time.Time           `json:"finished_at,omitempty"`
+}
+
+func (j *ImportJob) clone() *ImportJob {
+	c := *j
+	c.Summary = maps.Clone(j.Summary)
+	c.Rows = slices.Clone(j.Rows)
+
+	return &c
+}
+
+type ImportRequest struct {
+	Format Format
+	Key    ImportKey
+	// DryRun reports the outcome of every record without changing any product.
+	DryRun bool
+	Body   io.Reader
+}
+
+// Importer creates and updates products in bulk from catalog files, through a Service,
+// so that every change is validated and published as if it had been requested on its own.
+//
+// Records are matched to the existing products by name or SKU: unknown products are created,
+// while known ones get the name, description, price and SKU of their record. Amounts only set the
+// stock of created products, as stock is changed by restocks, and SKUs cannot be removed by imports.
+//
+// Jobs are kept in memory for a day after they finish, so their status must be asked to the
+// instance that started them, and is lost when it restarts.
+type Importer struct {
+	service Service
+	querier ProductQuerier
+
+	lock sync.Mutex
+	jobs map[string]*ImportJob
+}
+
+// importRetention is how long finished jobs are kept.
+const importRetention = 24 * time.Hour
+
+func NewImporter(service Service, querier ProductQuerier) *Importer {
+	return &Importer{
+		service: service,
+		querier: querier,
+		jobs:    make(map[string]*ImportJob),
+	}
+}
+
+// Start reads the catalog file of the request and starts importing its records in the background,
+// returning the job tracking them. It fails with *errors.ErrBadRequest if the file cannot be read,
+// while invalid records are reported by the job.
+// The job outlives ctx, but keeps its values.
+func (i *Importer) Start(req *ImportRequest, ctx context.Context) (*ImportJob, error) {
+	if req.Key != ImportByName && req.Key != ImportBySKU {
+		return nil, &errors.ErrBadRequest{Err: fmt.Errorf("unsupported import key %q, want name or sku", req.Key)}
+	}
+
+	records, err := readCatalog(req.Body, req.Format)
+	if err != nil {
+		return nil, &errors.ErrBadRequest{Err: fmt.Errorf("could not read %s catalog: %w", req.Format, err)}
+	}
+
+	job := &ImportJob{
+		Id:        uuid.New().String(),
+		Status:    ImportRunning,
+		DryRun:    req.DryRun,
+		Key:       req.Key,
+		Format:    req.Format,
+		Total:     len(records),
+		Summary:   make(map[ImportAction]int),
+		Rows:      make([]ImportRowResult, 0, len(records)),
+		StartedAt: time.Now().UTC(),
+	}
+
+	i.lock.Lock()
+	i.prune(job.StartedAt)
+	i.jobs[job.Id] = job
+	snapshot := job.clone()
+	i.lock.Unlock()
+
+	go i.run(job, records, context.WithoutCancel(ctx))
+
+	return snapshot, nil
+}
+
+// Job returns the current state of the job with the given id, failing with *ErrImportNotFound
+// if there is none.
+func (i *Importer) Job(id string) (*ImportJob, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	job, ok := i.jobs[id]
+	if !ok {
+		return nil, &ErrImportNotFound{JobId: id}
+	}
+
+	return job.clone(), nil
+}
+
+// prune removes the jobs that finished more than importRetention before now.
+func (i *Importer) prune(now time.Time) {
+	for id, job := range i.jobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > importRetention {
+			delete(i.jobs, id)
+		}
+	}
+}
+
+func (i *Importer) run(job *ImportJob, records []*catalogRecord, ctx context.Context) {
+	checkDuplicates(records)
+
+	var aborted error
+	for _, rec := range records {
+		result := ImportRowResult{Row: rec.Row, Action: ImportSkipped}
+		if aborted == nil {
+			result, aborted = i.importRecord(rec, job.Key, job.DryRun, ctx)
+		}
+
+		i.lock.Lock()
+		job.Rows = append(job.Rows, result)
+		job.Summary[result.Action]++
+		job.Processed++
+		i.lock.Unlock()
+	}
+
+	finished := time.Now().UTC()
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	job.Status = ImportCompleted
+	if aborted != nil {
+		job.Status = ImportAborted
+		job.Error = aborted.Error()
+	}
+	job.FinishedAt = &finished
+}
+
+// checkDuplicates fails the records whose name or SKU was already found in a previous record.
+// Products created by an import are not found by the following records until they are projected,
+// so they would otherwise be created twice.
+func checkDuplicates(records []*catalogRecord) {
+	names := make(map[string]int)
+	skus := make(map[string]int)
+
+	for _, rec := range records {
+		if rec.Err != nil {
+			continue
+		}
+
+		name, sku := strings.TrimSpace(rec.Name), strings.TrimSpace(rec.SKU)
+
+		if row, ok := names[name]; ok {
+			rec.Err = fmt.Errorf("name %s was already imported by row %d", name, row)
+			continue
+		}
+
+		if row, ok := skus[sku]; ok && sku != "" {
+			rec.Err = fmt.Errorf("sku %s was already imported by row %d", sku, row)
+			continue
+		}
+
+		names[name] = rec.Row
+		skus[sku] = rec.Row
+	}
+}
+
+// importRecord creates or updates the product of the record, or only reports what it would do
+// when dryRun is set. The error is only returned for internal errors, that abort the import.
+func (i *Importer) importRecord(rec *catalogRecord, key ImportKey, dryRun bool, ctx context.Context) (ImportRowResult, error) {
+	result := ImportRowResult{Row: rec.Row}
+
+	if err := i.upsert(rec, key, dryRun, &result, ctx); err != nil {
+		result.Action = ImportFailed
+		result.Error = err.Error()
+
+		if _, ok := err.(*errors.ErrInternal); ok {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+func (i *Importer) upsert(rec *catalogRecord, key ImportKey, dryRun bool, result *ImportRowResult, ctx context.Context) error {
+	if rec.Err != nil {
+		return rec.Err
+	}
+
+	create := &CreateProductRequest{
+		Name:        rec.Name,
+		Description: rec.Description,
+		Price:       rec.Price,
+		Amount:      rec.Amount,
+		SKU:         rec.SKU,
+	}
+	if err := create.Validate(); err != nil {
+		return err
+	}
+
+	var existing *Product
+	var err error
+	switch key {
+	case ImportByName:
+		result.Key = create.Name
+		existing, err = i.querier.FindByName(create.Name, ctx)
+	case ImportBySKU:
+		if create.SKU == "" {
+			return fmt.Errorf("sku: cannot be blank when importing by sku")
+		}
+
+		result.Key = create.SKU
+		existing, err = i.querier.FindBySKU(create.SKU, ctx)
+	}
+
+	if _, ok := err.(*ErrNotFound); ok {
+		if dryRun {
+			err = i.checkFree(create.Name, create.SKU, "", ctx)
+		} else {
+			existing, err = i.service.Create(create, ctx)
+		}
+		if err != nil {
+			return err
+		}
+
+		result.Action = ImportCreated
+		if existing != nil {
+			result.ProductId = existing.Id
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	result.ProductId = existing.Id
+
+	update := &UpdateProductRequest{Id: existing.Id}
+	if create.Name != existing.Name {
+		update.Name = create.Name
+	}
+	if create.Description != existing.Description {
+		update.Description = create.Description
+	}
+	if create.Price != existing.Price {
+		update.Price = create.Price
+	}
+	if create.SKU != "" && create.SKU != existing.SKU {
+		update.SKU = create.SKU
+	}
+
+	if *update == (UpdateProductRequest{Id: existing.Id}) {
+		result.Action = ImportUnchanged
+		return nil
+	}
+
+	if dryRun {
+		// Products found by their SKU can be renamed, but not with the name of another product.
+		err = i.checkFree(update.Name, update.SKU, existing.Id, ctx)
+	} else {
+		err = i.service.Update(update, ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	result.Action = ImportUpdated
+	return nil
+}
+
+// checkFree fails with *ErrAlreadyExists if the name or the SKU belong to a product other than
+// the one with the given id, as the Service would.
+func (i *Importer) checkFree(name, sku string, id ProductId, ctx context.Context) error {
+	if err := checkName(i.querier, name, id, ctx); err != nil {
+		return err
+	}
+
+	return checkSKU(i.querier, sku, id, ctx)
+}
@@ -0,0 +1,202 @@
+package products
+
+import (
+	"context"
+	"fmt"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+
+	"github.com/giornetta/microshop/cloudevents"
+	"github.com/giornetta/microshop/errors"
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/eventstore"
+)
+
+type eventIngester struct {
+	querier    ProductQuerier
+	warehouses WarehouseQuerier
+	aggregates AggregateRepository
+}
+
+// NewEventIngester returns an Ingester appending the events it is given to the streams of their products,
+// in a single append, for the outbox to publish them like those of the Service. It records the price changes
+// and stock alerts following them as well.
+//
+// Only the creation, update and deletion of products can be ingested, as the other events follow from them.
+// They are validated like the requests of the Service, and the names and SKUs they give must not be taken
+// by other products, either in the projection or by the events ingested before them.
+func NewEventIngester(querier ProductQuerier, warehouses WarehouseQuerier, aggregates AggregateRepository) cloudevents.Ingester {
+	return &eventIngester{
+		querier:    querier,
+		warehouses: warehouses,
+		aggregates: aggregates,
+	}
+}
+
+func (i *eventIngester) Ingest(received []cloudevents.Received, ctx context.Context) error {
+	var (
+		loaded     = make(map[ProductId]*Aggregate)
+		aggregates []*Aggregate
+	)
+
+	for _, r := range received {
+		id := ProductId(r.Event.Key())
+		if err := validation.Validate(id.String(), is.UUID); err != nil {
+			return &errors.ErrBadRequest{Err: fmt.Errorf("invalid product id %s: %w", id, err)}
+		}
+
+		a, ok := loaded[id]
+		if !ok {
+			var err error
+			if a, err = i.aggregates.Load(id, ctx); err != nil {
+				return &errors.ErrInternal{Err: err}
+			}
+
+			loaded[id] = a
+			aggregates = append(aggregates, a)
+		}
+
+		if err := i.apply(a, r.Event, loaded, ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := i.aggregates.SaveAll(aggregates, ctx); err != nil {
+		if _, ok := err.(*eventstore.ErrConcurrencyConflict); ok {
+			return err
+		}
+
+		return &errors.ErrInternal{Err: err}
+	}
+
+	return nil
+}
+
+// apply validates the event against the aggregate and the others loaded, then records it along with the events
+// following it.
+func (i *eventIngester) apply(a *Aggregate, evt events.Event, loaded map[ProductId]*Aggregate, ctx context.Context) error {
+	switch e := evt.(type) {
+	case events.ProductCreated:
+		if a.Created {
+			return &ErrAlreadyExists{ProductId: a.Id}
+		}
+
+		details := &CreateProductRequest{Name: e.Name, Description: e.Description, Price: e.Price, Amount: e.Amount, SKU: e.SKU, ReorderThreshold: e.ReorderThreshold}
+		if err := i.validate(a.Id, details, e.Stock, loaded, ctx); err != nil {
+			return err
+		}
+		e.Name, e.Description, e.SKU = details.Name, details.Description, details.SKU
+
+		a.record(e)
+		a.record(a.priceChanged(0, ""))
+	case events.ProductUpdated:
+		if !a.Exists() {
+			return &ErrNotFound{ProductId: a.Id}
+		}
+
+		details := &CreateProductRequest{Name: e.Name, Description: e.Description, Price: e.Price, Amount: e.Amount, SKU: e.SKU, ReorderThreshold: e.ReorderThreshold}
+		if err := i.validate(a.Id, details, e.Stock, loaded, ctx); err != nil {
+			return err
+		}
+		e.Name, e.Description, e.SKU = details.Name, details.Description, details.SKU
+
+		before := a.Product
+		a.record(e)
+		if a.Price != before.Price {
+			a.record(a.priceChanged(before.Price, ""))
+		}
+		for _, alert := range stockAlerts(&before, &a.Product) {
+			a.record(alert)
+		}
+	case events.ProductDeleted:
+		if !a.Exists() {
+			return &ErrNotFound{ProductId: a.Id}
+		}
+
+		a.record(e)
+	default:
+		return &errors.ErrBadRequest{Err: fmt.Errorf("%s events cannot be ingested, as they follow from other events", evt.Type())}
+	}
+
+	return nil
+}
+
+// validate checks the details of a product, carried by a creation or update, and its stock,
+// which must add up to its amount and be held by known warehouses.
+func (i *eventIngester) validate(id ProductId, details *CreateProductRequest, stock []events.WarehouseStock, loaded map[ProductId]*Aggregate, ctx context.Context) error {
+	if err := details.Validate(); err != nil {
+		return &errors.ErrBadRequest{Err: err}
+	}
+
+	if err := i.checkUnique(id, details.Name, details.SKU, loaded, ctx); err != nil {
+		return err
+	}
+
+	if len(stock) == 0 {
+		return nil
+	}
+
+	total := 0
+	seen := make(map[string]bool)
+	for _, s := range stock {
+		if s.Amount < 0 || seen[s.WarehouseId] {
+			return &errors.ErrBadRequest{Err: fmt.Errorf("invalid stock of warehouse %s", s.WarehouseId)}
+		}
+		seen[s.WarehouseId] = true
+		total += s.Amount
+
+		if err := checkWarehouse(i.warehouses, WarehouseId(s.WarehouseId), ctx); err != nil {
+			return err
+		}
+	}
+
+	if total != details.Amount {
+		return &errors.ErrBadRequest{Err: fmt.Errorf("stock adds up to %d instead of the amount %d", total, details.Amount)}
+	}
+
+	return nil
+}
+
+// checkUnique checks that no other product has the name or SKU the product with the given id is about to take.
+// The products loaded by the ingester are compared in the state the events ingested so far left them in,
+// which is the state the projection will find them in, as they may have been renamed or deleted.
+func (i *eventIngester) checkUnique(id ProductId, name, sku string, loaded map[ProductId]*Aggregate, ctx context.Context) error {
+	for _, other := range loaded {
+		if other.Id == id || !other.Exists() {
+			continue
+		}
+
+		if other.Name == name {
+			return &ErrAlreadyExists{Name: name}
+		}
+
+		if sku != "" && other.SKU == sku {
+			return &ErrAlreadyExists{SKU: sku}
+		}
+	}
+
+	p, err := i.querier.FindByName(name, ctx)
+	if err == nil {
+		if _, ok := loaded[p.Id]; !ok && p.Id != id {
+			return &ErrAlreadyExists{Name: name}
+		}
+	} else if _, ok := err.(*ErrNotFound); !ok {
+		return err
+	}
+
+	if sku == "" {
+		return nil
+	}
+
+	p, err = i.querier.FindBySKU(sku, ctx)
+	if err == nil {
+		if _, ok := loaded[p.Id]; !ok && p.Id != id {
+			return &ErrAlreadyExists{SKU: sku}
+		}
+	} else if _, ok := err.(*ErrNotFound); !ok {
+		return err
+	}
+
+	return nil
+}
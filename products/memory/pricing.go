@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/giornetta/microshop/products"
+)
+
+type priceHistoryRepository struct {
+	lock    sync.RWMutex
+	history map[products.ProductId][]products.PriceChange
+}
+
+func NewPriceHistoryRepository() products.PriceHistoryRepository {
+	return &priceHistoryRepository{
+		history: make(map[products.ProductId][]products.PriceChange),
+	}
+}
+
+func (r *priceHistoryRepository) Record(id products.ProductId, change *products.PriceChange, ctx context.Context) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	history := r.history[id]
+	i, found := slices.BinarySearchFunc(history, change.ChangedAt, func(c products.PriceChange, t time.Time) int {
+		return c.ChangedAt.Compare(t)
+	})
+	if found {
+		return nil
+	}
+
+	r.history[id] = slices.Insert(history, i, *change)
+	return nil
+}
+
+func (r *priceHistoryRepository) History(id products.ProductId, ctx context.Context) ([]*products.PriceChange, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var history []*products.PriceChange
+	for _, c := range r.history[id] {
+		c := c
+		history = append(history, &c)
+	}
+
+	return history, nil
+}
+
+type priceScheduleRepository struct {
+	lock      sync.RWMutex
+	schedules map[products.PriceScheduleId]products.PriceSchedule
+}
+
+func NewPriceScheduleRepository() products.PriceScheduleRepository {
+	return &priceScheduleRepository{
+		schedules: make(map[products.PriceScheduleId]products.PriceSchedule),
+	}
+}
+
+// cloneSchedule copies the schedule along with the values its pointers refer to, which must not be shared
+// with the callers.
+func cloneSchedule(s products.PriceSchedule) *products.PriceSchedule {
+	if s.EffectiveUntil != nil {
+		until := *s.EffectiveUntil
+		s.EffectiveUntil = &until
+	}
+
+	if s.PreviousPrice != nil {
+		previous := *s.PreviousPrice
+		s.PreviousPrice = &previous
+	}
+
+	return &s
+}
+
+func (r *priceScheduleRepository) Store(schedule *products.PriceSchedule, ctx context.Context) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if inEffect(schedule) {
+		for _, s := range r.schedules {
+			if s.ProductId == schedule.ProductId && s.Id != schedule.Id && inEffect(&s) && s.Overlaps(schedule) {
+				return &products.ErrPriceScheduleOverlaps{ScheduleId: s.Id}
+			}
+		}
+	}
+
+	r.schedules[schedule.Id] = *cloneSchedule(*schedule)
+	return nil
+}
+
+// inEffect reports whether the schedule is pending or active, the only ones that must not overlap.
+func inEffect(s *products.PriceSchedule) bool {
+	return s.Status == products.SchedulePending || s.Status == products.ScheduleActive
+}
+
+func (r *priceScheduleRepository) FindById(id products.PriceScheduleId, ctx context.Context) (*products.PriceSchedule, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	s, ok := r.schedules[id]
+	if !ok {
+		return nil, &products.ErrPriceScheduleNotFound{ScheduleId: id}
+	}
+
+	return cloneSchedule(s), nil
+}
+
+func (r *priceScheduleRepository) ListByProduct(id products.ProductId, ctx context.Context) ([]*products.PriceSchedule, error) {
+	return r.list(func(s *products.PriceSchedule) bool {
+		return s.ProductId == id
+	}), nil
+}
+
+func (r *priceScheduleRepository) ListDue(now time.Time, ctx context.Context) ([]*products.PriceSchedule, error) {
+	return r.list(func(s *products.PriceSchedule) bool {
+		switch s.Status {
+		case products.SchedulePending:
+			return !s.EffectiveFrom.After(now)
+		case products.ScheduleActive:
+			return s.EffectiveUntil != nil && !s.EffectiveUntil.After(now)
+		default:
+			return false
+		}
+	}), nil
+}
+
+func (r *priceScheduleRepository) list(match func(s *products.PriceSchedule) bool) []*products.PriceSchedule {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var schedules []*products.PriceSchedule
+	for _, s := range r.schedules {
+		if match(&s) {
+			schedules = append(schedules, cloneSchedule(s))
+		}
+	}
+
+	return schedules
+}
+
+func (r *priceScheduleRepository) Update(schedule *products.PriceSchedule, status products.PriceScheduleStatus, ctx context.Context) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	s, ok := r.schedules[schedule.Id]
+	if !ok {
+		return &products.ErrPriceScheduleNotFound{ScheduleId: schedule.Id}
+	}
+
+	if s.Status != status {
+		return &products.ErrPriceScheduleConflict{ScheduleId: s.Id, Status: s.Status}
+	}
+
+	r.schedules[schedule.Id] = *cloneSchedule(*schedule)
+	return nil
+}
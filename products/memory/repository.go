@@ -0,0 +1,149 @@
+// Package memory implements the repositories of products and warehouses in memory, standing in for Postgres
+// where a database is not available, such as in tests.
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/giornetta/microshop/products"
+)
+
+type repository struct {
+	lock     sync.RWMutex
+	products map[products.ProductId]products.Product
+}
+
+func NewProductRepository() products.ProductRepository {
+	return &repository{
+		products: make(map[products.ProductId]products.Product),
+	}
+}
+
+func (r *repository) FindById(id products.ProductId, ctx context.Context) (*products.Product, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	p, ok := r.products[id]
+	if !ok {
+		return nil, &products.ErrNotFound{ProductId: id}
+	}
+
+	return clone(p), nil
+}
+
+func (r *repository) FindByName(name string, ctx context.Context) (*products.Product, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for _, p := range r.products {
+		if p.Name == name {
+			return clone(p), nil
+		}
+	}
+
+	return nil, &products.ErrNotFound{Name: name}
+}
+
+func (r *repository) FindBySKU(sku string, ctx context.Context) (*products.Product, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for _, p := range r.products {
+		if sku != "" && p.SKU == sku {
+			return clone(p), nil
+		}
+	}
+
+	return nil, &products.ErrNotFound{SKU: sku}
+}
+
+func (r *repository) List(ctx context.Context) ([]*products.Product, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var prods []*products.Product
+	for _, p := range r.products {
+		prods = append(prods, clone(p))
+	}
+
+	return prods, nil
+}
+
+func (r *repository) ListLowStock(ctx context.Context) ([]*products.Product, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var prods []*products.Product
+	for _, p := range r.products {
+		if p.StockLow() {
+			prods = append(prods, clone(p))
+		}
+	}
+
+	return prods, nil
+}
+
+func (r *repository) Store(product *products.Product, ctx context.Context) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.products[product.Id]; ok {
+		return &products.ErrAlreadyExists{ProductId: product.Id}
+	}
+
+	for _, p := range r.products {
+		if p.Name == product.Name {
+			return &products.ErrAlreadyExists{Name: product.Name}
+		}
+
+		if product.SKU != "" && p.SKU == product.SKU {
+			return &products.ErrAlreadyExists{SKU: product.SKU}
+		}
+	}
+
+	r.products[product.Id] = *clone(*product)
+	return nil
+}
+
+func (r *repository) Update(product *products.Product, ctx context.Context) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.products[product.Id]; !ok {
+		return nil
+	}
+
+	for _, p := range r.products {
+		if p.Id == product.Id {
+			continue
+		}
+
+		if p.Name == product.Name {
+			return &products.ErrAlreadyExists{Name: product.Name}
+		}
+
+		if product.SKU != "" && p.SKU == product.SKU {
+			return &products.ErrAlreadyExists{SKU: product.SKU}
+		}
+	}
+
+	r.products[product.Id] = *clone(*product)
+	return nil
+}
+
+func (r *repository) Delete(id products.ProductId, ctx context.Context) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.products, id)
+	return nil
+}
+
+// clone returns a copy of p not sharing its stock, so that the stored products cannot be changed
+// through the ones passed to or returned by the repository.
+func clone(p products.Product) *products.Product {
+	p.Stock = slices.Clone(p.Stock)
+	return &p
+}
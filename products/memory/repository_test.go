@@ -0,0 +1,32 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/giornetta/microshop/products"
+	"github.com/giornetta/microshop/products/productstest"
+)
+
+func TestRepository(t *testing.T) {
+	productstest.TestRepository(t, func(t *testing.T) products.ProductRepository {
+		return NewProductRepository()
+	})
+}
+
+func TestWarehouseRepository(t *testing.T) {
+	productstest.TestWarehouseRepository(t, func(t *testing.T) products.WarehouseRepository {
+		return NewWarehouseRepository()
+	})
+}
+
+func TestPriceHistoryRepository(t *testing.T) {
+	productstest.TestPriceHistoryRepository(t, func(t *testing.T) products.PriceHistoryRepository {
+		return NewPriceHistoryRepository()
+	})
+}
+
+func TestPriceScheduleRepository(t *testing.T) {
+	productstest.TestPriceScheduleRepository(t, func(t *testing.T) products.PriceScheduleRepository {
+		return NewPriceScheduleRepository()
+	})
+}
@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/giornetta/microshop/products"
+)
+
+type warehouseRepository struct {
+	lock       sync.RWMutex
+	warehouses map[products.WarehouseId]products.Warehouse
+}
+
+// NewWarehouseRepository returns a WarehouseRepository holding the DefaultWarehouse, as the pg one does
+// once migrated.
+func NewWarehouseRepository() products.WarehouseRepository {
+	return &warehouseRepository{
+		warehouses: map[products.WarehouseId]products.Warehouse{
+			products.DefaultWarehouse: {Id: products.DefaultWarehouse, Name: "Default warehouse"},
+		},
+	}
+}
+
+func (r *warehouseRepository) FindById(id products.WarehouseId, ctx context.Context) (*products.Warehouse, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	w, ok := r.warehouses[id]
+	if !ok {
+		return nil, &products.ErrWarehouseNotFound{WarehouseId: id}
+	}
+
+	return &w, nil
+}
+
+func (r *warehouseRepository) List(ctx context.Context) ([]*products.Warehouse, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var warehouses []*products.Warehouse
+	for _, w := range r.warehouses {
+		w := w
+		warehouses = append(warehouses, &w)
+	}
+
+	return warehouses, nil
+}
+
+func (r *warehouseRepository) Store(warehouse *products.Warehouse, ctx context.Context) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.warehouses[warehouse.Id]; ok {
+		return &products.ErrWarehouseAlreadyExists{WarehouseId: warehouse.Id}
+	}
+
+	r.warehouses[warehouse.Id] = *warehouse
+	return nil
+}
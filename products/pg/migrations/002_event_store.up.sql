@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS event_streams (
+    aggregate_id    TEXT        NOT NULL,
+    version         INT         NOT NULL CHECK (version > 0),
+    event_type      TEXT        NOT NULL,
+    event_version   INT         NOT NULL,
+    payload         JSONB       NOT NULL,
+    recorded_at     TIMESTAMPTZ NOT NULL,
+
+    PRIMARY KEY (aggregate_id, version)
+);
+
+CREATE TABLE IF NOT EXISTS event_snapshots (
+    aggregate_id    TEXT        PRIMARY KEY,
+    version         INT         NOT NULL,
+    state           JSONB       NOT NULL,
+    taken_at        TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS reorder_threshold INT NOT NULL DEFAULT 0 CHECK (reorder_threshold >= 0);
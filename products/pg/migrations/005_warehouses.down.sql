@@ -0,0 +1,3 @@
+ALTER TABLE products DROP COLUMN IF EXISTS stock;
+
+DROP TABLE IF EXISTS warehouses;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS warehouses (
+    warehouse_id TEXT PRIMARY KEY,
+    name         TEXT NOT NULL,
+    location     TEXT NOT NULL DEFAULT ''
+);
+
+INSERT INTO warehouses (warehouse_id, name) VALUES ('default', 'Default warehouse') ON CONFLICT DO NOTHING;
+
+-- The stock held by each warehouse, as a JSON array of {"warehouse_id", "amount"} objects sorted by warehouse.
+-- Existing stock is moved to the default warehouse.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS stock JSONB NOT NULL DEFAULT '[]';
+
+UPDATE products
+SET stock = jsonb_build_array(jsonb_build_object('warehouse_id', 'default', 'amount', amount))
+WHERE amount > 0 AND stock = '[]';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS price_schedules;
+
+DROP TABLE IF EXISTS price_history;
@@ -0,0 +1,26 @@
+-- The price history is projected from the Product.PriceChanged events, which are recorded once per product and time.
+CREATE TABLE IF NOT EXISTS price_history (
+    product_id     UUID        NOT NULL,
+    price          REAL        NOT NULL,
+    previous_price REAL        NOT NULL,
+    changed_at     TIMESTAMPTZ NOT NULL,
+    schedule_id    UUID,
+    PRIMARY KEY (product_id, changed_at)
+);
+
+-- Schedules outlive their products, which the price scheduler cancels them for, so they do not reference them.
+CREATE TABLE IF NOT EXISTS price_schedules (
+    schedule_id     UUID        PRIMARY KEY,
+    product_id      UUID        NOT NULL,
+    price           REAL        NOT NULL CHECK (price > 0),
+    effective_from  TIMESTAMPTZ NOT NULL,
+    effective_until TIMESTAMPTZ CHECK (effective_until > effective_from),
+    status          TEXT        NOT NULL,
+    previous_price  REAL,
+    created_at      TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS price_schedules_product_id ON price_schedules (product_id);
+
+-- Only pending and active schedules are ever due.
+CREATE INDEX IF NOT EXISTS price_schedules_due ON price_schedules (status) WHERE status IN ('pending', 'active');
@@ -0,0 +1,9 @@
+-- Events appended to the streams until they are published. Those appended before the outbox
+-- existed were published along with their append.
+CREATE TABLE IF NOT EXISTS event_outbox (
+    position        BIGSERIAL   PRIMARY KEY,
+    aggregate_id    TEXT        NOT NULL,
+    version         INT         NOT NULL,
+
+    FOREIGN KEY (aggregate_id, version) REFERENCES event_streams(aggregate_id, version)
+);
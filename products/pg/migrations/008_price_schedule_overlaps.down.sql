@@ -0,0 +1,3 @@
+ALTER TABLE price_schedules DROP CONSTRAINT IF EXISTS price_schedules_no_overlap;
+
+DROP FUNCTION IF EXISTS price_schedule_range;
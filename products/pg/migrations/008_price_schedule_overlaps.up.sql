@@ -0,0 +1,27 @@
+-- btree_gist lets the exclusion constraint compare product ids with =.
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+-- The range a schedule is in effect: until it expires, or only when it takes effect if it never does,
+-- as such schedules never restore the previous price.
+CREATE OR REPLACE FUNCTION price_schedule_range(effective_from TIMESTAMPTZ, effective_until TIMESTAMPTZ)
+RETURNS TSTZRANGE LANGUAGE SQL IMMUTABLE AS $$
+    SELECT CASE
+        WHEN effective_until IS NULL THEN tstzrange(effective_from, effective_from, '[]')
+        ELSE tstzrange(effective_from, effective_until, '[)')
+    END
+$$;
+
+-- Schedules stored concurrently may overlap from before the constraint: the later ones are canceled.
+UPDATE price_schedules s SET status = 'canceled'
+WHERE s.status = 'pending' AND EXISTS (
+    SELECT 1 FROM price_schedules o
+    WHERE o.product_id = s.product_id
+      AND o.status IN ('pending', 'active')
+      AND (o.created_at, o.schedule_id) < (s.created_at, s.schedule_id)
+      AND price_schedule_range(o.effective_from, o.effective_until) && price_schedule_range(s.effective_from, s.effective_until)
+);
+
+ALTER TABLE price_schedules ADD CONSTRAINT price_schedules_no_overlap EXCLUDE USING gist (
+    product_id WITH =,
+    price_schedule_range(effective_from, effective_until) WITH &&
+) WHERE (status IN ('pending', 'active'));
@@ -0,0 +1,225 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/giornetta/microshop/errors"
+	"github.com/giornetta/microshop/postgres"
+	"github.com/giornetta/microshop/products"
+)
+
+type priceHistoryRepository struct {
+	pool  *pgxpool.Pool
+	table string
+}
+
+func NewPriceHistoryRepository(pool *pgxpool.Pool) products.PriceHistoryRepository {
+	return NewPriceHistoryRepositoryWithTable(pool, "price_history")
+}
+
+// NewPriceHistoryRepositoryWithTable returns a PriceHistoryRepository backed by the given table,
+// which must have the same columns as the price_history one.
+func NewPriceHistoryRepositoryWithTable(pool *pgxpool.Pool, table string) products.PriceHistoryRepository {
+	return &priceHistoryRepository{
+		pool:  pool,
+		table: pgx.Identifier{table}.Sanitize(),
+	}
+}
+
+func (r *priceHistoryRepository) Record(id products.ProductId, change *products.PriceChange, ctx context.Context) error {
+	if _, err := r.pool.Exec(
+		ctx,
+		fmt.Sprintf(`INSERT INTO %s(product_id, price, previous_price, changed_at, schedule_id)
+		VALUES($1, $2, $3, $4, NULLIF($5, '')::UUID)
+		ON CONFLICT DO NOTHING;`, r.table),
+		id, change.Price, change.PreviousPrice, change.ChangedAt, change.ScheduleId,
+	); err != nil {
+		return &errors.ErrInternal{Err: err}
+	}
+
+	return nil
+}
+
+func (r *priceHistoryRepository) History(id products.ProductId, ctx context.Context) ([]*products.PriceChange, error) {
+	var history []*products.PriceChange
+
+	rows, err := r.pool.Query(
+		ctx,
+		fmt.Sprintf(`SELECT price, previous_price, changed_at, COALESCE(schedule_id::TEXT, '')
+		FROM %s WHERE product_id = $1 ORDER BY changed_at`, r.table),
+		id,
+	)
+	if err != nil {
+		return nil, &errors.ErrInternal{Err: err}
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c products.PriceChange
+		if err := rows.Scan(&c.Price, &c.PreviousPrice, &c.ChangedAt, &c.ScheduleId); err != nil {
+			return nil, &errors.ErrInternal{Err: err}
+		}
+
+		c.ChangedAt = c.ChangedAt.UTC()
+		history = append(history, &c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, &errors.ErrInternal{Err: err}
+	}
+
+	return history, nil
+}
+
+type priceScheduleRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPriceScheduleRepository(pool *pgxpool.Pool) products.PriceScheduleRepository {
+	return &priceScheduleRepository{
+		pool: pool,
+	}
+}
+
+// scheduleColumns are selected by every query, in the order scanSchedule reads them.
+const scheduleColumns = "schedule_id, product_id, price, effective_from, effective_until, status, previous_price, created_at"
+
+func scanSchedule(row pgx.Row) (*products.PriceSchedule, error) {
+	var s products.PriceSchedule
+
+	if err := row.Scan(&s.Id, &s.ProductId, &s.Price, &s.EffectiveFrom, &s.EffectiveUntil, &s.Status, &s.PreviousPrice, &s.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	// Times are read in the local time zone, and written in UTC by the service.
+	s.EffectiveFrom = s.EffectiveFrom.UTC()
+	if s.EffectiveUntil != nil {
+		until := s.EffectiveUntil.UTC()
+		s.EffectiveUntil = &until
+	}
+	s.CreatedAt = s.CreatedAt.UTC()
+
+	return &s, nil
+}
+
+func (r *priceScheduleRepository) Store(schedule *products.PriceSchedule, ctx context.Context) error {
+	if _, err := r.pool.Exec(
+		ctx,
+		"INSERT INTO price_schedules("+scheduleColumns+") VALUES($1, $2, $3, $4, $5, $6, $7, $8);",
+		schedule.Id, schedule.ProductId, schedule.Price, schedule.EffectiveFrom, schedule.EffectiveUntil,
+		schedule.Status, schedule.PreviousPrice, schedule.CreatedAt,
+	); err != nil {
+		if postgres.ExclusionViolation(err) {
+			return r.overlapping(schedule, ctx)
+		}
+
+		return &errors.ErrInternal{Err: err}
+	}
+
+	return nil
+}
+
+// overlapping returns the *products.ErrPriceScheduleOverlaps naming a schedule that schedule overlaps,
+// after the exclusion constraint rejected it.
+func (r *priceScheduleRepository) overlapping(schedule *products.PriceSchedule, ctx context.Context) error {
+	var id products.PriceScheduleId
+	if err := r.pool.QueryRow(
+		ctx,
+		`SELECT schedule_id FROM price_schedules
+		WHERE product_id = $1 AND status IN ('pending', 'active')
+		AND price_schedule_range(effective_from, effective_until) && price_schedule_range($2, $3)
+		LIMIT 1`,
+		schedule.ProductId, schedule.EffectiveFrom, schedule.EffectiveUntil,
+	).Scan(&id); err != nil {
+		// The other schedule may have been completed or canceled since.
+		if err == pgx.ErrNoRows {
+			return &products.ErrPriceScheduleOverlaps{}
+		}
+
+		return &errors.ErrInternal{Err: err}
+	}
+
+	return &products.ErrPriceScheduleOverlaps{ScheduleId: id}
+}
+
+func (r *priceScheduleRepository) FindById(id products.PriceScheduleId, ctx context.Context) (*products.PriceSchedule, error) {
+	s, err := scanSchedule(r.pool.QueryRow(ctx, "SELECT "+scheduleColumns+" FROM price_schedules WHERE schedule_id = $1", id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, &products.ErrPriceScheduleNotFound{ScheduleId: id}
+		}
+
+		return nil, &errors.ErrInternal{Err: err}
+	}
+
+	return s, nil
+}
+
+func (r *priceScheduleRepository) ListByProduct(id products.ProductId, ctx context.Context) ([]*products.PriceSchedule, error) {
+	return r.list("SELECT "+scheduleColumns+" FROM price_schedules WHERE product_id = $1", id, ctx)
+}
+
+func (r *priceScheduleRepository) ListDue(now time.Time, ctx context.Context) ([]*products.PriceSchedule, error) {
+	return r.list(
+		`SELECT `+scheduleColumns+` FROM price_schedules
+		WHERE (status = 'pending' AND effective_from <= $1) OR (status = 'active' AND effective_until <= $1)`,
+		now,
+		ctx,
+	)
+}
+
+// list returns the schedules selected by query, which takes a single argument.
+func (r *priceScheduleRepository) list(query string, arg any, ctx context.Context) ([]*products.PriceSchedule, error) {
+	var schedules []*products.PriceSchedule
+
+	rows, err := r.pool.Query(ctx, query, arg)
+	if err != nil {
+		return nil, &errors.ErrInternal{Err: err}
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, &errors.ErrInternal{Err: err}
+		}
+
+		schedules = append(schedules, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, &errors.ErrInternal{Err: err}
+	}
+
+	return schedules, nil
+}
+
+func (r *priceScheduleRepository) Update(schedule *products.PriceSchedule, status products.PriceScheduleStatus, ctx context.Context) error {
+	tag, err := r.pool.Exec(
+		ctx,
+		`UPDATE price_schedules
+		SET price = $3, effective_from = $4, effective_until = $5, status = $6, previous_price = $7
+		WHERE schedule_id = $1 AND status = $2;`,
+		schedule.Id, status, schedule.Price, schedule.EffectiveFrom, schedule.EffectiveUntil, schedule.Status, schedule.PreviousPrice,
+	)
+	if err != nil {
+		return &errors.ErrInternal{Err: err}
+	}
+
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	// The schedule is missing or has another status, which FindById tells apart.
+	current, err := r.FindById(schedule.Id, ctx)
+	if err != nil {
+		return err
+	}
+
+	return &products.ErrPriceScheduleConflict{ScheduleId: current.Id, Status: current.Status}
+}
@@ -2,105 +2,177 @@ package pg
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 
 	"github.com/giornetta/microshop/errors"
+	"github.com/giornetta/microshop/postgres"
 	"github.com/giornetta/microshop/products"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type repository struct {
-	pool *pgxpool.Pool
+	pool  *pgxpool.Pool
+	table string
 }
 
 func NewProductRepository(pool *pgxpool.Pool) products.ProductRepository {
+	return NewProductRepositoryWithTable(pool, "products")
+}
+
+// NewProductRepositoryWithTable returns a ProductRepository backed by the given table,
+// which must have the same columns as the products one.
+func NewProductRepositoryWithTable(pool *pgxpool.Pool, table string) products.ProductRepository {
 	return &repository{
-		pool: pool,
+		pool:  pool,
+		table: pgx.Identifier{table}.Sanitize(),
 	}
 }
 
-func (r *repository) FindById(id products.ProductId, ctx context.Context) (*products.Product, error) {
-	var product products.Product
+// columns are selected by every query, in the order scan reads them. Products without a SKU
+// store NULL, so that the unique constraint only applies to the others.
+const columns = "product_id, name, description, price, amount, COALESCE(sku, ''), reorder_threshold, stock"
+
+// marshalStock encodes the stock of a product for its JSONB column, as an empty array when there is none.
+func marshalStock(stock []products.WarehouseStock) (string, error) {
+	if stock == nil {
+		stock = []products.WarehouseStock{}
+	}
+
+	b, err := json.Marshal(stock)
+	return string(b), err
+}
+
+func scan(row pgx.Row) (*products.Product, error) {
+	var p products.Product
+
+	if err := row.Scan(&p.Id, &p.Name, &p.Description, &p.Price, &p.Amount, &p.SKU, &p.ReorderThreshold, &p.Stock); err != nil {
+		return nil, err
+	}
+
+	// Products without stock have an empty array, left nil as in the other repositories.
+	if len(p.Stock) == 0 {
+		p.Stock = nil
+	}
+
+	return &p, nil
+}
 
-	err := r.pool.QueryRow(ctx, "SELECT * FROM products WHERE product_id = $1", id).Scan(
-		&product.Id, &product.Name, &product.Description, &product.Price, &product.Amount)
+func (r *repository) findBy(column string, value any, notFound *products.ErrNotFound, ctx context.Context) (*products.Product, error) {
+	product, err := scan(r.pool.QueryRow(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE %s = $1", columns, r.table, column), value))
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, &products.ErrNotFound{ProductId: id}
+			return nil, notFound
 		}
 
 		return nil, &errors.ErrInternal{Err: err}
 	}
 
-	return &product, nil
+	return product, nil
+}
+
+func (r *repository) FindById(id products.ProductId, ctx context.Context) (*products.Product, error) {
+	return r.findBy("product_id", id, &products.ErrNotFound{ProductId: id}, ctx)
 }
 
 func (r *repository) FindByName(name string, ctx context.Context) (*products.Product, error) {
-	var product products.Product
+	return r.findBy("name", name, &products.ErrNotFound{Name: name}, ctx)
+}
 
-	if err := r.pool.QueryRow(
-		ctx,
-		"SELECT product_id, name, description, price, amount FROM products WHERE name = $1",
-		name,
-	).Scan(&product.Id, &product.Name, &product.Description, &product.Price, &product.Amount); err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, &products.ErrNotFound{Name: name}
-		}
+func (r *repository) FindBySKU(sku string, ctx context.Context) (*products.Product, error) {
+	return r.findBy("sku", sku, &products.ErrNotFound{SKU: sku}, ctx)
+}
 
-		return nil, &errors.ErrInternal{Err: err}
-	}
+func (r *repository) List(ctx context.Context) ([]*products.Product, error) {
+	return r.list(fmt.Sprintf("SELECT %s FROM %s", columns, r.table), ctx)
+}
 
-	return &product, nil
+func (r *repository) ListLowStock(ctx context.Context) ([]*products.Product, error) {
+	return r.list(fmt.Sprintf("SELECT %s FROM %s WHERE amount <= reorder_threshold", columns, r.table), ctx)
 }
 
-func (r *repository) List(ctx context.Context) ([]*products.Product, error) {
+func (r *repository) list(query string, ctx context.Context) ([]*products.Product, error) {
 	var prods []*products.Product
 
-	rows, err := r.pool.Query(ctx, "SELECT product_id, name, description, price, amount FROM products")
-	if err != nil && err != pgx.ErrNoRows {
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
 		return nil, &errors.ErrInternal{Err: err}
 	}
+	defer rows.Close()
 
 	for rows.Next() {
-		var p products.Product
-
-		if err := rows.Scan(&p.Id, &p.Name, &p.Description, &p.Price, &p.Amount); err != nil {
+		p, err := scan(rows)
+		if err != nil {
 			return nil, &errors.ErrInternal{Err: err}
 		}
 
-		prods = append(prods, &p)
+		prods = append(prods, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, &errors.ErrInternal{Err: err}
 	}
 
 	return prods, nil
 }
 
 func (r *repository) Store(product *products.Product, ctx context.Context) error {
+	stock, err := marshalStock(product.Stock)
+	if err != nil {
+		return &errors.ErrInternal{Err: err}
+	}
+
 	if _, err := r.pool.Exec(
 		ctx,
-		"INSERT INTO products(product_id, name, description, price, amount) VALUES($1, $2, $3, $4, $5);",
-		product.Id, product.Name, product.Description, product.Price, product.Amount,
+		fmt.Sprintf("INSERT INTO %s(product_id, name, description, price, amount, sku, reorder_threshold, stock) VALUES($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8);", r.table),
+		product.Id, product.Name, product.Description, product.Price, product.Amount, product.SKU, product.ReorderThreshold, stock,
 	); err != nil {
-		return err
+		if violation, primaryKey := postgres.UniqueViolation(err); violation {
+			switch {
+			case primaryKey:
+				return &products.ErrAlreadyExists{ProductId: product.Id}
+			case postgres.UniqueViolationOn(err, "sku"):
+				return &products.ErrAlreadyExists{SKU: product.SKU}
+			default:
+				return &products.ErrAlreadyExists{Name: product.Name}
+			}
+		}
+
+		return &errors.ErrInternal{Err: err}
 	}
 
 	return nil
 }
 
 func (r *repository) Update(product *products.Product, ctx context.Context) error {
+	stock, err := marshalStock(product.Stock)
+	if err != nil {
+		return &errors.ErrInternal{Err: err}
+	}
+
 	if _, err := r.pool.Exec(
 		ctx,
-		"UPDATE products SET name = $1, description = $2, price = $3, amount = $4 WHERE product_id = $5",
-		product.Name, product.Description, product.Price, product.Amount, product.Id,
+		fmt.Sprintf("UPDATE %s SET name = $1, description = $2, price = $3, amount = $4, sku = NULLIF($5, ''), reorder_threshold = $6, stock = $7 WHERE product_id = $8", r.table),
+		product.Name, product.Description, product.Price, product.Amount, product.SKU, product.ReorderThreshold, stock, product.Id,
 	); err != nil {
-		return err
+		if violation, _ := postgres.UniqueViolation(err); violation {
+			if postgres.UniqueViolationOn(err, "sku") {
+				return &products.ErrAlreadyExists{SKU: product.SKU}
+			}
+
+			return &products.ErrAlreadyExists{Name: product.Name}
+		}
+
+		return &errors.ErrInternal{Err: err}
 	}
 
 	return nil
 }
 
 func (r *repository) Delete(id products.ProductId, ctx context.Context) error {
-	if _, err := r.pool.Exec(ctx, "DELETE FROM products WHERE product_id = $1;", id); err != nil {
-		return err
+	if _, err := r.pool.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE product_id = $1;", r.table), id); err != nil {
+		return &errors.ErrInternal{Err: err}
 	}
 
 	return nil
@@ -0,0 +1,34 @@
+package pg
+
+import (
+	"testing"
+
+	"github.com/giornetta/microshop/postgres/postgrestest"
+	"github.com/giornetta/microshop/products"
+	"github.com/giornetta/microshop/products/productstest"
+)
+
+// TestRepository runs against the server given by postgrestest.URLEnv, and is skipped without one.
+func TestRepository(t *testing.T) {
+	productstest.TestRepository(t, func(t *testing.T) products.ProductRepository {
+		return NewProductRepository(postgrestest.Database(t, Migrations))
+	})
+}
+
+func TestWarehouseRepository(t *testing.T) {
+	productstest.TestWarehouseRepository(t, func(t *testing.T) products.WarehouseRepository {
+		return NewWarehouseRepository(postgrestest.Database(t, Migrations))
+	})
+}
+
+func TestPriceHistoryRepository(t *testing.T) {
+	productstest.TestPriceHistoryRepository(t, func(t *testing.T) products.PriceHistoryRepository {
+		return NewPriceHistoryRepository(postgrestest.Database(t, Migrations))
+	})
+}
+
+func TestPriceScheduleRepository(t *testing.T) {
+	productstest.TestPriceScheduleRepository(t, func(t *testing.T) products.PriceScheduleRepository {
+		return NewPriceScheduleRepository(postgrestest.Database(t, Migrations))
+	})
+}
@@ -0,0 +1,77 @@
+package pg
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/giornetta/microshop/errors"
+	"github.com/giornetta/microshop/postgres"
+	"github.com/giornetta/microshop/products"
+)
+
+type warehouseRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewWarehouseRepository(pool *pgxpool.Pool) products.WarehouseRepository {
+	return &warehouseRepository{
+		pool: pool,
+	}
+}
+
+func (r *warehouseRepository) FindById(id products.WarehouseId, ctx context.Context) (*products.Warehouse, error) {
+	var w products.Warehouse
+
+	if err := r.pool.QueryRow(ctx, "SELECT warehouse_id, name, location FROM warehouses WHERE warehouse_id = $1", id).Scan(&w.Id, &w.Name, &w.Location); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, &products.ErrWarehouseNotFound{WarehouseId: id}
+		}
+
+		return nil, &errors.ErrInternal{Err: err}
+	}
+
+	return &w, nil
+}
+
+func (r *warehouseRepository) List(ctx context.Context) ([]*products.Warehouse, error) {
+	var warehouses []*products.Warehouse
+
+	rows, err := r.pool.Query(ctx, "SELECT warehouse_id, name, location FROM warehouses")
+	if err != nil {
+		return nil, &errors.ErrInternal{Err: err}
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var w products.Warehouse
+		if err := rows.Scan(&w.Id, &w.Name, &w.Location); err != nil {
+			return nil, &errors.ErrInternal{Err: err}
+		}
+
+		warehouses = append(warehouses, &w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, &errors.ErrInternal{Err: err}
+	}
+
+	return warehouses, nil
+}
+
+func (r *warehouseRepository) Store(warehouse *products.Warehouse, ctx context.Context) error {
+	if _, err := r.pool.Exec(
+		ctx,
+		"INSERT INTO warehouses(warehouse_id, name, location) VALUES($1, $2, $3);",
+		warehouse.Id, warehouse.Name, warehouse.Location,
+	); err != nil {
+		if violation, _ := postgres.UniqueViolation(err); violation {
+			return &products.ErrWarehouseAlreadyExists{WarehouseId: warehouse.Id}
+		}
+
+		return &errors.ErrInternal{Err: err}
+	}
+
+	return nil
+}
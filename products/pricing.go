@@ -0,0 +1,292 @@
+package products
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+
+	"github.com/giornetta/microshop/errors"
+	"github.com/giornetta/microshop/events"
+)
+
+// PriceChange is an entry of the price history of a product.
+type PriceChange struct {
+	Price float32 `json:"price"`
+	// PreviousPrice is zero for the price set by the creation of the product.
+	PreviousPrice float32   `json:"previous_price"`
+	ChangedAt     time.Time `json:"changed_at"`
+	// ScheduleId is the price schedule that applied the change, empty if it was requested directly.
+	ScheduleId PriceScheduleId `json:"schedule_id,omitempty"`
+}
+
+// PriceHistoryRepository stores the price history of the products, projected from the Product.PriceChanged events.
+// Products created before the history was introduced only have the changes that followed. The history is
+// not rebuilt by replays, which leave it as it is, since recording the replayed changes again does nothing.
+// It is implemented by pg and memory, which must both pass the contract of productstest.TestPriceHistoryRepository.
+// Every method fails with *errors.ErrInternal when the underlying storage does.
+type PriceHistoryRepository interface {
+	// Record adds a change to the history of the product. Recording a change at the same time as another one
+	// of the same product does nothing, so that events can be handled again.
+	Record(id ProductId, change *PriceChange, ctx context.Context) error
+	// History returns the changes of the product from the oldest, and no error when there are none.
+	History(id ProductId, ctx context.Context) ([]*PriceChange, error)
+}
+
+type priceHistoryHandler struct {
+	repository PriceHistoryRepository
+}
+
+// NewPriceHistoryHandler returns a Handler recording the Product.PriceChanged events in the history of their product,
+// and ignoring the others.
+func NewPriceHistoryHandler(repository PriceHistoryRepository) events.Handler {
+	return &priceHistoryHandler{
+		repository: repository,
+	}
+}
+
+func (h *priceHistoryHandler) Handle(evt events.Event, ctx context.Context) error {
+	e, ok := evt.(events.ProductPriceChanged)
+	if !ok {
+		return nil
+	}
+
+	changedAt, err := time.Parse(time.RFC3339Nano, e.ChangedAt)
+	if err != nil {
+		return fmt.Errorf("invalid price change time: %w", err)
+	}
+
+	return h.repository.Record(ProductId(e.ProductId), &PriceChange{
+		Price:         e.Price,
+		PreviousPrice: e.PreviousPrice,
+		ChangedAt:     changedAt.UTC(),
+		ScheduleId:    PriceScheduleId(e.ScheduleId),
+	}, ctx)
+}
+
+type PriceScheduleId string
+
+func (id PriceScheduleId) String() string {
+	return string(id)
+}
+
+type PriceScheduleStatus string
+
+const (
+	// SchedulePending schedules have not taken effect yet.
+	SchedulePending PriceScheduleStatus = "pending"
+	// ScheduleActive schedules are in effect, until they expire and the previous price is restored.
+	ScheduleActive PriceScheduleStatus = "active"
+	// ScheduleCompleted schedules took effect and, if they had an end, expired.
+	ScheduleCompleted PriceScheduleStatus = "completed"
+	// ScheduleCanceled schedules were canceled, or their product deleted, before taking effect.
+	ScheduleCanceled PriceScheduleStatus = "canceled"
+)
+
+// PriceSchedule is a change of the price of a product taking effect at EffectiveFrom, and lasting until
+// EffectiveUntil if it has one, when the previous price is restored. Schedules are applied by the PriceScheduler.
+type PriceSchedule struct {
+	Id             PriceScheduleId     `json:"schedule_id"`
+	ProductId      ProductId           `json:"product_id"`
+	Price          float32             `json:"price"`
+	EffectiveFrom  time.Time           `json:"effective_from"`
+	EffectiveUntil *time.Time          `json:"effective_until,omitempty"`
+	Status         PriceScheduleStatus `json:"status"`
+	// PreviousPrice is the price of the product when the schedule took effect, restored when it expires.
+	PreviousPrice *float32  `json:"previous_price,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// next returns the time of the next transition of the schedule: when it takes effect if it is pending,
+// and when it expires if it is active.
+func (s *PriceSchedule) next() time.Time {
+	if s.Status == ScheduleActive && s.EffectiveUntil != nil {
+		return *s.EffectiveUntil
+	}
+
+	return s.EffectiveFrom
+}
+
+// Overlaps reports whether s and o would take effect at the same time, or one of them while the other is in effect,
+// which would let the expiry of one undo the other.
+func (s *PriceSchedule) Overlaps(o *PriceSchedule) bool {
+	return s.EffectiveFrom.Equal(o.EffectiveFrom) || s.covers(o.EffectiveFrom) || o.covers(s.EffectiveFrom)
+}
+
+// covers reports whether t falls after the schedule takes effect and before it expires.
+// Schedules without an end cover no time, as they never restore the previous price.
+func (s *PriceSchedule) covers(t time.Time) bool {
+	return s.EffectiveUntil != nil && t.After(s.EffectiveFrom) && t.Before(*s.EffectiveUntil)
+}
+
+// PriceScheduleRepository stores the price schedules. Like warehouses, schedules are written directly,
+// instead of being projected from events: the events are published by the PriceScheduler as it applies them.
+// It is implemented by pg and memory, which must both pass the contract of productstest.TestPriceScheduleRepository.
+// Every method fails with *errors.ErrInternal when the underlying storage does.
+type PriceScheduleRepository interface {
+	// Store adds the schedule. A pending or active schedule overlapping another pending or active schedule
+	// of the same product is not stored, and Store fails with *ErrPriceScheduleOverlaps naming the other one.
+	Store(schedule *PriceSchedule, ctx context.Context) error
+	// FindById fails with *ErrPriceScheduleNotFound if there is no schedule with the given id.
+	FindById(id PriceScheduleId, ctx context.Context) (*PriceSchedule, error)
+	// ListByProduct returns the schedules of the product in no particular order, and no error when there are none.
+	ListByProduct(id ProductId, ctx context.Context) ([]*PriceSchedule, error)
+	// ListDue returns the pending schedules taking effect at now or before it, and the active ones expiring
+	// at now or before it, in no particular order.
+	ListDue(now time.Time, ctx context.Context) ([]*PriceSchedule, error)
+	// Update replaces the schedule with the same id if it still has the given status, failing with
+	// *ErrPriceScheduleConflict if it has another one, and with *ErrPriceScheduleNotFound if there is none.
+	Update(schedule *PriceSchedule, status PriceScheduleStatus, ctx context.Context) error
+}
+
+type SchedulePriceRequest struct {
+	ProductId     ProductId
+	Price         float32
+	EffectiveFrom time.Time
+	// EffectiveUntil is when the previous price is restored. The price is kept when nil.
+	EffectiveUntil *time.Time
+}
+
+// Validate checks the request at the time now.
+func (r *SchedulePriceRequest) Validate(now time.Time) error {
+	// Schedules cannot expire before taking effect, nor in the past.
+	expiresAfter := r.EffectiveFrom
+	if now.After(expiresAfter) {
+		expiresAfter = now
+	}
+
+	return validation.ValidateStruct(r,
+		validation.Field(&r.ProductId,
+			validation.Required,
+		),
+		validation.Field(&r.Price,
+			validation.Required,
+			validation.Min(float32(0)).Exclusive(),
+		),
+		validation.Field(&r.EffectiveFrom,
+			validation.Required,
+		),
+		validation.Field(&r.EffectiveUntil,
+			validation.Min(expiresAfter).Exclusive().Error("must be after effective_from and in the future"),
+		),
+	)
+}
+
+// PricingService serves the price history of the products and manages their price schedules.
+type PricingService interface {
+	// History returns the price history of the product from the oldest change, failing with *ErrNotFound
+	// if the product does not exist.
+	History(id ProductId, ctx context.Context) ([]*PriceChange, error)
+	// Schedule adds a price schedule, failing with *ErrPriceScheduleOverlaps if it overlaps a pending or active
+	// schedule of the product. Schedules taking effect in the past are applied as soon as possible.
+	Schedule(req *SchedulePriceRequest, ctx context.Context) (*PriceSchedule, error)
+	// ListSchedules returns the price schedules of the product sorted by EffectiveFrom, failing with *ErrNotFound
+	// if the product does not exist.
+	ListSchedules(id ProductId, ctx context.Context) ([]*PriceSchedule, error)
+	// CancelSchedule cancels a pending schedule, and makes an active one expire now, so that its previous price
+	// is restored. It fails with *ErrPriceScheduleConflict if the schedule is completed or canceled already.
+	CancelSchedule(productId ProductId, id PriceScheduleId, ctx context.Context) (*PriceSchedule, error)
+}
+
+type pricingService struct {
+	querier   ProductQuerier
+	history   PriceHistoryRepository
+	schedules PriceScheduleRepository
+}
+
+// NewPricingService returns a PricingService checking the products against the projection queried through querier.
+func NewPricingService(querier ProductQuerier, history PriceHistoryRepository, schedules PriceScheduleRepository) PricingService {
+	return &pricingService{
+		querier:   querier,
+		history:   history,
+		schedules: schedules,
+	}
+}
+
+func (s *pricingService) History(id ProductId, ctx context.Context) ([]*PriceChange, error) {
+	if _, err := s.querier.FindById(id, ctx); err != nil {
+		return nil, err
+	}
+
+	return s.history.History(id, ctx)
+}
+
+func (s *pricingService) Schedule(req *SchedulePriceRequest, ctx context.Context) (*PriceSchedule, error) {
+	now := time.Now()
+	if err := req.Validate(now); err != nil {
+		return nil, &errors.ErrBadRequest{Err: err}
+	}
+
+	if _, err := s.querier.FindById(req.ProductId, ctx); err != nil {
+		return nil, err
+	}
+
+	// Times are truncated to microseconds, the precision they are stored with.
+	schedule := &PriceSchedule{
+		Id:            PriceScheduleId(uuid.New().String()),
+		ProductId:     req.ProductId,
+		Price:         req.Price,
+		EffectiveFrom: req.EffectiveFrom.UTC().Truncate(time.Microsecond),
+		Status:        SchedulePending,
+		CreatedAt:     now.UTC().Truncate(time.Microsecond),
+	}
+	if req.EffectiveUntil != nil {
+		until := req.EffectiveUntil.UTC().Truncate(time.Microsecond)
+		schedule.EffectiveUntil = &until
+	}
+
+	// The repository rejects overlapping schedules, as checking them here would race with other requests.
+	if err := s.schedules.Store(schedule, ctx); err != nil {
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+func (s *pricingService) ListSchedules(id ProductId, ctx context.Context) ([]*PriceSchedule, error) {
+	if _, err := s.querier.FindById(id, ctx); err != nil {
+		return nil, err
+	}
+
+	schedules, err := s.schedules.ListByProduct(id, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(schedules, func(a, b *PriceSchedule) int {
+		return a.EffectiveFrom.Compare(b.EffectiveFrom)
+	})
+
+	return schedules, nil
+}
+
+func (s *pricingService) CancelSchedule(productId ProductId, id PriceScheduleId, ctx context.Context) (*PriceSchedule, error) {
+	schedule, err := s.schedules.FindById(id, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if schedule.ProductId != productId {
+		return nil, &ErrPriceScheduleNotFound{ScheduleId: id}
+	}
+
+	canceled := *schedule
+	switch schedule.Status {
+	case SchedulePending:
+		canceled.Status = ScheduleCanceled
+	case ScheduleActive:
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		canceled.EffectiveUntil = &now
+	default:
+		return nil, &ErrPriceScheduleConflict{ScheduleId: id, Status: schedule.Status}
+	}
+
+	if err := s.schedules.Update(&canceled, schedule.Status, ctx); err != nil {
+		return nil, err
+	}
+
+	return &canceled, nil
+}
@@ -0,0 +1,45 @@
+package products
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedulePriceRequestValidate(t *testing.T) {
+	now := time.Date(2024, 11, 29, 12, 0, 0, 0, time.UTC)
+	at := func(offset time.Duration) *time.Time {
+		v := now.Add(offset)
+		return &v
+	}
+
+	tests := []struct {
+		name  string
+		from  time.Duration
+		until *time.Time
+		valid bool
+	}{
+		{name: "without end", from: time.Hour, valid: true},
+		{name: "in the past without end", from: -time.Hour, valid: true},
+		{name: "ending after taking effect", from: time.Hour, until: at(2 * time.Hour), valid: true},
+		{name: "ending when taking effect", from: time.Hour, until: at(time.Hour)},
+		{name: "ending before taking effect", from: time.Hour, until: at(30 * time.Minute)},
+		{name: "in effect", from: -time.Hour, until: at(time.Minute), valid: true},
+		{name: "expired", from: -time.Hour, until: at(-time.Minute)},
+		{name: "expiring now", from: -time.Hour, until: at(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &SchedulePriceRequest{
+				ProductId:      missingId,
+				Price:          34.9,
+				EffectiveFrom:  now.Add(tt.from),
+				EffectiveUntil: tt.until,
+			}
+
+			if err := req.Validate(now); (err == nil) != tt.valid {
+				t.Errorf("Validate returned %v, want valid=%t", err, tt.valid)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package products
 
 import (
 	"context"
+	"regexp"
 	"strings"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
@@ -19,29 +20,59 @@ type Product struct {
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
 	Price       float32   `json:"price"`
-	Amount      int       `json:"amount"`
+	// Amount is the stock available across every warehouse.
+	Amount int `json:"amount"`
+	// SKU identifies the product in the catalogs of merchandisers. It is optional, but unique when set.
+	SKU string `json:"sku,omitempty"`
+	// ReorderThreshold is the stock at or below which the product is low on stock, and should be reordered.
+	// Products with no threshold are only low on stock when they run out.
+	ReorderThreshold int `json:"reorder_threshold"`
+	// Stock is the stock held by each warehouse, sorted by warehouse. Warehouses without items are left out.
+	Stock []WarehouseStock `json:"stock,omitempty"`
 }
 
-// TODO This should be changed
-func (p *Product) UpdateStock(amountDelta int) {
-	p.Amount += amountDelta
-	if p.Amount < 0 {
-		p.Amount = 0
-	}
+// StockLow reports whether the stock is at or below the reorder threshold, which it is when out of stock.
+func (p *Product) StockLow() bool {
+	return p.Amount <= p.ReorderThreshold
+}
+
+// OutOfStock reports whether no item of the product is left.
+func (p *Product) OutOfStock() bool {
+	return p.Amount == 0
 }
 
+// ProductQuerier reads the projection of the products.
+// Every method fails with *errors.ErrInternal when the underlying storage does.
 type ProductQuerier interface {
+	// FindById fails with *ErrNotFound if there is no product with the given id.
 	FindById(id ProductId, ctx context.Context) (*Product, error)
+	// FindByName fails with *ErrNotFound if there is no product with the given name.
 	FindByName(name string, ctx context.Context) (*Product, error)
+	// FindBySKU fails with *ErrNotFound if there is no product with the given, non empty, SKU.
+	FindBySKU(sku string, ctx context.Context) (*Product, error)
+	// List returns all the products in no particular order, and no error when there are none.
 	List(ctx context.Context) ([]*Product, error)
+	// ListLowStock returns the products that are low on stock, as reported by Product.StockLow,
+	// in no particular order.
+	ListLowStock(ctx context.Context) ([]*Product, error)
 }
 
+// ProductStorer writes the projection of the products, as its events are handled.
+// Every method fails with *errors.ErrInternal when the underlying storage does.
 type ProductStorer interface {
+	// Store adds a product, failing with *ErrAlreadyExists if its id, name or SKU is already taken.
+	// Any number of products can have no SKU.
 	Store(product *Product, ctx context.Context) error
+	// Update replaces the product with the same id, failing with *ErrAlreadyExists if its name or SKU
+	// is taken by another product. Updating a missing product does nothing,
+	// so that the events of deleted products can be handled again.
 	Update(product *Product, ctx context.Context) error
+	// Delete removes the product with the given id. Deleting a missing product does nothing.
 	Delete(id ProductId, ctx context.Context) error
 }
 
+// ProductRepository is implemented by pg and memory, which must both pass the contract
+// of productstest.TestRepository.
 type ProductRepository interface {
 	ProductQuerier
 	ProductStorer
@@ -51,21 +82,35 @@ type Service interface {
 	Create(req *CreateProductRequest, ctx context.Context) (*Product, error)
 	GetById(productId ProductId, ctx context.Context) (*Product, error)
 	List(ctx context.Context) ([]*Product, error)
+	ListLowStock(ctx context.Context) ([]*Product, error)
 	Update(req *UpdateProductRequest, ctx context.Context) error
 	Restock(req *RestockProductRequest, ctx context.Context) error
+	// TransferStock moves items of a product from a warehouse to another.
+	TransferStock(req *TransferStockRequest, ctx context.Context) error
+	// ChangePrice sets the price of a product, doing nothing if it already has it.
+	ChangePrice(req *ChangePriceRequest, ctx context.Context) error
+	// AdjustStock applies many stock adjustments at once, returning the result of each one.
+	// It only fails when the request as a whole is invalid or cannot be processed.
+	AdjustStock(req *AdjustStockRequest, ctx context.Context) ([]StockAdjustmentResult, error)
 	Delete(productId ProductId, ctx context.Context) error
 }
 
+// skuPattern matches SKUs made of letters, digits and dashes, such as KB-1042-BLK.
+var skuPattern = regexp.MustCompile(`^[A-Za-z0-9]+(-[A-Za-z0-9]+)*$`)
+
 type CreateProductRequest struct {
-	Name        string
-	Description string
-	Price       float32
-	Amount      int
+	Name             string
+	Description      string
+	Price            float32
+	Amount           int
+	SKU              string
+	ReorderThreshold int
 }
 
 func (r *CreateProductRequest) Validate() error {
 	r.Name = strings.TrimSpace(r.Name)
 	r.Description = strings.TrimSpace(r.Description)
+	r.SKU = strings.TrimSpace(r.SKU)
 
 	return validation.ValidateStruct(r,
 		validation.Field(&r.Name,
@@ -85,6 +130,13 @@ func (r *CreateProductRequest) Validate() error {
 		validation.Field(&r.Amount,
 			validation.Min(0),
 		),
+		validation.Field(&r.SKU,
+			validation.Length(3, 32),
+			validation.Match(skuPattern),
+		),
+		validation.Field(&r.ReorderThreshold,
+			validation.Min(0),
+		),
 	)
 }
 
@@ -93,39 +145,59 @@ type UpdateProductRequest struct {
 	Name        string
 	Description string
 	Price       float32
+	SKU         string
+	// ReorderThreshold is left unchanged when nil, as zero disables the threshold.
+	ReorderThreshold *int
 }
 
 func (r *UpdateProductRequest) Validate() error {
 	r.Name = strings.TrimSpace(r.Name)
 	r.Description = strings.TrimSpace(r.Description)
+	r.SKU = strings.TrimSpace(r.SKU)
+
+	// Something must be updated.
+	empty := r.Name == "" && r.Description == "" && r.Price == 0 && r.SKU == "" && r.ReorderThreshold == nil
 
 	return validation.ValidateStruct(r,
 		validation.Field(&r.Id,
 			validation.Required,
 		),
 		validation.Field(&r.Name,
-			validation.Required.When(r.Description == "" && r.Price == 0),
+			validation.Required.When(empty),
 			validation.Length(4, 32),
 			is.ASCII,
 		),
 		validation.Field(&r.Description,
-			validation.Required.When(r.Name == "" && r.Price == 0),
+			validation.Required.When(empty),
 			validation.Length(10, 256),
 			is.ASCII,
 		),
 		validation.Field(&r.Price,
-			validation.Required.When(r.Description == "" && r.Name == ""),
+			validation.Required.When(empty),
 			validation.Min(0.).Exclusive(),
 		),
+		validation.Field(&r.SKU,
+			validation.Length(3, 32),
+			validation.Match(skuPattern),
+		),
+		validation.Field(&r.ReorderThreshold,
+			validation.Min(0),
+		),
 	)
 }
 
 type RestockProductRequest struct {
 	Id     ProductId
 	Amount int
+	// WarehouseId is the warehouse receiving the items, the DefaultWarehouse when empty.
+	WarehouseId WarehouseId
 }
 
 func (r *RestockProductRequest) Validate() error {
+	if r.WarehouseId == "" {
+		r.WarehouseId = DefaultWarehouse
+	}
+
 	return validation.ValidateStruct(r,
 		validation.Field(&r.Id,
 			validation.Required,
@@ -134,5 +206,52 @@ func (r *RestockProductRequest) Validate() error {
 			validation.Required,
 			validation.Min(0).Exclusive(),
 		),
+		validation.Field(&r.WarehouseId,
+			warehouseIdRules...,
+		),
+	)
+}
+
+type TransferStockRequest struct {
+	Id       ProductId
+	From     WarehouseId
+	To       WarehouseId
+	Quantity int
+}
+
+func (r *TransferStockRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Id,
+			validation.Required,
+		),
+		validation.Field(&r.From,
+			append([]validation.Rule{validation.Required}, warehouseIdRules...)...,
+		),
+		validation.Field(&r.To,
+			append([]validation.Rule{validation.Required, validation.NotIn(r.From).Error("must differ from the source warehouse")}, warehouseIdRules...)...,
+		),
+		validation.Field(&r.Quantity,
+			validation.Required,
+			validation.Min(0).Exclusive(),
+		),
+	)
+}
+
+type ChangePriceRequest struct {
+	Id    ProductId
+	Price float32
+	// ScheduleId is the price schedule applying the change, empty when it is requested directly.
+	ScheduleId PriceScheduleId
+}
+
+func (r *ChangePriceRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Id,
+			validation.Required,
+		),
+		validation.Field(&r.Price,
+			validation.Required,
+			validation.Min(float32(0)).Exclusive(),
+		),
 	)
 }
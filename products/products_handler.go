@@ -27,6 +27,10 @@ func (h *productHandler) Handle(evt events.Event, ctx context.Context) error {
 		err = h.handleUpdated(evt.(events.ProductUpdated), ctx)
 	case events.ProductDeletedType:
 		err = h.handleDeleted(evt.(events.ProductDeleted), ctx)
+	case events.ProductStockLowType, events.ProductOutOfStockType, events.ProductStockChangedType, events.ProductStockTransferredType:
+		// These events follow the update that changed the stock, which is already projected.
+	case events.ProductPriceChangedType:
+		// The price is projected by the creation or update it follows, and its history by the PriceHistoryHandler.
 	default:
 		err = fmt.Errorf("unknown event type: %v", evt.Type())
 	}
@@ -36,14 +40,27 @@ func (h *productHandler) Handle(evt events.Event, ctx context.Context) error {
 
 func (h *productHandler) handleCreated(evt events.ProductCreated, ctx context.Context) error {
 	p := &Product{
-		Id:          ProductId(evt.ProductId),
-		Name:        evt.Name,
-		Description: evt.Description,
-		Price:       evt.Price,
-		Amount:      evt.Amount,
+		Id:               ProductId(evt.ProductId),
+		Name:             evt.Name,
+		Description:      evt.Description,
+		Price:            evt.Price,
+		Amount:           evt.Amount,
+		SKU:              evt.SKU,
+		ReorderThreshold: evt.ReorderThreshold,
+		Stock:            stockFromEvent(evt.Stock, evt.Amount),
 	}
 
 	if err := h.repository.Store(p, ctx); err != nil {
+		// Events are published at least once: a creation handled already is ignored, while the creation of
+		// another product with the same name or SKU can never be handled.
+		if exists, ok := err.(*ErrAlreadyExists); ok {
+			if exists.ProductId == p.Id {
+				return nil
+			}
+
+			return &events.ErrRejected{Err: err}
+		}
+
 		return err
 	}
 
@@ -52,14 +69,22 @@ func (h *productHandler) handleCreated(evt events.ProductCreated, ctx context.Co
 
 func (h *productHandler) handleUpdated(evt events.ProductUpdated, ctx context.Context) error {
 	p := &Product{
-		Id:          ProductId(evt.ProductId),
-		Name:        evt.Name,
-		Description: evt.Description,
-		Price:       evt.Price,
-		Amount:      evt.Amount,
+		Id:               ProductId(evt.ProductId),
+		Name:             evt.Name,
+		Description:      evt.Description,
+		Price:            evt.Price,
+		Amount:           evt.Amount,
+		SKU:              evt.SKU,
+		ReorderThreshold: evt.ReorderThreshold,
+		Stock:            stockFromEvent(evt.Stock, evt.Amount),
 	}
 
 	if err := h.repository.Update(p, ctx); err != nil {
+		// The product would be renamed to the name or SKU of another one.
+		if _, ok := err.(*ErrAlreadyExists); ok {
+			return &events.ErrRejected{Err: err}
+		}
+
 		return err
 	}
 
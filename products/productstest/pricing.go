@@ -0,0 +1,339 @@
+package productstest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giornetta/microshop/products"
+)
+
+// TestPriceHistoryRepository checks the behaviour of the repositories returned by newRepository,
+// documented on products.PriceHistoryRepository. Every subtest gets a new repository.
+func TestPriceHistoryRepository(t *testing.T, newRepository func(t *testing.T) products.PriceHistoryRepository) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, r products.PriceHistoryRepository)
+	}{
+		{"RecordAndHistory", testRecordAndHistory},
+		{"RecordTwice", testRecordTwice},
+		{"EmptyHistory", testEmptyHistory},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepository(t))
+		})
+	}
+}
+
+// testTime returns a time of the test truncated to microseconds, the precision of the repositories.
+func testTime(offset time.Duration) time.Time {
+	return time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC).Add(offset).Truncate(time.Microsecond)
+}
+
+func testRecordAndHistory(t *testing.T, r products.PriceHistoryRepository) {
+	id := products.ProductId(uuid.NewString())
+	want := []*products.PriceChange{
+		{Price: 49.5, ChangedAt: testTime(0)},
+		{Price: 39.5, PreviousPrice: 49.5, ChangedAt: testTime(time.Hour), ScheduleId: products.PriceScheduleId(uuid.NewString())},
+		{Price: 44.5, PreviousPrice: 39.5, ChangedAt: testTime(2*time.Hour + time.Microsecond)},
+	}
+
+	// Changes may be recorded out of order, but are returned from the oldest.
+	for _, i := range []int{1, 0, 2} {
+		if err := r.Record(id, want[i], context.Background()); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	if err := r.Record(products.ProductId(uuid.NewString()), &products.PriceChange{Price: 10, ChangedAt: testTime(0)}, context.Background()); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	got, err := r.History(id, context.Background())
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("History returned %v, want %v", got, want)
+	}
+}
+
+func testRecordTwice(t *testing.T, r products.PriceHistoryRepository) {
+	id := products.ProductId(uuid.NewString())
+	change := &products.PriceChange{Price: 39.5, PreviousPrice: 49.5, ChangedAt: testTime(0)}
+
+	for i := 0; i < 2; i++ {
+		if err := r.Record(id, change, context.Background()); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	got, err := r.History(id, context.Background())
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(got) != 1 || *got[0] != *change {
+		t.Errorf("History returned %v, want only %v", got, change)
+	}
+}
+
+func testEmptyHistory(t *testing.T, r products.PriceHistoryRepository) {
+	got, err := r.History(products.ProductId(uuid.NewString()), context.Background())
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("History returned %v, want none", got)
+	}
+}
+
+// TestPriceScheduleRepository checks the behaviour of the repositories returned by newRepository,
+// documented on products.PriceScheduleRepository. Every subtest gets a new repository.
+func TestPriceScheduleRepository(t *testing.T, newRepository func(t *testing.T) products.PriceScheduleRepository) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, r products.PriceScheduleRepository)
+	}{
+		{"StoreAndFindSchedule", testStoreAndFindSchedule},
+		{"FindMissingSchedule", testFindMissingSchedule},
+		{"StoreOverlappingSchedule", testStoreOverlappingSchedule},
+		{"ListByProduct", testListByProduct},
+		{"ListDue", testListDue},
+		{"UpdateSchedule", testUpdateSchedule},
+		{"UpdateConflict", testUpdateConflict},
+		{"UpdateMissingSchedule", testUpdateMissingSchedule},
+		{"ReturnsScheduleCopies", testReturnsScheduleCopies},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepository(t))
+		})
+	}
+}
+
+func newSchedule(productId products.ProductId, from time.Duration, until *time.Duration, status products.PriceScheduleStatus) *products.PriceSchedule {
+	s := &products.PriceSchedule{
+		Id:            products.PriceScheduleId(uuid.NewString()),
+		ProductId:     productId,
+		Price:         34.9,
+		EffectiveFrom: testTime(from),
+		Status:        status,
+		CreatedAt:     testTime(-time.Hour),
+	}
+
+	if until != nil {
+		u := testTime(*until)
+		s.EffectiveUntil = &u
+	}
+
+	return s
+}
+
+func duration(d time.Duration) *time.Duration {
+	return &d
+}
+
+func storeSchedules(t *testing.T, r products.PriceScheduleRepository, schedules ...*products.PriceSchedule) {
+	t.Helper()
+
+	for _, s := range schedules {
+		if err := r.Store(s, context.Background()); err != nil {
+			t.Fatalf("Store: %v", err)
+		}
+	}
+}
+
+// scheduleIds returns the ids of the schedules, to compare lists in no particular order.
+func scheduleIds(schedules []*products.PriceSchedule) map[products.PriceScheduleId]bool {
+	ids := make(map[products.PriceScheduleId]bool)
+	for _, s := range schedules {
+		ids[s.Id] = true
+	}
+
+	return ids
+}
+
+func testStoreAndFindSchedule(t *testing.T, r products.PriceScheduleRepository) {
+	want := newSchedule(products.ProductId(uuid.NewString()), 0, duration(time.Hour), products.SchedulePending)
+	storeSchedules(t, r, want)
+
+	got, err := r.FindById(want.Id, context.Background())
+	if err != nil {
+		t.Fatalf("FindById: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindById returned %+v, want %+v", got, want)
+	}
+}
+
+func testFindMissingSchedule(t *testing.T, r products.PriceScheduleRepository) {
+	id := products.PriceScheduleId(uuid.NewString())
+	_, err := r.FindById(id, context.Background())
+
+	var notFound *products.ErrPriceScheduleNotFound
+	if !errors.As(err, &notFound) || notFound.ScheduleId != id {
+		t.Fatalf("got error %v, want *ErrPriceScheduleNotFound", err)
+	}
+}
+
+func testStoreOverlappingSchedule(t *testing.T, r products.PriceScheduleRepository) {
+	id := products.ProductId(uuid.NewString())
+	active := newSchedule(id, 0, duration(time.Hour), products.ScheduleActive)
+	storeSchedules(t, r,
+		active,
+		// Schedules may follow each other, and overlap those no longer in effect, or other products'.
+		newSchedule(id, time.Hour, nil, products.SchedulePending),
+		newSchedule(id, 0, nil, products.ScheduleCanceled),
+		newSchedule(id, -time.Hour, duration(time.Hour), products.ScheduleCompleted),
+		newSchedule(products.ProductId(uuid.NewString()), 0, duration(time.Hour), products.SchedulePending),
+	)
+
+	for _, s := range []*products.PriceSchedule{
+		newSchedule(id, 30*time.Minute, nil, products.SchedulePending),
+		newSchedule(id, 0, nil, products.SchedulePending),
+		newSchedule(id, -time.Hour, duration(time.Minute), products.SchedulePending),
+	} {
+		err := r.Store(s, context.Background())
+
+		var overlaps *products.ErrPriceScheduleOverlaps
+		if !errors.As(err, &overlaps) || overlaps.ScheduleId != active.Id {
+			t.Fatalf("got error %v storing %+v, want *ErrPriceScheduleOverlaps", err, s)
+		}
+
+		if _, err := r.FindById(s.Id, context.Background()); err == nil {
+			t.Errorf("Store kept the overlapping schedule %+v", s)
+		}
+	}
+}
+
+func testListByProduct(t *testing.T, r products.PriceScheduleRepository) {
+	id := products.ProductId(uuid.NewString())
+	first := newSchedule(id, 0, duration(time.Hour), products.ScheduleCompleted)
+	second := newSchedule(id, 2*time.Hour, nil, products.SchedulePending)
+	storeSchedules(t, r, first, second, newSchedule(products.ProductId(uuid.NewString()), 0, nil, products.SchedulePending))
+
+	got, err := r.ListByProduct(id, context.Background())
+	if err != nil {
+		t.Fatalf("ListByProduct: %v", err)
+	}
+	if ids := scheduleIds(got); len(got) != 2 || !ids[first.Id] || !ids[second.Id] {
+		t.Errorf("ListByProduct returned %+v, want %s and %s", got, first.Id, second.Id)
+	}
+
+	none, err := r.ListByProduct(products.ProductId(uuid.NewString()), context.Background())
+	if err != nil {
+		t.Fatalf("ListByProduct: %v", err)
+	}
+	if len(none) != 0 {
+		t.Errorf("ListByProduct returned %+v for a product without schedules", none)
+	}
+}
+
+func testListDue(t *testing.T, r products.PriceScheduleRepository) {
+	id := products.ProductId(uuid.NewString())
+	taking := newSchedule(id, 0, duration(time.Hour), products.SchedulePending)
+	takingNow := newSchedule(id, time.Hour, nil, products.SchedulePending)
+	expiring := newSchedule(id, -time.Hour, duration(0), products.ScheduleActive)
+	storeSchedules(t, r,
+		taking, takingNow, expiring,
+		newSchedule(id, time.Hour+time.Microsecond, nil, products.SchedulePending),
+		newSchedule(products.ProductId(uuid.NewString()), -time.Hour, duration(2*time.Hour), products.ScheduleActive),
+		newSchedule(id, -2*time.Hour, duration(-time.Hour), products.ScheduleCompleted),
+		newSchedule(id, -2*time.Hour, nil, products.ScheduleCanceled),
+	)
+
+	got, err := r.ListDue(testTime(time.Hour), context.Background())
+	if err != nil {
+		t.Fatalf("ListDue: %v", err)
+	}
+	if ids := scheduleIds(got); len(got) != 3 || !ids[taking.Id] || !ids[takingNow.Id] || !ids[expiring.Id] {
+		t.Errorf("ListDue returned %+v, want %s, %s and %s", got, taking.Id, takingNow.Id, expiring.Id)
+	}
+}
+
+func testUpdateSchedule(t *testing.T, r products.PriceScheduleRepository) {
+	s := newSchedule(products.ProductId(uuid.NewString()), 0, duration(time.Hour), products.SchedulePending)
+	storeSchedules(t, r, s)
+
+	previous := float32(39.5)
+	want := *s
+	want.Status = products.ScheduleActive
+	want.PreviousPrice = &previous
+	if err := r.Update(&want, products.SchedulePending, context.Background()); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	got, err := r.FindById(s.Id, context.Background())
+	if err != nil {
+		t.Fatalf("FindById: %v", err)
+	}
+	if !reflect.DeepEqual(got, &want) {
+		t.Errorf("FindById returned %+v, want %+v", got, &want)
+	}
+}
+
+func testUpdateConflict(t *testing.T, r products.PriceScheduleRepository) {
+	s := newSchedule(products.ProductId(uuid.NewString()), 0, nil, products.ScheduleCanceled)
+	storeSchedules(t, r, s)
+
+	completed := *s
+	completed.Status = products.ScheduleCompleted
+	err := r.Update(&completed, products.SchedulePending, context.Background())
+
+	var conflict *products.ErrPriceScheduleConflict
+	if !errors.As(err, &conflict) || conflict.ScheduleId != s.Id || conflict.Status != products.ScheduleCanceled {
+		t.Fatalf("got error %v, want *ErrPriceScheduleConflict", err)
+	}
+
+	got, err := r.FindById(s.Id, context.Background())
+	if err != nil {
+		t.Fatalf("FindById: %v", err)
+	}
+	if got.Status != products.ScheduleCanceled {
+		t.Errorf("conflicting Update changed the status to %s", got.Status)
+	}
+}
+
+func testUpdateMissingSchedule(t *testing.T, r products.PriceScheduleRepository) {
+	s := newSchedule(products.ProductId(uuid.NewString()), 0, nil, products.SchedulePending)
+	err := r.Update(s, products.SchedulePending, context.Background())
+
+	var notFound *products.ErrPriceScheduleNotFound
+	if !errors.As(err, &notFound) || notFound.ScheduleId != s.Id {
+		t.Fatalf("got error %v, want *ErrPriceScheduleNotFound", err)
+	}
+}
+
+func testReturnsScheduleCopies(t *testing.T, r products.PriceScheduleRepository) {
+	s := newSchedule(products.ProductId(uuid.NewString()), 0, duration(time.Hour), products.SchedulePending)
+	storeSchedules(t, r, s)
+	*s.EffectiveUntil = testTime(2 * time.Hour)
+
+	got, err := r.FindById(s.Id, context.Background())
+	if err != nil {
+		t.Fatalf("FindById: %v", err)
+	}
+	if !got.EffectiveUntil.Equal(testTime(time.Hour)) {
+		t.Fatalf("Store kept the schedule it was given, which now expires at %v", got.EffectiveUntil)
+	}
+
+	*got.EffectiveUntil = testTime(2 * time.Hour)
+	again, err := r.FindById(s.Id, context.Background())
+	if err != nil {
+		t.Fatalf("FindById: %v", err)
+	}
+	if !again.EffectiveUntil.Equal(testTime(time.Hour)) {
+		t.Errorf("FindById returned the stored schedule, which now expires at %v", again.EffectiveUntil)
+	}
+}
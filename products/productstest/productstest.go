@@ -0,0 +1,396 @@
+// Package productstest implements the contracts every products.ProductRepository
+// and products.WarehouseRepository must satisfy.
+package productstest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/giornetta/microshop/products"
+)
+
+// TestRepository checks the behaviour of the repositories returned by newRepository,
+// documented on products.ProductQuerier and products.ProductStorer.
+// Every subtest gets a new, empty repository.
+func TestRepository(t *testing.T, newRepository func(t *testing.T) products.ProductRepository) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, r products.ProductRepository)
+	}{
+		{"FindMissing", testFindMissing},
+		{"StoreAndFind", testStoreAndFind},
+		{"StoreDuplicateId", testStoreDuplicateId},
+		{"StoreDuplicateName", testStoreDuplicateName},
+		{"StoreAndFindBySKU", testStoreAndFindBySKU},
+		{"StoreDuplicateSKU", testStoreDuplicateSKU},
+		{"StoreWithoutSKU", testStoreWithoutSKU},
+		{"List", testList},
+		{"ListLowStock", testListLowStock},
+		{"Update", testUpdate},
+		{"UpdateMissing", testUpdateMissing},
+		{"UpdateDuplicateName", testUpdateDuplicateName},
+		{"UpdateDuplicateSKU", testUpdateDuplicateSKU},
+		{"Delete", testDelete},
+		{"DeleteMissing", testDeleteMissing},
+		{"ReturnsCopies", testReturnsCopies},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepository(t))
+		})
+	}
+}
+
+func newProduct(name string) *products.Product {
+	return &products.Product{
+		Id:          products.ProductId(uuid.NewString()),
+		Name:        name,
+		Description: "The description of " + name,
+		Price:       12.5,
+		Amount:      4,
+		Stock:       []products.WarehouseStock{{WarehouseId: products.DefaultWarehouse, Amount: 4}},
+	}
+}
+
+func store(t *testing.T, r products.ProductRepository, p *products.Product) {
+	t.Helper()
+
+	if err := r.Store(p, context.Background()); err != nil {
+		t.Fatalf("Store(%+v): %v", p, err)
+	}
+}
+
+func findById(t *testing.T, r products.ProductRepository, id products.ProductId) *products.Product {
+	t.Helper()
+
+	p, err := r.FindById(id, context.Background())
+	if err != nil {
+		t.Fatalf("FindById(%s): %v", id, err)
+	}
+
+	return p
+}
+
+func assertNotFound(t *testing.T, err error, want products.ErrNotFound) {
+	t.Helper()
+
+	var notFound *products.ErrNotFound
+	if !errors.As(err, &notFound) {
+		t.Fatalf("got error %v, want *ErrNotFound", err)
+	}
+	if *notFound != want {
+		t.Errorf("got %+v, want %+v", *notFound, want)
+	}
+}
+
+func assertAlreadyExists(t *testing.T, err error, want products.ErrAlreadyExists) {
+	t.Helper()
+
+	var exists *products.ErrAlreadyExists
+	if !errors.As(err, &exists) {
+		t.Fatalf("got error %v, want *ErrAlreadyExists", err)
+	}
+	if *exists != want {
+		t.Errorf("got %+v, want %+v", *exists, want)
+	}
+}
+
+func testFindMissing(t *testing.T, r products.ProductRepository) {
+	id := products.ProductId(uuid.NewString())
+
+	_, err := r.FindById(id, context.Background())
+	assertNotFound(t, err, products.ErrNotFound{ProductId: id})
+
+	_, err = r.FindByName("Missing", context.Background())
+	assertNotFound(t, err, products.ErrNotFound{Name: "Missing"})
+
+	_, err = r.FindBySKU("MISSING-1", context.Background())
+	assertNotFound(t, err, products.ErrNotFound{SKU: "MISSING-1"})
+}
+
+func testStoreAndFind(t *testing.T, r products.ProductRepository) {
+	want := newProduct("Keyboard")
+	store(t, r, want)
+
+	if got := findById(t, r, want.Id); !reflect.DeepEqual(got, want) {
+		t.Errorf("FindById returned %+v, want %+v", got, want)
+	}
+
+	got, err := r.FindByName(want.Name, context.Background())
+	if err != nil {
+		t.Fatalf("FindByName: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindByName returned %+v, want %+v", got, want)
+	}
+}
+
+func testStoreDuplicateId(t *testing.T, r products.ProductRepository) {
+	original := newProduct("Keyboard")
+	store(t, r, original)
+
+	duplicate := newProduct("Mouse")
+	duplicate.Id = original.Id
+
+	err := r.Store(duplicate, context.Background())
+	assertAlreadyExists(t, err, products.ErrAlreadyExists{ProductId: original.Id})
+
+	if got := findById(t, r, original.Id); !reflect.DeepEqual(got, original) {
+		t.Errorf("duplicate replaced %+v with %+v", original, got)
+	}
+}
+
+func testStoreDuplicateName(t *testing.T, r products.ProductRepository) {
+	original := newProduct("Keyboard")
+	store(t, r, original)
+
+	duplicate := newProduct("Keyboard")
+
+	err := r.Store(duplicate, context.Background())
+	assertAlreadyExists(t, err, products.ErrAlreadyExists{Name: original.Name})
+
+	_, err = r.FindById(duplicate.Id, context.Background())
+	assertNotFound(t, err, products.ErrNotFound{ProductId: duplicate.Id})
+}
+
+func testStoreAndFindBySKU(t *testing.T, r products.ProductRepository) {
+	want := newProduct("Keyboard")
+	want.SKU = "KB-1042"
+	store(t, r, want)
+
+	got, err := r.FindBySKU(want.SKU, context.Background())
+	if err != nil {
+		t.Fatalf("FindBySKU: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindBySKU returned %+v, want %+v", got, want)
+	}
+}
+
+func testStoreDuplicateSKU(t *testing.T, r products.ProductRepository) {
+	original := newProduct("Keyboard")
+	original.SKU = "KB-1042"
+	store(t, r, original)
+
+	duplicate := newProduct("Mouse")
+	duplicate.SKU = original.SKU
+
+	err := r.Store(duplicate, context.Background())
+	assertAlreadyExists(t, err, products.ErrAlreadyExists{SKU: original.SKU})
+
+	_, err = r.FindById(duplicate.Id, context.Background())
+	assertNotFound(t, err, products.ErrNotFound{ProductId: duplicate.Id})
+}
+
+func testStoreWithoutSKU(t *testing.T, r products.ProductRepository) {
+	for _, name := range []string{"Keyboard", "Mouse"} {
+		p := newProduct(name)
+		store(t, r, p)
+
+		if got := findById(t, r, p.Id); got.SKU != "" {
+			t.Errorf("product stored without a SKU has SKU %q", got.SKU)
+		}
+	}
+
+	_, err := r.FindBySKU("", context.Background())
+	assertNotFound(t, err, products.ErrNotFound{})
+}
+
+func testList(t *testing.T, r products.ProductRepository) {
+	empty, err := r.List(context.Background())
+	if err != nil {
+		t.Fatalf("List of an empty repository: %v", err)
+	}
+	if len(empty) != 0 {
+		t.Fatalf("List of an empty repository returned %+v", empty)
+	}
+
+	want := map[products.ProductId]products.Product{}
+	for _, name := range []string{"Keyboard", "Mouse", "Monitor"} {
+		p := newProduct(name)
+		store(t, r, p)
+		want[p.Id] = *p
+	}
+
+	list, err := r.List(context.Background())
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+
+	got := map[products.ProductId]products.Product{}
+	for _, p := range list {
+		got[p.Id] = *p
+	}
+
+	if len(list) != len(want) || len(got) != len(want) {
+		t.Fatalf("List returned %d products, want %d", len(list), len(want))
+	}
+	for id, p := range want {
+		if !reflect.DeepEqual(got[id], p) {
+			t.Errorf("List returned %+v, want %+v", got[id], p)
+		}
+	}
+}
+
+func testListLowStock(t *testing.T, r products.ProductRepository) {
+	above, at, below := newProduct("Keyboard"), newProduct("Mouse"), newProduct("Monitor")
+	above.ReorderThreshold = above.Amount - 1
+	at.ReorderThreshold = at.Amount
+	below.Amount, below.ReorderThreshold, below.Stock = 0, 0, nil
+
+	for _, p := range []*products.Product{above, at, below} {
+		store(t, r, p)
+	}
+
+	list, err := r.ListLowStock(context.Background())
+	if err != nil {
+		t.Fatalf("ListLowStock: %v", err)
+	}
+
+	got := map[products.ProductId]products.Product{}
+	for _, p := range list {
+		got[p.Id] = *p
+	}
+
+	if len(list) != 2 || !reflect.DeepEqual(got[at.Id], *at) || !reflect.DeepEqual(got[below.Id], *below) {
+		t.Errorf("ListLowStock returned %+v, want %+v and %+v", list, at, below)
+	}
+}
+
+func testUpdate(t *testing.T, r products.ProductRepository) {
+	p := newProduct("Keyboard")
+	store(t, r, p)
+
+	want := &products.Product{
+		Id:               p.Id,
+		Name:             "Mechanical Keyboard",
+		Description:      "A keyboard with mechanical switches",
+		Price:            49.5,
+		Amount:           7,
+		SKU:              "KB-1042",
+		ReorderThreshold: 5,
+		Stock: []products.WarehouseStock{
+			{WarehouseId: products.DefaultWarehouse, Amount: 3},
+			{WarehouseId: "milan", Amount: 4},
+		},
+	}
+	if err := r.Update(want, context.Background()); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	if got := findById(t, r, p.Id); !reflect.DeepEqual(got, want) {
+		t.Errorf("updated to %+v, want %+v", got, want)
+	}
+
+	_, err := r.FindByName(p.Name, context.Background())
+	assertNotFound(t, err, products.ErrNotFound{Name: p.Name})
+}
+
+func testUpdateMissing(t *testing.T, r products.ProductRepository) {
+	p := newProduct("Keyboard")
+
+	if err := r.Update(p, context.Background()); err != nil {
+		t.Fatalf("Update of a missing product: %v", err)
+	}
+
+	_, err := r.FindById(p.Id, context.Background())
+	assertNotFound(t, err, products.ErrNotFound{ProductId: p.Id})
+}
+
+func testUpdateDuplicateName(t *testing.T, r products.ProductRepository) {
+	keyboard := newProduct("Keyboard")
+	store(t, r, keyboard)
+	original := newProduct("Mouse")
+	store(t, r, original)
+
+	renamed := *original
+	renamed.Name = keyboard.Name
+
+	err := r.Update(&renamed, context.Background())
+	assertAlreadyExists(t, err, products.ErrAlreadyExists{Name: keyboard.Name})
+
+	if got := findById(t, r, original.Id); !reflect.DeepEqual(got, original) {
+		t.Errorf("duplicate name changed %+v to %+v", original, got)
+	}
+}
+
+func testUpdateDuplicateSKU(t *testing.T, r products.ProductRepository) {
+	keyboard := newProduct("Keyboard")
+	keyboard.SKU = "KB-1042"
+	store(t, r, keyboard)
+	original := newProduct("Mouse")
+	store(t, r, original)
+
+	updated := *original
+	updated.SKU = keyboard.SKU
+
+	err := r.Update(&updated, context.Background())
+	assertAlreadyExists(t, err, products.ErrAlreadyExists{SKU: keyboard.SKU})
+
+	if got := findById(t, r, original.Id); !reflect.DeepEqual(got, original) {
+		t.Errorf("duplicate SKU changed %+v to %+v", original, got)
+	}
+
+	// A product keeps its own name and SKU.
+	if err := r.Update(keyboard, context.Background()); err != nil {
+		t.Errorf("Update without changes: %v", err)
+	}
+}
+
+func testDelete(t *testing.T, r products.ProductRepository) {
+	p := newProduct("Keyboard")
+	store(t, r, p)
+	other := newProduct("Mouse")
+	store(t, r, other)
+
+	if err := r.Delete(p.Id, context.Background()); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	_, err := r.FindById(p.Id, context.Background())
+	assertNotFound(t, err, products.ErrNotFound{ProductId: p.Id})
+
+	findById(t, r, other.Id)
+
+	// The name of a deleted product can be taken again.
+	store(t, r, newProduct(p.Name))
+}
+
+func testDeleteMissing(t *testing.T, r products.ProductRepository) {
+	if err := r.Delete(products.ProductId(uuid.NewString()), context.Background()); err != nil {
+		t.Fatalf("Delete of a missing product: %v", err)
+	}
+}
+
+func testReturnsCopies(t *testing.T, r products.ProductRepository) {
+	want := newProduct("Keyboard")
+	store(t, r, want)
+
+	stored := *want
+	stored.Stock = slices.Clone(want.Stock)
+	want.Amount = 100
+	want.Stock[0].Amount = 100
+
+	got := findById(t, r, want.Id)
+	got.Amount = 200
+	got.Stock[0].Amount = 200
+
+	list, err := r.List(context.Background())
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	list[0].Amount = 300
+	list[0].Stock[0].Amount = 300
+
+	if got := findById(t, r, want.Id); !reflect.DeepEqual(*got, stored) {
+		t.Errorf("changing the products stored or returned changed the repository to %+v, want %+v", got, stored)
+	}
+}
@@ -0,0 +1,95 @@
+package productstest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/giornetta/microshop/products"
+)
+
+// TestWarehouseRepository checks the behaviour of the repositories returned by newRepository,
+// documented on products.WarehouseRepository. Every subtest gets a new repository.
+func TestWarehouseRepository(t *testing.T, newRepository func(t *testing.T) products.WarehouseRepository) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, r products.WarehouseRepository)
+	}{
+		{"DefaultWarehouse", testDefaultWarehouse},
+		{"StoreAndFindWarehouse", testStoreAndFindWarehouse},
+		{"StoreDuplicateWarehouse", testStoreDuplicateWarehouse},
+		{"FindMissingWarehouse", testFindMissingWarehouse},
+		{"ListWarehouses", testListWarehouses},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepository(t))
+		})
+	}
+}
+
+func testDefaultWarehouse(t *testing.T, r products.WarehouseRepository) {
+	w, err := r.FindById(products.DefaultWarehouse, context.Background())
+	if err != nil {
+		t.Fatalf("FindById(%s): %v", products.DefaultWarehouse, err)
+	}
+	if w.Id != products.DefaultWarehouse || w.Name == "" {
+		t.Errorf("FindById(%s) returned %+v", products.DefaultWarehouse, w)
+	}
+}
+
+func testStoreAndFindWarehouse(t *testing.T, r products.WarehouseRepository) {
+	want := &products.Warehouse{Id: "milan-1", Name: "Milan", Location: "Via Roma 1, Milan"}
+	if err := r.Store(want, context.Background()); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	got, err := r.FindById(want.Id, context.Background())
+	if err != nil {
+		t.Fatalf("FindById: %v", err)
+	}
+	if *got != *want {
+		t.Errorf("FindById returned %+v, want %+v", got, want)
+	}
+}
+
+func testStoreDuplicateWarehouse(t *testing.T, r products.WarehouseRepository) {
+	err := r.Store(&products.Warehouse{Id: products.DefaultWarehouse, Name: "Another"}, context.Background())
+
+	var exists *products.ErrWarehouseAlreadyExists
+	if !errors.As(err, &exists) || exists.WarehouseId != products.DefaultWarehouse {
+		t.Fatalf("got error %v, want *ErrWarehouseAlreadyExists", err)
+	}
+}
+
+func testFindMissingWarehouse(t *testing.T, r products.WarehouseRepository) {
+	_, err := r.FindById("missing", context.Background())
+
+	var notFound *products.ErrWarehouseNotFound
+	if !errors.As(err, &notFound) || notFound.WarehouseId != "missing" {
+		t.Fatalf("got error %v, want *ErrWarehouseNotFound", err)
+	}
+}
+
+func testListWarehouses(t *testing.T, r products.WarehouseRepository) {
+	if err := r.Store(&products.Warehouse{Id: "milan-1", Name: "Milan"}, context.Background()); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	list, err := r.List(context.Background())
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+
+	got := map[products.WarehouseId]bool{}
+	for _, w := range list {
+		got[w.Id] = true
+	}
+
+	if len(list) != 2 || !got[products.DefaultWarehouse] || !got["milan-1"] {
+		t.Errorf("List returned %+v, want the default warehouse and milan-1", list)
+	}
+}
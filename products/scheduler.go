@@ -0,0 +1,201 @@
+package products
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/giornetta/microshop/log"
+)
+
+// PriceScheduler applies the price schedules through a Service: it sets the price of the pending schedules
+// once they take effect, then restores the previous price of the active ones once they expire.
+//
+// Schedules are stored, so those falling due while no scheduler is running are applied, in order, as soon as
+// one starts. Every transition can be applied again, which lets many instances run a scheduler at once:
+// prices are not changed when the product already has them, and the status of a schedule is only updated
+// by the first scheduler getting to it.
+//
+// Prices are read from the aggregates of the products rather than from the projection, which lags behind
+// the changes made by the Service, including those of the scheduler itself.
+type PriceScheduler struct {
+	service    Service
+	aggregates AggregateRepository
+	schedules  PriceScheduleRepository
+	interval   time.Duration
+	logger     *slog.Logger
+}
+
+// NewPriceScheduler returns a PriceScheduler looking for due schedules every interval, and reading the prices
+// they replace from the aggregates loaded through aggregates.
+func NewPriceScheduler(service Service, aggregates AggregateRepository, schedules PriceScheduleRepository, interval time.Duration, logger *slog.Logger) *PriceScheduler {
+	return &PriceScheduler{
+		service:    service,
+		aggregates: aggregates,
+		schedules:  schedules,
+		interval:   interval,
+		logger:     logger,
+	}
+}
+
+// Run applies the due schedules right away, then every interval, until ctx is canceled.
+// Schedules that cannot be applied are logged and retried at the following tick, so Run returns nil.
+func (s *PriceScheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(time.Now(), ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick applies the schedules due at now, in the order of their transitions. Expiries come first when a schedule
+// takes effect as another one expires, and the transitions of a product are stopped at the first that fails.
+// A transition that started is completed even if ctx is canceled meanwhile, but no other one is started.
+func (s *PriceScheduler) tick(now time.Time, ctx context.Context) {
+	due, err := s.schedules.ListDue(now, context.WithoutCancel(ctx))
+	if err != nil {
+		s.logger.ErrorCtx(ctx, "could not list due price schedules", slog.String("err", err.Error()))
+		return
+	}
+
+	slices.SortFunc(due, func(a, b *PriceSchedule) int {
+		if c := a.next().Compare(b.next()); c != 0 {
+			return c
+		}
+
+		switch {
+		case a.Status == b.Status:
+			return 0
+		case a.Status == ScheduleActive:
+			return -1
+		default:
+			return 1
+		}
+	})
+
+	failed := make(map[ProductId]bool)
+	for _, schedule := range due {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if failed[schedule.ProductId] {
+			continue
+		}
+
+		err := s.transition(schedule, context.WithoutCancel(ctx))
+		if _, ok := err.(*ErrPriceScheduleConflict); ok || err == nil {
+			// Conflicts mean another scheduler, or a cancellation, got to the schedule first.
+			continue
+		}
+
+		failed[schedule.ProductId] = true
+		s.logger.ErrorCtx(log.WithProductId(ctx, schedule.ProductId.String()), "could not apply price schedule",
+			slog.String("schedule_id", schedule.Id.String()),
+			slog.String("status", string(schedule.Status)),
+			slog.String("err", err.Error()),
+		)
+	}
+}
+
+func (s *PriceScheduler) transition(schedule *PriceSchedule, ctx context.Context) error {
+	if schedule.Status == ScheduleActive {
+		return s.expire(schedule, ctx)
+	}
+
+	return s.apply(schedule, ctx)
+}
+
+// apply sets the price of a pending schedule. The price it replaces is stored first, so that it can still be
+// restored if the scheduler stops right after setting the new one.
+func (s *PriceScheduler) apply(schedule *PriceSchedule, ctx context.Context) error {
+	if schedule.PreviousPrice == nil {
+		price, err := s.price(schedule.ProductId, ctx)
+		if err != nil {
+			return s.finishIfDeleted(schedule, ScheduleCanceled, err, ctx)
+		}
+
+		claimed := *schedule
+		claimed.PreviousPrice = &price
+		if err := s.schedules.Update(&claimed, SchedulePending, ctx); err != nil {
+			return err
+		}
+		schedule = &claimed
+	}
+
+	if err := s.service.ChangePrice(&ChangePriceRequest{
+		Id:         schedule.ProductId,
+		Price:      schedule.Price,
+		ScheduleId: schedule.Id,
+	}, ctx); err != nil {
+		return s.finishIfDeleted(schedule, ScheduleCanceled, err, ctx)
+	}
+
+	applied := *schedule
+	applied.Status = ScheduleCompleted
+	if schedule.EffectiveUntil != nil {
+		applied.Status = ScheduleActive
+	}
+
+	return s.schedules.Update(&applied, SchedulePending, ctx)
+}
+
+// expire restores the previous price of an active schedule, and completes it. The price is left alone if it was
+// changed while the schedule was in effect, as the change is more recent than the schedule.
+func (s *PriceScheduler) expire(schedule *PriceSchedule, ctx context.Context) error {
+	price, err := s.price(schedule.ProductId, ctx)
+	if err != nil {
+		return s.finishIfDeleted(schedule, ScheduleCompleted, err, ctx)
+	}
+
+	if price == schedule.Price && schedule.PreviousPrice != nil {
+		if err := s.service.ChangePrice(&ChangePriceRequest{
+			Id:         schedule.ProductId,
+			Price:      *schedule.PreviousPrice,
+			ScheduleId: schedule.Id,
+		}, ctx); err != nil {
+			return s.finishIfDeleted(schedule, ScheduleCompleted, err, ctx)
+		}
+	}
+
+	completed := *schedule
+	completed.Status = ScheduleCompleted
+
+	return s.schedules.Update(&completed, ScheduleActive, ctx)
+}
+
+// price returns the current price of the product, failing with *ErrNotFound if it was deleted.
+func (s *PriceScheduler) price(id ProductId, ctx context.Context) (float32, error) {
+	a, err := s.aggregates.Load(id, ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if !a.Exists() {
+		return 0, &ErrNotFound{ProductId: id}
+	}
+
+	return a.Price, nil
+}
+
+// finishIfDeleted moves the schedule to status if err reports that its product was deleted, as the schedule
+// cannot be applied anymore, and returns err otherwise.
+func (s *PriceScheduler) finishIfDeleted(schedule *PriceSchedule, status PriceScheduleStatus, err error, ctx context.Context) error {
+	if _, ok := err.(*ErrNotFound); !ok {
+		return err
+	}
+
+	finished := *schedule
+	finished.Status = status
+
+	return s.schedules.Update(&finished, schedule.Status, ctx)
+}
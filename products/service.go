@@ -3,147 +3,53 @@ package products
 import (
 	"context"
 
-	"github.com/google/uuid"
 	"golang.org/x/exp/slog"
 
 	"github.com/giornetta/microshop/errors"
-	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/log"
 )
 
-type service struct {
-	querier   ProductQuerier
-	publisher events.Publisher
-}
-
-func NewService(querier ProductQuerier, publisher events.Publisher) Service {
-	return &service{
-		querier:   querier,
-		publisher: publisher,
-	}
-}
-
-func (s *service) Create(req *CreateProductRequest, ctx context.Context) (*Product, error) {
-	if err := req.Validate(); err != nil {
-		return nil, err
-	}
-
-	_, err := s.querier.FindByName(req.Name, ctx)
-	if err == nil {
-		return nil, &ErrAlreadyExists{Name: req.Name}
-	}
-
-	if _, ok := err.(*ErrNotFound); !ok {
-		return nil, err
-	}
-
-	product := &Product{
-		Id:          ProductId(uuid.New().String()),
-		Name:        req.Name,
-		Description: req.Description,
-		Price:       req.Price,
-		Amount:      req.Amount,
+// checkName fails with *ErrAlreadyExists if name belongs to a product other than the one with the given id.
+// An empty name, which leaves the name of a product unchanged, is always free.
+func checkName(querier ProductQuerier, name string, id ProductId, ctx context.Context) error {
+	if name == "" {
+		return nil
 	}
 
-	if err := s.publisher.Publish(events.ProductCreated{
-		ProductEvent: events.ProductEvent{ProductId: product.Id.String()},
-		Name:         product.Name,
-		Description:  product.Description,
-		Price:        product.Price,
-		Amount:       product.Amount,
-	}, ctx); err != nil {
-		return nil, &errors.ErrInternal{Err: err}
-	}
-
-	return product, nil
-}
-
-func (s *service) GetById(productId ProductId, ctx context.Context) (*Product, error) {
-	p, err := s.querier.FindById(productId, ctx)
+	p, err := querier.FindByName(name, ctx)
 	if err != nil {
-		return nil, err
-	}
-
-	return p, nil
-}
-
-func (s *service) List(ctx context.Context) ([]*Product, error) {
-	prods, err := s.querier.List(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	return prods, nil
-}
-
-func (s *service) Update(req *UpdateProductRequest, ctx context.Context) error {
-	if err := req.Validate(); err != nil {
-		return &errors.ErrBadRequest{Err: err}
-	}
+		if _, ok := err.(*ErrNotFound); ok {
+			return nil
+		}
 
-	product, err := s.querier.FindById(req.Id, ctx)
-	if err != nil {
 		return err
 	}
 
-	if req.Name != "" {
-		product.Name = req.Name
-	}
-
-	if req.Description != "" {
-		product.Description = req.Description
-	}
-
-	if req.Price != 0 {
-		product.Price = req.Price
-	}
-
-	if err := s.publisher.Publish(events.ProductUpdated{
-		ProductEvent: events.ProductEvent{ProductId: product.Id.String()},
-		Name:         product.Name,
-		Description:  product.Description,
-		Price:        product.Price,
-		Amount:       product.Amount,
-	}, ctx); err != nil {
-		return &errors.ErrInternal{Err: err}
+	if p.Id != id {
+		return &ErrAlreadyExists{Name: name}
 	}
 
 	return nil
 }
 
-func (s *service) Restock(req *RestockProductRequest, ctx context.Context) error {
-	if err := req.Validate(); err != nil {
-		return err
+// checkSKU fails with *ErrAlreadyExists if sku belongs to a product other than the one with the given id.
+// Products without a SKU never conflict.
+func checkSKU(querier ProductQuerier, sku string, id ProductId, ctx context.Context) error {
+	if sku == "" {
+		return nil
 	}
 
-	product, err := s.querier.FindById(req.Id, ctx)
+	p, err := querier.FindBySKU(sku, ctx)
 	if err != nil {
-		return err
-	}
-
-	product.UpdateStock(req.Amount)
-
-	if err := s.publisher.Publish(events.ProductUpdated{
-		ProductEvent: events.ProductEvent{ProductId: product.Id.String()},
-		Name:         product.Name,
-		Description:  product.Description,
-		Price:        product.Price,
-		Amount:       product.Amount,
-	}, ctx); err != nil {
-		return &errors.ErrInternal{Err: err}
-	}
-
-	return nil
-}
+		if _, ok := err.(*ErrNotFound); ok {
+			return nil
+		}
 
-func (s *service) Delete(productId ProductId, ctx context.Context) error {
-	if _, err := s.querier.FindById(productId, ctx); err != nil {
 		return err
 	}
 
-	if err := s.publisher.Publish(events.ProductDeleted{
-		ProductEvent: events.ProductEvent{ProductId: productId.String()},
-	}, ctx); err != nil {
-		return &errors.ErrInternal{Err: err}
+	if p.Id != id {
+		return &ErrAlreadyExists{SKU: sku}
 	}
 
 	return nil
@@ -165,7 +71,7 @@ func (s *loggingService) Create(req *CreateProductRequest, ctx context.Context)
 	p, err := s.service.Create(req, ctx)
 	if err != nil {
 		if e, ok := err.(*errors.ErrInternal); ok {
-			s.logger.Error("could not create product",
+			s.logger.ErrorCtx(ctx, "could not create product",
 				slog.String("method", "Create"),
 				slog.String("err", e.Cause().Error()),
 			)
@@ -178,12 +84,12 @@ func (s *loggingService) Create(req *CreateProductRequest, ctx context.Context)
 }
 
 func (s *loggingService) GetById(productId ProductId, ctx context.Context) (*Product, error) {
+	ctx = log.WithProductId(ctx, productId.String())
 	p, err := s.service.GetById(productId, ctx)
 	if err != nil {
 		if e, ok := err.(*errors.ErrInternal); ok {
-			s.logger.Error("could not find product by id",
+			s.logger.ErrorCtx(ctx, "could not find product by id",
 				slog.String("method", "GetById"),
-				slog.String("product_id", productId.String()),
 				slog.String("err", e.Cause().Error()),
 			)
 		}
@@ -198,7 +104,7 @@ func (s *loggingService) List(ctx context.Context) ([]*Product, error) {
 	prods, err := s.service.List(ctx)
 	if err != nil {
 		if e, ok := err.(*errors.ErrInternal); ok {
-			s.logger.Error("could not list products",
+			s.logger.ErrorCtx(ctx, "could not list products",
 				slog.String("method", "List"),
 				slog.String("err", e.Cause().Error()),
 			)
@@ -210,13 +116,29 @@ func (s *loggingService) List(ctx context.Context) ([]*Product, error) {
 	return prods, nil
 }
 
+func (s *loggingService) ListLowStock(ctx context.Context) ([]*Product, error) {
+	prods, err := s.service.ListLowStock(ctx)
+	if err != nil {
+		if e, ok := err.(*errors.ErrInternal); ok {
+			s.logger.ErrorCtx(ctx, "could not list products low on stock",
+				slog.String("method", "ListLowStock"),
+				slog.String("err", e.Cause().Error()),
+			)
+		}
+
+		return nil, err
+	}
+
+	return prods, nil
+}
+
 func (s *loggingService) Restock(req *RestockProductRequest, ctx context.Context) error {
+	ctx = log.WithProductId(ctx, req.Id.String())
 	err := s.service.Restock(req, ctx)
 	if err != nil {
 		if e, ok := err.(*errors.ErrInternal); ok {
-			s.logger.Error("could not restock product",
+			s.logger.ErrorCtx(ctx, "could not restock product",
 				slog.String("method", "Restock"),
-				slog.String("product_id", req.Id.String()),
 				slog.String("err", e.Cause().Error()),
 			)
 		}
@@ -227,13 +149,72 @@ func (s *loggingService) Restock(req *RestockProductRequest, ctx context.Context
 	return nil
 }
 
+func (s *loggingService) TransferStock(req *TransferStockRequest, ctx context.Context) error {
+	ctx = log.WithProductId(ctx, req.Id.String())
+	err := s.service.TransferStock(req, ctx)
+	if err != nil {
+		if e, ok := err.(*errors.ErrInternal); ok {
+			s.logger.ErrorCtx(ctx, "could not transfer stock",
+				slog.String("method", "TransferStock"),
+				slog.String("err", e.Cause().Error()),
+			)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *loggingService) ChangePrice(req *ChangePriceRequest, ctx context.Context) error {
+	ctx = log.WithProductId(ctx, req.Id.String())
+	err := s.service.ChangePrice(req, ctx)
+	if err != nil {
+		if e, ok := err.(*errors.ErrInternal); ok {
+			s.logger.ErrorCtx(ctx, "could not change price",
+				slog.String("method", "ChangePrice"),
+				slog.String("err", e.Cause().Error()),
+			)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *loggingService) AdjustStock(req *AdjustStockRequest, ctx context.Context) ([]StockAdjustmentResult, error) {
+	results, err := s.service.AdjustStock(req, ctx)
+	if err != nil {
+		if e, ok := err.(*errors.ErrInternal); ok {
+			s.logger.ErrorCtx(ctx, "could not adjust stock",
+				slog.String("method", "AdjustStock"),
+				slog.String("err", e.Cause().Error()),
+			)
+		}
+
+		return nil, err
+	}
+
+	for _, r := range results {
+		if e, ok := r.Err.(*errors.ErrInternal); ok {
+			s.logger.ErrorCtx(log.WithProductId(ctx, r.ProductId.String()), "could not adjust stock of product",
+				slog.String("method", "AdjustStock"),
+				slog.String("err", e.Cause().Error()),
+			)
+		}
+	}
+
+	return results, nil
+}
+
 func (s *loggingService) Update(req *UpdateProductRequest, ctx context.Context) error {
+	ctx = log.WithProductId(ctx, req.Id.String())
 	err := s.service.Update(req, ctx)
 	if err != nil {
 		if e, ok := err.(*errors.ErrInternal); ok {
-			s.logger.Error("could not update product",
+			s.logger.ErrorCtx(ctx, "could not update product",
 				slog.String("method", "Update"),
-				slog.String("product_id", req.Id.String()),
 				slog.String("err", e.Cause().Error()),
 			)
 		}
@@ -245,12 +226,12 @@ func (s *loggingService) Update(req *UpdateProductRequest, ctx context.Context)
 }
 
 func (s *loggingService) Delete(productId ProductId, ctx context.Context) error {
+	ctx = log.WithProductId(ctx, productId.String())
 	err := s.service.Delete(productId, ctx)
 	if err != nil {
 		if e, ok := err.(*errors.ErrInternal); ok {
-			s.logger.Error("could not delete product",
+			s.logger.ErrorCtx(ctx, "could not delete product",
 				slog.String("method", "Delete"),
-				slog.String("product_id", productId.String()),
 				slog.String("err", e.Cause().Error()),
 			)
 		}
@@ -0,0 +1,221 @@
+package products
+
+import (
+	"context"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+
+	"github.com/giornetta/microshop/errors"
+)
+
+// AdjustmentMode decides what happens to the valid adjustments of a batch when others are not.
+type AdjustmentMode string
+
+const (
+	// AllOrNothing applies the adjustments only if every one of them is valid.
+	AllOrNothing AdjustmentMode = "all_or_nothing"
+	// BestEffort applies the valid adjustments, reporting the others.
+	BestEffort AdjustmentMode = "best_effort"
+)
+
+// MaxStockAdjustments is the number of adjustments accepted in a single batch.
+const MaxStockAdjustments = 1000
+
+// StockAdjustment changes the stock of a product held by a warehouse by Quantity, which is negative
+// when items are removed.
+type StockAdjustment struct {
+	Id       ProductId
+	Quantity int
+	// WarehouseId is the DefaultWarehouse when empty.
+	WarehouseId WarehouseId
+}
+
+// validate is not named Validate, which would make ozzo-validation check the adjustments together
+// with their request.
+func (a StockAdjustment) validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Id,
+			validation.Required,
+			is.UUID,
+		),
+		validation.Field(&a.Quantity,
+			validation.Required,
+		),
+		validation.Field(&a.WarehouseId,
+			warehouseIdRules...,
+		),
+	)
+}
+
+// AdjustStockRequest adjusts the stock of many products at once. Adjustments of the same product
+// are applied in order, each one to the stock left by the previous ones.
+type AdjustStockRequest struct {
+	Adjustments []StockAdjustment
+	Mode        AdjustmentMode
+}
+
+// Validate only checks the request as a whole: the adjustments are validated one by one,
+// so that each of them gets its own result.
+func (r *AdjustStockRequest) Validate() error {
+	if r.Mode == "" {
+		r.Mode = AllOrNothing
+	}
+
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Adjustments,
+			validation.Required,
+			validation.Length(1, MaxStockAdjustments),
+		),
+		validation.Field(&r.Mode,
+			validation.In(AllOrNothing, BestEffort),
+		),
+	)
+}
+
+type AdjustmentStatus string
+
+const (
+	AdjustmentApplied AdjustmentStatus = "applied"
+	// AdjustmentRejected adjustments are invalid, or cannot be applied to their product.
+	AdjustmentRejected AdjustmentStatus = "rejected"
+	// AdjustmentAborted adjustments are valid, but were not applied because others of
+	// their AllOrNothing batch were rejected.
+	AdjustmentAborted AdjustmentStatus = "aborted"
+	// AdjustmentFailed adjustments could not be stored or published.
+	AdjustmentFailed AdjustmentStatus = "failed"
+)
+
+// StockAdjustmentResult is the outcome of an adjustment, in the order of the request.
+type StockAdjustmentResult struct {
+	ProductId   ProductId        `json:"product_id"`
+	WarehouseId WarehouseId      `json:"warehouse_id"`
+	Quantity    int              `json:"quantity"`
+	Status      AdjustmentStatus `json:"status"`
+	// Amount is the stock of the product held by the warehouse after the adjustment, when it was applied.
+	Amount int    `json:"amount,omitempty"`
+	Error  string `json:"error,omitempty"`
+
+	// Err is the error the adjustment was rejected or failed with.
+	Err error `json:"-"`
+}
+
+// stockBatch holds the adjustments of a request, applied to the aggregates of their products
+// but not saved yet.
+type stockBatch struct {
+	results []StockAdjustmentResult
+	// aggregates are in the order they were first adjusted, and only hold valid adjustments.
+	aggregates []*Aggregate
+	// items are the results of the valid adjustments of every aggregate.
+	items    map[ProductId][]int
+	rejected bool
+}
+
+// newStockBatch applies the adjustments of req to the aggregates returned by load, loading each product
+// and checking each warehouse once. It only fails when load or warehouses do with *errors.ErrInternal,
+// while the other errors reject their adjustments.
+func newStockBatch(req *AdjustStockRequest, load func(id ProductId, ctx context.Context) (*Aggregate, error), warehouses WarehouseQuerier, ctx context.Context) (*stockBatch, error) {
+	b := &stockBatch{
+		results: make([]StockAdjustmentResult, len(req.Adjustments)),
+		items:   make(map[ProductId][]int),
+	}
+
+	loaded := make(map[ProductId]*Aggregate)
+	loadErrs := make(map[ProductId]error)
+	warehouseErrs := make(map[WarehouseId]error)
+
+	for i, adj := range req.Adjustments {
+		if adj.WarehouseId == "" {
+			adj.WarehouseId = DefaultWarehouse
+		}
+
+		b.results[i] = StockAdjustmentResult{ProductId: adj.Id, WarehouseId: adj.WarehouseId, Quantity: adj.Quantity}
+
+		if err := adj.validate(); err != nil {
+			b.reject(i, err)
+			continue
+		}
+
+		err, ok := warehouseErrs[adj.WarehouseId]
+		if !ok {
+			err = checkWarehouse(warehouses, adj.WarehouseId, ctx)
+			if _, ok := err.(*errors.ErrInternal); ok {
+				return nil, err
+			}
+
+			warehouseErrs[adj.WarehouseId] = err
+		}
+		if err != nil {
+			b.reject(i, err)
+			continue
+		}
+
+		if err, ok := loadErrs[adj.Id]; ok {
+			b.reject(i, err)
+			continue
+		}
+
+		a, ok := loaded[adj.Id]
+		if !ok {
+			var err error
+			if a, err = load(adj.Id, ctx); err != nil {
+				if _, ok := err.(*errors.ErrInternal); ok {
+					return nil, err
+				}
+
+				loadErrs[adj.Id] = err
+				b.reject(i, err)
+				continue
+			}
+
+			loaded[adj.Id] = a
+			b.aggregates = append(b.aggregates, a)
+		}
+
+		if err := a.AdjustStock(adj.WarehouseId, adj.Quantity); err != nil {
+			b.reject(i, err)
+			continue
+		}
+
+		b.results[i].Amount = a.StockIn(adj.WarehouseId)
+		b.items[a.Id] = append(b.items[a.Id], i)
+	}
+
+	return b, nil
+}
+
+func (b *stockBatch) reject(i int, err error) {
+	b.results[i].Status = AdjustmentRejected
+	b.results[i].Error = err.Error()
+	b.results[i].Err = err
+	b.rejected = true
+}
+
+// abort marks the valid adjustments as aborted.
+func (b *stockBatch) abort() {
+	for _, items := range b.items {
+		for _, i := range items {
+			b.results[i].Status = AdjustmentAborted
+			b.results[i].Amount = 0
+		}
+	}
+}
+
+// fail marks the valid adjustments of the aggregate as failed with err.
+func (b *stockBatch) fail(a *Aggregate, err error) {
+	for _, i := range b.items[a.Id] {
+		b.results[i].Status = AdjustmentFailed
+		b.results[i].Amount = 0
+		b.results[i].Error = err.Error()
+		b.results[i].Err = err
+	}
+}
+
+// apply marks the valid adjustments of the aggregates as applied.
+func (b *stockBatch) apply(aggregates []*Aggregate) {
+	for _, a := range aggregates {
+		for _, i := range b.items[a.Id] {
+			b.results[i].Status = AdjustmentApplied
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package products
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/giornetta/microshop/errors"
+)
+
+const missingId ProductId = "6b0f7a64-3a36-4b8f-9d0c-5f2d7a9b1e03"
+
+type warehouses map[WarehouseId]bool
+
+func (w warehouses) FindById(id WarehouseId, _ context.Context) (*Warehouse, error) {
+	if !w[id] {
+		return nil, &ErrWarehouseNotFound{WarehouseId: id}
+	}
+
+	return &Warehouse{Id: id}, nil
+}
+
+func (w warehouses) List(context.Context) ([]*Warehouse, error) {
+	return nil, nil
+}
+
+// loader returns a load function for newStockBatch serving the aggregates, and the number of times
+// each product was loaded.
+func loader(aggregates ...*Aggregate) (func(id ProductId, ctx context.Context) (*Aggregate, error), map[ProductId]int) {
+	loads := make(map[ProductId]int)
+
+	return func(id ProductId, _ context.Context) (*Aggregate, error) {
+		loads[id]++
+
+		for _, a := range aggregates {
+			if a.Id == id {
+				return a, nil
+			}
+		}
+
+		return nil, &ErrNotFound{ProductId: id}
+	}, loads
+}
+
+func statuses(results []StockAdjustmentResult) []AdjustmentStatus {
+	s := make([]AdjustmentStatus, len(results))
+	for i, r := range results {
+		s[i] = r.Status
+	}
+
+	return s
+}
+
+func amounts(results []StockAdjustmentResult) []int {
+	a := make([]int, len(results))
+	for i, r := range results {
+		a[i] = r.Amount
+	}
+
+	return a
+}
+
+func TestNewStockBatch(t *testing.T) {
+	keyboard, mouse := aggregate(keyboardId, 5, 2), aggregate(mouseId, 1, 0)
+	load, loads := loader(keyboard, mouse)
+
+	b, err := newStockBatch(&AdjustStockRequest{Adjustments: []StockAdjustment{
+		{Id: keyboardId, Quantity: -2},
+		{Id: mouseId, Quantity: 3, WarehouseId: "north"},
+		{Id: keyboardId, Quantity: -1},
+		{Id: missingId, Quantity: 1},
+		{Id: keyboardId, Quantity: -2},
+		{Id: keyboardId, Quantity: -1},
+		{Id: mouseId, Quantity: 1, WarehouseId: "south"},
+		{Id: "not-a-uuid", Quantity: 1},
+		{Id: mouseId, Quantity: 0},
+		{Id: missingId, Quantity: 2},
+	}}, load, warehouses{DefaultWarehouse: true, "north": true}, context.Background())
+	if err != nil {
+		t.Fatalf("newStockBatch: %v", err)
+	}
+
+	wantStatuses := []AdjustmentStatus{
+		"", "", "", AdjustmentRejected, "", AdjustmentRejected, AdjustmentRejected, AdjustmentRejected, AdjustmentRejected, AdjustmentRejected,
+	}
+	if got := statuses(b.results); !reflect.DeepEqual(got, wantStatuses) {
+		t.Errorf("statuses are %v, want %v", got, wantStatuses)
+	}
+
+	// Adjustments of the same product apply to the stock left by the previous ones.
+	if got, want := amounts(b.results), []int{3, 3, 2, 0, 0, 0, 0, 0, 0, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("amounts are %v, want %v", got, want)
+	}
+
+	for i, wantErr := range map[int]any{3: &ErrNotFound{}, 5: &ErrInsufficientStock{}, 6: &ErrWarehouseNotFound{}, 9: &ErrNotFound{}} {
+		if reflect.TypeOf(b.results[i].Err) != reflect.TypeOf(wantErr) {
+			t.Errorf("adjustment %d was rejected with %v, want %T", i, b.results[i].Err, wantErr)
+		}
+	}
+
+	if !b.rejected {
+		t.Error("batch with rejected adjustments is not marked as rejected")
+	}
+
+	if !reflect.DeepEqual(b.aggregates, []*Aggregate{keyboard, mouse}) {
+		t.Errorf("batch holds aggregates %v, want those adjusted in order", b.aggregates)
+	}
+
+	if want := map[ProductId]int{keyboardId: 1, mouseId: 1, missingId: 1}; !reflect.DeepEqual(loads, want) {
+		t.Errorf("loaded products %v times, want each once", loads)
+	}
+
+	if got, want := b.items[keyboardId], []int{0, 2, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("keyboard adjustments are %v, want %v", got, want)
+	}
+	if got, want := b.items[mouseId], []int{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("mouse adjustments are %v, want %v", got, want)
+	}
+}
+
+func TestNewStockBatchInternalErrors(t *testing.T) {
+	internal := &errors.ErrInternal{Err: fmt.Errorf("connection lost")}
+
+	load := func(ProductId, context.Context) (*Aggregate, error) { return nil, internal }
+	req := &AdjustStockRequest{Adjustments: []StockAdjustment{{Id: keyboardId, Quantity: 1}}}
+
+	if _, err := newStockBatch(req, load, warehouses{DefaultWarehouse: true}, context.Background()); err != internal {
+		t.Fatalf("newStockBatch() error = %v, want the internal error of load", err)
+	}
+}
+
+func TestStockBatchAbort(t *testing.T) {
+	load, _ := loader(aggregate(keyboardId, 5, 2), aggregate(mouseId, 1, 0))
+
+	b, err := newStockBatch(&AdjustStockRequest{Adjustments: []StockAdjustment{
+		{Id: keyboardId, Quantity: -1},
+		{Id: mouseId, Quantity: -2},
+		{Id: keyboardId, Quantity: 4},
+	}}, load, warehouses{DefaultWarehouse: true}, context.Background())
+	if err != nil {
+		t.Fatalf("newStockBatch: %v", err)
+	}
+
+	b.abort()
+
+	if got, want := statuses(b.results), []AdjustmentStatus{AdjustmentAborted, AdjustmentRejected, AdjustmentAborted}; !reflect.DeepEqual(got, want) {
+		t.Errorf("statuses are %v, want %v", got, want)
+	}
+	if got, want := amounts(b.results), []int{0, 0, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("amounts are %v, want none of aborted adjustments", got)
+	}
+}
+
+func TestStockBatchPartialFailures(t *testing.T) {
+	load, _ := loader(aggregate(keyboardId, 5, 2), aggregate(mouseId, 1, 0))
+
+	b, err := newStockBatch(&AdjustStockRequest{Mode: BestEffort, Adjustments: []StockAdjustment{
+		{Id: keyboardId, Quantity: -1},
+		{Id: mouseId, Quantity: 2},
+		{Id: keyboardId, Quantity: -2},
+		{Id: mouseId, Quantity: -5},
+	}}, load, warehouses{DefaultWarehouse: true}, context.Background())
+	if err != nil {
+		t.Fatalf("newStockBatch: %v", err)
+	}
+	keyboard, mouse := b.aggregates[0], b.aggregates[1]
+
+	b.fail(keyboard, fmt.Errorf("could not save"))
+	b.apply([]*Aggregate{mouse})
+
+	want := []AdjustmentStatus{AdjustmentFailed, AdjustmentApplied, AdjustmentFailed, AdjustmentRejected}
+	if got := statuses(b.results); !reflect.DeepEqual(got, want) {
+		t.Errorf("statuses are %v, want %v", got, want)
+	}
+	if b.results[0].Error != "could not save" || b.results[2].Error != "could not save" {
+		t.Errorf("failed adjustments report %q and %q", b.results[0].Error, b.results[2].Error)
+	}
+}
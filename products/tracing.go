@@ -0,0 +1,169 @@
+package products
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/giornetta/microshop/tracing"
+)
+
+type tracingService struct {
+	service Service
+	tracer  trace.Tracer
+}
+
+func NewTracingService(tracer trace.Tracer, service Service) Service {
+	return &tracingService{
+		service: service,
+		tracer:  tracer,
+	}
+}
+
+func (s *tracingService) Create(req *CreateProductRequest, ctx context.Context) (*Product, error) {
+	ctx, span := s.tracer.Start(ctx, "products.Service/Create")
+	defer span.End()
+
+	p, err := s.service.Create(req, ctx)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.String("product_id", p.Id.String()))
+
+	return p, nil
+}
+
+func (s *tracingService) GetById(productId ProductId, ctx context.Context) (*Product, error) {
+	ctx, span := s.tracer.Start(ctx, "products.Service/GetById", trace.WithAttributes(
+		attribute.String("product_id", productId.String()),
+	))
+	defer span.End()
+
+	p, err := s.service.GetById(productId, ctx)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (s *tracingService) List(ctx context.Context) ([]*Product, error) {
+	ctx, span := s.tracer.Start(ctx, "products.Service/List")
+	defer span.End()
+
+	prods, err := s.service.List(ctx)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return prods, nil
+}
+
+func (s *tracingService) ListLowStock(ctx context.Context) ([]*Product, error) {
+	ctx, span := s.tracer.Start(ctx, "products.Service/ListLowStock")
+	defer span.End()
+
+	prods, err := s.service.ListLowStock(ctx)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return prods, nil
+}
+
+func (s *tracingService) Update(req *UpdateProductRequest, ctx context.Context) error {
+	ctx, span := s.tracer.Start(ctx, "products.Service/Update", trace.WithAttributes(
+		attribute.String("product_id", req.Id.String()),
+	))
+	defer span.End()
+
+	err := s.service.Update(req, ctx)
+	tracing.RecordError(span, err)
+
+	return err
+}
+
+func (s *tracingService) Restock(req *RestockProductRequest, ctx context.Context) error {
+	ctx, span := s.tracer.Start(ctx, "products.Service/Restock", trace.WithAttributes(
+		attribute.String("product_id", req.Id.String()),
+		attribute.String("warehouse_id", req.WarehouseId.String()),
+		attribute.Int("amount", req.Amount),
+	))
+	defer span.End()
+
+	err := s.service.Restock(req, ctx)
+	tracing.RecordError(span, err)
+
+	return err
+}
+
+func (s *tracingService) TransferStock(req *TransferStockRequest, ctx context.Context) error {
+	ctx, span := s.tracer.Start(ctx, "products.Service/TransferStock", trace.WithAttributes(
+		attribute.String("product_id", req.Id.String()),
+		attribute.String("from_warehouse_id", req.From.String()),
+		attribute.String("to_warehouse_id", req.To.String()),
+		attribute.Int("quantity", req.Quantity),
+	))
+	defer span.End()
+
+	err := s.service.TransferStock(req, ctx)
+	tracing.RecordError(span, err)
+
+	return err
+}
+
+func (s *tracingService) ChangePrice(req *ChangePriceRequest, ctx context.Context) error {
+	ctx, span := s.tracer.Start(ctx, "products.Service/ChangePrice", trace.WithAttributes(
+		attribute.String("product_id", req.Id.String()),
+		attribute.Float64("price", float64(req.Price)),
+		attribute.String("schedule_id", req.ScheduleId.String()),
+	))
+	defer span.End()
+
+	err := s.service.ChangePrice(req, ctx)
+	tracing.RecordError(span, err)
+
+	return err
+}
+
+func (s *tracingService) AdjustStock(req *AdjustStockRequest, ctx context.Context) ([]StockAdjustmentResult, error) {
+	ctx, span := s.tracer.Start(ctx, "products.Service/AdjustStock", trace.WithAttributes(
+		attribute.Int("adjustments", len(req.Adjustments)),
+		attribute.String("mode", string(req.Mode)),
+	))
+	defer span.End()
+
+	results, err := s.service.AdjustStock(req, ctx)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	applied := 0
+	for _, r := range results {
+		if r.Status == AdjustmentApplied {
+			applied++
+		}
+	}
+	span.SetAttributes(attribute.Int("applied", applied))
+
+	return results, nil
+}
+
+func (s *tracingService) Delete(productId ProductId, ctx context.Context) error {
+	ctx, span := s.tracer.Start(ctx, "products.Service/Delete", trace.WithAttributes(
+		attribute.String("product_id", productId.String()),
+	))
+	defer span.End()
+
+	err := s.service.Delete(productId, ctx)
+	tracing.RecordError(span, err)
+
+	return err
+}
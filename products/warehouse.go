@@ -0,0 +1,234 @@
+package products
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+
+	"github.com/giornetta/microshop/errors"
+	"github.com/giornetta/microshop/events"
+)
+
+// WarehouseId is a short code chosen when the warehouse is created, such as milan-1,
+// so that it can be read on pick lists.
+type WarehouseId string
+
+func (id WarehouseId) String() string {
+	return string(id)
+}
+
+// DefaultWarehouse always exists. It holds the stock of the products created before warehouses were
+// introduced, and the stock of the restocks and adjustments not naming a warehouse.
+const DefaultWarehouse WarehouseId = events.DefaultWarehouseId
+
+type Warehouse struct {
+	Id       WarehouseId `json:"warehouse_id"`
+	Name     string      `json:"name"`
+	Location string      `json:"location"`
+}
+
+// WarehouseStock is the stock of a product held by a warehouse.
+type WarehouseStock struct {
+	WarehouseId WarehouseId `json:"warehouse_id"`
+	Amount      int         `json:"amount"`
+}
+
+// WarehouseQuerier reads the warehouses.
+// Every method fails with *errors.ErrInternal when the underlying storage does.
+type WarehouseQuerier interface {
+	// FindById fails with *ErrWarehouseNotFound if there is no warehouse with the given id.
+	FindById(id WarehouseId, ctx context.Context) (*Warehouse, error)
+	// List returns all the warehouses in no particular order.
+	List(ctx context.Context) ([]*Warehouse, error)
+}
+
+// WarehouseRepository stores the warehouses. Unlike products, warehouses are written directly
+// instead of being projected from events, as they rarely change and nothing reacts to their changes.
+// It is implemented by pg and memory, which both start with the DefaultWarehouse.
+type WarehouseRepository interface {
+	WarehouseQuerier
+	// Store adds a warehouse, failing with *ErrWarehouseAlreadyExists if its id is already taken.
+	Store(warehouse *Warehouse, ctx context.Context) error
+}
+
+// warehouseIdPattern matches ids made of lowercase letters, digits and dashes.
+var warehouseIdPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+var warehouseIdRules = []validation.Rule{
+	validation.Length(2, 32),
+	validation.Match(warehouseIdPattern),
+}
+
+type CreateWarehouseRequest struct {
+	Id       WarehouseId
+	Name     string
+	Location string
+}
+
+func (r *CreateWarehouseRequest) Validate() error {
+	r.Id = WarehouseId(strings.TrimSpace(r.Id.String()))
+	r.Name = strings.TrimSpace(r.Name)
+	r.Location = strings.TrimSpace(r.Location)
+
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Id,
+			append([]validation.Rule{validation.Required}, warehouseIdRules...)...,
+		),
+		validation.Field(&r.Name,
+			validation.Required,
+			validation.Length(2, 64),
+			is.ASCII,
+		),
+		validation.Field(&r.Location,
+			validation.Length(0, 256),
+		),
+	)
+}
+
+type WarehouseService interface {
+	Create(req *CreateWarehouseRequest, ctx context.Context) (*Warehouse, error)
+	GetById(id WarehouseId, ctx context.Context) (*Warehouse, error)
+	// List returns all the warehouses, sorted by id.
+	List(ctx context.Context) ([]*Warehouse, error)
+}
+
+type warehouseService struct {
+	repository WarehouseRepository
+}
+
+func NewWarehouseService(repository WarehouseRepository) WarehouseService {
+	return &warehouseService{
+		repository: repository,
+	}
+}
+
+func (s *warehouseService) Create(req *CreateWarehouseRequest, ctx context.Context) (*Warehouse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, &errors.ErrBadRequest{Err: err}
+	}
+
+	w := &Warehouse{
+		Id:       req.Id,
+		Name:     req.Name,
+		Location: req.Location,
+	}
+
+	if err := s.repository.Store(w, ctx); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (s *warehouseService) GetById(id WarehouseId, ctx context.Context) (*Warehouse, error) {
+	return s.repository.FindById(id, ctx)
+}
+
+func (s *warehouseService) List(ctx context.Context) ([]*Warehouse, error) {
+	warehouses, err := s.repository.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(warehouses, func(a, b *Warehouse) int {
+		return strings.Compare(a.Id.String(), b.Id.String())
+	})
+
+	return warehouses, nil
+}
+
+// checkWarehouse fails with *ErrWarehouseNotFound if the warehouse does not exist.
+func checkWarehouse(querier WarehouseQuerier, id WarehouseId, ctx context.Context) error {
+	_, err := querier.FindById(id, ctx)
+	return err
+}
+
+// StockIn returns the stock of the product held by the warehouse.
+func (p *Product) StockIn(id WarehouseId) int {
+	for _, s := range p.Stock {
+		if s.WarehouseId == id {
+			return s.Amount
+		}
+	}
+
+	return 0
+}
+
+// addStock adds quantity items to the stock of the warehouse, or removes them when quantity is negative,
+// failing with *ErrInsufficientStock if the warehouse does not hold enough of them.
+func (p *Product) addStock(id WarehouseId, quantity int) error {
+	amount := p.StockIn(id) + quantity
+	if amount < 0 {
+		return &ErrInsufficientStock{ProductId: p.Id, WarehouseId: id, Amount: p.StockIn(id), Quantity: quantity}
+	}
+
+	p.setStock(id, amount)
+	return nil
+}
+
+// setStock sets the stock of the warehouse, keeping Stock sorted by warehouse, without empty warehouses,
+// and Amount their sum.
+func (p *Product) setStock(id WarehouseId, amount int) {
+	i, found := slices.BinarySearchFunc(p.Stock, id, func(s WarehouseStock, id WarehouseId) int {
+		return strings.Compare(s.WarehouseId.String(), id.String())
+	})
+
+	// The slice is copied, as it may be shared with the state the product was copied from.
+	stock := slices.Clone(p.Stock)
+	switch {
+	case found && amount == 0:
+		stock = slices.Delete(stock, i, i+1)
+	case found:
+		stock[i].Amount = amount
+	case amount > 0:
+		stock = slices.Insert(stock, i, WarehouseStock{WarehouseId: id, Amount: amount})
+	}
+
+	p.Stock = stock
+	p.Amount = 0
+	for _, s := range stock {
+		p.Amount += s.Amount
+	}
+}
+
+// stockFromEvent returns the stock carried by an event. Events published before warehouses were introduced
+// are upcast with their stock, but those ingested from producers keeping no warehouses may still have none:
+// their whole amount is then held by the DefaultWarehouse.
+func stockFromEvent(stock []events.WarehouseStock, amount int) []WarehouseStock {
+	if len(stock) == 0 {
+		return defaultStock(amount)
+	}
+
+	s := make([]WarehouseStock, len(stock))
+	for i, ws := range stock {
+		s[i] = WarehouseStock{WarehouseId: WarehouseId(ws.WarehouseId), Amount: ws.Amount}
+	}
+
+	return s
+}
+
+func stockToEvent(stock []WarehouseStock) []events.WarehouseStock {
+	if len(stock) == 0 {
+		return nil
+	}
+
+	s := make([]events.WarehouseStock, len(stock))
+	for i, ws := range stock {
+		s[i] = events.WarehouseStock{WarehouseId: ws.WarehouseId.String(), Amount: ws.Amount}
+	}
+
+	return s
+}
+
+// defaultStock returns the stock of amount items held by the DefaultWarehouse.
+func defaultStock(amount int) []WarehouseStock {
+	if amount <= 0 {
+		return nil
+	}
+
+	return []WarehouseStock{{WarehouseId: DefaultWarehouse, Amount: amount}}
+}
@@ -0,0 +1,226 @@
+// Package schemaregistry is a local, file-based stand-in for a schema registry,
+// assigning stable IDs to the schemas used by binary event codecs.
+package schemaregistry
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Format string
+
+const (
+	Avro     Format = "AVRO"
+	Protobuf Format = "PROTOBUF"
+)
+
+type Schema struct {
+	ID         int    `json:"id"`
+	Subject    string `json:"subject"`
+	Format     Format `json:"format"`
+	Definition string `json:"definition"`
+}
+
+type Registry interface {
+	// Register stores the schema under the given subject, returning its ID.
+	// Registering the same definition twice returns the same ID.
+	Register(subject string, format Format, definition string) (int, error)
+	// Lookup returns the schema with the given ID.
+	Lookup(id int) (*Schema, error)
+}
+
+type ErrNotFound struct {
+	ID int
+}
+
+func (err *ErrNotFound) Error() string {
+	return fmt.Sprintf("schema with id=%d was not found", err.ID)
+}
+
+type fileRegistry struct {
+	dir string
+
+	lock    sync.RWMutex
+	schemas map[int]*Schema
+}
+
+// NewFileRegistry returns a Registry storing every schema as a JSON file in the given directory,
+// so that it can be shared by every process running on the same machine.
+func NewFileRegistry(dir string) (Registry, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	r := &fileRegistry{
+		dir:     dir,
+		schemas: make(map[int]*Schema),
+	}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *fileRegistry) Register(subject string, format Format, definition string) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for {
+		if err := r.load(); err != nil {
+			return 0, err
+		}
+
+		nextId := 1
+		for id, s := range r.schemas {
+			if s.Subject == subject && s.Format == format && s.Definition == definition {
+				return id, nil
+			}
+
+			if id >= nextId {
+				nextId = id + 1
+			}
+		}
+
+		schema := &Schema{
+			ID:         nextId,
+			Subject:    subject,
+			Format:     format,
+			Definition: definition,
+		}
+
+		err := r.write(schema)
+		if errors.Is(err, os.ErrExist) {
+			// Another process registered a schema with the same ID, try again with the next one.
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		r.schemas[schema.ID] = schema
+		return schema.ID, nil
+	}
+}
+
+func (r *fileRegistry) Lookup(id int) (*Schema, error) {
+	r.lock.RLock()
+	s, ok := r.schemas[id]
+	r.lock.RUnlock()
+
+	if ok {
+		return s, nil
+	}
+
+	// The schema might have been registered by another process.
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	s, ok = r.schemas[id]
+	if !ok {
+		return nil, &ErrNotFound{ID: id}
+	}
+
+	return s, nil
+}
+
+func (r *fileRegistry) load() error {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		idStr, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok {
+			continue
+		}
+
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			continue
+		}
+
+		if _, ok := r.schemas[id]; ok {
+			continue
+		}
+
+		payload, err := os.ReadFile(filepath.Join(r.dir, e.Name()))
+		if err != nil {
+			return err
+		}
+
+		var s Schema
+		if err := json.Unmarshal(payload, &s); err != nil {
+			return fmt.Errorf("could not parse schema %s: %w", e.Name(), err)
+		}
+
+		r.schemas[id] = &s
+	}
+
+	return nil
+}
+
+// write stores the schema in the file named after its ID, failing with os.ErrExist if there already is one.
+// The schema is written to a temporary file first and linked into place once complete, so that other
+// processes loading the directory never read it half written.
+func (r *fileRegistry) write(s *Schema) error {
+	payload, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(r.dir, fmt.Sprintf(".%d-*.tmp", s.ID))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(payload); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// Unlike a rename, a link does not replace the schema another process registered with the same ID.
+	return os.Link(f.Name(), filepath.Join(r.dir, fmt.Sprintf("%d.json", s.ID)))
+}
+
+const magicByte = 0
+
+// Frame prefixes the payload with the ID of the schema it was encoded with,
+// using the same wire format as the Confluent Schema Registry.
+func Frame(id int, payload []byte) []byte {
+	framed := make([]byte, 5, 5+len(payload))
+	framed[0] = magicByte
+	binary.BigEndian.PutUint32(framed[1:], uint32(id))
+
+	return append(framed, payload...)
+}
+
+// Unframe returns the schema ID and the payload of a message produced by Frame.
+func Unframe(framed []byte) (int, []byte, error) {
+	if len(framed) < 5 || framed[0] != magicByte {
+		return 0, nil, errors.New("payload is not framed with a schema id")
+	}
+
+	return int(binary.BigEndian.Uint32(framed[1:5])), framed[5:], nil
+}
@@ -0,0 +1,166 @@
+package schemaregistry
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func newRegistry(t *testing.T, dir string) Registry {
+	t.Helper()
+
+	r, err := NewFileRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewFileRegistry: %v", err)
+	}
+
+	return r
+}
+
+func register(t *testing.T, r Registry, subject string, format Format, definition string) int {
+	t.Helper()
+
+	id, err := r.Register(subject, format, definition)
+	if err != nil {
+		t.Fatalf("Register(%s): %v", subject, err)
+	}
+
+	return id
+}
+
+func TestRegisterReusesIds(t *testing.T) {
+	r := newRegistry(t, t.TempDir())
+
+	first := register(t, r, "products-Product.Created", Avro, `{"type":"record"}`)
+	if again := register(t, r, "products-Product.Created", Avro, `{"type":"record"}`); again != first {
+		t.Errorf("registering the same schema again returned id %d, want %d", again, first)
+	}
+
+	// Any difference in subject, format or definition makes another schema.
+	ids := map[int]bool{first: true}
+	for _, s := range []Schema{
+		{Subject: "products-Product.Updated", Format: Avro, Definition: `{"type":"record"}`},
+		{Subject: "products-Product.Created", Format: Protobuf, Definition: `{"type":"record"}`},
+		{Subject: "products-Product.Created", Format: Avro, Definition: `{"type":"record","name":"v2"}`},
+	} {
+		id := register(t, r, s.Subject, s.Format, s.Definition)
+		if ids[id] {
+			t.Errorf("registering %+v reused id %d", s, id)
+		}
+		ids[id] = true
+	}
+}
+
+func TestRegistriesShareDirectory(t *testing.T) {
+	dir := t.TempDir()
+	first, second := newRegistry(t, dir), newRegistry(t, dir)
+
+	// The registries stand for processes that loaded the directory before either registered anything.
+	a := register(t, first, "products-Product.Created", Avro, "a")
+	b := register(t, second, "products-Product.Updated", Avro, "b")
+	if a == b {
+		t.Fatalf("registries sharing a directory both assigned id %d", a)
+	}
+
+	if again := register(t, second, "products-Product.Created", Avro, "a"); again != a {
+		t.Errorf("registering a schema of the other registry returned id %d, want %d", again, a)
+	}
+
+	s, err := first.Lookup(b)
+	if err != nil {
+		t.Fatalf("Lookup(%d): %v", b, err)
+	}
+	if s.Subject != "products-Product.Updated" || s.Definition != "b" {
+		t.Errorf("Lookup(%d) returned %+v", b, s)
+	}
+
+	// Schemas outlive the registries that registered them.
+	if s, err := newRegistry(t, dir).Lookup(a); err != nil || s.Definition != "a" {
+		t.Errorf("Lookup(%d) after reopening returned %+v, %v", a, s, err)
+	}
+}
+
+func TestLookupMissing(t *testing.T) {
+	r := newRegistry(t, t.TempDir())
+
+	_, err := r.Lookup(7)
+
+	var notFound *ErrNotFound
+	if !errors.As(err, &notFound) || notFound.ID != 7 {
+		t.Errorf("got error %v, want *ErrNotFound", err)
+	}
+}
+
+func TestFrame(t *testing.T) {
+	payload := []byte("payload")
+
+	id, got, err := Unframe(Frame(258, payload))
+	if err != nil {
+		t.Fatalf("Unframe: %v", err)
+	}
+	if id != 258 || !bytes.Equal(got, payload) {
+		t.Errorf("Unframe returned id %d and %q, want 258 and %q", id, got, payload)
+	}
+
+	for name, framed := range map[string][]byte{
+		"a bad magic byte": append([]byte{1}, Frame(258, payload)[1:]...),
+		"no schema id":     {0, 0, 1},
+		"nothing":          nil,
+	} {
+		if _, _, err := Unframe(framed); err == nil {
+			t.Errorf("unframing a payload with %s succeeded", name)
+		}
+	}
+}
+
+func TestRegisterConcurrently(t *testing.T) {
+	dir := t.TempDir()
+
+	// Processes loading the directory while others register must never read a schema half written.
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			r, err := NewFileRegistry(dir)
+			if err != nil {
+				errs <- err
+				return
+			}
+			for j := 0; j < 10; j++ {
+				if _, err := r.Register(fmt.Sprintf("subject-%d", i), Avro, strconv.Itoa(j)); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if _, err := NewFileRegistry(dir); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 80 {
+		t.Errorf("directory holds %d files, want the 80 schemas alone", len(entries))
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"time"
@@ -8,20 +9,24 @@ import (
 
 func New(h http.Handler, opt *Options) *http.Server {
 	return &http.Server{
-		Addr:    fmt.Sprintf(":%d", opt.Port),
-		Handler: h,
-		// TODO Add TLS Support
-		TLSConfig:    nil,
-		ReadTimeout:  opt.ReadTimeout,
-		WriteTimeout: opt.WriteTimeout,
-		IdleTimeout:  opt.IdleTimeout,
+		Addr:              fmt.Sprintf(":%d", opt.Port),
+		Handler:           h,
+		TLSConfig:         opt.TLSConfig,
+		ReadTimeout:       opt.ReadTimeout,
+		ReadHeaderTimeout: opt.ReadHeaderTimeout,
+		WriteTimeout:      opt.WriteTimeout,
+		IdleTimeout:       opt.IdleTimeout,
 	}
 }
 
 type Options struct {
 	Port int
 
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	IdleTimeout  time.Duration
+	// TLSConfig serves HTTPS when set, see NewTLSConfig.
+	TLSConfig *tls.Config
+
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
 }
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// TLSOptions configures HTTPS for the server.
+type TLSOptions struct {
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFile enables mutual TLS: clients must present a certificate signed by one of these CAs.
+	ClientCAFile string
+
+	// Logger reports the files that cannot be reloaded, slog.Default() when nil.
+	Logger *slog.Logger
+}
+
+// NewTLSConfig returns a TLS configuration serving the certificate in opt.
+// Certificate, key and client CA files are reloaded when they change on disk,
+// so rotated certificates are picked up without restarting the server.
+func NewTLSConfig(opt *TLSOptions) (*tls.Config, error) {
+	if opt.CertFile == "" || opt.KeyFile == "" {
+		return nil, errors.New("tls requires both a certificate and a key file")
+	}
+
+	r := &reloader{opt: opt, logger: opt.Logger}
+	if r.logger == nil {
+		r.logger = slog.Default()
+	}
+
+	if _, err := r.config(); err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.config()
+		},
+	}, nil
+}
+
+// reloader caches the TLS configuration built from the files of opt,
+// rebuilding it whenever one of them is modified.
+type reloader struct {
+	opt    *TLSOptions
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	modTime time.Time
+	cfg     *tls.Config
+}
+
+// config returns the cached configuration, rebuilding it if the files were modified since.
+// The previous certificate keeps being served while the files cannot be read, as happens when a rotation
+// replaces them, and the configuration only fails when none was ever loaded.
+func (r *reloader) config() (*tls.Config, error) {
+	modTime, err := r.lastModified()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		return r.previous(err)
+	}
+
+	if r.cfg != nil && !modTime.After(r.modTime) {
+		return r.cfg, nil
+	}
+
+	cfg, err := r.load()
+	if err != nil {
+		// Keep serving the previous certificate while a rotation is half written.
+		return r.previous(err)
+	}
+
+	r.cfg, r.modTime = cfg, modTime
+	return cfg, nil
+}
+
+// previous returns the cached configuration, logging the error that prevented reloading it,
+// or fails with the error if there is none.
+func (r *reloader) previous(err error) (*tls.Config, error) {
+	if r.cfg == nil {
+		return nil, err
+	}
+
+	r.logger.Warn("could not reload the TLS certificate, serving the previous one", slog.String("err", err.Error()))
+	return r.cfg, nil
+}
+
+func (r *reloader) lastModified() (time.Time, error) {
+	var latest time.Time
+
+	for _, f := range []string{r.opt.CertFile, r.opt.KeyFile, r.opt.ClientCAFile} {
+		if f == "" {
+			continue
+		}
+
+		info, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+func (r *reloader) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(r.opt.CertFile, r.opt.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load certificate: %w", err)
+	}
+
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if r.opt.ClientCAFile != "" {
+		pool, err := loadCertPool(r.opt.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return cfg, nil
+}
+
+// loadCertPool returns a pool with the PEM encoded certificates of file.
+func loadCertPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read CA file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", file)
+	}
+
+	return pool, nil
+}
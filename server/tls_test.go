@@ -0,0 +1,141 @@
+package server
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self-signed certificate for localhost and its key to the files,
+// modified at the given time, and returns the certificate.
+func writeCertificate(t *testing.T, certFile, keyFile string, modTime time.Time) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for file, block := range map[string]*pem.Block{certFile: {Type: "CERTIFICATE", Bytes: der}, keyFile: {Type: "EC PRIVATE KEY", Bytes: keyDer}} {
+		if err := os.WriteFile(file, pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+// handshake connects to the server, trusting any certificate for localhost, and returns the one it served.
+func handshake(t *testing.T, s *httptest.Server) *x509.Certificate {
+	t.Helper()
+
+	conn, err := tls.Dial("tcp", s.Listener.Addr().String(), &tls.Config{
+		ServerName:         "localhost",
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	defer conn.Close()
+
+	return conn.ConnectionState().PeerCertificates[0]
+}
+
+func TestTLSReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	start := time.Now().Add(-time.Minute)
+
+	first := writeCertificate(t, certFile, keyFile, start)
+
+	cfg, err := NewTLSConfig(&TLSOptions{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("NewTLSConfig: %v", err)
+	}
+
+	s := httptest.NewUnstartedServer(http.NotFoundHandler())
+	s.TLS = cfg
+	s.StartTLS()
+	defer s.Close()
+
+	if got := handshake(t, s); !bytes.Equal(got.Raw, first.Raw) {
+		t.Fatal("served a certificate other than the configured one")
+	}
+
+	// A rotation removes the certificate for a while: the previous one keeps being served.
+	if err := os.Remove(certFile); err != nil {
+		t.Fatal(err)
+	}
+	if got := handshake(t, s); !bytes.Equal(got.Raw, first.Raw) {
+		t.Fatal("served another certificate while the certificate file was missing")
+	}
+
+	// A rotation half written, with a key not matching the certificate, keeps the previous one as well.
+	if err := os.WriteFile(certFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := handshake(t, s); !bytes.Equal(got.Raw, first.Raw) {
+		t.Fatal("served another certificate while the certificate file was invalid")
+	}
+
+	second := writeCertificate(t, certFile, keyFile, start.Add(time.Minute))
+	if got := handshake(t, s); !bytes.Equal(got.Raw, second.Raw) {
+		t.Fatal("did not serve the rotated certificate")
+	}
+}
+
+func TestNewTLSConfigMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := NewTLSConfig(&TLSOptions{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key")}); err == nil {
+		t.Fatal("NewTLSConfig succeeded without certificate files")
+	}
+	if _, err := NewTLSConfig(&TLSOptions{CertFile: filepath.Join(dir, "tls.crt")}); err == nil {
+		t.Fatal("NewTLSConfig succeeded without a key file")
+	}
+}
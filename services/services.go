@@ -0,0 +1,156 @@
+// Package services wires the products and customers services, for bootstrap.Start to run them.
+package services
+
+import (
+	"net/http"
+
+	"github.com/giornetta/microshop/bootstrap"
+	"github.com/giornetta/microshop/customers"
+	customersPg "github.com/giornetta/microshop/customers/pg"
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/eventstore"
+	eventstorePg "github.com/giornetta/microshop/eventstore/pg"
+	"github.com/giornetta/microshop/log"
+	"github.com/giornetta/microshop/metrics"
+	"github.com/giornetta/microshop/products"
+	productsPg "github.com/giornetta/microshop/products/pg"
+	"github.com/giornetta/microshop/tracing"
+)
+
+// ProductStorage is where the products service keeps its projections, price schedules and events.
+type ProductStorage struct {
+	Products       products.ProductRepository
+	Warehouses     products.WarehouseRepository
+	PriceHistory   products.PriceHistoryRepository
+	PriceSchedules products.PriceScheduleRepository
+	Events         eventstore.Store
+}
+
+// PgProductStorage returns the storage of the products service in the database of d.
+func PgProductStorage(d *bootstrap.Deps) *ProductStorage {
+	return &ProductStorage{
+		Products:       productsPg.NewProductRepository(d.Pool),
+		Warehouses:     productsPg.NewWarehouseRepository(d.Pool),
+		PriceHistory:   productsPg.NewPriceHistoryRepository(d.Pool),
+		PriceSchedules: productsPg.NewPriceScheduleRepository(d.Pool),
+		Events:         eventstorePg.NewEventStore(d.Pool),
+	}
+}
+
+// Products returns the products service, keeping its data in the storage returned by storage.
+func Products(storage func(d *bootstrap.Deps) *ProductStorage) bootstrap.Service {
+	return bootstrap.Service{
+		Name:       "products-service",
+		Topic:      events.ProductTopic,
+		Migrations: productsPg.Migrations,
+		Build: func(d *bootstrap.Deps) http.Handler {
+			return buildProducts(d, storage(d))
+		},
+	}
+}
+
+func buildProducts(d *bootstrap.Deps, storage *ProductStorage) http.Handler {
+	handlers := []events.Handler{
+		products.NewProductHandler(storage.Products),
+		products.NewPriceHistoryHandler(storage.PriceHistory),
+	}
+	if sink := bootstrap.Notifications(&d.Config.Notifications, d.Logger.With("svc", "Notifications")); sink != nil {
+		handlers = append(handlers, products.NewStockAlertHandler(sink, d.Logger.With("svc", "StockAlertHandler")))
+	}
+
+	productHandler := log.NewEventHandler(
+		d.Logger.With("svc", "ProductHandler"),
+		metrics.NewEventHandler(events.Handlers(handlers...)),
+	)
+	d.Listener.Handle(events.ProductTopic, productHandler)
+
+	aggregates := NewAggregateRepository(d, storage.Events, storage.Products)
+	service := NewProductService(d, aggregates, storage.Products, storage.Warehouses)
+	d.Go("event relay", NewRelay(d, storage.Events).Run)
+	d.AcceptEvents(products.NewEventIngester(storage.Products, storage.Warehouses, aggregates))
+
+	scheduler := products.NewPriceScheduler(
+		service,
+		aggregates,
+		storage.PriceSchedules,
+		d.Config.Pricing.ScheduleInterval,
+		d.Logger.With("svc", "PriceScheduler"),
+	)
+	d.Go("price scheduler", scheduler.Run)
+
+	return products.NewRouter(
+		service,
+		products.NewImporter(service, storage.Products),
+		products.NewWarehouseService(storage.Warehouses),
+		products.NewPricingService(storage.Products, storage.PriceHistory, storage.PriceSchedules),
+	)
+}
+
+// NewAggregateRepository returns the repository of the product aggregates stored in store, bootstrapping
+// those without a stream from the projection queried through productRepository.
+func NewAggregateRepository(d *bootstrap.Deps, store eventstore.Store, productRepository products.ProductQuerier) products.AggregateRepository {
+	return products.NewAggregateRepository(
+		store,
+		productRepository,
+		d.Config.EventStore.SnapshotEvery,
+		d.Logger.With("svc", "AggregateRepository"),
+	)
+}
+
+// NewProductService returns the products Service, saving the aggregates and querying the projection through
+// productRepository. It does not need d.Listener.
+func NewProductService(d *bootstrap.Deps, aggregates products.AggregateRepository, productRepository products.ProductRepository, warehouses products.WarehouseQuerier) products.Service {
+	return products.NewLoggingService(
+		d.Logger.With("svc", "Service"),
+		products.NewTracingService(
+			tracing.Tracer(),
+			products.NewEventSourcedService(productRepository, warehouses, aggregates),
+		),
+	)
+}
+
+// NewRelay returns the Relay publishing the events appended to store with d.Publisher.
+func NewRelay(d *bootstrap.Deps, store eventstore.Store) *eventstore.Relay {
+	return eventstore.NewRelay(store, d.Publisher, d.Config.EventStore.RelayInterval, d.Logger.With("svc", "EventRelay"))
+}
+
+// PgCustomerRepository returns the repository of the customers service in the database of d.
+func PgCustomerRepository(d *bootstrap.Deps) customers.CustomerRepository {
+	return customersPg.NewCustomerRepository(d.Pool)
+}
+
+// Customers returns the customers service, keeping its customers in the repository returned by repository.
+func Customers(repository func(d *bootstrap.Deps) customers.CustomerRepository) bootstrap.Service {
+	return bootstrap.Service{
+		Name:       "customers-service",
+		Topic:      events.CustomerTopic,
+		Migrations: customersPg.Migrations,
+		Build: func(d *bootstrap.Deps) http.Handler {
+			return buildCustomers(d, repository(d))
+		},
+	}
+}
+
+func buildCustomers(d *bootstrap.Deps, customerRepository customers.CustomerRepository) http.Handler {
+	customersHandler := log.NewEventHandler(
+		d.Logger.With("svc", "CustomerHandler"),
+		metrics.NewEventHandler(customers.NewCustomerHandler(customerRepository)),
+	)
+	d.Listener.Handle(events.CustomerTopic, customersHandler)
+
+	d.AcceptEvents(customers.NewEventIngester(customerRepository, d.Publisher))
+
+	return customers.NewRouter(NewCustomerService(d, customerRepository))
+}
+
+// NewCustomerService returns the customers Service, querying the projection through customerRepository.
+// It does not need d.Listener.
+func NewCustomerService(d *bootstrap.Deps, customerRepository customers.CustomerRepository) customers.Service {
+	return customers.NewLoggingService(
+		d.Logger.With("svc", "Service"),
+		customers.NewTracingService(
+			tracing.Tracer(),
+			customers.NewService(customerRepository, d.Publisher),
+		),
+	)
+}
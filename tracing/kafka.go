@@ -0,0 +1,82 @@
+package tracing
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RecordCarrier adapts the headers of a Kafka record to a propagation.TextMapCarrier,
+// so that the trace context travels with the events.
+type RecordCarrier struct {
+	Record *kgo.Record
+}
+
+func (c RecordCarrier) Get(key string) string {
+	for _, h := range c.Record.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+
+	return ""
+}
+
+func (c RecordCarrier) Set(key, value string) {
+	for i, h := range c.Record.Headers {
+		if h.Key == key {
+			c.Record.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+
+	c.Record.Headers = append(c.Record.Headers, kgo.RecordHeader{Key: key, Value: []byte(value)})
+}
+
+func (c RecordCarrier) Keys() []string {
+	keys := make([]string, len(c.Record.Headers))
+	for i, h := range c.Record.Headers {
+		keys[i] = h.Key
+	}
+
+	return keys
+}
+
+// StartProduce starts a producer span for the record and injects its context into the record headers.
+func StartProduce(record *kgo.Record, ctx context.Context) (context.Context, trace.Span) {
+	ctx, span := Tracer().Start(ctx, "publish "+record.Topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypePublish,
+			semconv.MessagingDestinationName(record.Topic),
+			semconv.MessagingKafkaMessageKey(string(record.Key)),
+		),
+	)
+
+	otel.GetTextMapPropagator().Inject(ctx, RecordCarrier{Record: record})
+
+	return ctx, span
+}
+
+// StartConsume restores the trace context propagated in the record headers,
+// and starts a consumer span for its processing.
+func StartConsume(record *kgo.Record, ctx context.Context) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, RecordCarrier{Record: record})
+
+	return Tracer().Start(ctx, "process "+record.Topic,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypeDeliver,
+			semconv.MessagingDestinationName(record.Topic),
+			semconv.MessagingKafkaMessageKey(string(record.Key)),
+			semconv.MessagingDestinationPartitionID(strconv.Itoa(int(record.Partition))),
+			semconv.MessagingKafkaMessageOffset(int(record.Offset)),
+		),
+	)
+}
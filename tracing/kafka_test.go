@@ -0,0 +1,114 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/giornetta/microshop/events"
+	"github.com/giornetta/microshop/kafka"
+	"github.com/giornetta/microshop/kafka/kafkatest"
+	"github.com/giornetta/microshop/tracing"
+)
+
+// record installs a tracer provider recording the spans in memory, and the propagator of Setup,
+// until t ends.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return recorder
+}
+
+// spanContexts hands the span context of every event it handles to the test.
+type spanContexts chan trace.SpanContext
+
+func (c spanContexts) Handle(_ events.Event, ctx context.Context) error {
+	c <- trace.SpanContextFromContext(ctx)
+	return nil
+}
+
+func TestKafkaPropagation(t *testing.T) {
+	recorder := record(t)
+
+	cluster := kafkatest.Cluster(t, 1)
+	publisher := kafka.NewEventPublisher(kafkatest.Client(t, cluster), events.NewCodecs())
+
+	listener := kafka.NewListener(kafkatest.Client(t, cluster,
+		kgo.ConsumerGroup("tracing"),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+	), events.NewCodecs())
+	handled := make(spanContexts, 1)
+	listener.Handle(events.ProductTopic, handled)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	listened := make(chan error, 1)
+	go func() {
+		listened <- listener.Listen(ctx)
+	}()
+
+	reqCtx, request := tracing.Tracer().Start(context.Background(), "request")
+	if err := publisher.Publish(events.ProductDeleted{
+		ProductEvent: events.ProductEvent{ProductId: "2f9c5d3a-7b1e-4c8a-9e6f-0d4b3a2c1e5f"},
+	}, reqCtx); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	request.End()
+
+	var got trace.SpanContext
+	select {
+	case got = <-handled:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the event was not handled")
+	}
+
+	// The consumer span ends once the event is handled, before the listener stops.
+	cancel()
+	if err := <-listened; err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+
+	if got.TraceID() != request.SpanContext().TraceID() {
+		t.Fatalf("the event was handled in trace %s, want the trace of the request %s", got.TraceID(), request.SpanContext().TraceID())
+	}
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range recorder.Ended() {
+		spans[s.Name()] = s
+	}
+
+	topic := events.ProductTopic.String()
+	publish, process := spans["publish "+topic], spans["process "+topic]
+	if publish == nil || process == nil {
+		t.Fatalf("recorded spans %v, want the publish and process spans of %s", spans, topic)
+	}
+
+	// The request publishes the event, whose handling continues the trace from the record headers.
+	if publish.SpanKind() != trace.SpanKindProducer || publish.Parent().SpanID() != request.SpanContext().SpanID() {
+		t.Errorf("publish span is a %s child of %s, want a producer child of the request", publish.SpanKind(), publish.Parent().SpanID())
+	}
+	if process.SpanKind() != trace.SpanKindConsumer || process.Parent().SpanID() != publish.SpanContext().SpanID() || !process.Parent().IsRemote() {
+		t.Errorf("process span is a %s child of %s, want a consumer child of the remote publish span", process.SpanKind(), process.Parent().SpanID())
+	}
+	if got.SpanID() != process.SpanContext().SpanID() {
+		t.Errorf("the event was handled in span %s, want the process span %s", got.SpanID(), process.SpanContext().SpanID())
+	}
+}
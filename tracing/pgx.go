@@ -0,0 +1,55 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type pgxTracer struct{}
+
+// NewPgxTracer returns a pgx.QueryTracer starting a client span for every query.
+func NewPgxTracer() pgx.QueryTracer {
+	return pgxTracer{}
+}
+
+type pgxSpanKey struct{}
+
+func (pgxTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, span := Tracer().Start(ctx, "postgres "+operation(data.SQL),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBQueryText(data.SQL),
+			semconv.DBNamespace(conn.Config().Database),
+		),
+	)
+
+	return context.WithValue(ctx, pgxSpanKey{}, span)
+}
+
+func (pgxTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	span, ok := ctx.Value(pgxSpanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+
+	if data.Err != pgx.ErrNoRows {
+		RecordError(span, data.Err)
+	}
+
+	span.End()
+}
+
+// operation returns the SQL verb of the query, used to name its span.
+func operation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "query"
+	}
+
+	return strings.ToUpper(fields[0])
+}
@@ -0,0 +1,104 @@
+// Package tracing sets up OpenTelemetry tracing, and provides the instrumentation
+// shared by the HTTP servers, Kafka clients and Postgres pools of the services.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/giornetta/microshop"
+
+const (
+	// NoExporter disables tracing.
+	NoExporter = ""
+	// StdoutExporter prints spans to stdout, for local runs.
+	StdoutExporter = "stdout"
+	// OTLPExporter sends spans to an OpenTelemetry collector over OTLP/HTTP.
+	OTLPExporter = "otlp"
+)
+
+type Options struct {
+	ServiceName string
+	Exporter    string
+	// Endpoint is the host:port of the OTLP collector. When empty, the OTEL_EXPORTER_OTLP_ENDPOINT
+	// environment variable or the default localhost:4318 are used.
+	Endpoint string
+	Insecure bool
+}
+
+// Setup installs the global tracer provider and the W3C trace context propagator.
+// The returned function flushes pending spans and must be called before the application exits.
+func Setup(opt *Options, ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+
+	switch opt.Exporter {
+	case NoExporter:
+		return func(context.Context) error { return nil }, nil
+	case StdoutExporter:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case OTLPExporter:
+		var opts []otlptracehttp.Option
+		if opt.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(opt.Endpoint))
+		}
+		if opt.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", opt.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(opt.ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer used by microshop instrumentation.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// RecordError marks the span as failed if err is not nil.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}